@@ -113,7 +113,15 @@ func NewLoadDatabaseCmd() *cobra.Command {
 
 			// TODO: check read-only flag in config before that?
 			typ, _ := cmd.Flags().GetString(flagLoadFormat)
-			if err = internal.Load(h.QuadWriter, quad.DefaultBatch, load, typ); err != nil {
+			if cp, _ := cmd.Flags().GetString("checkpoint"); cp != "" {
+				if typ != "" && typ != "nquads" {
+					return fmt.Errorf("resumable load is not supported for %q format", typ)
+				}
+				err = internal.LoadResumable(h.QuadWriter, quad.DefaultBatch, load, cp)
+			} else {
+				err = internal.Load(h.QuadWriter, quad.DefaultBatch, load, typ)
+			}
+			if err != nil {
 				return err
 			}
 
@@ -127,6 +135,7 @@ func NewLoadDatabaseCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().Bool("init", false, "initialize the database before using it")
+	cmd.Flags().String("checkpoint", "", "file to save the load progress to; if it exists, load resumes after the last committed batch (N-Quads only)")
 	registerLoadFlags(cmd)
 	registerDumpFlags(cmd)
 	return cmd
@@ -29,14 +29,10 @@ func main() {
 
 	path := filepath.Join(os.Getenv("GOPATH"), "src", *packageName)
 
-	fset := token.NewFileSet()
-	pkgs, err := parser.ParseDir(fset, path, nil, parser.ParseComments)
+	dp, err := loadPackage(path, *packageName)
 	if err != nil {
 		panic(err)
 	}
-	p := pkgs[filepath.Base(*packageName)]
-
-	dp := doc.New(p, *packageName, doc.AllDecls)
 
 	var w io.Writer = os.Stdout
 	if fname := *out; fname != "" && fname != "-" {
@@ -68,33 +64,68 @@ func main() {
 	}
 }
 
-func writeDocs(w io.Writer, dp *doc.Package) {
-	type Type struct {
-		Title string
-		Name  string
+func loadPackage(dir, name string) (*doc.Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := pkgs[filepath.Base(name)]
+	if !ok {
+		return nil, fmt.Errorf("package %q not found in %s", filepath.Base(name), dir)
+	}
+	return doc.New(p, name, doc.AllDecls), nil
+}
+
+// objectSuffix is a suffix of type names that are exposed as query objects.
+const objectSuffix = "Object"
+
+// titles overrides section titles for some query objects.
+var titles = map[string]string{
+	"graphObject": "The `graph` object",
+	"pathObject":  "Path object",
+}
+
+var reName = regexp.MustCompile("Name:\\s+`(\\w+)`")
+
+// objectName returns a name of the query object, as it's visible in the query language.
+// It can be set in type docs with "Name: `name`", or derived from the type name.
+func objectName(tp *doc.Type) string {
+	if sub := reName.FindStringSubmatch(tp.Doc); sub != nil {
+		return sub[1]
 	}
+	return strings.TrimSuffix(tp.Name, objectSuffix)
+}
 
-	names := map[string]Type{
-		"graphObject": {
-			Title: "The `graph` object",
-			Name:  "graph",
-		},
-		"pathObject": {
-			Title: "Path object",
-			Name:  "path",
-		},
+// isQueryObject checks if the type is exposed as a query object.
+func isQueryObject(tp *doc.Type) bool {
+	if tp.Name == objectSuffix || !strings.HasSuffix(tp.Name, objectSuffix) {
+		return false
+	}
+	for _, m := range tp.Methods {
+		if isExported(m.Name) {
+			return true
+		}
 	}
+	return false
+}
+
+func writeDocs(w io.Writer, dp *doc.Package) {
 	for _, tp := range dp.Types {
-		t, ok := names[tp.Name]
-		if !ok {
+		if !isQueryObject(tp) {
 			continue
 		}
+		name := objectName(tp)
+		title, ok := titles[tp.Name]
+		if !ok {
+			title = "The `" + name + "` object"
+		}
 		s := tp.Doc
 		if i := strings.IndexAny(s, "\n\r"); i >= 0 {
 			s = s[i+1:]
 		}
 		s = strings.TrimSpace(s)
-		fmt.Fprintf(w, "## %s\n\n", t.Title)
+		fmt.Fprintf(w, "## %s\n\n", title)
 		fmt.Fprintf(w, "%s\n\n", funcDocs(s))
 		for _, m := range tp.Methods {
 			if !isExported(m.Name) {
@@ -102,7 +133,7 @@ func writeDocs(w io.Writer, dp *doc.Package) {
 			}
 			m.Doc = strings.TrimSpace(m.Doc)
 			sig := Signature(m)
-			fmt.Fprintf(w, "### `%s.%s%s`\n\n%s\n\n", t.Name, m.Name, sig, funcDocs(m.Doc))
+			fmt.Fprintf(w, "### `%s.%s%s`\n\n%s\n\n", name, m.Name, sig, funcDocs(m.Doc))
 		}
 	}
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestWriteDocsGizmo(t *testing.T) {
+	dp, err := loadPackage("../../query/gizmo", "github.com/caivega/cayley/query/gizmo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	writeDocs(buf, dp)
+	out := buf.String()
+	for _, s := range []string{
+		"## The `graph` object\n",
+		"## Path object\n",
+		"### `graph.V(*)`\n",
+		"### `path.Limit(limit)`\n\nLimit limits a number of nodes for current path.",
+		"### `path.Skip(offset)`\n\nSkip skips a number of nodes for current path.",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected docs to contain %q", s)
+		}
+	}
+}
+
+const testSource = `package gizmo
+
+// pathObject is a Path object.
+type pathObject struct{}
+
+// Sample is a new path method.
+func (p *pathObject) Sample(n int) *pathObject { return p }
+
+func (p *pathObject) internal() {}
+
+// statsObject is a new query object.
+//
+// Name: ` + "`stats`" + `
+//
+// It reports statistics.
+type statsObject struct{}
+
+// Count returns a number of quads.
+func (s *statsObject) Count() int { return 0 }
+
+// helperObject has no exported methods.
+type helperObject struct{}
+
+func (h *helperObject) help() {}
+`
+
+func TestWriteDocsDiscover(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "gizmo.go", testSource, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &ast.Package{Name: "gizmo", Files: map[string]*ast.File{"gizmo.go": f}}
+	dp := doc.New(p, "github.com/caivega/cayley/query/gizmo", doc.AllDecls)
+	buf := bytes.NewBuffer(nil)
+	writeDocs(buf, dp)
+	exp := "## Path object\n\n" +
+		"TODO: docs\n\n" +
+		"### `path.Sample(n)`\n\nSample is a new path method.\n\n\n" +
+		"## The `stats` object\n\n" +
+		"Name: `stats`\n\nIt reports statistics.\n\n\n" +
+		"### `stats.Count()`\n\nCount returns a number of quads.\n\n\n"
+	if got := buf.String(); got != exp {
+		t.Errorf("unexpected docs:\n%s\nexpected:\n%s", got, exp)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	// supported backends
 	_ "github.com/caivega/cayley/graph/kv/bolt"
 	_ "github.com/caivega/cayley/graph/kv/btree"
+	_ "github.com/caivega/cayley/graph/kv/dynamodb"
 	_ "github.com/caivega/cayley/graph/kv/leveldb"
+	_ "github.com/caivega/cayley/graph/kv/redis"
 	_ "github.com/caivega/cayley/graph/memstore"
 	_ "github.com/caivega/cayley/graph/nosql/elastic"
 	_ "github.com/caivega/cayley/graph/nosql/mongo"
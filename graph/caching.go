@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/caivega/cayley/internal/lru"
+	"github.com/caivega/cayley/quad"
+)
+
+var (
+	_ QuadStore    = (*CachingQuadStore)(nil)
+	_ BatchDeleter = (*CachingQuadStore)(nil)
+)
+
+// NewCachingQuadStore wraps a quad store with an LRU cache for ValueOf and NameOf lookups.
+//
+// Cache entries of all values of written quads are invalidated on each write, thus all writes to the underlying
+// quad store must go through the wrapper to keep the cache consistent. If size <= 0, caching is disabled.
+//
+// Optional interfaces of the underlying quad store are available through Unwrap.
+func NewCachingQuadStore(qs QuadStore, size int) *CachingQuadStore {
+	c := &CachingQuadStore{QuadStore: qs}
+	if size > 0 {
+		c.ids = make(map[interface{}]valueKey)
+		c.values = lru.NewWithEvict(size, func(_, v interface{}) {
+			c.forget(v.(cacheEntry))
+		})
+	}
+	return c
+}
+
+// CachingQuadStore is a quad store wrapper that caches value <-> id mapping.
+type CachingQuadStore struct {
+	QuadStore
+	// values holds both directions of the mapping in a single entry, thus they are always evicted together.
+	values *lru.Cache // valueKey -> cacheEntry; nil if caching is disabled
+
+	mu  sync.Mutex
+	ids map[interface{}]valueKey // ToKey(id) -> key of an entry in values
+}
+
+// valueKey is a cache key of a value. It includes the type, since values of different types
+// may have the same string representation.
+type valueKey struct {
+	typ reflect.Type
+	str string
+}
+
+func valueCacheKey(v quad.Value) valueKey {
+	return valueKey{typ: reflect.TypeOf(v), str: v.String()}
+}
+
+// cacheEntry is a cached mapping between a value and its id.
+type cacheEntry struct {
+	key valueKey
+	id  Value
+	v   quad.Value
+}
+
+func (qs *CachingQuadStore) put(v quad.Value, id Value) {
+	e := cacheEntry{key: valueCacheKey(v), id: id, v: v}
+	qs.values.Put(e.key, e)
+	qs.mu.Lock()
+	qs.ids[ToKey(id)] = e.key
+	qs.mu.Unlock()
+}
+
+// forget removes the id of an entry that is no longer in the values cache.
+func (qs *CachingQuadStore) forget(e cacheEntry) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	k := ToKey(e.id)
+	if qs.ids[k] == e.key {
+		delete(qs.ids, k)
+	}
+}
+
+// ValueOf returns a value id from the cache or resolves it using an underlying quad store.
+func (qs *CachingQuadStore) ValueOf(v quad.Value) Value {
+	if v == nil {
+		return nil
+	} else if qs.values == nil {
+		return qs.QuadStore.ValueOf(v)
+	}
+	if e, ok := qs.values.Get(valueCacheKey(v)); ok {
+		return e.(cacheEntry).id
+	}
+	id := qs.QuadStore.ValueOf(v)
+	if id == nil {
+		// do not cache missing values, they may be added later
+		return nil
+	}
+	qs.put(v, id)
+	return id
+}
+
+// NameOf returns a value from the cache or resolves it using an underlying quad store.
+func (qs *CachingQuadStore) NameOf(id Value) quad.Value {
+	if id == nil {
+		return nil
+	} else if qs.values == nil {
+		return qs.QuadStore.NameOf(id)
+	}
+	k := ToKey(id)
+	qs.mu.Lock()
+	key, ok := qs.ids[k]
+	qs.mu.Unlock()
+	if ok {
+		if e, ok := qs.values.Get(key); ok && ToKey(e.(cacheEntry).id) == k {
+			return e.(cacheEntry).v
+		}
+	}
+	v := qs.QuadStore.NameOf(id)
+	if v == nil {
+		return nil
+	}
+	qs.put(v, id)
+	return v
+}
+
+// ApplyDeltas applies deltas to an underlying quad store and invalidates cache entries
+// for all values of changed quads.
+func (qs *CachingQuadStore) ApplyDeltas(in []Delta, opts IgnoreOpts) error {
+	// invalidate before and after the write, since concurrent readers may fill the cache in between
+	qs.invalidate(in)
+	err := qs.QuadStore.ApplyDeltas(in, opts)
+	qs.invalidate(in)
+	return err
+}
+
+// DeleteByPredicate implements BatchDeleter. It returns ErrOperationNotSupported if the underlying quad store
+// does not implement it.
+//
+// Nodes affected by the delete are not known in advance, thus the whole cache is reset.
+func (qs *CachingQuadStore) DeleteByPredicate(ctx context.Context, pred quad.Value) (int64, error) {
+	bd, ok := Unwrap(qs.QuadStore).(BatchDeleter)
+	if !ok {
+		return 0, ErrOperationNotSupported
+	}
+	qs.reset()
+	n, err := bd.DeleteByPredicate(ctx, pred)
+	qs.reset()
+	return n, err
+}
+
+func (qs *CachingQuadStore) reset() {
+	if qs.values == nil {
+		return
+	}
+	qs.values.Purge()
+	qs.mu.Lock()
+	qs.ids = make(map[interface{}]valueKey)
+	qs.mu.Unlock()
+}
+
+func (qs *CachingQuadStore) invalidate(in []Delta) {
+	if qs.values == nil {
+		return
+	}
+	for _, d := range in {
+		for _, dir := range quad.Directions {
+			v := d.Quad.Get(dir)
+			if v == nil {
+				continue
+			}
+			key := valueCacheKey(v)
+			if e, ok := qs.values.Get(key); ok {
+				qs.values.Del(key)
+				qs.forget(e.(cacheEntry))
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package graph_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/writer"
+)
+
+type countingStore struct {
+	graph.QuadStore
+	valueOf, nameOf int
+}
+
+func (qs *countingStore) ValueOf(v quad.Value) graph.Value {
+	qs.valueOf++
+	return qs.QuadStore.ValueOf(v)
+}
+
+func (qs *countingStore) NameOf(v graph.Value) quad.Value {
+	qs.nameOf++
+	return qs.QuadStore.NameOf(v)
+}
+
+func TestCachingQuadStore(t *testing.T) {
+	inner := &countingStore{QuadStore: memstore.New(
+		quad.MakeIRI("a", "follows", "b", ""),
+		quad.MakeIRI("b", "follows", "c", ""),
+	)}
+	qs := graph.NewCachingQuadStore(inner, 10)
+
+	a := qs.ValueOf(quad.IRI("a"))
+	if a == nil {
+		t.Fatal("expected value to be found")
+	}
+	for i := 0; i < 3; i++ {
+		if v := qs.ValueOf(quad.IRI("a")); v != a {
+			t.Fatalf("unexpected value: %v vs %v", v, a)
+		}
+		if v := qs.NameOf(a); v != quad.IRI("a") {
+			t.Fatalf("unexpected name: %v", v)
+		}
+	}
+	if inner.valueOf != 1 || inner.nameOf != 0 {
+		t.Fatalf("unexpected number of calls: %d, %d", inner.valueOf, inner.nameOf)
+	}
+
+	// missing values should not be cached
+	for i := 0; i < 2; i++ {
+		if v := qs.ValueOf(quad.IRI("d")); v != nil {
+			t.Fatalf("unexpected value: %v", v)
+		}
+	}
+	if inner.valueOf != 3 {
+		t.Fatalf("unexpected number of calls: %d", inner.valueOf)
+	}
+
+	err := qs.ApplyDeltas([]graph.Delta{
+		{Quad: quad.MakeIRI("a", "follows", "b", ""), Action: graph.Delete},
+	}, graph.IgnoreOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := qs.ValueOf(quad.IRI("a")); v != nil {
+		t.Fatalf("expected value to be removed, got: %v", v)
+	}
+	if inner.valueOf != 4 {
+		t.Fatalf("unexpected number of calls: %d", inner.valueOf)
+	}
+	if v := qs.NameOf(a); v != nil {
+		t.Fatalf("expected name to be removed, got: %v", v)
+	}
+	if inner.nameOf != 1 {
+		t.Fatalf("unexpected number of calls: %d", inner.nameOf)
+	}
+}
+
+func TestCachingQuadStoreOpts(t *testing.T) {
+	inner := memstore.New(
+		quad.Quad{Subject: quad.IRI("a"), Predicate: quad.IRI("name"), Object: quad.String("1")},
+		quad.Quad{Subject: quad.IRI("a"), Predicate: quad.IRI("age"), Object: quad.Int(1)},
+	)
+	// caching is disabled for a zero size
+	qs := graph.NewCachingQuadStore(inner, 0)
+	if v := qs.ValueOf(quad.IRI("a")); v == nil {
+		t.Fatal("expected value to be found")
+	}
+
+	// values with the same string representation, but a different type must not collide
+	qs = graph.NewCachingQuadStore(inner, 10)
+	for _, v := range []quad.Value{quad.String("1"), quad.Int(1), quad.String("1")} {
+		if got := qs.NameOf(qs.ValueOf(v)); got != v {
+			t.Errorf("unexpected value: %#v vs %#v", got, v)
+		}
+	}
+
+	// optional interfaces of the underlying store are not hidden
+	if _, ok := graph.Unwrap(qs).(graph.Stats); !ok {
+		t.Error("expected the underlying store to be unwrapped")
+	}
+}
+
+func TestCachingQuadStoreEvicted(t *testing.T) {
+	inner := &countingStore{QuadStore: memstore.New(
+		quad.MakeIRI("a", "follows", "b", ""),
+		quad.MakeIRI("c", "follows", "d", ""),
+	)}
+	qs := graph.NewCachingQuadStore(inner, 2)
+
+	a := qs.ValueOf(quad.IRI("a"))
+	qs.ValueOf(quad.IRI("b"))
+	qs.ValueOf(quad.IRI("c"))
+	// both directions of the mapping are evicted together
+	if v := qs.NameOf(a); v != quad.IRI("a") {
+		t.Fatalf("unexpected name: %v", v)
+	} else if inner.nameOf != 1 {
+		t.Fatalf("expected evicted name to be resolved again, got %d calls", inner.nameOf)
+	}
+
+	err := qs.ApplyDeltas([]graph.Delta{
+		{Quad: quad.MakeIRI("a", "follows", "b", ""), Action: graph.Delete},
+	}, graph.IgnoreOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := qs.NameOf(a); v != nil {
+		t.Fatalf("expected name to be removed, got: %v", v)
+	} else if v = qs.NameOf(qs.ValueOf(quad.IRI("c"))); v != quad.IRI("c") {
+		t.Fatalf("unexpected name: %v", v)
+	}
+}
+
+// batchStore implements BatchDeleter by writing deltas directly to the underlying store.
+type batchStore struct {
+	graph.QuadStore
+}
+
+func (qs batchStore) DeleteByPredicate(ctx context.Context, pred quad.Value) (int64, error) {
+	var del []graph.Delta
+	it := qs.QuadIterator(quad.Predicate, qs.ValueOf(pred))
+	defer it.Close()
+	for it.Next(ctx) {
+		del = append(del, graph.Delta{Quad: qs.Quad(it.Result()), Action: graph.Delete})
+	}
+	if err := it.Err(); err != nil {
+		return 0, err
+	}
+	return int64(len(del)), qs.QuadStore.ApplyDeltas(del, graph.IgnoreOpts{})
+}
+
+func TestCachingQuadStoreDeleteByPredicate(t *testing.T) {
+	inner := memstore.New(
+		quad.MakeIRI("a", "follows", "b", ""),
+		quad.MakeIRI("b", "status", "cool", ""),
+	)
+	qs := graph.NewCachingQuadStore(batchStore{inner}, 10)
+	w, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cool := qs.ValueOf(quad.IRI("cool"))
+	if cool == nil {
+		t.Fatal("expected value to be found")
+	}
+
+	n, err := graph.DeleteByPredicate(context.TODO(), qs, w, quad.IRI("status"))
+	if err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected number of deleted quads: %d", n)
+	}
+	if v := qs.ValueOf(quad.IRI("cool")); v != nil {
+		t.Fatalf("expected value to be removed, got: %v", v)
+	}
+	if v := qs.NameOf(cool); v != nil {
+		t.Fatalf("expected name to be removed, got: %v", v)
+	}
+}
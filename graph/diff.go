@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"context"
+	"sort"
+
+	"github.com/caivega/cayley/quad"
+)
+
+// Diff compares the contents of two quad stores and returns quads that are present only in the first
+// or only in the second store. Both lists are sorted in canonical order (see quad.ByQuadString).
+//
+// Stores are compared subject by subject: quads of each subject are loaded from both stores, sorted
+// and merge-joined, thus only quads of a single subject are held in memory at once, in addition to the results.
+func Diff(ctx context.Context, a, b QuadStore) (onlyA, onlyB []quad.Quad, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// all subjects of the first store, compared to the same subjects in the second one
+	err = eachNode(ctx, a, func(v quad.Value) error {
+		qa, err := subjectQuads(ctx, a, v)
+		if err != nil {
+			return err
+		} else if len(qa) == 0 {
+			return nil // not a subject; compared in the second pass, if necessary
+		}
+		qb, err := subjectQuads(ctx, b, v)
+		if err != nil {
+			return err
+		}
+		onlyA, onlyB = mergeDiff(onlyA, onlyB, qa, qb)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// subjects that exist only in the second store
+	err = eachNode(ctx, b, func(v quad.Value) error {
+		// node may exist in the first store without being a subject there, thus check quads instead of ValueOf
+		if ok, err := hasSubject(ctx, a, v); err != nil {
+			return err
+		} else if ok {
+			return nil // already compared
+		}
+		qb, err := subjectQuads(ctx, b, v)
+		if err != nil {
+			return err
+		}
+		onlyB = append(onlyB, qb...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Sort(quad.ByQuadString(onlyA))
+	sort.Sort(quad.ByQuadString(onlyB))
+	return onlyA, onlyB, nil
+}
+
+// eachNode calls a function for each node in the quad store, stopping on the first error.
+func eachNode(ctx context.Context, qs QuadStore, fnc func(v quad.Value) error) error {
+	it := qs.NodesAllIterator()
+	defer it.Close()
+	for it.Next(ctx) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		v := qs.NameOf(it.Result())
+		if v == nil {
+			continue
+		}
+		if err := fnc(v); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// subjectQuads returns all quads with a given subject, sorted in canonical order.
+func subjectQuads(ctx context.Context, qs QuadStore, v quad.Value) ([]quad.Quad, error) {
+	sv := qs.ValueOf(v)
+	if sv == nil {
+		return nil, nil
+	}
+	var out []quad.Quad
+	err := Iterate(ctx, qs.QuadIterator(quad.Subject, sv)).On(qs).Each(func(q Value) {
+		out = append(out, qs.Quad(q))
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(quad.ByQuadString(out))
+	return out, nil
+}
+
+// hasSubject checks if the quad store has at least one quad with a given subject.
+func hasSubject(ctx context.Context, qs QuadStore, v quad.Value) (bool, error) {
+	sv := qs.ValueOf(v)
+	if sv == nil {
+		return false, nil
+	}
+	it := qs.QuadIterator(quad.Subject, sv)
+	defer it.Close()
+	if it.Next(ctx) {
+		return true, nil
+	}
+	return false, it.Err()
+}
+
+// compareQuads compares quads in canonical order.
+func compareQuads(q1, q2 quad.Quad) int {
+	for _, d := range quad.Directions {
+		s1, s2 := quad.StringOf(q1.Get(d)), quad.StringOf(q2.Get(d))
+		if s1 < s2 {
+			return -1
+		} else if s1 > s2 {
+			return +1
+		}
+	}
+	return 0
+}
+
+// mergeDiff merge-joins two sorted lists of quads and appends quads present only in one of them
+// to the corresponding output list.
+func mergeDiff(onlyA, onlyB, qa, qb []quad.Quad) ([]quad.Quad, []quad.Quad) {
+	for len(qa) != 0 && len(qb) != 0 {
+		switch c := compareQuads(qa[0], qb[0]); {
+		case c < 0:
+			onlyA = append(onlyA, qa[0])
+			qa = qa[1:]
+		case c > 0:
+			onlyB = append(onlyB, qb[0])
+			qb = qb[1:]
+		default:
+			qa, qb = qa[1:], qb[1:]
+		}
+	}
+	onlyA = append(onlyA, qa...)
+	onlyB = append(onlyB, qb...)
+	return onlyA, onlyB
+}
@@ -0,0 +1,81 @@
+package graph_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+)
+
+func TestDiff(t *testing.T) {
+	var (
+		shared1 = quad.MakeIRI("a", "follows", "b", "")
+		shared2 = quad.MakeIRI("b", "follows", "c", "g")
+		onlyA   = quad.MakeIRI("a", "follows", "c", "")
+		onlyB1  = quad.MakeIRI("b", "follows", "c", "")
+		onlyB2  = quad.MakeIRI("d", "follows", "a", "")
+	)
+	a := memstore.New(shared1, onlyA, shared2)
+	b := memstore.New(onlyB2, shared2, onlyB1, shared1)
+
+	gotA, gotB, err := graph.Diff(context.TODO(), a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []quad.Quad{onlyA}; !reflect.DeepEqual(gotA, exp) {
+		t.Errorf("unexpected quads in the first store:\n%v\nvs\n%v", gotA, exp)
+	}
+	if exp := []quad.Quad{onlyB1, onlyB2}; !reflect.DeepEqual(gotB, exp) {
+		t.Errorf("unexpected quads in the second store:\n%v\nvs\n%v", gotB, exp)
+	}
+
+	gotA, gotB, err = graph.Diff(context.TODO(), a, a)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(gotA) != 0 || len(gotB) != 0 {
+		t.Errorf("expected no difference, got: %v, %v", gotA, gotB)
+	}
+}
+
+// anyValueStore resolves all values to non-nil ids, the same way SQL and NoSQL backends do.
+type anyValueStore struct {
+	graph.QuadStore
+}
+
+type missingValue struct {
+	v quad.Value
+}
+
+func (v missingValue) Key() interface{} { return v }
+
+func (qs anyValueStore) ValueOf(v quad.Value) graph.Value {
+	if id := qs.QuadStore.ValueOf(v); id != nil {
+		return id
+	}
+	return missingValue{v: v}
+}
+
+func TestDiffAnyValue(t *testing.T) {
+	var (
+		shared = quad.MakeIRI("a", "follows", "b", "")
+		onlyA  = quad.MakeIRI("c", "follows", "a", "")
+		onlyB1 = quad.MakeIRI("b", "follows", "a", "")
+		onlyB2 = quad.MakeIRI("d", "follows", "b", "")
+	)
+	a := anyValueStore{memstore.New(shared, onlyA)}
+	b := anyValueStore{memstore.New(onlyB2, shared, onlyB1)}
+
+	gotA, gotB, err := graph.Diff(context.TODO(), a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []quad.Quad{onlyA}; !reflect.DeepEqual(gotA, exp) {
+		t.Errorf("unexpected quads in the first store:\n%v\nvs\n%v", gotA, exp)
+	}
+	if exp := []quad.Quad{onlyB1, onlyB2}; !reflect.DeepEqual(gotB, exp) {
+		t.Errorf("unexpected quads in the second store:\n%v\nvs\n%v", gotB, exp)
+	}
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/quad/nquads"
+)
+
+// ExportProgressEvery is the number of quads written by ExportNQuads between progress callbacks.
+var ExportProgressEvery int64 = 10000
+
+// ExportNQuads writes all quads from the quad store to w in N-Quads format.
+//
+// Progress callback (if set) is called with a total number of written quads every
+// ExportProgressEvery quads, and once more when the export is finished.
+// Export stops early if the context is cancelled.
+func ExportNQuads(ctx context.Context, qs QuadStore, w io.Writer, progress func(n int64)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	it := qs.QuadsAllIterator()
+	defer it.Close()
+
+	bw := bufio.NewWriter(w)
+	qw := nquads.NewWriter(bw)
+	var n int64
+	for it.Next(ctx) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := qw.WriteQuad(qs.Quad(it.Result())); err != nil {
+			return err
+		}
+		n++
+		if progress != nil && ExportProgressEvery > 0 && n%ExportProgressEvery == 0 {
+			progress(n)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	} else if err = ctx.Err(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if progress != nil && (ExportProgressEvery <= 0 || n%ExportProgressEvery != 0) {
+		progress(n)
+	}
+	return nil
+}
+
+// ExportSharded writes all quads from the quad store in N-Quads format to a given number of shards,
+// which are serialized concurrently. Open function is called once for each shard to get its writer.
+//
+// Quads are partitioned by a hash of the subject, thus all quads of a single subject are written to the same shard.
+// Each shard is a valid N-Quads file, and the union of all shards equals to the full content of the quad store.
+// Export stops early if the context is cancelled or if any of the writers fails.
+func ExportSharded(ctx context.Context, qs QuadStore, shards int, open func(i int) io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if shards <= 0 {
+		return fmt.Errorf("invalid number of shards: %d", shards)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		errMu   sync.Mutex
+		lastErr error
+	)
+	setErr := func(err error) {
+		errMu.Lock()
+		if lastErr == nil {
+			lastErr = err
+		}
+		errMu.Unlock()
+		cancel()
+	}
+	chans := make([]chan quad.Quad, shards)
+	for i := range chans {
+		ch := make(chan quad.Quad, 128)
+		chans[i] = ch
+		w := open(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bw := bufio.NewWriter(w)
+			qw := nquads.NewWriter(bw)
+			for q := range ch {
+				if err := qw.WriteQuad(q); err != nil {
+					setErr(err)
+					// drain the channel to unblock the reader
+					for range ch {
+					}
+					return
+				}
+			}
+			if err := bw.Flush(); err != nil {
+				setErr(err)
+			}
+		}()
+	}
+
+	it := qs.QuadsAllIterator()
+	err := func() error {
+		defer it.Close()
+		for it.Next(ctx) {
+			q := qs.Quad(it.Result())
+			h := HashOf(q.Subject)
+			i := int(binary.BigEndian.Uint64(h[:8]) % uint64(shards))
+			select {
+			case chans[i] <- q:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}()
+	for _, ch := range chans {
+		close(ch)
+	}
+	wg.Wait()
+	if lastErr != nil {
+		return lastErr
+	}
+	return err
+}
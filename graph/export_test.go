@@ -0,0 +1,99 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/quad/nquads"
+)
+
+func TestExportNQuads(t *testing.T) {
+	var quads []quad.Quad
+	for i := 0; i < 25; i++ {
+		quads = append(quads, quad.MakeIRI(fmt.Sprintf("n%d", i), "follows", fmt.Sprintf("n%d", i+1), ""))
+	}
+	qs := memstore.New(quads...)
+
+	defer func(v int64) {
+		graph.ExportProgressEvery = v
+	}(graph.ExportProgressEvery)
+	graph.ExportProgressEvery = 10
+
+	var (
+		buf   bytes.Buffer
+		calls []int64
+	)
+	err := graph.ExportNQuads(context.TODO(), qs, &buf, func(n int64) {
+		calls = append(calls, n)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []int64{10, 20, 25}; !reflect.DeepEqual(calls, exp) {
+		t.Fatalf("unexpected progress calls: %v", calls)
+	}
+	got, err := quad.ReadAll(nquads.NewReader(&buf, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Sort(quad.ByQuadString(quads))
+	sort.Sort(quad.ByQuadString(got))
+	if !reflect.DeepEqual(got, quads) {
+		t.Fatalf("unexpected quads exported:\n%v\n%v", got, quads)
+	}
+
+	// cancel after the first progress callback
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	buf.Reset()
+	calls = nil
+	err = graph.ExportNQuads(ctx, qs, &buf, func(n int64) {
+		calls = append(calls, n)
+		cancel()
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected cancellation error, got: %v", err)
+	}
+	if exp := []int64{10}; !reflect.DeepEqual(calls, exp) {
+		t.Fatalf("unexpected progress calls: %v", calls)
+	}
+}
+
+func TestExportSharded(t *testing.T) {
+	var quads []quad.Quad
+	for i := 0; i < 50; i++ {
+		quads = append(quads, quad.MakeIRI(fmt.Sprintf("n%d", i), "follows", fmt.Sprintf("n%d", i+1), ""))
+	}
+	qs := memstore.New(quads...)
+
+	bufs := make([]bytes.Buffer, 2)
+	err := graph.ExportSharded(context.TODO(), qs, len(bufs), func(i int) io.Writer {
+		return &bufs[i]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []quad.Quad
+	for i := range bufs {
+		part, err := quad.ReadAll(nquads.NewReader(&bufs[i], false))
+		if err != nil {
+			t.Fatal(err)
+		} else if len(part) == 0 {
+			t.Errorf("shard %d is empty", i)
+		}
+		got = append(got, part...)
+	}
+	sort.Sort(quad.ByQuadString(quads))
+	sort.Sort(quad.ByQuadString(got))
+	if !reflect.DeepEqual(got, quads) {
+		t.Fatalf("unexpected quads exported:\n%v\n%v", got, quads)
+	}
+}
@@ -2,6 +2,7 @@ package graphtest
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"sort"
 	"testing"
@@ -52,6 +53,13 @@ var graphTests = []struct {
 	{"load typed quad", TestLoadTypedQuads},
 	{"add and remove", TestAddRemove},
 	{"node delete", TestNodeDelete},
+	{"delete by predicate", TestDeleteByPredicate},
+	{"delete by predicate cached", TestDeleteByPredicateCached},
+	{"delete label", TestDeleteLabel},
+	{"rename predicate", TestRenamePredicate},
+	{"predicate stats", TestPredicateStats},
+	{"count by label", TestCountByLabel},
+	{"batch values", TestBatchValues},
 	{"iterators and next result order", TestIteratorsAndNextResultOrderA},
 	{"compare typed values", TestCompareTypedValues},
 	{"schema", TestSchema},
@@ -84,6 +92,9 @@ func BenchmarkAll(t *testing.B, gen testutil.DatabaseFunc, conf *Config) {
 	t.Run("integration", func(t *testing.B) {
 		BenchmarkIntegration(t, gen, conf.AlwaysRunIntegration)
 	})
+	t.Run("names of", func(t *testing.B) {
+		BenchmarkNamesOf(t, gen, conf)
+	})
 }
 
 // This is a simple test graph.
@@ -1060,6 +1071,224 @@ func TestNodeDelete(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
 	}, true)
 }
 
+func TestDeleteByPredicate(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	w := testutil.MakeWriter(t, qs, opts, MakeQuadSet()...)
+
+	n, err := graph.DeleteByPredicate(context.TODO(), qs, w, quad.Raw("status"))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	var exp []quad.Quad
+	for _, q := range MakeQuadSet() {
+		if q.Predicate != quad.Raw("status") {
+			exp = append(exp, q)
+		}
+	}
+	ExpectIteratedQuads(t, qs, qs.QuadsAllIterator(), exp, true)
+
+	n, err = graph.DeleteByPredicate(context.TODO(), qs, w, quad.Raw("status"))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+	ExpectIteratedQuads(t, qs, qs.QuadsAllIterator(), exp, true)
+}
+
+func TestDeleteByPredicateCached(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	cqs := graph.NewCachingQuadStore(qs, 100)
+	w := testutil.MakeWriter(t, cqs, opts, MakeQuadSet()...)
+
+	vals := []quad.Value{quad.Raw("status"), quad.Raw("cool"), quad.Raw("status_graph"), quad.Raw("B")}
+	for _, v := range vals {
+		// warm up the cache
+		id := cqs.ValueOf(v)
+		require.NotNil(t, id, "%v", v)
+		require.Equal(t, v, cqs.NameOf(id))
+	}
+
+	n, err := graph.DeleteByPredicate(context.TODO(), cqs, w, quad.Raw("status"))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	for _, v := range vals {
+		exp, got := qs.ValueOf(v), cqs.ValueOf(v)
+		if exp == nil {
+			require.Nil(t, got, "%v", v)
+			continue
+		}
+		require.NotNil(t, got, "%v", v)
+		require.Equal(t, graph.ToKey(exp), graph.ToKey(got), "%v", v)
+		require.Equal(t, qs.NameOf(exp), cqs.NameOf(got), "%v", v)
+	}
+
+	var exp []quad.Quad
+	for _, q := range MakeQuadSet() {
+		if q.Predicate != quad.Raw("status") {
+			exp = append(exp, q)
+		}
+	}
+	ExpectIteratedQuads(t, cqs, cqs.QuadsAllIterator(), exp, true)
+}
+
+func TestDeleteLabel(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	w := testutil.MakeWriter(t, qs, opts, MakeQuadSet()...)
+	other := quad.Make("E", "status", "smart", "smart_graph")
+	err := w.AddQuad(other)
+	require.NoError(t, err)
+
+	label := quad.String("status_graph")
+	exp := []quad.Quad{other}
+	for _, q := range MakeQuadSet() {
+		if q.Label != label {
+			exp = append(exp, q)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err = graph.DeleteLabel(ctx, qs, w, label)
+	require.Equal(t, context.Canceled, err)
+
+	n, err := graph.DeleteLabel(context.TODO(), qs, w, label)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	ExpectIteratedQuads(t, qs, qs.QuadsAllIterator(), exp, true)
+
+	n, err = graph.DeleteLabel(context.TODO(), qs, w, label)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+	ExpectIteratedQuads(t, qs, qs.QuadsAllIterator(), exp, true)
+}
+
+func TestRenamePredicate(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	w := testutil.MakeWriter(t, qs, opts, MakeQuadSet()...)
+
+	var exp []quad.Quad
+	for _, q := range MakeQuadSet() {
+		if q.Predicate == quad.Raw("status") {
+			q.Predicate = quad.Raw("state")
+		}
+		exp = append(exp, q)
+	}
+
+	n, err := graph.RenamePredicate(context.TODO(), qs, w, quad.Raw("status"), quad.Raw("state"))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	ExpectIteratedQuads(t, qs, qs.QuadsAllIterator(), exp, true)
+
+	n, err = graph.RenamePredicate(context.TODO(), qs, w, quad.Raw("status"), quad.Raw("state"))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+	ExpectIteratedQuads(t, qs, qs.QuadsAllIterator(), exp, true)
+}
+
+func TestPredicateStats(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	w := testutil.MakeWriter(t, qs, opts, MakeQuadSet()...)
+
+	st, err := graph.PredicateStats(qs)
+	if err == graph.ErrOperationNotSupported {
+		t.SkipNow()
+	}
+	require.NoError(t, err)
+	exp := make(map[quad.Value]int64)
+	for _, q := range MakeQuadSet() {
+		exp[q.Predicate]++
+	}
+	require.Equal(t, exp, st)
+
+	rm := quad.Make("G", "status", "cool", "status_graph")
+	err = w.RemoveQuad(rm)
+	require.NoError(t, err)
+	exp[rm.Predicate]--
+
+	st, err = graph.PredicateStats(qs)
+	require.NoError(t, err)
+	require.Equal(t, exp, st)
+}
+
+func TestCountByLabel(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	w := testutil.MakeWriter(t, qs, opts, MakeQuadSet()...)
+	err := w.AddQuad(quad.Make("E", "status", "smart", "smart_graph"))
+	require.NoError(t, err)
+
+	st, err := graph.CountByLabel(nil, qs)
+	if err == graph.ErrOperationNotSupported {
+		t.SkipNow()
+	}
+	require.NoError(t, err)
+	exp := map[quad.Value]int64{
+		nil:                         8,
+		quad.String("status_graph"): 3,
+		quad.String("smart_graph"):  1,
+	}
+	require.Equal(t, exp, st)
+
+	err = w.RemoveQuad(quad.Make("G", "status", "cool", "status_graph"))
+	require.NoError(t, err)
+	exp[quad.String("status_graph")]--
+
+	st, err = graph.CountByLabel(nil, qs)
+	require.NoError(t, err)
+	require.Equal(t, exp, st)
+}
+
+func TestBatchValues(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	testutil.MakeWriter(t, qs, opts, MakeQuadSet()...)
+
+	ctx := context.TODO()
+	nodes := []quad.Value{
+		quad.String("A"), quad.String("B"), quad.String("follows"),
+		quad.String("status_graph"), quad.String("B"), quad.String("unknown"),
+	}
+
+	exp := make([]graph.Value, len(nodes))
+	for i, v := range nodes {
+		exp[i] = qs.ValueOf(v)
+	}
+	got, err := graph.LookupValues(ctx, qs, nodes)
+	require.NoError(t, err)
+	require.Equal(t, exp, got)
+
+	// resolve values that are known to the store, plus an unknown one
+	refs := append([]graph.Value{}, got[:len(got)-1]...)
+	refs = append(refs, nil)
+	if unk := got[len(got)-1]; unk != nil {
+		refs = append(refs, unk)
+	}
+	names := make([]quad.Value, len(refs))
+	for i, v := range refs {
+		names[i] = qs.NameOf(v)
+	}
+	got2, err := graph.ValuesOf(ctx, qs, refs)
+	require.NoError(t, err)
+	require.Equal(t, names, got2)
+	for _, v := range got2[:len(nodes)-1] {
+		require.NotNil(t, v)
+	}
+	for _, v := range got2[len(nodes)-1:] {
+		require.Nil(t, v)
+	}
+}
+
 func TestSchema(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
 	qs, opts, closer := gen(t)
 	defer closer()
@@ -1091,3 +1320,43 @@ func TestSchema(t testing.TB, gen testutil.DatabaseFunc, conf *Config) {
 	require.NoError(t, err)
 	require.Equal(t, p, p2)
 }
+
+func BenchmarkNamesOf(b *testing.B, gen testutil.DatabaseFunc, conf *Config) {
+	qs, opts, closer := gen(b)
+	defer closer()
+
+	// use more nodes than backends usually cache
+	const n = 2048
+	quads := make([]quad.Quad, 0, n)
+	nodes := make([]quad.Value, 0, n)
+	for i := 0; i < n; i++ {
+		node := quad.String(fmt.Sprintf("node%d", i))
+		quads = append(quads, quad.Make(node, "is", "node", nil))
+		nodes = append(nodes, node)
+	}
+	testutil.MakeWriter(b, qs, opts, quads...)
+
+	ctx := context.TODO()
+	refs, err := graph.LookupValues(ctx, qs, nodes)
+	require.NoError(b, err)
+
+	b.Run("loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, v := range refs {
+				if qs.NameOf(v) == nil {
+					b.Fatal("value not found")
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			vals, err := graph.ValuesOf(ctx, qs, refs)
+			if err != nil {
+				b.Fatal(err)
+			} else if vals[len(vals)-1] == nil {
+				b.Fatal("value not found")
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package graphtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/quad"
+)
+
+// Op is a single call recorded by Recorder.
+type Op struct {
+	Method string        // name of the QuadStore method
+	Args   []interface{} // arguments of the call
+}
+
+var (
+	_ graph.QuadStore   = (*Recorder)(nil)
+	_ graph.BatchValuer = (*Recorder)(nil)
+)
+
+// Recorder is a QuadStore that delegates all calls to another store and records them for later assertions.
+// It is safe for concurrent use.
+//
+// Recorder implements BatchValuer, thus batch lookups are recorded as a single RefsOf or NamesOf call,
+// even if the underlying store resolves them one by one. Other optional interfaces of the store are hidden.
+type Recorder struct {
+	qs graph.QuadStore
+
+	mu  sync.Mutex
+	ops []Op
+}
+
+// NewRecorder wraps a quad store with a Recorder.
+func NewRecorder(qs graph.QuadStore) *Recorder {
+	return &Recorder{qs: qs}
+}
+
+func (r *Recorder) record(method string, args ...interface{}) {
+	r.mu.Lock()
+	r.ops = append(r.ops, Op{Method: method, Args: args})
+	r.mu.Unlock()
+}
+
+// Ops returns all recorded calls in the order they were made.
+func (r *Recorder) Ops() []Op {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Op(nil), r.ops...)
+}
+
+// Count returns the number of recorded calls of a given method.
+func (r *Recorder) Count(method string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, op := range r.ops {
+		if op.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset clears the log of recorded calls.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.ops = nil
+	r.mu.Unlock()
+}
+
+func (r *Recorder) ApplyDeltas(in []graph.Delta, opts graph.IgnoreOpts) error {
+	r.record("ApplyDeltas", append([]graph.Delta(nil), in...), opts)
+	return r.qs.ApplyDeltas(in, opts)
+}
+
+func (r *Recorder) Quad(v graph.Value) quad.Quad {
+	r.record("Quad", v)
+	return r.qs.Quad(v)
+}
+
+func (r *Recorder) QuadIterator(d quad.Direction, v graph.Value) graph.Iterator {
+	r.record("QuadIterator", d, v)
+	return r.qs.QuadIterator(d, v)
+}
+
+func (r *Recorder) NodesAllIterator() graph.Iterator {
+	r.record("NodesAllIterator")
+	return r.qs.NodesAllIterator()
+}
+
+func (r *Recorder) QuadsAllIterator() graph.Iterator {
+	r.record("QuadsAllIterator")
+	return r.qs.QuadsAllIterator()
+}
+
+func (r *Recorder) ValueOf(v quad.Value) graph.Value {
+	r.record("ValueOf", v)
+	return r.qs.ValueOf(v)
+}
+
+func (r *Recorder) NameOf(v graph.Value) quad.Value {
+	r.record("NameOf", v)
+	return r.qs.NameOf(v)
+}
+
+func (r *Recorder) RefsOf(ctx context.Context, vals []quad.Value) ([]graph.Value, error) {
+	r.record("RefsOf", append([]quad.Value(nil), vals...))
+	return graph.LookupValues(ctx, r.qs, vals)
+}
+
+func (r *Recorder) NamesOf(ctx context.Context, vals []graph.Value) ([]quad.Value, error) {
+	r.record("NamesOf", append([]graph.Value(nil), vals...))
+	return graph.ValuesOf(ctx, r.qs, vals)
+}
+
+func (r *Recorder) Size() int64 {
+	r.record("Size")
+	return r.qs.Size()
+}
+
+func (r *Recorder) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool) {
+	r.record("OptimizeIterator", it)
+	return r.qs.OptimizeIterator(it)
+}
+
+func (r *Recorder) Close() error {
+	r.record("Close")
+	return r.qs.Close()
+}
+
+func (r *Recorder) QuadDirection(v graph.Value, d quad.Direction) graph.Value {
+	r.record("QuadDirection", v, d)
+	return r.qs.QuadDirection(v, d)
+}
@@ -0,0 +1,51 @@
+package graphtest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/graphtest"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	q := quad.MakeIRI("a", "follows", "b", "")
+	r := graphtest.NewRecorder(memstore.New())
+
+	deltas := []graph.Delta{{Quad: q, Action: graph.Add}}
+	require.NoError(t, r.ApplyDeltas(deltas, graph.IgnoreOpts{}))
+
+	a := r.ValueOf(quad.IRI("a"))
+	require.NotNil(t, a)
+	require.Equal(t, quad.IRI("a"), r.NameOf(a))
+
+	it := r.QuadIterator(quad.Subject, a)
+	require.True(t, it.Next(context.TODO()))
+	require.Equal(t, q, r.Quad(it.Result()))
+	require.NoError(t, it.Close())
+
+	vals, err := graph.ValuesOf(context.TODO(), r, []graph.Value{a})
+	require.NoError(t, err)
+	require.Equal(t, []quad.Value{quad.IRI("a")}, vals)
+
+	ops := r.Ops()
+	methods := make([]string, 0, len(ops))
+	for _, op := range ops {
+		methods = append(methods, op.Method)
+	}
+	require.Equal(t, []string{
+		"ApplyDeltas", "ValueOf", "NameOf", "QuadIterator", "Quad", "NamesOf",
+	}, methods)
+	require.Equal(t, []interface{}{deltas, graph.IgnoreOpts{}}, ops[0].Args)
+	require.Equal(t, []interface{}{quad.IRI("a")}, ops[1].Args)
+	require.Equal(t, []interface{}{quad.Subject, a}, ops[3].Args)
+
+	require.Equal(t, 1, r.Count("ValueOf"))
+	require.Equal(t, 0, r.Count("NodesAllIterator"))
+
+	r.Reset()
+	require.Empty(t, r.Ops())
+}
@@ -365,6 +365,32 @@ func (c *IterateChain) SendValues(qs QuadStore, out chan<- quad.Value) error {
 	return c.it.Err()
 }
 
+// IterateValues runs the iterator in a separate goroutine, resolves each result with NameOf and
+// sends it to the returned channel. Channel is closed when iteration ends or the context is cancelled.
+//
+// The returned function reports an iteration error. It blocks until the iteration ends, thus it
+// should be called after the channel is closed.
+func IterateValues(ctx context.Context, qs QuadStore, it Iterator) (<-chan quad.Value, func() error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := make(chan quad.Value)
+	done := make(chan struct{})
+	var err error
+	go func() {
+		defer close(out)
+		err = Iterate(ctx, it).On(qs).SendValues(nil, out)
+		if err == nil {
+			err = ctx.Err()
+		}
+		close(done)
+	}()
+	return out, func() error {
+		<-done
+		return err
+	}
+}
+
 // TagValues is an analog of TagEach, but it will additionally call NameOf
 // for each graph.Value before passing the map to a callback.
 func (c *IterateChain) TagValues(qs QuadStore, fnc func(map[string]quad.Value)) error {
@@ -0,0 +1,65 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+)
+
+func TestIterateValues(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("a", "follows", "b", ""),
+		quad.MakeIRI("b", "follows", "c", ""),
+	)
+	ch, errf := graph.IterateValues(context.TODO(), qs, qs.NodesAllIterator())
+	var got []string
+	for v := range ch {
+		got = append(got, v.String())
+	}
+	if err := errf(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if exp := []string{"<a>", "<b>", "<c>", "<follows>"}; fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("unexpected values: %v, expected: %v", got, exp)
+	}
+}
+
+func TestIterateValuesCancel(t *testing.T) {
+	var quads []quad.Quad
+	for i := 0; i < 100; i++ {
+		quads = append(quads, quad.MakeIRI(fmt.Sprintf("n%d", i), "follows", fmt.Sprintf("n%d", i+1), ""))
+	}
+	qs := memstore.New(quads...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, errf := graph.IterateValues(ctx, qs, qs.NodesAllIterator())
+	if v := <-ch; v == nil {
+		t.Fatal("expected a value")
+	}
+	cancel()
+
+	// the channel must be closed without reading all the values
+	timeout := time.After(time.Second)
+	for n := 0; ; n++ {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				if err := errf(); err != context.Canceled {
+					t.Fatalf("expected cancellation error, got: %v", err)
+				}
+				return
+			} else if n > 0 {
+				t.Fatal("iteration continued after cancellation")
+			}
+		case <-timeout:
+			t.Fatal("channel was not closed")
+		}
+	}
+}
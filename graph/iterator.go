@@ -19,11 +19,19 @@ package graph
 import (
 	"context"
 	"strings"
+	"sync/atomic"
 
 	"github.com/caivega/cayley/clog"
 	"github.com/caivega/cayley/quad"
 )
 
+var nextIteratorID uint64 = 1
+
+// NextUID returns a new unique iterator ID.
+func NextUID() uint64 {
+	return atomic.AddUint64(&nextIteratorID, 1) - 1
+}
+
 type Tagger struct {
 	tags      []string
 	fixedTags map[string]Value
@@ -271,6 +279,9 @@ const (
 	Skip        = Type("skip")
 	Regex       = Type("regexp")
 	Count       = Type("count")
+	Reduce      = Type("reduce")
+	SavePath    = Type("savepath")
+	ContainedIn = Type("containedin")
 	Recursive   = Type("recursive")
 )
 
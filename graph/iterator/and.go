@@ -32,10 +32,12 @@ type And struct {
 	itCount           int
 	primaryIt         graph.Iterator
 	checkList         []graph.Iterator
+	stats             []graph.IteratorStats // cached stats of subiterators; see subStats
 	result            graph.Value
 	runstats          graph.IteratorStats
 	err               error
 	qs                graph.QuadStore
+	planner           Planner
 }
 
 // NewAnd creates an And iterator. `qs` is only required when needing a handle
@@ -52,6 +54,12 @@ func NewAnd(qs graph.QuadStore, sub ...graph.Iterator) *And {
 	return it
 }
 
+// SetPlanner sets a planner that chooses the execution order of subiterators on Optimize.
+// If not set, DefaultPlanner is used.
+func (it *And) SetPlanner(p Planner) {
+	it.planner = p
+}
+
 func (it *And) UID() uint64 {
 	return it.uid
 }
@@ -85,11 +93,14 @@ func (it *And) TagResults(dst map[string]graph.Value) {
 
 func (it *And) Clone() graph.Iterator {
 	and := NewAnd(it.qs)
+	and.planner = it.planner
 	and.AddSubIterator(it.primaryIt.Clone())
 	and.tags.CopyFrom(it)
 	for _, sub := range it.internalIterators {
 		and.AddSubIterator(sub.Clone())
 	}
+	// clones report the same stats as the originals
+	and.stats = it.stats
 	if it.checkList != nil {
 		and.optimizeContains()
 	}
@@ -117,6 +128,7 @@ func (it *And) String() string {
 // subiterator statistics. Without Optimize(), the order added is the order
 // used.
 func (it *And) AddSubIterator(sub graph.Iterator) {
+	it.stats = nil
 	if it.itCount > 0 {
 		it.internalIterators = append(it.internalIterators, sub)
 		it.itCount++
@@ -67,11 +67,18 @@ func (it *And) Optimize() (graph.Iterator, bool) {
 		return out, true
 	}
 
+	// Limit iterators ignore the limit on Contains(), thus they must be materialized
+	// to be safely reordered.
+	its = materializeLimits(its)
+
+	// Stats are requested many times while reordering, thus compute them only once.
+	stats := statsForIterators(its)
+
 	// And now, without changing any of the iterators, we reorder them. it_list is
 	// now a permutation of itself, but the contents are unchanged.
-	its = it.optimizeOrder(its)
+	its, stats = it.optimizeOrder(its, stats)
 
-	its = materializeIts(its)
+	its, stats = materializeIts(its, stats)
 
 	// Okay! At this point we have an optimized order.
 
@@ -83,6 +90,8 @@ func (it *And) Optimize() (graph.Iterator, bool) {
 	for _, sub := range its {
 		newAnd.AddSubIterator(sub)
 	}
+	newAnd.stats = stats
+	newAnd.planner = it.planner
 
 	// Move the tags hanging on us (like any good replacement).
 	newAnd.tags.CopyFrom(it)
@@ -150,80 +159,50 @@ func (*And) optimizeReplacement(its []graph.Iterator) graph.Iterator {
 	return nil
 }
 
-// optimizeOrder(l) takes a list and returns a list, containing the same contents
-// but with a new ordering, however it wishes.
-func (it *And) optimizeOrder(its []graph.Iterator) []graph.Iterator {
-	var (
-		// bad contains iterators that can't be (efficiently) nexted, such as
-		// graph.Optional or graph.Not. Separate them out and tack them on at the end.
-		bad      []graph.Iterator
-		best     graph.Iterator
-		bestCost = int64(1 << 62)
-	)
-
-	// Find the iterator with the projected "best" total cost.
-	// Total cost is defined as The Next()ed iterator's cost to Next() out
-	// all of it's contents, and to Contains() each of those against everyone
-	// else.
-	for _, root := range its {
-		if !graph.CanNext(root) {
-			bad = append(bad, root)
-			continue
-		}
-		rootStats := root.Stats()
-		cost := rootStats.NextCost
-		for _, f := range its {
-			if !graph.CanNext(f) {
-				continue
-			}
-			if f == root {
-				continue
-			}
-			stats := f.Stats()
-			cost += stats.ContainsCost * (1 + (rootStats.Size / (stats.Size + 1)))
-		}
-		cost *= rootStats.Size
-		if clog.V(3) {
-			clog.Infof("And: %v Root: %v Total Cost: %v Best: %v", it.UID(), root.UID(), cost, bestCost)
-		}
-		if cost < bestCost {
-			best = root
-			bestCost = cost
-		}
-	}
-	if clog.V(3) {
-		clog.Infof("And: %v Choosing: %v Best: %v", it.UID(), best.UID(), bestCost)
+// statsForIterators returns the stats for each iterator in the list.
+func statsForIterators(its []graph.Iterator) []graph.IteratorStats {
+	stats := make([]graph.IteratorStats, len(its))
+	for i, sub := range its {
+		stats[i] = sub.Stats()
 	}
+	return stats
+}
 
-	// TODO(barakmich): Optimization of order need not stop here. Picking a smart
-	// Contains() order based on probability of getting a false Contains() first is
-	// useful (fail faster).
-
-	var out []graph.Iterator
-	// Put the best iterator (the one we wish to Next()) at the front...
-	if best != nil {
-		out = append(out, best)
+// optimizeOrder(l) takes a list and returns a list, containing the same contents
+// but with a new ordering chosen by the planner. Stats of each iterator must be
+// passed in the same order and are returned reordered as well.
+func (it *And) optimizeOrder(its []graph.Iterator, stats []graph.IteratorStats) ([]graph.Iterator, []graph.IteratorStats) {
+	p := it.planner
+	if p == nil && !graph.CanStatsOptimize(it.qs) {
+		p = WrittenPlanner{}
+	} else if p == nil {
+		p = DefaultPlanner
 	}
-
-	// ... push everyone else after...
-	for _, it := range its {
-		if !graph.CanNext(it) {
-			continue
-		}
-		if it != best {
-			out = append(out, it)
-		}
+	order := p.Plan(its, stats)
+	if err := checkPlan(its, order); err != nil {
+		clog.Warningf("And: %v: invalid plan, using a default one: %v", it.UID(), err)
+		order = CostPlanner{}.Plan(its, stats)
 	}
-
-	// ...and finally, the difficult children on the end.
-	return append(out, bad...)
+	outIts := make([]graph.Iterator, 0, len(order))
+	outStats := make([]graph.IteratorStats, 0, len(order))
+	for _, i := range order {
+		outIts = append(outIts, its[i])
+		outStats = append(outStats, stats[i])
+	}
+	return outIts, outStats
 }
 
-type byCost []graph.Iterator
+type byCost struct {
+	its   []graph.Iterator
+	stats []graph.IteratorStats
+}
 
-func (c byCost) Len() int           { return len(c) }
-func (c byCost) Less(i, j int) bool { return c[i].Stats().ContainsCost < c[j].Stats().ContainsCost }
-func (c byCost) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byCost) Len() int           { return len(c.its) }
+func (c byCost) Less(i, j int) bool { return c.stats[i].ContainsCost < c.stats[j].ContainsCost }
+func (c byCost) Swap(i, j int) {
+	c.its[i], c.its[j] = c.its[j], c.its[i]
+	c.stats[i], c.stats[j] = c.stats[j], c.stats[i]
+}
 
 // optimizeContains() creates an alternate check list, containing the same contents
 // but with a new ordering, however it wishes.
@@ -233,7 +212,11 @@ func (it *And) optimizeContains() {
 	// This involves providing GetSubIterators with a slice to fill.
 	// Generally this is a worthwhile thing to do in other places as well.
 	it.checkList = it.SubIterators()
-	sort.Sort(byCost(it.checkList))
+	if !graph.CanStatsOptimize(it.qs) {
+		return
+	}
+	stats := append([]graph.IteratorStats{}, it.subStats()...)
+	sort.Stable(byCost{its: it.checkList, stats: stats})
 }
 
 // If we're replacing ourselves by a single iterator, we need to grab the
@@ -330,33 +313,47 @@ func hasOneUsefulIterator(its []graph.Iterator) graph.Iterator {
 	return nil
 }
 
-func materializeIts(its []graph.Iterator) []graph.Iterator {
+// materializeLimits wraps small Limit iterators into Materialize, so the limit is
+// preserved on Contains() and the Limit can be placed anywhere in the And.
+func materializeLimits(its []graph.Iterator) []graph.Iterator {
+	for i, sub := range its {
+		if l, ok := sub.(*Limit); ok && l.limit > 0 && l.limit <= MaterializeLimit {
+			its[i] = NewMaterialize(l)
+		}
+	}
+	return its
+}
+
+func materializeIts(its []graph.Iterator, stats []graph.IteratorStats) ([]graph.Iterator, []graph.IteratorStats) {
 	var out []graph.Iterator
 
-	allStats := getStatsForSlice(its)
+	allStats := getStatsForSlice(stats)
 	out = append(out, its[0])
-	for _, it := range its[1:] {
-		stats := it.Stats()
-		if stats.Size*stats.NextCost < (stats.ContainsCost * (1 + (stats.Size / (allStats.Size + 1)))) {
+	for i, it := range its[1:] {
+		st := stats[i+1]
+		if st.Size*st.NextCost < (st.ContainsCost * (1 + (st.Size / (allStats.Size + 1)))) {
 			if graph.Height(it, graph.Materialize) > 10 {
-				out = append(out, NewMaterialize(it))
+				m := NewMaterialize(it)
+				out = append(out, m)
+				stats[i+1] = m.Stats()
 				continue
 			}
 		}
 		out = append(out, it)
 	}
-	return out
+	return out, stats
 }
 
-func getStatsForSlice(its []graph.Iterator) graph.IteratorStats {
-	primary := its[0]
-	primaryStats := primary.Stats()
+func getStatsForSlice(all []graph.IteratorStats) graph.IteratorStats {
+	if len(all) == 0 {
+		return graph.IteratorStats{}
+	}
+	primaryStats := all[0]
 	ContainsCost := primaryStats.ContainsCost
 	NextCost := primaryStats.NextCost
 	Size := primaryStats.Size
 	ExactSize := primaryStats.ExactSize
-	for _, sub := range its[1:] {
-		stats := sub.Stats()
+	for _, stats := range all[1:] {
 		NextCost += stats.ContainsCost * (1 + (primaryStats.Size / (stats.Size + 1)))
 		ContainsCost += stats.ContainsCost
 		if Size > stats.Size {
@@ -376,9 +373,19 @@ func getStatsForSlice(its []graph.Iterator) graph.IteratorStats {
 // and.Stats() lives here in and-iterator-optimize.go because it may
 // in the future return different statistics based on how it is optimized.
 // For now, however, it's pretty static.
+//
+// Stats of subiterators are computed once and cached until a new subiterator is added.
 func (it *And) Stats() graph.IteratorStats {
-	stats := getStatsForSlice(it.SubIterators())
+	stats := getStatsForSlice(it.subStats())
 	stats.Next = it.runstats.Next
 	stats.Contains = it.runstats.Contains
 	return stats
 }
+
+// subStats returns cached stats of subiterators, in the same order as SubIterators.
+func (it *And) subStats() []graph.IteratorStats {
+	if it.stats == nil {
+		it.stats = statsForIterators(it.SubIterators())
+	}
+	return it.stats
+}
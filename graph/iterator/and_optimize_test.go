@@ -25,6 +25,7 @@ import (
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/graph/graphmock"
 	. "github.com/caivega/cayley/graph/iterator"
+	"github.com/caivega/cayley/graph/memstore"
 )
 
 func TestIteratorPromotion(t *testing.T) {
@@ -164,3 +165,305 @@ func TestAndStatistics(t *testing.T) {
 		t.Error("And didn't optimize. Next cost old ", stats1.NextCost, "and new ", stats2.NextCost)
 	}
 }
+
+func TestAndLimitReorder(t *testing.T) {
+	qs := &graphmock.Oldstore{
+		Data: []string{},
+		Iter: NewFixed(),
+	}
+	newAnd := func() *And {
+		big := NewLimit(NewInt64(1, 10000, true), 100)
+		small := NewFixed(Int64Node(5), Int64Node(50), Int64Node(500))
+		return NewAnd(qs, big, small)
+	}
+	expect := iterated(newAnd())
+	if !reflect.DeepEqual(expect, []int{5, 50}) {
+		t.Fatalf("unexpected results: %v", expect)
+	}
+
+	newIt, changed := newAnd().Optimize()
+	if !changed {
+		t.Fatal("Didn't optimize")
+	}
+	subs := newIt.SubIterators()
+	if len(subs) != 2 {
+		t.Fatalf("unexpected iterator tree: %v", newIt)
+	}
+	if subs[0].Type() != graph.Fixed {
+		t.Errorf("expected the smallest iterator to lead, got: %v", subs[0].Type())
+	}
+	if subs[1].Type() != graph.Materialize || subs[1].SubIterators()[0].Type() != graph.Limit {
+		t.Errorf("expected limit to be materialized, got: %v", subs[1])
+	}
+	got := iterated(newIt)
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("results changed after optimization: got: %v, expected: %v", got, expect)
+	}
+}
+
+func TestAndLargeLimitOrder(t *testing.T) {
+	qs := &graphmock.Oldstore{
+		Data: []string{},
+		Iter: NewFixed(),
+	}
+	big := NewLimit(NewInt64(1, 10000, true), MaterializeLimit+1)
+	small := NewFixed(Int64Node(5), Int64Node(50), Int64Node(5000))
+	newIt, changed := NewAnd(qs, small, big).Optimize()
+	if !changed {
+		t.Fatal("Didn't optimize")
+	}
+	// limit is too large to materialize, thus it must be Next()ed
+	if sub := newIt.SubIterators()[0]; sub.Type() != graph.Limit {
+		t.Errorf("expected limit to lead, got: %v", sub.Type())
+	}
+	if got := iterated(newIt); !reflect.DeepEqual(got, []int{5, 50}) {
+		t.Errorf("unexpected results: %v", got)
+	}
+}
+
+func TestAndTwoLargeLimits(t *testing.T) {
+	qs := &graphmock.Oldstore{
+		Data: []string{},
+		Iter: NewFixed(),
+	}
+	newAnd := func(p Planner) *And {
+		small := NewFixed(Int64Node(5), Int64Node(50), Int64Node(5000))
+		first := NewLimit(NewInt64(1, 10000, true), MaterializeLimit+1)
+		second := NewLimit(NewInt64(1, 10000, true), MaterializeLimit+2)
+		a := NewAnd(qs, small, first, second)
+		a.SetPlanner(p)
+		return a
+	}
+	expect := iterated(newAnd(nil))
+	for _, c := range []struct {
+		name    string
+		planner Planner
+	}{
+		{"cost", CostPlanner{}},
+		{"written", WrittenPlanner{}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			newIt, changed := newAnd(c.planner).Optimize()
+			if !changed {
+				t.Fatal("Didn't optimize")
+			}
+			// only one limit can be Next()ed, thus the original order must be kept
+			var sizes []int64
+			for _, sub := range newIt.SubIterators() {
+				sz, _ := sub.Size()
+				sizes = append(sizes, sz)
+			}
+			if exp := []int64{3, MaterializeLimit + 1, MaterializeLimit + 2}; !reflect.DeepEqual(sizes, exp) {
+				t.Errorf("unexpected order of iterators: %v, expected: %v", sizes, exp)
+			}
+			if got := iterated(newIt); !reflect.DeepEqual(got, expect) {
+				t.Errorf("results changed after optimization: got: %v, expected: %v", got, expect)
+			}
+		})
+	}
+}
+
+func TestAndTwoLargeLimitsNoNext(t *testing.T) {
+	qs := &graphmock.Oldstore{
+		Data: []string{},
+		Iter: NewFixed(),
+	}
+	for _, c := range []struct {
+		name    string
+		planner Planner
+	}{
+		{"cost", CostPlanner{}},
+		{"written", WrittenPlanner{}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			opt := NewOptional(NewFixed(Int64Node(5)))
+			small := NewFixed(Int64Node(5), Int64Node(50), Int64Node(5000))
+			first := NewLimit(NewInt64(1, 10000, true), MaterializeLimit+1)
+			second := NewLimit(NewInt64(1, 10000, true), MaterializeLimit+2)
+			a := NewAnd(qs, opt, first, second, small)
+			a.SetPlanner(c.planner)
+			newIt, changed := a.Optimize()
+			if !changed {
+				t.Fatal("Didn't optimize")
+			}
+			// optional cannot be Next()ed, thus the first limit leads and the rest keeps the original order
+			var types []graph.Type
+			var sizes []int64
+			for _, sub := range newIt.SubIterators() {
+				sz, _ := sub.Size()
+				types = append(types, sub.Type())
+				sizes = append(sizes, sz)
+			}
+			if exp := []graph.Type{graph.Limit, graph.Optional, graph.Limit, graph.Fixed}; !reflect.DeepEqual(types, exp) {
+				t.Errorf("unexpected order of iterators: %v, expected: %v", types, exp)
+			} else if sizes[0] != MaterializeLimit+1 {
+				t.Errorf("expected the first limit to lead, got size: %v", sizes[0])
+			}
+			if got := iterated(newIt); !reflect.DeepEqual(got, []int{5, 50}) {
+				t.Errorf("unexpected results: %v", got)
+			}
+		})
+	}
+}
+
+func newFixedRange(n, step int) *Fixed {
+	it := NewFixed()
+	for i := 0; i < n; i++ {
+		it.Add(Int64Node(i * step))
+	}
+	return it
+}
+
+func newSizedAnd(qs graph.QuadStore) *And {
+	// all iterators share values that are multiples of 1000
+	return NewAnd(qs,
+		newFixedRange(100000, 1),
+		newFixedRange(10, 1000),
+		newFixedRange(1000, 10),
+	)
+}
+
+func TestAndSizeOrder(t *testing.T) {
+	qs := &graphmock.Oldstore{
+		Data: []string{},
+		Iter: NewFixed(),
+	}
+	expect := iterated(newSizedAnd(qs))
+	if len(expect) != 10 {
+		t.Fatalf("unexpected results: %v", expect)
+	}
+
+	newIt, changed := newSizedAnd(qs).Optimize()
+	if !changed {
+		t.Fatal("Didn't optimize")
+	}
+	subs := newIt.SubIterators()
+	if len(subs) != 3 {
+		t.Fatalf("unexpected iterator tree: %v", newIt)
+	}
+	for i, exp := range []int64{10, 1000, 100000} {
+		if size := subs[i].Stats().Size; size != exp {
+			t.Errorf("unexpected order: iterator %d has size %d, expected %d", i, size, exp)
+		}
+	}
+	got := iterated(newIt)
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("results changed after optimization: got: %v, expected: %v", got, expect)
+	}
+}
+
+func BenchmarkAndOptimize(b *testing.B) {
+	qs := &graphmock.Oldstore{
+		Data: []string{},
+		Iter: NewFixed(),
+	}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		a := newSizedAnd(qs)
+		b.StartTimer()
+		newIt, _ := a.Optimize()
+		if n := len(iterated(newIt)); n != 10 {
+			b.Fatalf("unexpected number of results: %d", n)
+		}
+	}
+}
+
+type orderPlanner []int
+
+func (p orderPlanner) Plan(its []graph.Iterator, stats []graph.IteratorStats) []int {
+	return p
+}
+
+func TestAndPlanner(t *testing.T) {
+	qs := &graphmock.Oldstore{
+		Data: []string{},
+		Iter: NewFixed(),
+	}
+	newAnd := func(p Planner) *And {
+		big := NewFixed()
+		for i := 1; i <= 100; i++ {
+			big.Add(Int64Node(i))
+		}
+		small := NewFixed(Int64Node(5), Int64Node(50), Int64Node(500))
+		a := NewAnd(qs, small, big)
+		if p != nil {
+			a.SetPlanner(p)
+		}
+		return a
+	}
+	expect := iterated(newAnd(nil))
+	if !reflect.DeepEqual(expect, []int{5, 50}) {
+		t.Fatalf("unexpected results: %v", expect)
+	}
+	for _, c := range []struct {
+		name    string
+		planner Planner
+		first   int64
+	}{
+		{"default", nil, 3},
+		{"cost", CostPlanner{}, 3},
+		{"forced", orderPlanner{1, 0}, 100},
+		{"invalid", orderPlanner{1, 1}, 3},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			newIt, changed := newAnd(c.planner).Optimize()
+			if !changed {
+				t.Fatal("Didn't optimize")
+			}
+			subs := newIt.SubIterators()
+			if len(subs) != 2 {
+				t.Fatalf("unexpected iterator tree: %v", newIt)
+			}
+			if sz, _ := subs[0].Size(); sz != c.first {
+				t.Errorf("unexpected leading iterator: %v, expected size: %v", subs[0], c.first)
+			}
+			got := iterated(newIt)
+			sort.Ints(got)
+			if !reflect.DeepEqual(got, expect) {
+				t.Errorf("results changed after optimization: got: %v, expected: %v", got, expect)
+			}
+		})
+	}
+}
+
+func TestAndNoStatsOptimize(t *testing.T) {
+	qs, err := graph.NewQuadStore(memstore.QuadStoreType, "", graph.Options{
+		graph.OptStatsOptimize: false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer qs.Close()
+	if graph.CanStatsOptimize(qs) {
+		t.Fatal("expected stats optimization to be disabled")
+	}
+
+	big := NewFixed()
+	for i := 1; i <= 100; i++ {
+		big.Add(Int64Node(i))
+	}
+	mid := NewFixed(Int64Node(5), Int64Node(50), Int64Node(60), Int64Node(500))
+	small := NewFixed(Int64Node(5), Int64Node(50), Int64Node(500))
+	a := NewAnd(qs, big, mid, small)
+
+	newIt, changed := a.Optimize()
+	if !changed {
+		t.Fatal("Didn't optimize")
+	}
+	subs := newIt.SubIterators()
+	var sizes []int64
+	for _, sub := range subs {
+		sz, _ := sub.Size()
+		sizes = append(sizes, sz)
+	}
+	if !reflect.DeepEqual(sizes, []int64{100, 4, 3}) {
+		t.Errorf("unexpected order of iterators: %v", sizes)
+	}
+	got := iterated(newIt)
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, []int{5, 50}) {
+		t.Errorf("unexpected results: %v", got)
+	}
+}
@@ -0,0 +1,165 @@
+package iterator
+
+import (
+	"context"
+
+	"github.com/caivega/cayley/graph"
+)
+
+var _ graph.Iterator = &ContainedIn{}
+
+// ContainedIn iterator returns results of the primary iterator that are contained in the oracle iterator.
+// Unlike And, the oracle is never Next()ed, it is only checked with Contains(), thus it is
+// efficient for oracles that are cheap to check but expensive to iterate.
+type ContainedIn struct {
+	uid      uint64
+	tags     graph.Tagger
+	primary  graph.Iterator
+	oracle   graph.Iterator
+	result   graph.Value
+	runstats graph.IteratorStats
+	err      error
+}
+
+// NewContainedIn creates an iterator that filters results of the primary iterator with the oracle.
+func NewContainedIn(primary, oracle graph.Iterator) *ContainedIn {
+	return &ContainedIn{
+		uid:     NextUID(),
+		primary: primary,
+		oracle:  oracle,
+	}
+}
+
+func (it *ContainedIn) UID() uint64 {
+	return it.uid
+}
+
+// Reset resets the internal iterators and the iterator itself.
+func (it *ContainedIn) Reset() {
+	it.result = nil
+	it.err = nil
+	it.primary.Reset()
+	it.oracle.Reset()
+}
+
+func (it *ContainedIn) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *ContainedIn) TagResults(dst map[string]graph.Value) {
+	it.tags.TagResult(dst, it.Result())
+	it.primary.TagResults(dst)
+	it.oracle.TagResults(dst)
+}
+
+func (it *ContainedIn) Clone() graph.Iterator {
+	out := NewContainedIn(it.primary.Clone(), it.oracle.Clone())
+	out.tags.CopyFrom(it)
+	return out
+}
+
+// SubIterators returns a slice of the sub iterators. The first iterator is the primary one.
+func (it *ContainedIn) SubIterators() []graph.Iterator {
+	return []graph.Iterator{it.primary, it.oracle}
+}
+
+// Next advances the primary iterator until a value contained in the oracle is found.
+func (it *ContainedIn) Next(ctx context.Context) bool {
+	graph.NextLogIn(it)
+	it.runstats.Next += 1
+
+	for it.primary.Next(ctx) {
+		curr := it.primary.Result()
+		if it.oracle.Contains(ctx, curr) {
+			it.result = curr
+			it.runstats.ContainsNext += 1
+			return graph.NextLogOut(it, true)
+		}
+		if it.err = it.oracle.Err(); it.err != nil {
+			return graph.NextLogOut(it, false)
+		}
+	}
+	it.err = it.primary.Err()
+	return graph.NextLogOut(it, false)
+}
+
+func (it *ContainedIn) Err() error {
+	return it.err
+}
+
+func (it *ContainedIn) Result() graph.Value {
+	return it.result
+}
+
+// Contains checks whether the value is contained in both the primary iterator and the oracle.
+func (it *ContainedIn) Contains(ctx context.Context, val graph.Value) bool {
+	graph.ContainsLogIn(it, val)
+	it.runstats.Contains += 1
+
+	if !it.primary.Contains(ctx, val) {
+		it.err = it.primary.Err()
+		return graph.ContainsLogOut(it, val, false)
+	}
+	if !it.oracle.Contains(ctx, val) {
+		it.err = it.oracle.Err()
+		return graph.ContainsLogOut(it, val, false)
+	}
+	it.result = val
+	return graph.ContainsLogOut(it, val, true)
+}
+
+// NextPath checks whether there is another path to the current value, first through the primary iterator,
+// and then through the oracle, the same way as And does.
+func (it *ContainedIn) NextPath(ctx context.Context) bool {
+	if it.primary.NextPath(ctx) {
+		return true
+	} else if it.err = it.primary.Err(); it.err != nil {
+		return false
+	}
+	if it.oracle.NextPath(ctx) {
+		return true
+	}
+	it.err = it.oracle.Err()
+	return false
+}
+
+// Close closes both iterators. It returns the first error it encounters.
+func (it *ContainedIn) Close() error {
+	err := it.primary.Close()
+	if _err := it.oracle.Close(); _err != nil && err == nil {
+		err = _err
+	}
+	return err
+}
+
+func (it *ContainedIn) Type() graph.Type { return graph.ContainedIn }
+
+func (it *ContainedIn) Optimize() (graph.Iterator, bool) {
+	primary, opt1 := it.primary.Optimize()
+	oracle, opt2 := it.oracle.Optimize()
+	it.primary, it.oracle = primary, oracle
+	return it, opt1 || opt2
+}
+
+func (it *ContainedIn) Stats() graph.IteratorStats {
+	primary := it.primary.Stats()
+	oracle := it.oracle.Stats()
+	return graph.IteratorStats{
+		NextCost:     primary.NextCost + oracle.ContainsCost,
+		ContainsCost: primary.ContainsCost + oracle.ContainsCost,
+		Size:         primary.Size,
+		ExactSize:    false,
+		Next:         it.runstats.Next,
+		Contains:     it.runstats.Contains,
+		ContainsNext: it.runstats.ContainsNext,
+	}
+}
+
+func (it *ContainedIn) Size() (int64, bool) {
+	st := it.Stats()
+	return st.Size, st.ExactSize
+}
+
+func (it *ContainedIn) String() string {
+	return "ContainedIn"
+}
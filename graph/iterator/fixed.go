@@ -25,6 +25,7 @@ import (
 	"fmt"
 
 	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/quad"
 )
 
 var _ graph.Iterator = &Fixed{}
@@ -35,10 +36,15 @@ type Fixed struct {
 	uid       uint64
 	tags      graph.Tagger
 	values    []graph.Value
+	set       map[interface{}]graph.Value // values indexed by key; built lazily for large sets
 	lastIndex int
 	result    graph.Value
 }
 
+// fixedSetThreshold is the number of values in Fixed iterator after which Contains uses a hash set instead
+// of a linear scan.
+const fixedSetThreshold = 32
+
 // Creates a new Fixed iterator with a custom comparator.
 func NewFixed(vals ...graph.Value) *Fixed {
 	it := &Fixed{
@@ -51,6 +57,46 @@ func NewFixed(vals ...graph.Value) *Fixed {
 	return it
 }
 
+// ErrUnknownValue is returned by NewFixedValuesChecked if a value is not present in the quad store.
+type ErrUnknownValue struct {
+	Value quad.Value
+}
+
+func (e ErrUnknownValue) Error() string {
+	return fmt.Sprintf("unknown value: %v", e.Value)
+}
+
+// NewFixedValues creates a new Fixed iterator from a list of values, resolving them with a given quad store.
+// Values that are not present in the quad store are skipped.
+func NewFixedValues(qs graph.QuadStore, vals ...quad.Value) graph.Iterator {
+	it, err := NewFixedValuesChecked(qs, true, vals...)
+	if err != nil {
+		return NewError(err)
+	}
+	return it
+}
+
+// NewFixedValuesChecked is the same as NewFixedValues, but allows to return an ErrUnknownValue error
+// for values that are not present in the quad store, instead of skipping them.
+func NewFixedValuesChecked(qs graph.QuadStore, skipUnknown bool, vals ...quad.Value) (*Fixed, error) {
+	it := NewFixed()
+	gvals, err := graph.LookupValues(context.TODO(), qs, vals)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		gv := gvals[i]
+		if gv == nil {
+			if skipUnknown {
+				continue
+			}
+			return nil, ErrUnknownValue{Value: v}
+		}
+		it.Add(gv)
+	}
+	return it, nil
+}
+
 func (it *Fixed) UID() uint64 {
 	return it.uid
 }
@@ -83,6 +129,21 @@ func (it *Fixed) Clone() graph.Iterator {
 // TODO(barakmich): This ought to be a set someday, disallowing repeated values.
 func (it *Fixed) Add(v graph.Value) {
 	it.values = append(it.values, v)
+	if it.set != nil {
+		it.addToSet(v)
+	}
+}
+
+func (it *Fixed) addToSet(v graph.Value) {
+	k := graph.ToKey(v)
+	if _, ok := it.set[k]; !ok {
+		it.set[k] = v
+	}
+}
+
+// indexed checks if Contains will use a hash set.
+func (it *Fixed) indexed() bool {
+	return len(it.values) > fixedSetThreshold
 }
 
 // Values returns a list of values stored in iterator. Slice should not be modified.
@@ -99,11 +160,23 @@ func (it *Fixed) Type() graph.Type { return graph.Fixed }
 
 // Check if the passed value is equal to one of the values stored in the iterator.
 func (it *Fixed) Contains(ctx context.Context, v graph.Value) bool {
-	// Could be optimized by keeping it sorted or using a better datastructure.
-	// However, for fixed iterators, which are by definition kind of tiny, this
-	// isn't a big issue.
+	// Fixed iterators are usually tiny, thus a linear scan is sufficient for them.
+	// Large sets (for example, a long list of values in Has) are indexed on the first use.
 	graph.ContainsLogIn(it, v)
 	vk := graph.ToKey(v)
+	if it.indexed() {
+		if it.set == nil {
+			it.set = make(map[interface{}]graph.Value, len(it.values))
+			for _, x := range it.values {
+				it.addToSet(x)
+			}
+		}
+		if x, ok := it.set[vk]; ok {
+			it.result = x
+			return graph.ContainsLogOut(it, v, true)
+		}
+		return graph.ContainsLogOut(it, v, false)
+	}
 	for _, x := range it.values {
 		if graph.ToKey(x) == vk {
 			it.result = x
@@ -158,12 +231,16 @@ func (it *Fixed) Size() (int64, bool) {
 	return int64(len(it.values)), true
 }
 
-// As we right now have to scan the entire list, Next and Contains are linear with the
-// size. However, a better data structure could remove these limits.
+// Next is linear with the size. Contains is linear as well, unless the set is large enough
+// to be indexed.
 func (it *Fixed) Stats() graph.IteratorStats {
 	s, exact := it.Size()
+	cost := s
+	if it.indexed() {
+		cost = 1
+	}
 	return graph.IteratorStats{
-		ContainsCost: s,
+		ContainsCost: cost,
 		NextCost:     s,
 		Size:         s,
 		ExactSize:    exact,
@@ -0,0 +1,81 @@
+// Copyright 2026 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/graphmock"
+	. "github.com/caivega/cayley/graph/iterator"
+	"github.com/caivega/cayley/quad"
+)
+
+func TestFixedValues(t *testing.T) {
+	ctx := context.TODO()
+	qs := &graphmock.Oldstore{
+		Data: []string{1: "a", 2: "b", 3: "c"},
+	}
+	vals := []quad.Value{quad.Raw("a"), quad.Raw("x"), quad.Raw("c"), quad.Raw("y")}
+
+	it := NewFixedValues(qs, vals...)
+	var got []graph.Value
+	for it.Next(ctx) {
+		got = append(got, it.Result())
+	}
+	if exp := []graph.Value{Int64Node(1), Int64Node(3)}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected values: got: %v, expected: %v", got, exp)
+	}
+
+	_, err := NewFixedValuesChecked(qs, false, vals...)
+	if e, ok := err.(ErrUnknownValue); !ok {
+		t.Errorf("expected unknown value error, got: %v", err)
+	} else if e.Value != quad.Raw("x") {
+		t.Errorf("unexpected value in error: %v", e.Value)
+	}
+
+	fixed, err := NewFixedValuesChecked(qs, false, vals[0], vals[2])
+	if err != nil {
+		t.Fatal(err)
+	} else if n, _ := fixed.Size(); n != 2 {
+		t.Errorf("unexpected size: %d", n)
+	}
+}
+
+func TestFixedContainsLarge(t *testing.T) {
+	ctx := context.TODO()
+	it := NewFixed()
+	for i := 0; i < 100; i += 2 {
+		it.Add(Int64Node(i))
+	}
+	if st := it.Stats(); st.ContainsCost != 1 {
+		t.Errorf("expected large set to be indexed, got contains cost: %d", st.ContainsCost)
+	}
+	for i := 0; i < 100; i++ {
+		ok := it.Contains(ctx, Int64Node(i))
+		if exp := i%2 == 0; ok != exp {
+			t.Errorf("unexpected result for %d: %v", i, ok)
+		} else if ok && it.Result() != Int64Node(i) {
+			t.Errorf("unexpected value for %d: %v", i, it.Result())
+		}
+	}
+	// values added after the first lookup must be visible as well
+	it.Add(Int64Node(101))
+	if !it.Contains(ctx, Int64Node(101)) {
+		t.Errorf("expected to find a value added after indexing")
+	}
+}
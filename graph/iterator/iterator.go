@@ -19,19 +19,13 @@ package iterator
 import (
 	"context"
 	"fmt"
-	"sync/atomic"
 
 	"github.com/caivega/cayley/graph"
 )
 
-var nextIteratorID uint64
-
-func init() {
-	atomic.StoreUint64(&nextIteratorID, 1)
-}
-
+// NextUID returns a new unique iterator ID. It is an alias for graph.NextUID.
 func NextUID() uint64 {
-	return atomic.AddUint64(&nextIteratorID, 1) - 1
+	return graph.NextUID()
 }
 
 var (
@@ -105,6 +105,18 @@ func (it *Limit) Optimize() (graph.Iterator, bool) {
 		return optimizedPrimaryIt, true
 	}
 	it.primaryIt = optimizedPrimaryIt
+	if sub, ok := it.primaryIt.(*Limit); ok && sub.limit > 0 {
+		// collapse nested limits into one
+		if sub.limit < it.limit {
+			it.limit = sub.limit
+		}
+		it.primaryIt = sub.primaryIt
+		optimized = true
+	}
+	if sz, exact := it.primaryIt.Size(); exact && sz <= it.limit {
+		// limit will never be reached
+		return it.primaryIt, true
+	}
 	return it, optimized
 }
 
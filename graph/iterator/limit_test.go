@@ -46,3 +46,23 @@ func TestLimitIteratorBasics(t *testing.T) {
 		}
 	}
 }
+
+func TestLimitIteratorOptimize(t *testing.T) {
+	allIt := NewInt64(1, 10, true)
+	it, changed := NewLimit(NewLimit(allIt, 3), 5).Optimize()
+	if !changed {
+		t.Error("Nested limits were not collapsed")
+	}
+	if it.String() != "Limit(3)" || it.SubIterators()[0] != allIt {
+		t.Errorf("Unexpected iterator after optimization: %v", it)
+	}
+	if got := iterated(it); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("Failed to iterate Limit correctly: got:%v", got)
+	}
+
+	fixed := NewFixed(Int64Node(1), Int64Node(2))
+	it, changed = NewLimit(fixed, 5).Optimize()
+	if !changed || it != fixed {
+		t.Errorf("Limit larger than the iterator size was not removed: %v", it)
+	}
+}
@@ -0,0 +1,40 @@
+package iterator
+
+import (
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/quad"
+)
+
+// QuadPattern returns an iterator for all quads matching a given pattern. Nil components are wildcards.
+//
+// Quad indexes for all bound components are intersected, and the most selective one is Next()ed.
+// It's not a part of graph package, since it depends on iterators defined here.
+func QuadPattern(qs graph.QuadStore, s, p, o, l *quad.Value) graph.Iterator {
+	var its []graph.Iterator
+	for _, c := range []struct {
+		dir quad.Direction
+		val *quad.Value
+	}{
+		{quad.Subject, s},
+		{quad.Predicate, p},
+		{quad.Object, o},
+		{quad.Label, l},
+	} {
+		if c.val == nil {
+			continue
+		}
+		v := qs.ValueOf(*c.val)
+		if v == nil {
+			closeIteratorList(its, nil)
+			return NewNull()
+		}
+		its = append(its, qs.QuadIterator(c.dir, v))
+	}
+	if len(its) == 0 {
+		return qs.QuadsAllIterator()
+	} else if len(its) == 1 {
+		return its[0]
+	}
+	it, _ := NewAnd(qs, its...).Optimize()
+	return it
+}
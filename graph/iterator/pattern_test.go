@@ -0,0 +1,63 @@
+package iterator_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/caivega/cayley/graph"
+	. "github.com/caivega/cayley/graph/iterator"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+)
+
+func TestQuadPattern(t *testing.T) {
+	quads := []quad.Quad{
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("charlie", "follows", "bob", ""),
+		quad.MakeIRI("bob", "follows", "dani", ""),
+		quad.MakeIRI("alice", "likes", "bob", "g"),
+		quad.MakeIRI("dani", "status", "cool", "g"),
+	}
+	qs := memstore.New(quads...)
+
+	val := func(s string) *quad.Value {
+		var v quad.Value = quad.IRI(s)
+		return &v
+	}
+	var tests = []struct {
+		name       string
+		s, p, o, l *quad.Value
+		expect     []quad.Quad
+	}{
+		{name: "all", expect: quads},
+		{name: "subject", s: val("alice"), expect: []quad.Quad{quads[0], quads[3]}},
+		{name: "predicate and object", p: val("follows"), o: val("bob"), expect: []quad.Quad{quads[0], quads[1]}},
+		{name: "label", l: val("g"), expect: []quad.Quad{quads[3], quads[4]}},
+		{name: "all bound", s: val("alice"), p: val("likes"), o: val("bob"), l: val("g"), expect: []quad.Quad{quads[3]}},
+		{name: "no match", s: val("dani"), p: val("follows")},
+		{name: "missing value", p: val("hates")},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			it := QuadPattern(qs, c.s, c.p, c.o, c.l)
+			defer it.Close()
+			var got []quad.Quad
+			err := graph.Iterate(context.TODO(), it).Each(func(v graph.Value) {
+				got = append(got, qs.Quad(v))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Sort(quad.ByQuadString(got))
+			expect := append([]quad.Quad{}, c.expect...)
+			sort.Sort(quad.ByQuadString(expect))
+			if len(got) != 0 || len(expect) != 0 {
+				if !reflect.DeepEqual(got, expect) {
+					t.Errorf("unexpected quads:\n%v\nexpected:\n%v", got, expect)
+				}
+			}
+		})
+	}
+}
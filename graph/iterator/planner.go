@@ -0,0 +1,209 @@
+package iterator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/caivega/cayley/clog"
+	"github.com/caivega/cayley/graph"
+)
+
+// Planner decides the execution order of And subiterators during optimization.
+type Planner interface {
+	// Plan returns the execution order of subiterators as a permutation of their indexes.
+	// The first iterator is Next()ed and the rest are checked with Contains() in the given order.
+	//
+	// Stats are passed in the same order as iterators. Only iterators that pass graph.CanNext
+	// can be placed first, and a Limit iterator must be placed first, since it ignores the limit on Contains().
+	// If there is more than one Limit, the original order must be kept (see LimitOrder).
+	Plan(its []graph.Iterator, stats []graph.IteratorStats) []int
+}
+
+// DefaultPlanner is used by And iterators that have no planner set.
+var DefaultPlanner Planner = CostPlanner{}
+
+// LimitOrder returns the order of iterators imposed by Limit iterators that were not materialized.
+//
+// A single Limit must be Next()ed to preserve the limit, thus it is returned as the first iterator.
+// Only one Limit can be Next()ed, and others would ignore their limits on Contains(), thus if there
+// is more than one Limit, the original order of all iterators is returned, the same one as for
+// an And that was not optimized. If the first iterator cannot be Next()ed, the first Limit that can
+// is moved to the front instead. It returns nil if the order is not constrained.
+func LimitOrder(its []graph.Iterator) []int {
+	var limits []int
+	for i, sub := range its {
+		if l, ok := sub.(*Limit); ok && l.limit > 0 {
+			limits = append(limits, i)
+		}
+	}
+	switch len(limits) {
+	case 0:
+		return nil
+	case 1:
+		return limits
+	}
+	first := 0
+	if !graph.CanNext(its[0]) {
+		for _, i := range limits {
+			if graph.CanNext(its[i]) {
+				first = i
+				break
+			}
+		}
+	}
+	order := make([]int, 0, len(its))
+	order = append(order, first)
+	for i := range its {
+		if i != first {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+var _ Planner = CostPlanner{}
+
+// CostPlanner is a default planner. It Next()s the iterator with the lowest projected total cost
+// and checks the rest from the smallest to the largest, so Contains() fails faster.
+type CostPlanner struct{}
+
+func (CostPlanner) Plan(its []graph.Iterator, stats []graph.IteratorStats) []int {
+	var (
+		// bad contains iterators that can't be (efficiently) nexted, such as
+		// graph.Optional or graph.Not. Separate them out and tack them on at the end.
+		bad      []int
+		best     = -1
+		bestCost = int64(1 << 62)
+	)
+
+	// A Limit that is too large to be materialized must be Next()ed to preserve the limit.
+	pinned := false
+	if order := LimitOrder(its); len(order) > 1 {
+		return order
+	} else if len(order) == 1 {
+		best, pinned = order[0], true
+	}
+
+	// Find the iterator with the projected "best" total cost.
+	// Total cost is defined as The Next()ed iterator's cost to Next() out
+	// all of it's contents, and to Contains() each of those against everyone
+	// else.
+	for i, root := range its {
+		if !graph.CanNext(root) {
+			bad = append(bad, i)
+			continue
+		}
+		if pinned {
+			continue
+		}
+		rootStats := stats[i]
+		cost := rootStats.NextCost
+		for j, f := range its {
+			if !graph.CanNext(f) {
+				continue
+			}
+			if j == i {
+				continue
+			}
+			cost += stats[j].ContainsCost * (1 + (rootStats.Size / (stats[j].Size + 1)))
+		}
+		cost *= rootStats.Size
+		if clog.V(3) {
+			clog.Infof("And: Root: %v Total Cost: %v Best: %v", root.UID(), cost, bestCost)
+		}
+		if cost < bestCost {
+			best = i
+			bestCost = cost
+		}
+	}
+	if clog.V(3) && best >= 0 {
+		clog.Infof("And: Choosing: %v Best: %v", its[best].UID(), bestCost)
+	}
+
+	var order []int
+	// Put the best iterator (the one we wish to Next()) at the front...
+	if best >= 0 {
+		order = append(order, best)
+	}
+
+	// ... push everyone else after, smallest first, so Contains() fails faster...
+	var rest []int
+	for i, sub := range its {
+		if !graph.CanNext(sub) {
+			continue
+		}
+		if i != best {
+			rest = append(rest, i)
+		}
+	}
+	sort.SliceStable(rest, func(a, b int) bool {
+		return stats[rest[a]].Size < stats[rest[b]].Size
+	})
+	order = append(order, rest...)
+
+	// ...and finally, the difficult children on the end.
+	order = append(order, bad...)
+
+	return order
+}
+
+var _ Planner = WrittenPlanner{}
+
+// WrittenPlanner keeps iterators in the order they were added to And. It only moves the first
+// iterator that can be Next()ed to the front, and iterators that cannot be Next()ed to the end.
+// A Limit iterator is always placed first, and the order is not changed at all if there is more than one.
+//
+// It is used for quad stores that disable reordering based on size estimates (see graph.OptStatsOptimize).
+type WrittenPlanner struct{}
+
+func (WrittenPlanner) Plan(its []graph.Iterator, _ []graph.IteratorStats) []int {
+	first := -1
+	if order := LimitOrder(its); len(order) > 1 {
+		return order
+	} else if len(order) == 1 {
+		first = order[0]
+	}
+	if first < 0 {
+		for i, sub := range its {
+			if graph.CanNext(sub) {
+				first = i
+				break
+			}
+		}
+	}
+	order := make([]int, 0, len(its))
+	if first >= 0 {
+		order = append(order, first)
+	}
+	var bad []int
+	for i, sub := range its {
+		if i == first {
+			continue
+		} else if !graph.CanNext(sub) {
+			bad = append(bad, i)
+			continue
+		}
+		order = append(order, i)
+	}
+	return append(order, bad...)
+}
+
+// checkPlan verifies that the order returned by the planner is a valid permutation of iterators.
+func checkPlan(its []graph.Iterator, order []int) error {
+	if len(order) != len(its) {
+		return fmt.Errorf("expected %d iterators, got %d", len(its), len(order))
+	}
+	seen := make([]bool, len(its))
+	for _, i := range order {
+		if i < 0 || i >= len(its) {
+			return fmt.Errorf("iterator index out of range: %d", i)
+		} else if seen[i] {
+			return fmt.Errorf("duplicate iterator index: %d", i)
+		}
+		seen[i] = true
+	}
+	if len(order) != 0 && !graph.CanNext(its[order[0]]) {
+		return fmt.Errorf("iterator %v cannot be Next()ed", its[order[0]])
+	}
+	return nil
+}
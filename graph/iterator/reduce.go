@@ -0,0 +1,167 @@
+package iterator
+
+import (
+	"context"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/quad"
+)
+
+var _ graph.Iterator = &Reduce{}
+
+// ReduceFunc combines an accumulated value with the next value of the stream.
+type ReduceFunc func(acc, v quad.Value) quad.Value
+
+// Reduce iterator folds all values of underlying iterator and returns a single accumulated value.
+// Values are processed as they are produced, without buffering.
+type Reduce struct {
+	uid    uint64
+	it     graph.Iterator
+	tag    string
+	init   quad.Value
+	fn     ReduceFunc
+	done   bool
+	tags   graph.Tagger
+	result quad.Value
+	qs     graph.QuadStore
+}
+
+// NewReduce creates a new iterator that folds values of a provided subiterator with a given function.
+// If tag is not empty, values bound to the tag are used instead of the results of subiterator.
+//
+// The init value is returned for an empty subiterator. If the accumulated value is nil, iterator returns no results.
+func NewReduce(it graph.Iterator, qs graph.QuadStore, tag string, init quad.Value, fn ReduceFunc) *Reduce {
+	return &Reduce{
+		uid: NextUID(),
+		it:  it, qs: qs,
+		tag: tag, init: init, fn: fn,
+	}
+}
+
+func (it *Reduce) UID() uint64 {
+	return it.uid
+}
+
+// Reset resets the internal iterators and the iterator itself.
+func (it *Reduce) Reset() {
+	it.done = false
+	it.result = nil
+	it.it.Reset()
+}
+
+func (it *Reduce) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *Reduce) TagResults(dst map[string]graph.Value) {
+	it.tags.TagResult(dst, it.Result())
+}
+
+func (it *Reduce) Clone() graph.Iterator {
+	it2 := NewReduce(it.it.Clone(), it.qs, it.tag, it.init, it.fn)
+	it2.Tagger().CopyFrom(it)
+	return it2
+}
+
+// SubIterators returns a slice of the sub iterators.
+func (it *Reduce) SubIterators() []graph.Iterator {
+	return []graph.Iterator{it.it}
+}
+
+func (it *Reduce) value() quad.Value {
+	v := it.it.Result()
+	if it.tag != "" {
+		m := make(map[string]graph.Value)
+		it.it.TagResults(m)
+		v = m[it.tag]
+	}
+	if v == nil {
+		return nil
+	} else if pv, ok := v.(graph.PreFetchedValue); ok {
+		return pv.NameOf()
+	}
+	return it.qs.NameOf(v)
+}
+
+func (it *Reduce) fold() {
+	if v := it.value(); v != nil {
+		it.result = it.fn(it.result, v)
+	}
+}
+
+// Next folds all results of underlying iterator.
+func (it *Reduce) Next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+	it.done = true
+	it.result = it.init
+	for it.it.Next(ctx) {
+		it.fold()
+		for it.it.NextPath(ctx) {
+			it.fold()
+		}
+	}
+	if it.it.Err() != nil {
+		it.result = nil
+		return false
+	}
+	return it.result != nil
+}
+
+func (it *Reduce) Err() error {
+	return it.it.Err()
+}
+
+func (it *Reduce) Result() graph.Value {
+	if it.result == nil {
+		return nil
+	}
+	return graph.PreFetched(it.result)
+}
+
+func (it *Reduce) Contains(ctx context.Context, val graph.Value) bool {
+	if !it.done {
+		it.Next(ctx)
+	}
+	if it.result == nil {
+		return false
+	}
+	if v, ok := val.(graph.PreFetchedValue); ok {
+		return v.NameOf() == it.result
+	}
+	return it.qs.NameOf(val) == it.result
+}
+
+func (it *Reduce) NextPath(ctx context.Context) bool {
+	return false
+}
+
+func (it *Reduce) Close() error {
+	return it.it.Close()
+}
+
+func (it *Reduce) Type() graph.Type { return graph.Reduce }
+
+func (it *Reduce) Optimize() (graph.Iterator, bool) {
+	sub, optimized := it.it.Optimize()
+	it.it = sub
+	return it, optimized
+}
+
+func (it *Reduce) Stats() graph.IteratorStats {
+	sub := it.it.Stats()
+	stats := graph.IteratorStats{
+		NextCost:  sub.NextCost * sub.Size,
+		Size:      1,
+		ExactSize: false,
+	}
+	stats.ContainsCost = stats.NextCost
+	return stats
+}
+
+func (it *Reduce) Size() (int64, bool) {
+	return 1, false
+}
+
+func (it *Reduce) String() string { return "Reduce" }
@@ -0,0 +1,148 @@
+package iterator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/quad"
+)
+
+var _ graph.Value = PathNodes(nil)
+
+// PathNodes is a sequence of nodes that were traversed to reach a result.
+// It is bound to a tag by SavePath iterator.
+type PathNodes []quad.Value
+
+func (p PathNodes) Key() interface{} {
+	arr := make([]string, 0, len(p))
+	for _, v := range p {
+		arr = append(arr, quad.StringOf(v))
+	}
+	return strings.Join(arr, "\x00")
+}
+
+var _ graph.Iterator = &SavePath{}
+
+// SavePath iterator collects values of a given list of step tags into PathNodes and binds it to a tag.
+// Step tags are removed from tagged results. Consecutive steps with the same value are recorded once.
+type SavePath struct {
+	uid   uint64
+	it    graph.Iterator
+	qs    graph.QuadStore
+	tag   string
+	steps []string
+	tags  graph.Tagger
+}
+
+// NewSavePath creates a new iterator that binds values of step tags of a provided subiterator as PathNodes to a tag.
+func NewSavePath(it graph.Iterator, qs graph.QuadStore, tag string, steps []string) *SavePath {
+	return &SavePath{
+		uid: NextUID(),
+		it:  it, qs: qs,
+		tag: tag, steps: steps,
+	}
+}
+
+func (it *SavePath) UID() uint64 {
+	return it.uid
+}
+
+func (it *SavePath) Reset() {
+	it.it.Reset()
+}
+
+func (it *SavePath) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *SavePath) nameOf(v graph.Value) quad.Value {
+	if pv, ok := v.(graph.PreFetchedValue); ok {
+		return pv.NameOf()
+	}
+	return it.qs.NameOf(v)
+}
+
+func (it *SavePath) TagResults(dst map[string]graph.Value) {
+	m := make(map[string]graph.Value)
+	it.it.TagResults(m)
+	var (
+		nodes = make(PathNodes, 0, len(it.steps))
+		last  interface{}
+	)
+	for _, t := range it.steps {
+		v, ok := m[t]
+		if !ok {
+			continue
+		}
+		delete(m, t)
+		if v == nil {
+			continue
+		} else if k := v.Key(); len(nodes) != 0 && k == last {
+			continue
+		} else {
+			last = k
+		}
+		if nv := it.nameOf(v); nv != nil {
+			nodes = append(nodes, nv)
+		}
+	}
+	for k, v := range m {
+		dst[k] = v
+	}
+	dst[it.tag] = nodes
+	it.tags.TagResult(dst, it.Result())
+}
+
+func (it *SavePath) Clone() graph.Iterator {
+	it2 := NewSavePath(it.it.Clone(), it.qs, it.tag, it.steps)
+	it2.Tagger().CopyFrom(it)
+	return it2
+}
+
+// SubIterators returns a slice of the sub iterators.
+func (it *SavePath) SubIterators() []graph.Iterator {
+	return []graph.Iterator{it.it}
+}
+
+func (it *SavePath) Next(ctx context.Context) bool {
+	return it.it.Next(ctx)
+}
+
+func (it *SavePath) NextPath(ctx context.Context) bool {
+	return it.it.NextPath(ctx)
+}
+
+func (it *SavePath) Contains(ctx context.Context, val graph.Value) bool {
+	return it.it.Contains(ctx, val)
+}
+
+func (it *SavePath) Err() error {
+	return it.it.Err()
+}
+
+func (it *SavePath) Result() graph.Value {
+	return it.it.Result()
+}
+
+func (it *SavePath) Close() error {
+	return it.it.Close()
+}
+
+func (it *SavePath) Type() graph.Type { return graph.SavePath }
+
+func (it *SavePath) Optimize() (graph.Iterator, bool) {
+	sub, optimized := it.it.Optimize()
+	it.it = sub
+	return it, optimized
+}
+
+func (it *SavePath) Stats() graph.IteratorStats {
+	return it.it.Stats()
+}
+
+func (it *SavePath) Size() (int64, bool) {
+	return it.it.Size()
+}
+
+func (it *SavePath) String() string { return "SavePath(" + it.tag + ")" }
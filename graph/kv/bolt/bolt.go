@@ -57,6 +57,10 @@ func Create(path string, opt graph.Options) (kv.BucketKV, error) {
 		clog.Errorf("Error: couldn't create Bolt database: %v", err)
 		return nil, err
 	}
+	if err = setNoSync(db, opt); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return &DB{DB: db}, nil
 }
 
@@ -66,16 +70,34 @@ func Open(path string, opt graph.Options) (kv.BucketKV, error) {
 		clog.Errorf("Error, couldn't open! %v", err)
 		return nil, err
 	}
-	// BoolKey returns false on non-existence. IE, Sync by default.
-	db.NoSync, err = opt.BoolKey("nosync", false)
-	if err != nil {
+	if err = setNoSync(db, opt); err != nil {
 		db.Close()
 		return nil, err
 	}
+	return &DB{DB: db}, nil
+}
+
+// setNoSync disables fsync after each transaction if "no_sync" (or "nosync") option is set.
+//
+// It makes bulk imports much faster, but the data written since the last Sync
+// can be lost or the database can be corrupted if the process or the machine crashes.
+func setNoSync(db *bolt.DB, opt graph.Options) error {
+	// BoolKey returns false on non-existence. IE, Sync by default.
+	noSync, err := opt.BoolKey("no_sync", false)
+	if err != nil {
+		return err
+	}
+	if !noSync {
+		noSync, err = opt.BoolKey("nosync", false)
+		if err != nil {
+			return err
+		}
+	}
+	db.NoSync = noSync
 	if db.NoSync {
 		clog.Infof("Running in nosync mode")
 	}
-	return &DB{DB: db}, nil
+	return nil
 }
 
 type DB struct {
@@ -90,6 +112,12 @@ func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
+// Sync flushes the database file to disk. It is only useful in no-sync mode,
+// since every transaction is synced otherwise.
+func (db *DB) Sync() error {
+	return db.DB.Sync()
+}
+
 func (db *DB) Tx(update bool) (kv.BucketTx, error) {
 	tx, err := db.DB.Begin(update)
 	if err != nil {
@@ -15,6 +15,7 @@
 package bolt
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -22,6 +23,7 @@ import (
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/graph/kv"
 	"github.com/caivega/cayley/graph/kv/kvtest"
+	"github.com/caivega/cayley/quad"
 )
 
 func makeBolt(t testing.TB) (kv.BucketKV, graph.Options, func()) {
@@ -47,3 +49,127 @@ func TestBolt(t *testing.T) {
 func BenchmarkBolt(b *testing.B) {
 	kvtest.BenchmarkAll(b, makeBolt, nil)
 }
+
+func TestNoSync(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test_"+Type)
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	opts := graph.Options{"no_sync": true}
+	db, err := Create(tmpDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.(*DB).DB.NoSync {
+		t.Fatal("expected no-sync mode")
+	}
+	if err = kv.Init(db, opts); err != nil {
+		t.Fatal(err)
+	}
+	qs, err := kv.New(db, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := quad.MakeIRI("a", "b", "c", "")
+	if err = qs.ApplyDeltas([]graph.Delta{{Quad: q, Action: graph.Add}}, graph.IgnoreOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = qs.(*kv.QuadStore).Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err = qs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(tmpDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qs, err = kv.New(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer qs.Close()
+	got, err := quad.ReadAll(graph.NewQuadStoreReader(qs))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(got) != 1 || got[0] != q {
+		t.Fatalf("unexpected quads: %v", got)
+	}
+}
+
+func TestPing(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test_"+Type)
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Create(tmpDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = kv.Init(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	qs, err := kv.New(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err = graph.Ping(ctx, qs); err != nil {
+		t.Fatal("unexpected error for live database:", err)
+	}
+	if err = qs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = graph.Ping(ctx, qs); err == nil {
+		t.Fatal("expected an error for closed database")
+	}
+}
+
+func TestGraphPrefix(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test_"+Type)
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	quads := map[string]quad.Quad{
+		"one": quad.MakeIRI("a", "b", "c", ""),
+		"two": quad.MakeIRI("d", "e", "f", ""),
+	}
+	for prefix, q := range quads {
+		opts := graph.Options{kv.OptGraphPrefix: prefix}
+		if err = graph.InitQuadStore(Type, tmpDir, opts); err != nil {
+			t.Fatal(err)
+		}
+		qs, err := graph.NewQuadStore(Type, tmpDir, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = qs.ApplyDeltas([]graph.Delta{{Quad: q, Action: graph.Add}}, graph.IgnoreOpts{})
+		qs.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for prefix, q := range quads {
+		qs, err := graph.NewQuadStore(Type, tmpDir, graph.Options{kv.OptGraphPrefix: prefix})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []quad.Quad
+		err = graph.Iterate(context.TODO(), qs.QuadsAllIterator()).Each(func(v graph.Value) {
+			got = append(got, qs.Quad(v))
+		})
+		qs.Close()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(got) != 1 || got[0] != q {
+			t.Errorf("unexpected quads for %q: %v", prefix, got)
+		}
+	}
+}
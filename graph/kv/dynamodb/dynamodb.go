@@ -0,0 +1,435 @@
+// Copyright 2026 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	ddb "github.com/aws/aws-sdk-go/service/dynamodb"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/kv"
+)
+
+func init() {
+	kv.Register(Type, kv.Registration{
+		NewFunc:      Open,
+		InitFunc:     Create,
+		IsPersistent: true,
+	})
+}
+
+const (
+	Type = "dynamodb"
+
+	defaultTable = "cayley"
+
+	attrBucket = "b"
+	attrKey    = "k"
+	attrValue  = "v"
+
+	// keyPref is added to all keys, since DynamoDB does not allow empty key attributes.
+	keyPref = 'k'
+
+	// maxGetItems is the maximal number of keys in a single BatchGetItem request.
+	maxGetItems = 100
+	// maxTxItems is the maximal number of writes in a single TransactWriteItems request.
+	maxTxItems = 25
+
+	// minRetryDelay and maxRetryDelay limit the backoff before retrying unprocessed keys.
+	minRetryDelay = 50 * time.Millisecond
+	maxRetryDelay = 5 * time.Second
+)
+
+func newDB(addr string, m graph.Options) (*DB, error) {
+	region, err := m.StringKey("region", "")
+	if err != nil {
+		return nil, err
+	}
+	table, err := m.StringKey("table", defaultTable)
+	if err != nil {
+		return nil, err
+	}
+	access, err := m.StringKey("access_key", "")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := m.StringKey("secret_key", "")
+	if err != nil {
+		return nil, err
+	}
+	conf := aws.NewConfig()
+	if region != "" {
+		conf = conf.WithRegion(region)
+	}
+	if addr != "" {
+		// custom endpoint, for example a local DynamoDB instance
+		conf = conf.WithEndpoint(addr)
+	}
+	if access != "" || secret != "" {
+		conf = conf.WithCredentials(credentials.NewStaticCredentials(access, secret, ""))
+	}
+	sess, err := session.NewSession(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{cli: ddb.New(sess), table: table}, nil
+}
+
+// Create connects to DynamoDB and creates a new table for the graph.
+func Create(addr string, m graph.Options) (kv.BucketKV, error) {
+	db, err := newDB(addr, m)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.cli.CreateTable(&ddb.CreateTableInput{
+		TableName: aws.String(db.table),
+		AttributeDefinitions: []*ddb.AttributeDefinition{
+			{AttributeName: aws.String(attrBucket), AttributeType: aws.String(ddb.ScalarAttributeTypeB)},
+			{AttributeName: aws.String(attrKey), AttributeType: aws.String(ddb.ScalarAttributeTypeB)},
+		},
+		KeySchema: []*ddb.KeySchemaElement{
+			{AttributeName: aws.String(attrBucket), KeyType: aws.String(ddb.KeyTypeHash)},
+			{AttributeName: aws.String(attrKey), KeyType: aws.String(ddb.KeyTypeRange)},
+		},
+		BillingMode: aws.String(ddb.BillingModePayPerRequest),
+	})
+	if e, ok := err.(awserr.Error); ok && e.Code() == ddb.ErrCodeResourceInUseException {
+		return nil, graph.ErrDatabaseExists
+	} else if err != nil {
+		return nil, err
+	}
+	err = db.cli.WaitUntilTableExists(&ddb.DescribeTableInput{TableName: aws.String(db.table)})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Open connects to DynamoDB and uses an existing table.
+func Open(addr string, m graph.Options) (kv.BucketKV, error) {
+	db, err := newDB(addr, m)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.cli.DescribeTable(&ddb.DescribeTableInput{TableName: aws.String(db.table)})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// DB is a KV implementation on top of a DynamoDB table.
+//
+// Bucket name and key are used as a partition and sort key of the table respectively.
+// Buckets exist implicitly, thus a missing bucket behaves like an empty one.
+type DB struct {
+	cli   *ddb.DynamoDB
+	table string
+}
+
+func (db *DB) Type() string {
+	return Type
+}
+func (db *DB) Close() error {
+	return nil
+}
+func (db *DB) Tx(update bool) (kv.BucketTx, error) {
+	tx := &Tx{db: db}
+	if update {
+		tx.writes = make(map[string]map[string]write)
+	}
+	return tx, nil
+}
+
+func (db *DB) itemKey(bucket, key []byte) map[string]*ddb.AttributeValue {
+	k := make([]byte, len(key)+1)
+	k[0] = keyPref
+	copy(k[1:], key)
+	return map[string]*ddb.AttributeValue{
+		attrBucket: {B: bucket},
+		attrKey:    {B: k},
+	}
+}
+
+type write struct {
+	val []byte
+	del bool
+}
+
+// Tx is a DynamoDB transaction.
+//
+// DynamoDB has no read snapshots, thus reads always observe the latest committed state.
+// Writes are buffered and are applied on Commit with TransactWriteItems. Since a single request
+// is limited in the number of items, large transactions are split into multiple requests,
+// and are only atomic within each request.
+type Tx struct {
+	db     *DB
+	writes map[string]map[string]write // bucket -> key -> write; nil for read-only transactions
+	err    error
+}
+
+func (tx *Tx) Commit(ctx context.Context) error {
+	if tx.err != nil || len(tx.writes) == 0 {
+		return tx.err
+	}
+	items := make([]*ddb.TransactWriteItem, 0, maxTxItems)
+	flush := func() error {
+		if len(items) == 0 {
+			return nil
+		}
+		_, err := tx.db.cli.TransactWriteItemsWithContext(ctx, &ddb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		items = items[:0]
+		return err
+	}
+	for b, m := range tx.writes {
+		for k, w := range m {
+			key := tx.db.itemKey([]byte(b), []byte(k))
+			if w.del {
+				items = append(items, &ddb.TransactWriteItem{Delete: &ddb.Delete{
+					TableName: aws.String(tx.db.table),
+					Key:       key,
+				}})
+			} else {
+				key[attrValue] = &ddb.AttributeValue{B: w.val}
+				items = append(items, &ddb.TransactWriteItem{Put: &ddb.Put{
+					TableName: aws.String(tx.db.table),
+					Item:      key,
+				}})
+			}
+			if len(items) >= maxTxItems {
+				if tx.err = flush(); tx.err != nil {
+					return tx.err
+				}
+			}
+		}
+	}
+	tx.err = flush()
+	tx.writes = nil
+	return tx.err
+}
+func (tx *Tx) Rollback() error {
+	tx.writes = nil
+	return tx.err
+}
+func (tx *Tx) Get(ctx context.Context, keys []kv.BucketKey) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	// keys are indexed by bucket and key, since BatchGetItem returns items in arbitrary order
+	idx := make(map[string]map[string][]int)
+	var req []map[string]*ddb.AttributeValue
+	for i, k := range keys {
+		if w, ok := tx.writes[string(k.Bucket)][string(k.Key)]; ok {
+			if !w.del {
+				vals[i] = w.val
+			}
+			continue
+		}
+		m := idx[string(k.Bucket)]
+		if m == nil {
+			m = make(map[string][]int)
+			idx[string(k.Bucket)] = m
+		}
+		if _, ok := m[string(k.Key)]; !ok {
+			req = append(req, tx.db.itemKey(k.Bucket, k.Key))
+		}
+		m[string(k.Key)] = append(m[string(k.Key)], i)
+	}
+	for len(req) != 0 {
+		n := len(req)
+		if n > maxGetItems {
+			n = maxGetItems
+		}
+		batch := req[:n]
+		req = req[n:]
+		delay := minRetryDelay
+		for len(batch) != 0 {
+			resp, err := tx.db.cli.BatchGetItemWithContext(ctx, &ddb.BatchGetItemInput{
+				RequestItems: map[string]*ddb.KeysAndAttributes{
+					tx.db.table: {Keys: batch, ConsistentRead: aws.Bool(true)},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range resp.Responses[tx.db.table] {
+				b, k := item[attrBucket].B, item[attrKey].B[1:]
+				for _, i := range idx[string(b)][string(k)] {
+					vals[i] = item[attrValue].B
+				}
+			}
+			// retry keys that were not processed due to limits
+			batch = nil
+			if un := resp.UnprocessedKeys[tx.db.table]; un != nil {
+				batch = un.Keys
+			}
+			if len(batch) == 0 {
+				break
+			}
+			// exponential backoff, as recommended for unprocessed keys
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+		}
+	}
+	return vals, nil
+}
+func (tx *Tx) Bucket(name []byte) kv.Bucket {
+	return &Bucket{tx: tx, name: name}
+}
+
+type Bucket struct {
+	tx   *Tx
+	name []byte
+}
+
+func (b *Bucket) Get(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	bk := make([]kv.BucketKey, 0, len(keys))
+	for _, k := range keys {
+		bk = append(bk, kv.BucketKey{Bucket: b.name, Key: k})
+	}
+	return b.tx.Get(ctx, bk)
+}
+func (b *Bucket) setWrite(k []byte, w write) error {
+	if b.tx.writes == nil {
+		return fmt.Errorf("write on ro tx")
+	}
+	m := b.tx.writes[string(b.name)]
+	if m == nil {
+		m = make(map[string]write)
+		b.tx.writes[string(b.name)] = m
+	}
+	m[string(k)] = w
+	return nil
+}
+func (b *Bucket) Put(k, v []byte) error {
+	return b.setWrite(k, write{val: append([]byte{}, v...)})
+}
+func (b *Bucket) Del(k []byte) error {
+	return b.setWrite(k, write{del: true})
+}
+func (b *Bucket) Scan(pref []byte) kv.KVIterator {
+	it := &Iterator{b: b, pref: pref, more: true}
+	// buffered writes are merged with items stored in the table
+	for k, w := range b.tx.writes[string(b.name)] {
+		if bytes.HasPrefix([]byte(k), pref) {
+			it.buf = append(it.buf, pair{key: []byte(k), write: w})
+		}
+	}
+	sort.Slice(it.buf, func(i, j int) bool {
+		return bytes.Compare(it.buf[i].key, it.buf[j].key) < 0
+	})
+	return it
+}
+
+type pair struct {
+	key []byte
+	write
+}
+
+// Iterator scans keys of a bucket with a given prefix in order.
+type Iterator struct {
+	b    *Bucket
+	pref []byte
+	last map[string]*ddb.AttributeValue // start key for the next page
+	more bool                           // more items can be loaded from the table
+
+	keys, vals [][]byte // current page of items loaded from the table
+	buf        []pair   // buffered writes with the prefix, sorted by key
+
+	key, val []byte
+	err      error
+}
+
+func (it *Iterator) loadPage(ctx context.Context) error {
+	pref := make([]byte, len(it.pref)+1)
+	pref[0] = keyPref
+	copy(pref[1:], it.pref)
+	resp, err := it.b.tx.db.cli.QueryWithContext(ctx, &ddb.QueryInput{
+		TableName:              aws.String(it.b.tx.db.table),
+		KeyConditionExpression: aws.String("#b = :b AND begins_with(#k, :p)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#b": aws.String(attrBucket),
+			"#k": aws.String(attrKey),
+		},
+		ExpressionAttributeValues: map[string]*ddb.AttributeValue{
+			":b": {B: it.b.name},
+			":p": {B: pref},
+		},
+		ExclusiveStartKey: it.last,
+		ConsistentRead:    aws.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+	it.last = resp.LastEvaluatedKey
+	it.more = len(it.last) != 0
+	for _, item := range resp.Items {
+		it.keys = append(it.keys, item[attrKey].B[1:])
+		it.vals = append(it.vals, item[attrValue].B)
+	}
+	return nil
+}
+
+func (it *Iterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		if len(it.keys) == 0 && it.more {
+			it.err = it.loadPage(ctx)
+			continue
+		}
+		stored := len(it.keys) != 0
+		if len(it.buf) != 0 && (!stored || bytes.Compare(it.buf[0].key, it.keys[0]) <= 0) {
+			w := it.buf[0]
+			it.buf = it.buf[1:]
+			if stored && bytes.Equal(w.key, it.keys[0]) {
+				// buffered write overrides the stored value
+				it.keys, it.vals = it.keys[1:], it.vals[1:]
+			}
+			if w.del {
+				continue
+			}
+			it.key, it.val = w.key, w.val
+			return true
+		} else if !stored {
+			return false
+		}
+		it.key, it.val = it.keys[0], it.vals[0]
+		it.keys, it.vals = it.keys[1:], it.vals[1:]
+		return true
+	}
+	return false
+}
+func (it *Iterator) Key() []byte { return it.key }
+func (it *Iterator) Val() []byte { return it.val }
+func (it *Iterator) Err() error {
+	return it.err
+}
+func (it *Iterator) Close() error {
+	return it.Err()
+}
@@ -0,0 +1,49 @@
+package dynamodb
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	ddb "github.com/aws/aws-sdk-go/service/dynamodb"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/kv"
+	"github.com/caivega/cayley/graph/kv/kvtest"
+)
+
+// endpointEnv is an environment variable with an address of DynamoDB-compatible endpoint
+// (for example, localstack) to run tests against.
+const endpointEnv = "CAYLEY_DYNAMODB_ENDPOINT"
+
+func makeDynamoDB(t testing.TB) (kv.BucketKV, graph.Options, func()) {
+	addr := os.Getenv(endpointEnv)
+	if addr == "" {
+		t.Skip(endpointEnv + " is not set")
+	}
+	opts := graph.Options{
+		"region":     "us-east-1",
+		"table":      fmt.Sprintf("cayley_test_%d", time.Now().UnixNano()),
+		"access_key": "test",
+		"secret_key": "test",
+	}
+	db, err := Create(addr, opts)
+	if err != nil {
+		t.Fatal("Failed to create DynamoDB table.", err)
+	}
+	return db, opts, func() {
+		d := db.(*DB)
+		d.cli.DeleteTable(&ddb.DeleteTableInput{TableName: aws.String(d.table)})
+		db.Close()
+	}
+}
+
+func TestDynamoDB(t *testing.T) {
+	kvtest.TestAll(t, makeDynamoDB, nil)
+}
+
+func BenchmarkDynamoDB(b *testing.B) {
+	kvtest.BenchmarkAll(b, makeDynamoDB, nil)
+}
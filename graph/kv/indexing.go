@@ -341,8 +341,22 @@ func (qs *QuadStore) decNodes(ctx context.Context, tx BucketTx, deltas []graphlo
 
 func (qs *QuadStore) ApplyDeltas(in []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
 	ctx := context.TODO()
+	defer graph.LogSlow(qs.log, qs.slow, "kv: apply deltas", graph.StartSlow(qs.log, qs.slow), graph.LogField{Key: "count", Value: len(in)})
 	qs.writer.Lock()
 	defer qs.writer.Unlock()
+	var err error
+	for i := 0; i < maxConflictRetries; i++ {
+		if err = qs.applyDeltas(ctx, in, ignoreOpts); err != ErrConflict {
+			return err
+		}
+		// state cached by the failed attempt may refer to ids that were never committed
+		qs.mapBucket = nil
+		qs.valueLRU.Purge()
+	}
+	return err
+}
+
+func (qs *QuadStore) applyDeltas(ctx context.Context, in []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
 	tx, err := qs.db.Tx(true)
 	if err != nil {
 		return err
@@ -11,8 +11,14 @@ var (
 	ErrNotFound     = errors.New("kv: not found")
 	ErrNoBucket     = errors.New("kv: bucket is missing")
 	ErrBucketExists = errors.New("kv: bucket already exists")
+	// ErrConflict is returned by Commit if a transaction was not applied because of a concurrent write.
+	// The transaction can be safely retried.
+	ErrConflict = errors.New("kv: transaction conflict")
 )
 
+// maxConflictRetries is the maximal number of times a conflicting transaction is retried.
+const maxConflictRetries = 10
+
 type Tx interface {
 	Commit(ctx context.Context) error
 	Rollback() error
@@ -73,7 +79,19 @@ type FlatKV interface {
 	Tx(update bool) (FlatTx, error)
 }
 
+// Update runs a function in a read-write transaction and commits it. The transaction is retried if it fails
+// with ErrConflict, thus the function may be called multiple times.
 func Update(ctx context.Context, kv BucketKV, update func(tx BucketTx) error) error {
+	var err error
+	for i := 0; i < maxConflictRetries; i++ {
+		if err = update1(ctx, kv, update); err != ErrConflict {
+			return err
+		}
+	}
+	return err
+}
+
+func update1(ctx context.Context, kv BucketKV, update func(tx BucketTx) error) error {
 	tx, err := kv.Tx(true)
 	if err != nil {
 		return err
@@ -0,0 +1,81 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/caivega/cayley/graph"
+)
+
+// OptGraphPrefix is the Options key for a prefix that namespaces all buckets of the quad store.
+// It allows to keep multiple independent graphs in a single database file.
+const OptGraphPrefix = "graph_prefix"
+
+// WithPrefix returns a BucketKV that stores all buckets under a given prefix. Stores with different prefixes
+// can share the same database without seeing each other's data.
+//
+// All graphs sharing the same database should use a prefix. Prefix must not contain a '/' character.
+// Closing the returned BucketKV closes the underlying database.
+func WithPrefix(kv BucketKV, prefix string) (BucketKV, error) {
+	if prefix == "" {
+		return kv, nil
+	} else if strings.IndexByte(prefix, bucketSep) >= 0 {
+		return nil, fmt.Errorf("kv: graph prefix should not contain %q: %q", bucketSep, prefix)
+	}
+	return &prefixKV{kv: kv, pref: []byte(prefix + string(bucketSep))}, nil
+}
+
+// withPrefixOpt wraps the database according to the OptGraphPrefix option.
+func withPrefixOpt(kv BucketKV, opt graph.Options) (BucketKV, error) {
+	prefix, err := opt.StringKey(OptGraphPrefix, "")
+	if err != nil {
+		return nil, err
+	}
+	return WithPrefix(kv, prefix)
+}
+
+var _ BucketKV = (*prefixKV)(nil)
+
+type prefixKV struct {
+	kv   BucketKV
+	pref []byte
+}
+
+func (kv *prefixKV) Type() string { return kv.kv.Type() }
+func (kv *prefixKV) Close() error { return kv.kv.Close() }
+func (kv *prefixKV) Sync() error {
+	if s, ok := kv.kv.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+func (kv *prefixKV) Tx(update bool) (BucketTx, error) {
+	tx, err := kv.kv.Tx(update)
+	if err != nil {
+		return nil, err
+	}
+	return &prefixTx{BucketTx: tx, pref: kv.pref}, nil
+}
+
+type prefixTx struct {
+	BucketTx
+	pref []byte
+}
+
+func (tx *prefixTx) bucket(name []byte) []byte {
+	b := make([]byte, len(tx.pref)+len(name))
+	n := copy(b, tx.pref)
+	copy(b[n:], name)
+	return b
+}
+func (tx *prefixTx) Bucket(name []byte) Bucket {
+	return tx.BucketTx.Bucket(tx.bucket(name))
+}
+func (tx *prefixTx) Get(ctx context.Context, keys []BucketKey) ([][]byte, error) {
+	nk := make([]BucketKey, len(keys))
+	for i, k := range keys {
+		nk[i] = BucketKey{Bucket: tx.bucket(k.Bucket), Key: k.Key}
+	}
+	return tx.BucketTx.Get(ctx, nk)
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/caivega/cayley/clog"
 	"github.com/caivega/cayley/graph"
@@ -50,7 +51,11 @@ func Register(name string, r Registration) {
 				return err
 			}
 			defer kv.Close()
-			if err = Init(kv, opt); err != nil {
+			pkv, err := withPrefixOpt(kv, opt)
+			if err != nil {
+				return err
+			}
+			if err = Init(pkv, opt); err != nil {
 				return err
 			}
 			return kv.Close()
@@ -60,6 +65,12 @@ func Register(name string, r Registration) {
 			if err != nil {
 				return nil, err
 			}
+			pkv, err := withPrefixOpt(kv, opt)
+			if err != nil {
+				kv.Close()
+				return nil, err
+			}
+			kv = pkv
 			if !r.IsPersistent {
 				if err = Init(kv, opt); err != nil {
 					kv.Close()
@@ -77,7 +88,11 @@ const (
 	nilDataVersion    = 1
 )
 
-var _ graph.BatchQuadStore = (*QuadStore)(nil)
+var (
+	_ graph.BatchQuadStore = (*QuadStore)(nil)
+	_ graph.Stats          = (*QuadStore)(nil)
+	_ graph.StatsOptimizer = (*QuadStore)(nil)
+)
 
 type QuadStore struct {
 	db BucketKV
@@ -99,10 +114,15 @@ type QuadStore struct {
 		buf []byte
 		*boom.DeletableBloomFilter
 	}
+
+	log  graph.Logger
+	slow time.Duration
+	// noStatsOptimize disables the reordering of iterators based on size estimates.
+	noStatsOptimize bool
 }
 
 func newQuadStore(kv BucketKV) *QuadStore {
-	qs := &QuadStore{db: kv}
+	qs := &QuadStore{db: kv, log: graph.NopLogger{}}
 	qs.indexes.all = DefaultQuadIndexes
 	return qs
 }
@@ -128,9 +148,21 @@ func Init(kv BucketKV, opt graph.Options) error {
 	return nil
 }
 
-func New(kv BucketKV, _ graph.Options) (graph.QuadStore, error) {
+func New(kv BucketKV, opt graph.Options) (graph.QuadStore, error) {
 	ctx := context.TODO()
 	qs := newQuadStore(kv)
+	var err error
+	if qs.log, err = opt.Logger(); err != nil {
+		return nil, err
+	}
+	if qs.slow, err = opt.DurationKey(graph.OptSlowQuery, graph.DefaultSlowQuery); err != nil {
+		return nil, err
+	}
+	so, err := opt.BoolKey(graph.OptStatsOptimize, true)
+	if err != nil {
+		return nil, err
+	}
+	qs.noStatsOptimize = !so
 	if vers, err := qs.getMetadata(ctx); err == ErrNoBucket {
 		return nil, graph.ErrNotInitialized
 	} else if err != nil {
@@ -142,6 +174,7 @@ func New(kv BucketKV, _ graph.Options) (graph.QuadStore, error) {
 	if err := qs.initBloomFilter(ctx); err != nil {
 		return nil, err
 	}
+	qs.log.Info("kv: opened database", graph.LogField{Key: "type", Value: kv.Type()})
 	return qs, nil
 }
 
@@ -188,6 +221,24 @@ func (qs *QuadStore) Close() error {
 	return qs.db.Close()
 }
 
+// Sync flushes all written data to disk, if the underlying database supports it.
+// It is only necessary if the database was opened with syncing disabled.
+func (qs *QuadStore) Sync() error {
+	if s, ok := qs.db.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Ping checks that the database is available by reading the metadata.
+func (qs *QuadStore) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := qs.getMetadata(ctx)
+	return err
+}
+
 func (qs *QuadStore) getMetadata(ctx context.Context) (int64, error) {
 	var vers int64
 	err := View(qs.db, func(tx BucketTx) error {
@@ -364,6 +415,7 @@ func (qs *QuadStore) QuadDirection(val graph.Value, d quad.Direction) graph.Valu
 }
 
 func (qs *QuadStore) getPrimitives(ctx context.Context, vals []uint64) ([]*proto.Primitive, error) {
+	defer graph.LogSlow(qs.log, qs.slow, "kv: get primitives", graph.StartSlow(qs.log, qs.slow), graph.LogField{Key: "count", Value: len(vals)})
 	tx, err := qs.db.Tx(false)
 	if err != nil {
 		return nil, err
@@ -30,6 +30,12 @@ func (qs *QuadStore) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool)
 	return it, false
 }
 
+// StatsOptimize reports if iterators can be reordered according to their size estimates.
+// It can be disabled with graph.OptStatsOptimize option.
+func (qs *QuadStore) StatsOptimize() bool {
+	return !qs.noStatsOptimize
+}
+
 func (qs *QuadStore) optimizeLinksTo(it *iterator.LinksTo) (graph.Iterator, bool) {
 	subs := it.SubIterators()
 	if len(subs) != 1 {
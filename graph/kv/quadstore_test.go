@@ -9,6 +9,7 @@ import (
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/graph/kv"
@@ -378,3 +379,193 @@ func (h bucketHook) Del(k []byte) error {
 func (h bucketHook) Scan(pref []byte) kv.KVIterator {
 	return h.b.Scan(pref)
 }
+
+func TestPredicateStatsIndex(t *testing.T) {
+	orig := kv.DefaultQuadIndexes
+	kv.DefaultQuadIndexes = append([]kv.QuadIndex{
+		{Dirs: []quad.Direction{quad.Predicate}},
+	}, orig...)
+	defer func() {
+		kv.DefaultQuadIndexes = orig
+	}()
+
+	kdb := btree.New()
+	err := kv.Init(kdb, nil)
+	require.NoError(t, err)
+	qs, err := kv.New(kdb, nil)
+	require.NoError(t, err)
+	defer qs.Close()
+
+	qw, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+	err = qw.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("a", "b", "c", ""),
+		quad.MakeIRI("a", "b", "d", ""),
+		quad.MakeIRI("c", "e", "d", ""),
+	})
+	require.NoError(t, err)
+	err = qw.RemoveQuad(quad.MakeIRI("a", "b", "d", ""))
+	require.NoError(t, err)
+
+	st, err := graph.PredicateStats(qs)
+	require.NoError(t, err)
+	require.Equal(t, map[quad.Value]int64{
+		quad.IRI("b"): 1,
+		quad.IRI("e"): 1,
+	}, st)
+}
+
+func TestCountByLabelIndex(t *testing.T) {
+	orig := kv.DefaultQuadIndexes
+	kv.DefaultQuadIndexes = append([]kv.QuadIndex{
+		{Dirs: []quad.Direction{quad.Label}},
+	}, orig...)
+	defer func() {
+		kv.DefaultQuadIndexes = orig
+	}()
+
+	kdb := btree.New()
+	err := kv.Init(kdb, nil)
+	require.NoError(t, err)
+	qs, err := kv.New(kdb, nil)
+	require.NoError(t, err)
+	defer qs.Close()
+
+	qw, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+	err = qw.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("a", "b", "c", "g1"),
+		quad.MakeIRI("a", "b", "d", "g1"),
+		quad.MakeIRI("c", "e", "d", "g2"),
+		quad.MakeIRI("c", "e", "f", ""),
+	})
+	require.NoError(t, err)
+	err = qw.RemoveQuad(quad.MakeIRI("a", "b", "d", "g1"))
+	require.NoError(t, err)
+
+	st, err := graph.CountByLabel(nil, qs)
+	require.NoError(t, err)
+	require.Equal(t, map[quad.Value]int64{
+		quad.IRI("g1"): 1,
+		quad.IRI("g2"): 1,
+		nil:            1,
+	}, st)
+}
+
+type logEvent struct {
+	level  string
+	msg    string
+	fields []graph.LogField
+}
+
+type recordLogger struct {
+	mu     sync.Mutex
+	events []logEvent
+}
+
+func (l *recordLogger) add(level, msg string, fields []graph.LogField) {
+	l.mu.Lock()
+	l.events = append(l.events, logEvent{level: level, msg: msg, fields: fields})
+	l.mu.Unlock()
+}
+
+func (l *recordLogger) Debug(msg string, fields ...graph.LogField) { l.add("debug", msg, fields) }
+func (l *recordLogger) Info(msg string, fields ...graph.LogField)  { l.add("info", msg, fields) }
+func (l *recordLogger) Warn(msg string, fields ...graph.LogField)  { l.add("warn", msg, fields) }
+func (l *recordLogger) Error(msg string, fields ...graph.LogField) { l.add("error", msg, fields) }
+
+// slowKV simulates a slow database by delaying read transactions.
+type slowKV struct {
+	kv.BucketKV
+	delay time.Duration
+}
+
+func (s *slowKV) Tx(update bool) (kv.BucketTx, error) {
+	if !update {
+		time.Sleep(s.delay)
+	}
+	return s.BucketKV.Tx(update)
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	kdb := btree.New()
+	err := kv.Init(kdb, nil)
+	require.NoError(t, err)
+
+	slow := &slowKV{BucketKV: kdb}
+	l := &recordLogger{}
+	qs, err := kv.New(slow, graph.Options{
+		graph.OptLogger:    l,
+		graph.OptSlowQuery: "5ms",
+	})
+	require.NoError(t, err)
+	defer qs.Close()
+
+	qw, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+	err = qw.AddQuad(quad.MakeIRI("a", "b", "c", ""))
+	require.NoError(t, err)
+
+	l.mu.Lock()
+	require.Equal(t, []logEvent{
+		{level: "info", msg: "kv: opened database", fields: []graph.LogField{{Key: "type", Value: "btree"}}},
+	}, l.events)
+	l.events = nil
+	l.mu.Unlock()
+
+	slow.delay = 10 * time.Millisecond
+	v := qs.ValueOf(quad.IRI("a"))
+	require.NotNil(t, v)
+	require.Equal(t, quad.IRI("a"), qs.NameOf(v))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	require.NotEmpty(t, l.events)
+	ev := l.events[len(l.events)-1]
+	require.Equal(t, "warn", ev.level)
+	require.Equal(t, "slow operation", ev.msg)
+	require.Equal(t, graph.LogField{Key: "op", Value: "kv: get primitives"}, ev.fields[0])
+	require.Equal(t, "duration", ev.fields[1].Key)
+	require.True(t, ev.fields[1].Value.(time.Duration) >= 5*time.Millisecond)
+	require.Equal(t, graph.LogField{Key: "count", Value: 1}, ev.fields[2])
+}
+
+func TestGraphPrefix(t *testing.T) {
+	db := btree.New()
+	open := func(prefix string) graph.QuadStore {
+		pdb, err := kv.WithPrefix(db, prefix)
+		require.NoError(t, err)
+		require.NoError(t, kv.Init(pdb, nil))
+		qs, err := kv.New(pdb, nil)
+		require.NoError(t, err)
+		return qs
+	}
+	qa, qb := open("a"), open("ab")
+
+	q1 := quad.MakeIRI("a", "follows", "b", "")
+	q2 := quad.MakeIRI("b", "follows", "c", "")
+	require.NoError(t, qa.ApplyDeltas([]graph.Delta{{Quad: q1, Action: graph.Add}}, graph.IgnoreOpts{}))
+	require.NoError(t, qb.ApplyDeltas([]graph.Delta{{Quad: q2, Action: graph.Add}}, graph.IgnoreOpts{}))
+
+	list := func(qs graph.QuadStore) []quad.Quad {
+		var out []quad.Quad
+		err := graph.Iterate(context.TODO(), qs.QuadsAllIterator()).Each(func(v graph.Value) {
+			out = append(out, qs.Quad(v))
+		})
+		require.NoError(t, err)
+		return out
+	}
+	require.Equal(t, []quad.Quad{q1}, list(qa))
+	require.Equal(t, []quad.Quad{q2}, list(qb))
+	require.Nil(t, qa.ValueOf(quad.IRI("c")))
+	require.Nil(t, qb.ValueOf(quad.IRI("a")))
+
+	// the same quad can be added to both stores
+	require.NoError(t, qb.ApplyDeltas([]graph.Delta{{Quad: q1, Action: graph.Add}}, graph.IgnoreOpts{}))
+	require.NoError(t, qa.ApplyDeltas([]graph.Delta{{Quad: q1, Action: graph.Delete}}, graph.IgnoreOpts{}))
+	require.Empty(t, list(qa))
+	require.Len(t, list(qb), 2)
+
+	_, err := kv.WithPrefix(db, "a/b")
+	require.Error(t, err)
+}
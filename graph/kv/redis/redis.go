@@ -0,0 +1,347 @@
+// Copyright 2026 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/kv"
+)
+
+func init() {
+	kv.Register(Type, kv.Registration{
+		NewFunc:      Open,
+		InitFunc:     Create,
+		IsPersistent: true,
+	})
+}
+
+const (
+	Type = "redis"
+
+	defaultNamespace = "cayley"
+	// scanBatch is the number of keys loaded at once by iterators.
+	scanBatch = 1000
+)
+
+func newDB(addr string, m graph.Options) (*DB, error) {
+	pass, err := m.StringKey("password", "")
+	if err != nil {
+		return nil, err
+	}
+	dbi, err := m.IntKey("database", 0)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := m.StringKey("namespace", defaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+	pool := &redis.Pool{
+		MaxIdle: 16,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr,
+				redis.DialPassword(pass),
+				redis.DialDatabase(dbi),
+			)
+		},
+	}
+	conn := pool.Get()
+	_, err = conn.Do("PING")
+	conn.Close()
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &DB{
+		pool: pool,
+		keys: ns + ":keys",
+		vals: ns + ":vals",
+	}, nil
+}
+
+// Create connects to a Redis server and checks that the namespace is empty.
+func Create(addr string, m graph.Options) (kv.BucketKV, error) {
+	db, err := newDB(addr, m)
+	if err != nil {
+		return nil, err
+	}
+	conn := db.pool.Get()
+	n, err := redis.Int(conn.Do("EXISTS", db.keys))
+	conn.Close()
+	if err != nil {
+		db.Close()
+		return nil, err
+	} else if n != 0 {
+		db.Close()
+		return nil, graph.ErrDatabaseExists
+	}
+	return kv.FromFlat(db), nil
+}
+
+// Open connects to a Redis server.
+func Open(addr string, m graph.Options) (kv.BucketKV, error) {
+	db, err := newDB(addr, m)
+	if err != nil {
+		return nil, err
+	}
+	return kv.FromFlat(db), nil
+}
+
+// DB is a flat KV implementation on top of Redis.
+//
+// All keys are stored in a sorted set to allow ordered prefix scans,
+// while values are stored in a separate hash.
+type DB struct {
+	pool *redis.Pool
+	keys string // name of the sorted set with all keys
+	vals string // name of the hash with values
+}
+
+func (db *DB) Type() string {
+	return Type
+}
+func (db *DB) Close() error {
+	return db.pool.Close()
+}
+func (db *DB) Tx(update bool) (kv.FlatTx, error) {
+	conn := db.pool.Get()
+	if err := conn.Err(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tx := &Tx{db: db, conn: conn}
+	if update {
+		// all reads of the transaction go to these keys; Commit fails if they were changed in the meantime
+		if _, err := conn.Do("WATCH", db.vals, db.keys); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tx.writes = make(map[string]write)
+	}
+	return tx, nil
+}
+
+type write struct {
+	val []byte
+	del bool
+}
+
+// Tx is a Redis transaction.
+//
+// Redis has no read snapshots, thus reads always observe the latest committed state.
+// Writes are buffered and are applied atomically on Commit with MULTI/EXEC. Read-write transactions
+// WATCH the keys they read, and Commit returns kv.ErrConflict if another client changed them.
+type Tx struct {
+	db     *DB
+	conn   redis.Conn
+	writes map[string]write // nil for read-only transactions
+	err    error
+}
+
+func (tx *Tx) close() {
+	if tx.conn != nil {
+		tx.conn.Close()
+		tx.conn = nil
+	}
+}
+
+func (tx *Tx) Commit(ctx context.Context) error {
+	defer tx.close()
+	if tx.err != nil || len(tx.writes) == 0 {
+		return tx.err
+	}
+	tx.conn.Send("MULTI")
+	for k, w := range tx.writes {
+		if w.del {
+			tx.conn.Send("HDEL", tx.db.vals, k)
+			tx.conn.Send("ZREM", tx.db.keys, k)
+		} else {
+			tx.conn.Send("HSET", tx.db.vals, k, w.val)
+			tx.conn.Send("ZADD", tx.db.keys, 0, k)
+		}
+	}
+	_, err := redis.Values(tx.conn.Do("EXEC"))
+	if err == redis.ErrNil {
+		// watched keys were modified
+		err = kv.ErrConflict
+	}
+	tx.err = err
+	return tx.err
+}
+func (tx *Tx) Rollback() error {
+	tx.close()
+	return tx.err
+}
+func (tx *Tx) Get(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	args := make([]interface{}, 1, len(keys)+1)
+	args[0] = tx.db.vals
+	var idx []int
+	for i, k := range keys {
+		if w, ok := tx.writes[string(k)]; ok {
+			if !w.del {
+				vals[i] = w.val
+			}
+			continue
+		}
+		idx = append(idx, i)
+		args = append(args, k)
+	}
+	if len(idx) == 0 {
+		return vals, nil
+	}
+	resp, err := redis.ByteSlices(tx.conn.Do("HMGET", args...))
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range idx {
+		vals[i] = resp[j]
+	}
+	return vals, nil
+}
+func (tx *Tx) Put(k, v []byte) error {
+	if tx.writes == nil {
+		return fmt.Errorf("put on ro tx")
+	}
+	tx.writes[string(k)] = write{val: append([]byte{}, v...)}
+	return nil
+}
+func (tx *Tx) Del(k []byte) error {
+	if tx.writes == nil {
+		return fmt.Errorf("del on ro tx")
+	}
+	tx.writes[string(k)] = write{del: true}
+	return nil
+}
+func (tx *Tx) Scan(pref []byte) kv.KVIterator {
+	it := &Iterator{tx: tx, pref: pref, more: true}
+	if len(pref) == 0 {
+		it.min = []byte("-")
+	} else {
+		it.min = append([]byte("["), pref...)
+	}
+	// buffered writes are merged with keys stored in Redis
+	for k, w := range tx.writes {
+		if bytes.HasPrefix([]byte(k), pref) {
+			it.buf = append(it.buf, pair{key: []byte(k), write: w})
+		}
+	}
+	sort.Slice(it.buf, func(i, j int) bool {
+		return bytes.Compare(it.buf[i].key, it.buf[j].key) < 0
+	})
+	return it
+}
+
+type pair struct {
+	key []byte
+	write
+}
+
+// Iterator scans keys with a given prefix in order.
+type Iterator struct {
+	tx   *Tx
+	pref []byte
+	min  []byte // lower bound for the next page of keys
+	more bool   // more keys can be loaded from Redis
+
+	keys, vals [][]byte // current page of keys loaded from Redis
+	buf        []pair   // buffered writes with the prefix, sorted by key
+
+	key, val []byte
+	err      error
+}
+
+func (it *Iterator) loadPage() error {
+	keys, err := redis.ByteSlices(it.tx.conn.Do("ZRANGEBYLEX", it.tx.db.keys, it.min, "+", "LIMIT", 0, scanBatch))
+	if err != nil {
+		return err
+	}
+	if len(keys) < scanBatch {
+		it.more = false
+	} else {
+		it.min = append([]byte("("), keys[len(keys)-1]...)
+	}
+	for i, k := range keys {
+		if !bytes.HasPrefix(k, it.pref) {
+			keys = keys[:i]
+			it.more = false
+			break
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 1, len(keys)+1)
+	args[0] = it.tx.db.vals
+	for _, k := range keys {
+		args = append(args, k)
+	}
+	vals, err := redis.ByteSlices(it.tx.conn.Do("HMGET", args...))
+	if err != nil {
+		return err
+	}
+	for i, v := range vals {
+		if v == nil {
+			continue // removed concurrently
+		}
+		it.keys = append(it.keys, keys[i])
+		it.vals = append(it.vals, v)
+	}
+	return nil
+}
+
+func (it *Iterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		if len(it.keys) == 0 && it.more {
+			it.err = it.loadPage()
+			continue
+		}
+		stored := len(it.keys) != 0
+		if len(it.buf) != 0 && (!stored || bytes.Compare(it.buf[0].key, it.keys[0]) <= 0) {
+			w := it.buf[0]
+			it.buf = it.buf[1:]
+			if stored && bytes.Equal(w.key, it.keys[0]) {
+				// buffered write overrides the stored value
+				it.keys, it.vals = it.keys[1:], it.vals[1:]
+			}
+			if w.del {
+				continue
+			}
+			it.key, it.val = w.key, w.val
+			return true
+		} else if !stored {
+			return false
+		}
+		it.key, it.val = it.keys[0], it.vals[0]
+		it.keys, it.vals = it.keys[1:], it.vals[1:]
+		return true
+	}
+	return false
+}
+func (it *Iterator) Key() []byte { return it.key }
+func (it *Iterator) Val() []byte { return it.val }
+func (it *Iterator) Err() error {
+	return it.err
+}
+func (it *Iterator) Close() error {
+	return it.Err()
+}
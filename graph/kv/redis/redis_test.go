@@ -0,0 +1,91 @@
+// +build docker
+
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/kv"
+	"github.com/caivega/cayley/graph/kv/kvtest"
+	"github.com/caivega/cayley/internal/dock"
+)
+
+func makeRedis(t testing.TB) (kv.BucketKV, graph.Options, func()) {
+	var conf dock.Config
+
+	conf.Image = "redis:4"
+	conf.OpenStdin = true
+	conf.Tty = true
+
+	addr, closer := dock.RunAndWait(t, conf, "6379", func(addr string) bool {
+		conn, err := redis.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+		_, err = conn.Do("PING")
+		return err == nil
+	})
+	db, err := Create(addr, nil)
+	if err != nil {
+		closer()
+		t.Fatal("Failed to create Redis database.", err)
+	}
+	return db, nil, func() {
+		db.Close()
+		closer()
+	}
+}
+
+func TestRedis(t *testing.T) {
+	kvtest.TestAll(t, makeRedis, nil)
+}
+
+func TestRedisConflict(t *testing.T) {
+	db, _, closer := makeRedis(t)
+	defer closer()
+	ctx := context.TODO()
+	bucket, key := []byte("b"), []byte("k")
+
+	tx1, err := db.Tx(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx1.Rollback()
+	if _, err = tx1.Bucket(bucket).Get(ctx, [][]byte{key}); err != nil {
+		t.Fatal(err)
+	}
+	// concurrent write to the same store
+	err = kv.Update(ctx, db, func(tx kv.BucketTx) error {
+		return tx.Bucket(bucket).Put(key, []byte("v2"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tx1.Bucket(bucket).Put(key, []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx1.Commit(ctx); err != kv.ErrConflict {
+		t.Fatalf("expected a conflict, got: %v", err)
+	}
+	err = kv.View(db, func(tx kv.BucketTx) error {
+		v, err := kv.GetOne(ctx, tx.Bucket(bucket), key)
+		if err != nil {
+			return err
+		} else if string(v) != "v2" {
+			t.Errorf("unexpected value: %q", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkRedis(b *testing.B) {
+	kvtest.BenchmarkAll(b, makeRedis, nil)
+}
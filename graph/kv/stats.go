@@ -0,0 +1,146 @@
+// Copyright 2026 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/proto"
+	"github.com/caivega/cayley/quad"
+)
+
+// PredicateStats returns the number of quads for each predicate.
+//
+// It scans the predicate index if one is configured, or the whole quad log otherwise.
+func (qs *QuadStore) PredicateStats() (map[quad.Value]int64, error) {
+	ctx := context.TODO()
+	cnt, err := qs.countByDir(ctx, quad.Predicate)
+	if err != nil {
+		return nil, err
+	}
+	return qs.countsToValues(ctx, cnt)
+}
+
+var _ graph.LabelStats = (*QuadStore)(nil)
+
+// CountByLabel returns the number of quads for each label. Quads without a label are counted under the nil key.
+//
+// It scans the label index if one is configured, or the whole quad log otherwise.
+func (qs *QuadStore) CountByLabel(ctx context.Context) (map[quad.Value]int64, error) {
+	cnt, err := qs.countByDir(ctx, quad.Label)
+	if err != nil {
+		return nil, err
+	}
+	unlabeled, ok := cnt[0]
+	delete(cnt, 0)
+	out, err := qs.countsToValues(ctx, cnt)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		out[nil] = unlabeled
+	}
+	return out, nil
+}
+
+// countByDir returns the number of quads for each value id in a given direction.
+func (qs *QuadStore) countByDir(ctx context.Context, dir quad.Direction) (map[uint64]int64, error) {
+	if ind, ok := qs.dirIndex(dir); ok {
+		return qs.countFromIndex(ctx, ind)
+	}
+	return qs.countFromLog(ctx, dir)
+}
+
+// countsToValues resolves value ids in the map of counts.
+func (qs *QuadStore) countsToValues(ctx context.Context, cnt map[uint64]int64) (map[quad.Value]int64, error) {
+	ids := make([]graph.Value, 0, len(cnt))
+	for id := range cnt {
+		ids = append(ids, Int64Value(id))
+	}
+	vals, err := qs.ValuesOf(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[quad.Value]int64, len(cnt))
+	for i, v := range vals {
+		if v == nil {
+			continue
+		}
+		out[v] = cnt[uint64(ids[i].(Int64Value))]
+	}
+	return out, nil
+}
+
+// dirIndex returns an index on a single given direction, if one is configured.
+func (qs *QuadStore) dirIndex(dir quad.Direction) (QuadIndex, bool) {
+	qs.indexes.RLock()
+	all := qs.indexes.all
+	qs.indexes.RUnlock()
+	for _, ind := range all {
+		if len(ind.Dirs) == 1 && ind.Dirs[0] == dir {
+			return ind, true
+		}
+	}
+	return QuadIndex{}, false
+}
+
+func (qs *QuadStore) countFromIndex(ctx context.Context, ind QuadIndex) (map[uint64]int64, error) {
+	cnt := make(map[uint64]int64)
+	err := View(qs.db, func(tx BucketTx) error {
+		return Each(ctx, tx.Bucket(ind.Bucket()), nil, func(k, v []byte) error {
+			ids, err := decodeIndex(v)
+			if err != nil {
+				return err
+			}
+			id := quadKeyEnc.Uint64(k)
+			for len(ids) != 0 {
+				batch := ids
+				if len(batch) > nextBatch {
+					batch = batch[:nextBatch]
+				}
+				ids = ids[len(batch):]
+				// index is not updated on deletes, so we need to check the log
+				prims, err := qs.getPrimitivesFromLog(ctx, tx, batch)
+				if err != nil {
+					return err
+				}
+				for _, p := range prims {
+					if p != nil && !p.Deleted {
+						cnt[id]++
+					}
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cnt, nil
+}
+
+func (qs *QuadStore) countFromLog(ctx context.Context, dir quad.Direction) (map[uint64]int64, error) {
+	cnt := make(map[uint64]int64)
+	err := graph.Iterate(ctx, NewAllIterator(false, qs, nil)).Each(func(v graph.Value) {
+		if p, ok := v.(*proto.Primitive); ok {
+			cnt[p.GetDirection(dir)]++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cnt, nil
+}
@@ -0,0 +1,155 @@
+package graph
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// OptLogger is the Options key for a per-store Logger.
+	OptLogger = "logger"
+	// OptSlowQuery is the Options key for a duration after which backend operations are reported as slow.
+	OptSlowQuery = "slow_query"
+)
+
+// DefaultSlowQuery is the default threshold for reporting slow backend operations.
+const DefaultSlowQuery = time.Second
+
+// LogField is a single key-value pair attached to a log event.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives structured events from quad store backends, such as slow queries,
+// transaction retries and connection events.
+type Logger interface {
+	Debug(msg string, fields ...LogField)
+	Info(msg string, fields ...LogField)
+	Warn(msg string, fields ...LogField)
+	Error(msg string, fields ...LogField)
+}
+
+// NopLogger is a Logger that discards all events.
+type NopLogger struct{}
+
+func (NopLogger) Debug(msg string, fields ...LogField) {}
+func (NopLogger) Info(msg string, fields ...LogField)  {}
+func (NopLogger) Warn(msg string, fields ...LogField)  {}
+func (NopLogger) Error(msg string, fields ...LogField) {}
+
+// globalLog holds a loggerRef with the Logger set by SetLogger.
+var globalLog atomic.Value
+
+// loggerRef allows to store a nil Logger in atomic.Value.
+type loggerRef struct {
+	l Logger
+}
+
+// SetLogger sets a global Logger used by all quad stores that have no logger set in their Options.
+// Passing nil disables logging. It is safe to call concurrently with running quad stores.
+func SetLogger(l Logger) {
+	if _, ok := l.(NopLogger); ok {
+		l = nil
+	}
+	globalLog.Store(loggerRef{l: l})
+}
+
+// getLogger returns the global Logger, or nil if logging is disabled.
+func getLogger() Logger {
+	ref, _ := globalLog.Load().(loggerRef)
+	return ref.l
+}
+
+// globalLogger forwards all events to the Logger set with SetLogger.
+type globalLogger struct{}
+
+func (globalLogger) Debug(msg string, fields ...LogField) {
+	if l := getLogger(); l != nil {
+		l.Debug(msg, fields...)
+	}
+}
+func (globalLogger) Info(msg string, fields ...LogField) {
+	if l := getLogger(); l != nil {
+		l.Info(msg, fields...)
+	}
+}
+func (globalLogger) Warn(msg string, fields ...LogField) {
+	if l := getLogger(); l != nil {
+		l.Warn(msg, fields...)
+	}
+}
+func (globalLogger) Error(msg string, fields ...LogField) {
+	if l := getLogger(); l != nil {
+		l.Error(msg, fields...)
+	}
+}
+
+// logEnabled checks if events sent to l are recorded anywhere.
+func logEnabled(l Logger) bool {
+	switch l.(type) {
+	case nil, NopLogger:
+		return false
+	case globalLogger:
+		return getLogger() != nil
+	}
+	return true
+}
+
+// Logger returns a Logger set for the store with OptLogger key.
+// If it's not set, a logger forwarding events to the global Logger will be returned.
+func (d Options) Logger() (Logger, error) {
+	if val, ok := d[OptLogger]; ok && val != nil {
+		if l, ok := val.(Logger); ok {
+			return l, nil
+		}
+		return globalLogger{}, fmt.Errorf("Invalid %s parameter type from config: %T", OptLogger, val)
+	}
+	return globalLogger{}, nil
+}
+
+// DurationKey returns a duration for a given key. Values can be set either as time.Duration, or as a string
+// in a format accepted by time.ParseDuration.
+func (d Options) DurationKey(key string, def time.Duration) (time.Duration, error) {
+	if val, ok := d[key]; ok {
+		switch v := val.(type) {
+		case time.Duration:
+			return v, nil
+		case string:
+			dt, err := time.ParseDuration(v)
+			if err != nil {
+				return def, fmt.Errorf("Invalid %s parameter from config: %v", key, err)
+			}
+			return dt, nil
+		}
+		return def, fmt.Errorf("Invalid %s parameter type from config: %T", key, val)
+	}
+	return def, nil
+}
+
+// StartSlow returns a start time of an operation that should be passed to LogSlow.
+// It returns a zero time if slow operations are not logged, so the clock is not read at all.
+func StartSlow(l Logger, threshold time.Duration) time.Time {
+	if threshold <= 0 || !logEnabled(l) {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// LogSlow reports an operation that started at a given time as slow, if it took longer than a threshold.
+// Zero or negative threshold, a nil logger or a zero start time disables the check.
+func LogSlow(l Logger, threshold time.Duration, op string, start time.Time, fields ...LogField) {
+	if threshold <= 0 || l == nil || start.IsZero() {
+		return
+	}
+	dt := time.Since(start)
+	if dt < threshold {
+		return
+	}
+	fields = append([]LogField{
+		{Key: "op", Value: op},
+		{Key: "duration", Value: dt},
+	}, fields...)
+	l.Warn("slow operation", fields...)
+}
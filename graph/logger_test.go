@@ -0,0 +1,72 @@
+package graph_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caivega/cayley/graph"
+)
+
+type countLogger struct {
+	warns int32
+}
+
+func (l *countLogger) Debug(msg string, fields ...graph.LogField) {}
+func (l *countLogger) Info(msg string, fields ...graph.LogField)  {}
+func (l *countLogger) Warn(msg string, fields ...graph.LogField)  { atomic.AddInt32(&l.warns, 1) }
+func (l *countLogger) Error(msg string, fields ...graph.LogField) {}
+
+func TestStartSlow(t *testing.T) {
+	defer graph.SetLogger(nil)
+	global, err := graph.Options{}.Logger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []graph.Logger{nil, graph.NopLogger{}, global} {
+		if st := graph.StartSlow(l, time.Nanosecond); !st.IsZero() {
+			t.Errorf("%T: expected no timing for disabled logger", l)
+		}
+	}
+	l := &countLogger{}
+	if st := graph.StartSlow(l, 0); !st.IsZero() {
+		t.Errorf("expected no timing without a threshold")
+	}
+	graph.SetLogger(l)
+	st := graph.StartSlow(global, time.Nanosecond)
+	if st.IsZero() {
+		t.Fatal("expected timing for the global logger")
+	}
+	time.Sleep(time.Millisecond)
+	graph.LogSlow(global, time.Nanosecond, "op", st)
+	if n := atomic.LoadInt32(&l.warns); n != 1 {
+		t.Errorf("expected one event, got %d", n)
+	}
+}
+
+func TestSetLoggerConcurrent(t *testing.T) {
+	defer graph.SetLogger(nil)
+	global, err := graph.Options{}.Logger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				graph.SetLogger(&countLogger{})
+				graph.SetLogger(nil)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				global.Warn("event")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -37,16 +37,17 @@ type AllIterator struct {
 	done bool
 }
 
-func newAllIterator(qs *QuadStore, nodes bool, maxid int64) *AllIterator {
+func newAllIterator(qs *QuadStore, nodes bool, all []*primitive, maxid int64) *AllIterator {
 	return &AllIterator{
 		uid: iterator.NextUID(),
-		qs:  qs, all: qs.cloneAll(), nodes: nodes,
+		qs:  qs, all: all, nodes: nodes,
 		i: -1, maxid: maxid,
 	}
 }
 
 func (it *AllIterator) Clone() graph.Iterator {
-	it2 := newAllIterator(it.qs, it.nodes, it.maxid)
+	// "all" slice is never modified, thus it can be shared with the clone
+	it2 := newAllIterator(it.qs, it.nodes, it.all, it.maxid)
 	it2.tags.CopyFrom(it)
 	return it2
 }
@@ -102,8 +103,10 @@ func (it *AllIterator) Contains(ctx context.Context, v graph.Value) bool {
 	if !ok {
 		return false
 	}
+	it.qs.mu.RLock()
 	p := it.qs.prim[id]
-	if p.ID > it.maxid {
+	it.qs.mu.RUnlock()
+	if p == nil || p.ID > it.maxid {
 		return false
 	}
 	if !it.ok(p) {
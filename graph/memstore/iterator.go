@@ -83,6 +83,9 @@ func (it *Iterator) Close() error {
 
 func (it *Iterator) Next(ctx context.Context) bool {
 	graph.NextLogIn(it)
+	// tree enumerator will seek to the last position if the tree was modified between calls
+	it.qs.mu.RLock()
+	defer it.qs.mu.RUnlock()
 	if it.iter == nil {
 		it.iter, it.err = it.tree.SeekFirst()
 		if it.err == io.EOF || it.iter == nil {
@@ -126,6 +129,8 @@ func (it *Iterator) SubIterators() []graph.Iterator {
 }
 
 func (it *Iterator) Size() (int64, bool) {
+	it.qs.mu.RLock()
+	defer it.qs.mu.RUnlock()
 	return int64(it.tree.Len()), true
 }
 
@@ -136,7 +141,10 @@ func (it *Iterator) Contains(ctx context.Context, v graph.Value) bool {
 	}
 	switch v := v.(type) {
 	case bnode:
-		if p, ok := it.tree.Get(int64(v)); ok {
+		it.qs.mu.RLock()
+		p, ok := it.tree.Get(int64(v))
+		it.qs.mu.RUnlock()
+		if ok {
 			it.cur = p
 			return graph.ContainsLogOut(it, v, true)
 		}
@@ -162,10 +170,11 @@ func (it *Iterator) Optimize() (graph.Iterator, bool) {
 }
 
 func (it *Iterator) Stats() graph.IteratorStats {
+	size, _ := it.Size()
 	return graph.IteratorStats{
-		ContainsCost: int64(math.Log(float64(it.tree.Len()))) + 1,
+		ContainsCost: int64(math.Log(float64(size))) + 1,
 		NextCost:     1,
-		Size:         int64(it.tree.Len()),
+		Size:         size,
 		ExactSize:    true,
 	}
 }
@@ -15,9 +15,14 @@
 package memstore
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/graph/iterator"
@@ -28,15 +33,35 @@ const QuadStoreType = "memstore"
 
 func init() {
 	graph.RegisterQuadStore(QuadStoreType, graph.QuadStoreRegistration{
-		NewFunc: func(string, graph.Options) (graph.QuadStore, error) {
-			return newQuadStore(), nil
+		NewFunc: func(_ string, opts graph.Options) (graph.QuadStore, error) {
+			qs := newQuadStore()
+			so, err := opts.BoolKey(graph.OptStatsOptimize, true)
+			if err != nil {
+				return nil, err
+			}
+			qs.noStatsOptimize = !so
+			n, err := opts.IntKey(OptBatchSize, 0)
+			if err != nil {
+				return nil, err
+			} else if n < 0 {
+				return nil, fmt.Errorf("memstore: invalid %s: %d", OptBatchSize, n)
+			}
+			qs.batchSize = n
+			return qs, nil
+		},
+		UpgradeFunc: nil,
+		InitFunc:    nil,
+		RestoreFunc: func(data []byte) (graph.QuadStore, error) {
+			return Restore(data)
 		},
-		UpgradeFunc:  nil,
-		InitFunc:     nil,
 		IsPersistent: false,
 	})
 }
 
+// OptBatchSize is the Options key for the number of deltas checked and applied at once by ApplyDeltas.
+// See QuadStore.ApplyDeltas for details.
+const OptBatchSize = "batch_size"
+
 type bnode int64
 
 func (n bnode) Key() interface{} { return n }
@@ -47,7 +72,11 @@ type qprim struct {
 
 func (n qprim) Key() interface{} { return n.p.ID }
 
-var _ quad.Writer = (*QuadStore)(nil)
+var (
+	_ quad.Writer          = (*QuadStore)(nil)
+	_ graph.Stats          = (*QuadStore)(nil)
+	_ graph.StatsOptimizer = (*QuadStore)(nil)
+)
 
 func cmp(a, b int64) int {
 	return int(a - b)
@@ -130,14 +159,30 @@ func (q internalQuad) Dir(d quad.Direction) int64 {
 	return n
 }
 
+// QuadStore is an in-memory quad store.
+//
+// It is safe for concurrent use. Writes are serialized, while reads and iterators may run in parallel with them.
+//
+// Quads and nodes are iterated in insertion order, thus stores built by inserting the same quads in the same
+// sequence will always produce the same results. A quad that is removed and added again is moved to the end.
 type QuadStore struct {
+	// noStatsOptimize disables the reordering of iterators based on size estimates.
+	// Set with graph.OptStatsOptimize option.
+	noStatsOptimize bool
+	// batchSize is the number of deltas checked and applied at once by ApplyDeltas; zero means no limit.
+	// Set with OptBatchSize option.
+	batchSize int
+
+	// mu protects all fields below. Readers (including iterators) hold a read lock only for the duration
+	// of a single call, thus writes are allowed between calls to iterator methods.
+	mu   sync.RWMutex
 	last int64
 	// TODO: string -> quad.Value once Raw -> typed resolution is unnecessary
 	vals    map[string]int64
 	quads   map[internalQuad]int64
 	prim    map[int64]*primitive
 	all     []*primitive // might not be sorted by id
-	reading bool         // someone else might be reading "all" slice - next insert/delete should clone it
+	reading int32        // someone else might be reading "all" slice - next insert/delete should clone it; accessed atomically
 	index   QuadDirectionIndex
 	horizon int64 // used only to assign ids to tx
 	// vip_index map[string]map[int64]map[string]map[int64]*b.Tree
@@ -147,7 +192,7 @@ type QuadStore struct {
 func New(quads ...quad.Quad) *QuadStore {
 	qs := newQuadStore()
 	for _, q := range quads {
-		qs.AddQuad(q)
+		qs.addQuad(q)
 	}
 	return qs
 }
@@ -161,11 +206,19 @@ func newQuadStore() *QuadStore {
 	}
 }
 
+// cloneAll returns a "all" slice that will not be modified by subsequent writes.
+// It can be called under a read lock.
 func (qs *QuadStore) cloneAll() []*primitive {
-	qs.reading = true
+	atomic.StoreInt32(&qs.reading, 1)
 	return qs.all
 }
 
+// readingAll checks if "all" slice was shared with readers. If it was, the flag is reset,
+// and the caller must allocate a new slice. It must be called under a write lock.
+func (qs *QuadStore) readingAll() bool {
+	return atomic.SwapInt32(&qs.reading, 0) != 0
+}
+
 func (qs *QuadStore) addPrimitive(p *primitive) int64 {
 	qs.last++
 	id := qs.last
@@ -177,12 +230,11 @@ func (qs *QuadStore) addPrimitive(p *primitive) int64 {
 
 func (qs *QuadStore) appendPrimitive(p *primitive) {
 	qs.prim[p.ID] = p
-	if !qs.reading {
+	if !qs.readingAll() {
 		qs.all = append(qs.all, p)
 	} else {
 		n := len(qs.all)
 		qs.all = append(qs.all[:n:n], p) // reallocate slice
-		qs.reading = false               // this is a new slice
 	}
 }
 
@@ -257,12 +309,16 @@ func (qs *QuadStore) lookupQuadDirs(p internalQuad) quad.Quad {
 
 // AddNode adds a blank node (with no value) to quad store. It returns an id of the node.
 func (qs *QuadStore) AddBNode() int64 {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
 	return qs.addPrimitive(&primitive{})
 }
 
 // AddNode adds a value to quad store. It returns an id of the value.
 // False is returned as a second parameter if value exists already.
 func (qs *QuadStore) AddValue(v quad.Value) (int64, bool) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
 	id, exists := qs.resolveVal(v, true)
 	return id, !exists
 }
@@ -282,6 +338,12 @@ func (qs *QuadStore) indexesForQuad(q internalQuad) []*Tree {
 // AddQuad adds a quad to quad store. It returns an id of the quad.
 // False is returned as a second parameter if quad exists already.
 func (qs *QuadStore) AddQuad(q quad.Quad) (int64, bool) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	return qs.addQuad(q)
+}
+
+func (qs *QuadStore) addQuad(q quad.Quad) (int64, bool) {
 	p, _ := qs.resolveQuad(q, true)
 	if id := qs.quads[p]; id != 0 {
 		return id, false
@@ -315,12 +377,20 @@ func (qs *QuadStore) deleteQuadNodes(q internalQuad) {
 			if p.refs < 0 {
 				panic("remove of deleted node")
 			} else if p.refs == 0 {
-				qs.Delete(id)
+				qs.delete(id)
 			}
 		}
 	}
 }
+
+// Delete removes a quad or a value with a given id from the store.
 func (qs *QuadStore) Delete(id int64) bool {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	return qs.delete(id)
+}
+
+func (qs *QuadStore) delete(id int64) bool {
 	p := qs.prim[id]
 	if p == nil {
 		return false
@@ -344,14 +414,13 @@ func (qs *QuadStore) Delete(id int64) bool {
 		}
 	}
 	if di >= 0 {
-		if !qs.reading {
+		if !qs.readingAll() {
 			qs.all = append(qs.all[:di], qs.all[di+1:]...)
 		} else {
 			all := make([]*primitive, 0, len(qs.all)-1)
 			all = append(all, qs.all[:di]...)
 			all = append(all, qs.all[di+1:]...)
 			qs.all = all
-			qs.reading = false // this is a new slice
 		}
 	}
 	qs.deleteQuadNodes(p.Quad)
@@ -367,46 +436,167 @@ func (qs *QuadStore) findQuad(q quad.Quad) (int64, internalQuad, bool) {
 	return id, p, id != 0
 }
 
+// ApplyDeltas applies a set of deltas to the store.
+//
+// The whole set is checked before any changes are made, and the lock is held until all deltas are applied,
+// thus readers never observe a partially applied set.
+//
+// If OptBatchSize is set, larger sets are checked and applied in batches of this size, which bounds the memory
+// used for the check. The result is the same as for a single batch. If one of the batches is rejected,
+// previous batches are reverted; the contents of the store is restored, but quads removed by reverted batches
+// are moved to the end of the iteration order. No changes need to be reverted if both IgnoreDup and
+// IgnoreMissing are set.
 func (qs *QuadStore) ApplyDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
-	// Precheck the whole transaction (if required)
+	for _, d := range deltas {
+		switch d.Action {
+		case graph.Add, graph.Delete:
+		default:
+			return &graph.DeltaError{Delta: d, Err: graph.ErrInvalidAction}
+		}
+	}
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	if n := qs.batchSize; n > 0 && len(deltas) > n {
+		if err := qs.applyBatches(deltas, n, ignoreOpts); err != nil {
+			return err
+		}
+	} else {
+		// Validate the whole batch first, so a rejected batch leaves the store unchanged
+		if err := qs.checkDeltas(deltas, ignoreOpts, nil); err != nil {
+			return err
+		}
+		qs.applyDeltas(deltas, nil)
+	}
+	qs.horizon++
+	return nil
+}
+
+// batchUndo records changes made by previous batches of ApplyDeltas.
+type batchUndo struct {
+	added   map[int64]struct{} // quads added by previous batches; set only if duplicates are checked
+	off     int                // offset of the current batch in the whole set
+	changes []int              // indexes of deltas that changed the store, in order
+}
+
+// applyBatches checks and applies deltas in batches of size n, and reverts all batches if one of them is rejected.
+// It must be called under a write lock.
+func (qs *QuadStore) applyBatches(deltas []graph.Delta, n int, ignoreOpts graph.IgnoreOpts) error {
+	var undo *batchUndo
 	if !ignoreOpts.IgnoreDup || !ignoreOpts.IgnoreMissing {
-		for _, d := range deltas {
-			switch d.Action {
-			case graph.Add:
-				if !ignoreOpts.IgnoreDup {
-					if _, _, ok := qs.findQuad(d.Quad); ok {
-						return &graph.DeltaError{Delta: d, Err: graph.ErrQuadExists}
-					}
-				}
-			case graph.Delete:
-				if !ignoreOpts.IgnoreMissing {
-					if _, _, ok := qs.findQuad(d.Quad); !ok {
-						return &graph.DeltaError{Delta: d, Err: graph.ErrQuadNotExist}
-					}
-				}
-			default:
-				return &graph.DeltaError{Delta: d, Err: graph.ErrInvalidAction}
+		undo = &batchUndo{}
+		if !ignoreOpts.IgnoreDup {
+			undo.added = make(map[int64]struct{})
+		}
+	}
+	for off := 0; off < len(deltas); off += n {
+		batch := deltas[off:]
+		if len(batch) > n {
+			batch = batch[:n]
+		}
+		if err := qs.checkDeltas(batch, ignoreOpts, undo); err != nil {
+			if undo != nil {
+				qs.revert(deltas, undo.changes)
+			}
+			return err
+		}
+		if undo != nil {
+			undo.off = off
+		}
+		qs.applyDeltas(batch, undo)
+	}
+	return nil
+}
+
+// revert reverts changes made by applyDeltas. It must be called under a write lock.
+func (qs *QuadStore) revert(deltas []graph.Delta, changes []int) {
+	for i := len(changes) - 1; i >= 0; i-- {
+		d := deltas[changes[i]]
+		switch d.Action {
+		case graph.Add:
+			if id, _, ok := qs.findQuad(d.Quad); ok {
+				qs.delete(id)
 			}
+		case graph.Delete:
+			qs.addQuad(d.Quad)
 		}
 	}
+}
 
+// checkDeltas validates the whole batch before any index is modified, thus a rejected batch leaves
+// the store unchanged. Deltas are checked in order, taking into account changes made by preceding deltas
+// of the same batch. Like in other backends, a quad added multiple times in a batch is not a duplicate.
+//
+// If undo is set, quads added by previous batches of the same set are not duplicates as well.
+func (qs *QuadStore) checkDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts, undo *batchUndo) error {
+	// state of quads changed by preceding deltas of this batch; true means added
+	var changed map[[4]string]bool
+	exists := func(q quad.Quad) (bool, bool) {
+		if added, ok := changed[quadKey(q)]; ok {
+			return added, added
+		}
+		id, _, ok := qs.findQuad(q)
+		if ok && undo != nil {
+			_, added := undo.added[id]
+			return ok, added
+		}
+		return ok, false
+	}
+	if ignoreOpts.IgnoreDup && ignoreOpts.IgnoreMissing {
+		return nil
+	}
 	for _, d := range deltas {
+		ok, added := exists(d.Quad)
 		switch d.Action {
 		case graph.Add:
-			qs.AddQuad(d.Quad)
+			if ok && !added && !ignoreOpts.IgnoreDup {
+				return &graph.DeltaError{Delta: d, Err: graph.ErrQuadExists}
+			}
 		case graph.Delete:
-			if id, _, ok := qs.findQuad(d.Quad); ok {
-				qs.Delete(id)
+			if !ok && !ignoreOpts.IgnoreMissing {
+				return &graph.DeltaError{Delta: d, Err: graph.ErrQuadNotExist}
 			}
-		default:
-			// TODO: ideally we should rollback it
-			return &graph.DeltaError{Delta: d, Err: graph.ErrInvalidAction}
 		}
+		if changed == nil {
+			changed = make(map[[4]string]bool)
+		}
+		changed[quadKey(d.Quad)] = d.Action == graph.Add
 	}
-	qs.horizon++
 	return nil
 }
 
+// quadKey returns a comparable key for a quad.
+func quadKey(q quad.Quad) [4]string {
+	var k [4]string
+	for i, dir := range quad.Directions {
+		k[i] = quad.StringOf(q.Get(dir))
+	}
+	return k
+}
+
+// applyDeltas applies deltas that were already checked. If undo is set, changes are recorded to it.
+func (qs *QuadStore) applyDeltas(deltas []graph.Delta, undo *batchUndo) {
+	for i, d := range deltas {
+		switch d.Action {
+		case graph.Add:
+			id, ok := qs.addQuad(d.Quad)
+			if ok && undo != nil {
+				if undo.added != nil {
+					undo.added[id] = struct{}{}
+				}
+				undo.changes = append(undo.changes, undo.off+i)
+			}
+		case graph.Delete:
+			if id, _, ok := qs.findQuad(d.Quad); ok {
+				qs.delete(id)
+				if undo != nil {
+					delete(undo.added, id)
+					undo.changes = append(undo.changes, undo.off+i)
+				}
+			}
+		}
+	}
+}
+
 func asID(v graph.Value) (int64, bool) {
 	switch v := v.(type) {
 	case bnode:
@@ -435,6 +625,8 @@ func (qs *QuadStore) quad(v graph.Value) (q internalQuad, ok bool) {
 }
 
 func (qs *QuadStore) Quad(index graph.Value) quad.Quad {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
 	q, ok := qs.quad(index)
 	if !ok {
 		return quad.Quad{}
@@ -447,6 +639,8 @@ func (qs *QuadStore) QuadIterator(d quad.Direction, value graph.Value) graph.Ite
 	if !ok {
 		return iterator.NewNull()
 	}
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
 	index, ok := qs.index.Get(d, id)
 	if ok && index.Len() != 0 {
 		return NewIterator(index, qs, d, id)
@@ -455,14 +649,86 @@ func (qs *QuadStore) QuadIterator(d quad.Direction, value graph.Value) graph.Ite
 }
 
 func (qs *QuadStore) Size() int64 {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
 	return int64(len(qs.prim))
 }
 
+// PredicateStats returns the number of quads for each predicate, as recorded by the predicate index.
+func (qs *QuadStore) PredicateStats() (map[quad.Value]int64, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	index := qs.index.index[quad.Predicate-1]
+	out := make(map[quad.Value]int64, len(index))
+	for id, tree := range index {
+		if n := tree.Len(); n != 0 {
+			out[qs.lookupVal(id)] = int64(n)
+		}
+	}
+	return out, nil
+}
+
+// CountByLabel returns the number of quads for each label, as recorded by the label index.
+// Quads without a label are counted under the nil key.
+func (qs *QuadStore) CountByLabel(ctx context.Context) (map[quad.Value]int64, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	index := qs.index.index[quad.Label-1]
+	out := make(map[quad.Value]int64, len(index)+1)
+	var labeled int64
+	for id, tree := range index {
+		if n := tree.Len(); n != 0 {
+			out[qs.lookupVal(id)] = int64(n)
+			labeled += int64(n)
+		}
+	}
+	if n := int64(len(qs.quads)) - labeled; n != 0 {
+		out[nil] = n
+	}
+	return out, nil
+}
+
+// AllPredicates returns all distinct predicates used by quads in the store, sorted by their string representation.
+func (qs *QuadStore) AllPredicates() []quad.Value {
+	qs.mu.RLock()
+	index := qs.index.index[quad.Predicate-1]
+	out := make([]quad.Value, 0, len(index))
+	for id, tree := range index {
+		if tree.Len() != 0 {
+			out = append(out, qs.lookupVal(id))
+		}
+	}
+	qs.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	return out
+}
+
+// Dump writes all quads in the store to w in N-Quads format. Quads are sorted, thus the output is deterministic.
+func (qs *QuadStore) Dump(w io.Writer) error {
+	qs.mu.RLock()
+	quads := make([]quad.Quad, 0, len(qs.quads))
+	for q := range qs.quads {
+		quads = append(quads, qs.lookupQuadDirs(q))
+	}
+	qs.mu.RUnlock()
+	sort.Sort(quad.ByQuadString(quads))
+	for _, q := range quads {
+		if _, err := io.WriteString(w, q.NQuad()+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (qs *QuadStore) ValueOf(name quad.Value) graph.Value {
 	if name == nil {
 		return nil
 	}
+	qs.mu.RLock()
 	id := qs.vals[name.String()]
+	qs.mu.RUnlock()
 	if id == 0 {
 		return nil
 	}
@@ -479,6 +745,8 @@ func (qs *QuadStore) NameOf(v graph.Value) quad.Value {
 	if !ok {
 		return nil
 	}
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
 	if _, ok = qs.prim[n]; !ok {
 		return nil
 	}
@@ -486,11 +754,15 @@ func (qs *QuadStore) NameOf(v graph.Value) quad.Value {
 }
 
 func (qs *QuadStore) QuadsAllIterator() graph.Iterator {
-	return newAllIterator(qs, false, qs.last)
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	return newAllIterator(qs, false, qs.cloneAll(), qs.last)
 }
 
 func (qs *QuadStore) QuadDirection(val graph.Value, d quad.Direction) graph.Value {
+	qs.mu.RLock()
 	q, ok := qs.quad(val)
+	qs.mu.RUnlock()
 	if !ok {
 		return nil
 	}
@@ -502,7 +774,12 @@ func (qs *QuadStore) QuadDirection(val graph.Value, d quad.Direction) graph.Valu
 }
 
 func (qs *QuadStore) NodesAllIterator() graph.Iterator {
-	return newAllIterator(qs, true, qs.last)
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	return newAllIterator(qs, true, qs.cloneAll(), qs.last)
 }
 
 func (qs *QuadStore) Close() error { return nil }
+
+// Ping always succeeds for in-memory store.
+func (qs *QuadStore) Ping(ctx context.Context) error { return nil }
@@ -15,9 +15,13 @@
 package memstore
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/caivega/cayley/graph"
@@ -245,3 +249,357 @@ func TestTransaction(t *testing.T) {
 		t.Error("Appended a new quad in a failed transaction")
 	}
 }
+
+func TestSnapshot(t *testing.T) {
+	qs, w, _ := makeTestStore(simpleGraph)
+	err := w.RemoveQuad(quad.MakeRaw("E", "follows", "F", ""))
+	require.NoError(t, err)
+
+	data := qs.Snapshot()
+	qs2, err := graph.NewQuadStoreFromSnapshot(QuadStoreType, data)
+	require.NoError(t, err)
+	require.Equal(t, data, qs2.(*QuadStore).Snapshot(), "snapshot is not deterministic")
+	require.Equal(t, qs.Size(), qs2.Size())
+
+	readAll := func(qs graph.QuadStore) []quad.Quad {
+		quads, err := quad.ReadAll(graph.NewQuadStoreReader(qs))
+		require.NoError(t, err)
+		sort.Sort(quad.ByQuadString(quads))
+		return quads
+	}
+	require.Equal(t, readAll(qs), readAll(qs2))
+
+	for _, v := range []quad.Value{quad.Raw("B"), quad.Raw("follows"), quad.Raw("status_graph")} {
+		require.Equal(t, qs.ValueOf(v), qs2.ValueOf(v))
+	}
+	require.Nil(t, qs2.ValueOf(quad.Raw("E")))
+
+	iterated := func(qs graph.QuadStore) []quad.Value {
+		var out []quad.Value
+		it := qs.QuadIterator(quad.Object, qs.ValueOf(quad.Raw("B")))
+		err := graph.Iterate(context.TODO(), it).On(qs).Each(func(v graph.Value) {
+			out = append(out, qs.Quad(v).Subject)
+		})
+		require.NoError(t, err)
+		return out
+	}
+	require.Equal(t, iterated(qs), iterated(qs2))
+
+	// restored store is independent from the original one
+	w2, err := writer.NewSingleReplication(qs2, nil)
+	require.NoError(t, err)
+	err = w2.AddQuad(quad.MakeRaw("E", "follows", "B", ""))
+	require.NoError(t, err)
+	require.Equal(t, len(readAll(qs))+1, len(readAll(qs2)))
+	require.Equal(t, data, qs.Snapshot())
+
+	_, err = Restore(data[:len(data)-3])
+	require.Error(t, err)
+}
+
+func TestConcurrentReadWrite(t *testing.T) {
+	qs, w, _ := makeTestStore(simpleGraph)
+	ctx := context.TODO()
+
+	const (
+		readers = 4
+		writes  = 200
+	)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errc := make(chan error, readers+1)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(stop)
+		for i := 0; i < writes; i++ {
+			q := quad.MakeRaw(fmt.Sprintf("n%d", i), "follows", "B", "")
+			if err := w.AddQuad(q); err != nil {
+				errc <- err
+				return
+			}
+			if i%2 == 1 {
+				if err := w.RemoveQuad(q); err != nil {
+					errc <- err
+					return
+				}
+			}
+		}
+	}()
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				b := qs.ValueOf(quad.Raw("B"))
+				if got := qs.NameOf(b); got != quad.Raw("B") {
+					errc <- fmt.Errorf("unexpected name: %v", got)
+					return
+				}
+				it := qs.QuadIterator(quad.Object, b)
+				err := graph.Iterate(ctx, it).Each(func(v graph.Value) {
+					qs.Quad(v)
+					qs.NameOf(qs.QuadDirection(v, quad.Subject))
+				})
+				if err == nil {
+					err = graph.Iterate(ctx, qs.QuadsAllIterator()).Each(func(v graph.Value) {
+						qs.Quad(v)
+					})
+				}
+				if err != nil {
+					errc <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		require.NoError(t, err)
+	}
+
+	var n int
+	it := qs.QuadIterator(quad.Object, qs.ValueOf(quad.Raw("B")))
+	err := graph.Iterate(ctx, it).Each(func(graph.Value) { n++ })
+	require.NoError(t, err)
+	require.Equal(t, 3+writes/2, n)
+}
+
+func TestAllPredicates(t *testing.T) {
+	qs, w, _ := makeTestStore(simpleGraph)
+	require.Equal(t, []quad.Value{quad.String("follows"), quad.String("status")}, qs.AllPredicates())
+
+	for _, q := range simpleGraph {
+		if q.Predicate == quad.String("status") {
+			require.NoError(t, w.RemoveQuad(q))
+		}
+	}
+	require.Equal(t, []quad.Value{quad.String("follows")}, qs.AllPredicates())
+}
+
+func TestDump(t *testing.T) {
+	qs1, _, _ := makeTestStore(simpleGraph)
+	rev := make([]quad.Quad, 0, len(simpleGraph))
+	for i := len(simpleGraph) - 1; i >= 0; i-- {
+		rev = append(rev, simpleGraph[i])
+	}
+	qs2, _, _ := makeTestStore(rev)
+	size := qs1.Size()
+
+	var b1, b2 bytes.Buffer
+	require.NoError(t, qs1.Dump(&b1))
+	require.NoError(t, qs2.Dump(&b2))
+	require.Equal(t, b1.String(), b2.String())
+
+	lines := strings.Split(strings.TrimSuffix(b1.String(), "\n"), "\n")
+	require.Len(t, lines, len(simpleGraph))
+	require.Equal(t, `"A" "follows" "B" .`, lines[0])
+	require.Equal(t, `"B" "status" "cool" "status_graph" .`, lines[2])
+	require.Equal(t, size, qs1.Size())
+}
+
+func makeDeltas(n, step int, act graph.Procedure) []graph.Delta {
+	deltas := make([]graph.Delta, 0, n/step)
+	for i := 0; i < n; i += step {
+		deltas = append(deltas, graph.Delta{
+			Quad:   quad.MakeIRI(fmt.Sprintf("n%d", i), "follows", fmt.Sprintf("n%d", i/3), ""),
+			Action: act,
+		})
+	}
+	return deltas
+}
+
+func TestApplyDeltasAtomic(t *testing.T) {
+	var (
+		q1 = quad.MakeIRI("a", "b", "c", "")
+		q2 = quad.MakeIRI("a", "b", "d", "")
+		q3 = quad.MakeIRI("a", "b", "e", "")
+	)
+	for _, c := range []struct {
+		name   string
+		deltas []graph.Delta
+		opts   graph.IgnoreOpts
+	}{
+		{
+			name: "duplicate add",
+			deltas: []graph.Delta{
+				{Quad: q2, Action: graph.Add},
+				{Quad: q1, Action: graph.Add},
+			},
+		},
+		{
+			name: "repeated delete",
+			deltas: []graph.Delta{
+				{Quad: q2, Action: graph.Add},
+				{Quad: q1, Action: graph.Delete},
+				{Quad: q1, Action: graph.Delete},
+			},
+		},
+		{
+			name: "invalid action",
+			deltas: []graph.Delta{
+				{Quad: q2, Action: graph.Add},
+				{Quad: q3, Action: graph.Procedure(0)},
+			},
+			opts: graph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			qs := New(q1)
+			exp := qs.Snapshot()
+			err := qs.ApplyDeltas(c.deltas, c.opts)
+			require.Error(t, err)
+			require.Equal(t, exp, qs.Snapshot())
+		})
+	}
+
+	// deltas are checked in order, including changes made by preceding deltas
+	qs := New(q1)
+	err := qs.ApplyDeltas([]graph.Delta{
+		{Quad: q1, Action: graph.Delete},
+		{Quad: q1, Action: graph.Add},
+		{Quad: q2, Action: graph.Add},
+		{Quad: q2, Action: graph.Add},
+		{Quad: q3, Action: graph.Add},
+		{Quad: q3, Action: graph.Delete},
+	}, graph.IgnoreOpts{})
+	require.NoError(t, err)
+	var got []quad.Quad
+	err = graph.Iterate(context.TODO(), qs.QuadsAllIterator()).Each(func(v graph.Value) {
+		got = append(got, qs.Quad(v))
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []quad.Quad{q1, q2}, got)
+}
+
+func newBatchStore(t testing.TB, n int) *QuadStore {
+	qs, err := graph.NewQuadStore(QuadStoreType, "", graph.Options{OptBatchSize: n})
+	require.NoError(t, err)
+	return qs.(*QuadStore)
+}
+
+func TestApplyDeltasBatch(t *testing.T) {
+	// quads added or removed multiple times across batches
+	var mixed []graph.Delta
+	for _, act := range []graph.Procedure{graph.Add, graph.Add, graph.Delete, graph.Add} {
+		for i := 0; i < 10; i++ {
+			mixed = append(mixed, graph.Delta{Quad: quad.MakeIRI(fmt.Sprintf("m%d", i), "follows", "n1", ""), Action: act})
+		}
+	}
+	for _, opts := range []graph.IgnoreOpts{
+		{},
+		{IgnoreDup: true, IgnoreMissing: true},
+	} {
+		single := New()
+		batch := newBatchStore(t, 7)
+		for _, deltas := range [][]graph.Delta{
+			makeDeltas(1000, 1, graph.Add),
+			makeDeltas(1000, 10, graph.Delete),
+			mixed,
+		} {
+			err := single.ApplyDeltas(deltas, opts)
+			require.NoError(t, err)
+			err = batch.ApplyDeltas(deltas, opts)
+			require.NoError(t, err)
+			require.Equal(t, single.Snapshot(), batch.Snapshot())
+		}
+	}
+
+	quads := func(qs *QuadStore) []quad.Quad {
+		var out []quad.Quad
+		err := graph.Iterate(context.TODO(), qs.QuadsAllIterator()).Each(func(v graph.Value) {
+			out = append(out, qs.Quad(v))
+		})
+		require.NoError(t, err)
+		return out
+	}
+
+	// the whole set is rejected, if one of the batches fails
+	missing := graph.Delta{Quad: quad.MakeIRI("a", "b", "c", ""), Action: graph.Delete}
+	for _, bad := range [][]graph.Delta{
+		append(append(makeDeltas(20, 1, graph.Delete), makeDeltas(30, 1, graph.Add)...), missing),
+		append(mixed[:20:20], makeDeltas(10, 1, graph.Add)...),
+	} {
+		batch := newBatchStore(t, 7)
+		err := batch.ApplyDeltas(makeDeltas(20, 1, graph.Add), graph.IgnoreOpts{})
+		require.NoError(t, err)
+		exp := quads(batch)
+		err = batch.ApplyDeltas(bad, graph.IgnoreOpts{})
+		require.Error(t, err)
+		require.ElementsMatch(t, exp, quads(batch))
+	}
+
+	_, err := graph.NewQuadStore(QuadStoreType, "", graph.Options{OptBatchSize: -1})
+	require.Error(t, err)
+}
+
+func BenchmarkApplyDeltas(b *testing.B) {
+	deltas := makeDeltas(100000, 1, graph.Add)
+	for _, c := range []struct {
+		name  string
+		batch int
+		opts  graph.IgnoreOpts
+	}{
+		{name: "single"},
+		{name: "batch", batch: 1000},
+		{name: "single ignore", opts: graph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true}},
+		{name: "batch ignore", batch: 1000, opts: graph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				qs := newBatchStore(b, c.batch)
+				if err := qs.ApplyDeltas(deltas, c.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderedIteration(t *testing.T) {
+	quads := makeDeltas(200, 1, graph.Add)
+	var exp []quad.Quad
+	for _, d := range quads {
+		exp = append(exp, d.Quad)
+	}
+	// delete some quads and add them back, so they are moved to the end
+	var (
+		del, add []graph.Delta
+		readd    []quad.Quad
+	)
+	for i := 0; i < len(exp); i += 20 {
+		del = append(del, graph.Delta{Quad: exp[i], Action: graph.Delete})
+		add = append(add, graph.Delta{Quad: exp[i], Action: graph.Add})
+		readd = append(readd, exp[i])
+	}
+	for i := len(exp) - 1; i >= 0; i-- {
+		if i%20 == 0 {
+			exp = append(exp[:i], exp[i+1:]...)
+		}
+	}
+	exp = append(exp, readd...)
+
+	list := func() []quad.Quad {
+		qs := New()
+		for _, deltas := range [][]graph.Delta{quads, del, add} {
+			require.NoError(t, qs.ApplyDeltas(deltas, graph.IgnoreOpts{}))
+		}
+		var out []quad.Quad
+		err := graph.Iterate(context.TODO(), qs.QuadsAllIterator()).Each(func(v graph.Value) {
+			out = append(out, qs.Quad(v))
+		})
+		require.NoError(t, err)
+		return out
+	}
+	got1, got2 := list(), list()
+	require.Equal(t, exp, got1)
+	require.Equal(t, got1, got2)
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/quad/pquads"
+)
+
+const (
+	snapshotMagic   = "cayley-memstore"
+	snapshotVersion = 1
+)
+
+const (
+	primNode  = 0 // blank node without a value
+	primValue = 1
+	primQuad  = 2
+)
+
+var errBadSnapshot = errors.New("memstore: malformed snapshot")
+
+type byID []*primitive
+
+func (a byID) Len() int           { return len(a) }
+func (a byID) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a byID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// Snapshot serializes all values, quads and their ids.
+//
+// The same store content always produces the same snapshot.
+// Use Restore or graph.NewQuadStoreFromSnapshot to create a copy of the store from it.
+func (qs *QuadStore) Snapshot() []byte {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	prims := make([]*primitive, 0, len(qs.prim))
+	for _, p := range qs.prim {
+		prims = append(prims, p)
+	}
+	sort.Sort(byID(prims))
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(snapshotMagic)
+	var tmp [binary.MaxVarintLen64]byte
+	putInt := func(v int64) {
+		n := binary.PutVarint(tmp[:], v)
+		buf.Write(tmp[:n])
+	}
+	putInt(snapshotVersion)
+	putInt(qs.last)
+	putInt(qs.horizon)
+	putInt(int64(len(prims)))
+	for _, p := range prims {
+		putInt(p.ID)
+		putInt(int64(p.refs))
+		switch {
+		case p.Value != nil:
+			data, err := pquads.MarshalValue(p.Value)
+			if err != nil {
+				// all quad values are supported by pquads
+				panic(fmt.Errorf("cannot encode %v: %v", p.Value, err))
+			}
+			buf.WriteByte(primValue)
+			putInt(int64(len(data)))
+			buf.Write(data)
+		case !p.Quad.Zero():
+			buf.WriteByte(primQuad)
+			for dir := quad.Subject; dir <= quad.Label; dir++ {
+				putInt(p.Quad.Dir(dir))
+			}
+		default:
+			buf.WriteByte(primNode)
+		}
+	}
+	return buf.Bytes()
+}
+
+// Restore creates a new in-memory quad store from a snapshot.
+func Restore(data []byte) (*QuadStore, error) {
+	if !bytes.HasPrefix(data, []byte(snapshotMagic)) {
+		return nil, errBadSnapshot
+	}
+	r := bufio.NewReader(bytes.NewReader(data[len(snapshotMagic):]))
+	var err error
+	getInt := func() int64 {
+		if err != nil {
+			return 0
+		}
+		var v int64
+		v, err = binary.ReadVarint(r)
+		return v
+	}
+	if vers := getInt(); err != nil {
+		return nil, errBadSnapshot
+	} else if vers != snapshotVersion {
+		return nil, fmt.Errorf("memstore: unsupported snapshot version: %d", vers)
+	}
+	qs := newQuadStore()
+	qs.last = getInt()
+	qs.horizon = getInt()
+	n := getInt()
+	if err != nil || n < 0 {
+		return nil, errBadSnapshot
+	}
+	qs.all = make([]*primitive, 0, int(n))
+	for i := int64(0); i < n; i++ {
+		p := &primitive{ID: getInt(), refs: int(getInt())}
+		var kind byte
+		if err == nil {
+			kind, err = r.ReadByte()
+		}
+		if err != nil {
+			break
+		}
+		switch kind {
+		case primValue:
+			sz := getInt()
+			if err != nil || sz < 0 {
+				return nil, errBadSnapshot
+			}
+			buf := make([]byte, sz)
+			if _, err = io.ReadFull(r, buf); err != nil {
+				break
+			}
+			if p.Value, err = pquads.UnmarshalValue(buf); err != nil {
+				return nil, err
+			}
+			qs.vals[p.Value.String()] = p.ID
+		case primQuad:
+			for dir := quad.Subject; dir <= quad.Label; dir++ {
+				p.Quad.SetDir(dir, getInt())
+			}
+			qs.quads[p.Quad] = p.ID
+			for _, t := range qs.indexesForQuad(p.Quad) {
+				t.Set(p.ID, p)
+			}
+		case primNode:
+		default:
+			return nil, errBadSnapshot
+		}
+		qs.prim[p.ID] = p
+		qs.all = append(qs.all, p)
+	}
+	if err != nil {
+		return nil, errBadSnapshot
+	}
+	return qs, nil
+}
@@ -110,6 +110,12 @@ func (db *DB) Close() error {
 	return nil
 }
 
+// Ping checks that Elasticsearch cluster is reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	_, err := db.cli.ClusterHealth().Do(ctx)
+	return err
+}
+
 type indType string
 
 const (
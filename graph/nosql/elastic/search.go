@@ -0,0 +1,143 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+
+	"gopkg.in/olivere/elastic.v5"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/nosql"
+	"github.com/caivega/cayley/graph/path"
+	"github.com/caivega/cayley/graph/shape"
+	"github.com/caivega/cayley/quad"
+)
+
+// ErrNotElastic is returned by MatchText for quad stores that are not backed by Elasticsearch.
+var ErrNotElastic = errors.New("elastic: full-text search is only supported by elastic backend")
+
+const (
+	// MatchTextLimit is the maximal number of matching values returned by a single MatchText query.
+	MatchTextLimit = 100
+
+	// field of a node document with a string representation of the value
+	fldNodeText = "value.str"
+)
+
+// MatchText runs a full-text "match" query against values of a given property and returns
+// a path with subjects that have a matching value, ordered by relevance.
+//
+// The quad store may be wrapped in a graph.Handle or a cache.
+//
+// If tag is not empty, a relevance score of each subject is saved to it as quad.Float.
+// Subjects with multiple matching values get the highest score among them.
+func MatchText(ctx context.Context, qs graph.QuadStore, field quad.IRI, query, tag string) (*path.Path, error) {
+	nqs, ok := graph.Unwrap(qs).(*nosql.QuadStore)
+	if !ok {
+		return nil, ErrNotElastic
+	}
+	db, ok := nqs.DB().(*DB)
+	if !ok {
+		return nil, ErrNotElastic
+	}
+	pred, _ := qs.ValueOf(field).(nosql.NodeHash)
+	hits, err := db.matchText(ctx, string(pred), query)
+	if err != nil {
+		return nil, err
+	}
+	s := make(shape.Union, 0, len(hits))
+	for _, h := range hits {
+		var sub shape.Shape = shape.Fixed{nosql.NodeHash(h.id)}
+		if tag != "" {
+			sub = shape.FixedTags{
+				Tags: map[string]graph.Value{tag: graph.PreFetched(quad.Float(h.score))},
+				On:   sub,
+			}
+		}
+		s = append(s, sub)
+	}
+	return path.PathFromIterator(qs, s.BuildIterator(qs)), nil
+}
+
+type scoredHit struct {
+	id    string
+	score float64
+}
+
+// matchText finds nodes matching a full-text query and returns subjects linked to them via a given predicate.
+func (db *DB) matchText(ctx context.Context, pred, query string) ([]scoredHit, error) {
+	const (
+		colNodes = "nodes"
+		colQuads = "quads"
+	)
+	resp, err := db.cli.Search(db.indexName(colNodes)).Type(colNodes).
+		Query(elastic.NewMatchQuery(fldNodeText, query)).
+		Size(MatchTextLimit).Do(ctx)
+	if err != nil {
+		return nil, err
+	} else if len(resp.Hits.Hits) == 0 {
+		return nil, nil
+	}
+	scores := make(map[string]float64, len(resp.Hits.Hits))
+	objs := make([]interface{}, 0, len(resp.Hits.Hits))
+	for _, h := range resp.Hits.Hits {
+		var sc float64
+		if h.Score != nil {
+			sc = *h.Score
+		}
+		scores[h.Id] = sc
+		objs = append(objs, h.Id)
+	}
+	it := db.cli.Scroll(db.indexName(colQuads)).Type(colQuads).Query(
+		elastic.NewBoolQuery().Filter(
+			elastic.NewTermQuery("predicate", pred),
+			elastic.NewTermsQuery("object", objs...),
+		),
+	)
+	subs := make(map[string]float64)
+	for {
+		resp, err := it.Do(ctx)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for _, h := range resp.Hits.Hits {
+			var q struct {
+				Subject string      `json:"subject"`
+				Object  string      `json:"object"`
+				Added   json.Number `json:"added"`
+				Deleted json.Number `json:"deleted"`
+			}
+			dec := json.NewDecoder(bytes.NewReader(*h.Source))
+			dec.UseNumber()
+			if err = dec.Decode(&q); err != nil {
+				return nil, err
+			}
+			// skip deleted quads
+			added, _ := q.Added.Float64()
+			deleted, _ := q.Deleted.Float64()
+			if added <= deleted {
+				continue
+			}
+			if sc, ok := subs[q.Subject]; !ok || scores[q.Object] > sc {
+				subs[q.Subject] = scores[q.Object]
+			}
+		}
+	}
+	out := make([]scoredHit, 0, len(subs))
+	for id, sc := range subs {
+		out = append(out, scoredHit{id: id, score: sc})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].score == out[j].score {
+			return out[i].id < out[j].id
+		}
+		return out[i].score > out[j].score
+	})
+	return out, nil
+}
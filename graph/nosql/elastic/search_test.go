@@ -0,0 +1,76 @@
+package elastic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/olivere/elastic.v5"
+
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+)
+
+const (
+	testNodeHits = `{"hits":{"total":2,"max_score":2.5,"hits":[
+	{"_index":"cayley_nodes","_type":"nodes","_id":"n1","_score":2.5,"_source":{"value":{"str":"graph database"}}},
+	{"_index":"cayley_nodes","_type":"nodes","_id":"n2","_score":1.0,"_source":{"value":{"str":"database"}}}
+]}}`
+	testQuadHits = `{"_scroll_id":"scroll1","hits":{"total":3,"hits":[
+	{"_index":"cayley_quads","_type":"quads","_id":"q1","_source":{"subject":"s1","predicate":"p","object":"n2","added":1}},
+	{"_index":"cayley_quads","_type":"quads","_id":"q2","_source":{"subject":"s2","predicate":"p","object":"n1","added":1}},
+	{"_index":"cayley_quads","_type":"quads","_id":"q3","_source":{"subject":"s3","predicate":"p","object":"n1","added":1,"deleted":1}}
+]}}`
+	testScrollEnd = `{"_scroll_id":"scroll1","hits":{"total":3,"hits":[]}}`
+)
+
+func TestMatchText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/cayley_nodes/nodes/_search"):
+			w.Write([]byte(testNodeHits))
+		case strings.HasPrefix(r.URL.Path, "/cayley_quads/quads/_search"):
+			w.Write([]byte(testQuadHits))
+		case strings.HasPrefix(r.URL.Path, "/_search/scroll"):
+			w.Write([]byte(testScrollEnd))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cli, err := elastic.NewClient(
+		elastic.SetURL(srv.URL),
+		elastic.SetSniff(false),
+		elastic.SetHealthcheck(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &DB{cli: cli, colls: make(map[string]collection)}
+	db.ind.pref = "cayley"
+
+	hits, err := db.matchText(context.TODO(), "p", "graph database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []scoredHit{
+		{id: "s2", score: 2.5},
+		{id: "s1", score: 1.0},
+	}
+	if !reflect.DeepEqual(hits, exp) {
+		t.Fatalf("unexpected results: %v, expected: %v", hits, exp)
+	}
+}
+
+func TestMatchTextNotSupported(t *testing.T) {
+	qs := memstore.New()
+	_, err := MatchText(context.TODO(), qs, quad.IRI("name"), "bob", "score")
+	if err != ErrNotElastic {
+		t.Fatalf("expected an error for non-elastic store, got: %v", err)
+	}
+}
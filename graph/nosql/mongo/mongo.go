@@ -18,6 +18,7 @@ const Type = "mongo"
 
 var (
 	_ nosql.BatchInserter = (*DB)(nil)
+	_ nosql.BatchFinder   = (*DB)(nil)
 )
 
 func init() {
@@ -99,6 +100,26 @@ func (db *DB) Close() error {
 	db.sess.Close()
 	return nil
 }
+
+// Ping checks that MongoDB server is reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			// mgo panics if the session is already closed
+			if r := recover(); r != nil {
+				errc <- fmt.Errorf("mongo: %v", r)
+			}
+		}()
+		errc <- db.sess.Ping()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 func (db *DB) EnsureIndex(ctx context.Context, col string, primary nosql.Index, secondary []nosql.Index) error {
 	if primary.Type != nosql.StringExact {
 		return fmt.Errorf("unsupported type of primary index: %v", primary.Type)
@@ -317,6 +338,36 @@ func (db *DB) FindByKey(ctx context.Context, col string, key nosql.Key) (nosql.D
 	}
 	return c.convDoc(m), nil
 }
+func (db *DB) FindByKeys(ctx context.Context, col string, keys []nosql.Key) ([]nosql.Document, error) {
+	c := db.colls[col]
+	out := make([]nosql.Document, len(keys))
+	if len(keys) == 0 {
+		return out, nil
+	}
+	ids := make([]string, 0, len(keys))
+	byID := make(map[string][]int, len(keys))
+	for i, k := range keys {
+		id := compKey(k)
+		if _, ok := byID[id]; !ok {
+			ids = append(ids, id)
+		}
+		byID[id] = append(byID[id], i)
+	}
+	it := c.c.Find(bson.M{idField: bson.M{"$in": ids}}).Iter()
+	var m bson.M
+	for it.Next(&m) {
+		id := compKey(c.getKey(m))
+		d := c.convDoc(m)
+		for _, i := range byID[id] {
+			out[i] = d
+		}
+		m = nil
+	}
+	if err := it.Close(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 func (db *DB) Query(col string) nosql.Query {
 	c := db.colls[col]
 	return &Query{c: &c}
@@ -269,6 +269,32 @@ type BatchInserter interface {
 	BatchInsert(col string) DocWriter
 }
 
+// BatchFinder is an optional interface for databases that can find multiple documents by keys at once.
+type BatchFinder interface {
+	// FindByKeys finds documents by their keys. Result has the same length as the list of keys,
+	// and contains nil for documents that not exist.
+	FindByKeys(ctx context.Context, col string, keys []Key) ([]Document, error)
+}
+
+// FindByKeys finds documents by their keys or emulates it if database has no support for batch lookups.
+// Result has the same length as the list of keys, and contains nil for documents that not exist.
+func FindByKeys(ctx context.Context, db Database, col string, keys []Key) ([]Document, error) {
+	if bf, ok := db.(BatchFinder); ok {
+		return bf.FindByKeys(ctx, col, keys)
+	}
+	out := make([]Document, len(keys))
+	for i, key := range keys {
+		d, err := db.FindByKey(ctx, col, key)
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		out[i] = d
+	}
+	return out, nil
+}
+
 // IndexType is a type of index for collection.
 type IndexType int
 
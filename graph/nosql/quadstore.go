@@ -180,7 +180,11 @@ func ensureIndexes(ctx context.Context, db Database) error {
 	if err != nil {
 		return err
 	}
-	err = db.EnsureIndex(ctx, colQuads, Index{
+	return db.EnsureIndex(ctx, colQuads, quadsPrimary, quadsSecondary)
+}
+
+var (
+	quadsPrimary = Index{
 		Fields: []string{
 			fldSubject,
 			fldPredicate,
@@ -188,16 +192,34 @@ func ensureIndexes(ctx context.Context, db Database) error {
 			fldLabel,
 		},
 		Type: StringExact,
-	}, []Index{
+	}
+	quadsSecondary = []Index{
 		{Fields: []string{fldSubject}, Type: StringExact},
 		{Fields: []string{fldPredicate}, Type: StringExact},
 		{Fields: []string{fldObject}, Type: StringExact},
 		{Fields: []string{fldLabel}, Type: StringExact},
-	})
-	if err != nil {
-		return err
 	}
-	return nil
+	// quadsPredicate are additional indexes for lookups of quads with a specific predicate.
+	quadsPredicate = []Index{
+		{Fields: []string{fldPredicate, fldSubject}, Type: StringExact},
+		{Fields: []string{fldPredicate, fldObject}, Type: StringExact},
+	}
+)
+
+var _ graph.Indexer = (*QuadStore)(nil)
+
+// EnsureIndexes implements graph.Indexer.
+//
+// Quad documents store hashes of predicates, thus instead of an index for each predicate it creates
+// compound indexes on predicate and subject or object, which serve lookups for any predicate.
+func (qs *QuadStore) EnsureIndexes(ctx context.Context, preds []quad.Value) error {
+	if len(preds) == 0 {
+		return nil
+	}
+	secondary := make([]Index, 0, len(quadsSecondary)+len(quadsPredicate))
+	secondary = append(secondary, quadsSecondary...)
+	secondary = append(secondary, quadsPredicate...)
+	return qs.db.EnsureIndex(ctx, colQuads, quadsPrimary, secondary)
 }
 
 func getKeyForQuad(t quad.Quad) Key {
@@ -581,6 +603,11 @@ func (qs *QuadStore) QuadsAllIterator() graph.Iterator {
 	return NewAllIterator(qs, "quads")
 }
 
+// DB returns the underlying database. It can be used by backends to implement specialized queries.
+func (qs *QuadStore) DB() Database {
+	return qs.db
+}
+
 func (qs *QuadStore) hashOf(s quad.Value) NodeHash {
 	return NodeHash(hashOf(s))
 }
@@ -622,6 +649,76 @@ func (qs *QuadStore) NameOf(v graph.Value) quad.Value {
 	return qv
 }
 
+var _ graph.BatchValuer = (*QuadStore)(nil)
+
+// RefsOf implements graph.BatchValuer. Values are resolved without querying the database.
+func (qs *QuadStore) RefsOf(ctx context.Context, vals []quad.Value) ([]graph.Value, error) {
+	out := make([]graph.Value, len(vals))
+	for i, v := range vals {
+		out[i] = qs.ValueOf(v)
+	}
+	return out, nil
+}
+
+// NamesOf implements graph.BatchValuer. Values that are not cached are loaded at once,
+// if the database supports it.
+func (qs *QuadStore) NamesOf(ctx context.Context, vals []graph.Value) ([]quad.Value, error) {
+	out := make([]quad.Value, len(vals))
+	var (
+		keys   []Key
+		hashes []NodeHash
+		// indexes of values that should be loaded, by hash
+		load = make(map[NodeHash][]int)
+	)
+	for i, v := range vals {
+		if v == nil {
+			continue
+		} else if pv, ok := v.(graph.PreFetchedValue); ok {
+			out[i] = pv.NameOf()
+			continue
+		}
+		hash, ok := v.(NodeHash)
+		if !ok {
+			return nil, fmt.Errorf("unexpected token: %T", v)
+		} else if hash == "" {
+			continue
+		} else if val, ok := qs.ids.Get(string(hash)); ok {
+			out[i] = val.(quad.Value)
+			continue
+		}
+		if _, ok := load[hash]; !ok {
+			keys = append(keys, hash.key())
+			hashes = append(hashes, hash)
+		}
+		load[hash] = append(load[hash], i)
+	}
+	if len(keys) == 0 {
+		return out, nil
+	}
+	docs, err := FindByKeys(ctx, qs.db, colNodes, keys)
+	if err != nil {
+		return nil, err
+	}
+	for j, nd := range docs {
+		if nd == nil {
+			continue
+		}
+		hash := hashes[j]
+		dv, _ := nd[fldValue].(Document)
+		qv, err := qs.opt.toQuadValue(dv)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't convert node %v: %v", hash, err)
+		}
+		if id, _ := nd[fldHash].(String); id == String(hash) && qv != nil {
+			qs.ids.Put(string(hash), qv)
+		}
+		for _, i := range load[hash] {
+			out[i] = qv
+		}
+	}
+	return out, nil
+}
+
 func (qs *QuadStore) Size() int64 {
 	// TODO(barakmich): Make size real; store it in the log, and retrieve it.
 	count, err := qs.db.Query(colQuads).Count(context.TODO())
@@ -636,6 +733,17 @@ func (qs *QuadStore) Close() error {
 	return qs.db.Close()
 }
 
+// Ping checks that the database is available, if the database implementation supports it.
+func (qs *QuadStore) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if hc, ok := qs.db.(graph.HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
 func (qs *QuadStore) QuadDirection(in graph.Value, d quad.Direction) graph.Value {
 	return NodeHash(in.(QuadHash).Get(d))
 }
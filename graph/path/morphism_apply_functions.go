@@ -227,6 +227,22 @@ func savePredicatesMorphism(isIn bool, tag string) morphism {
 	}
 }
 
+func saveQuadsMorphism(tag string) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) {
+			return saveQuadsMorphism(tag), ctx
+		},
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			out, err := shape.SaveQuads(in, tag)
+			if err != nil {
+				return iteratorShape{iterator.NewError(err)}, ctx
+			}
+			return out, ctx
+		},
+		tags: []string{tag},
+	}
+}
+
 type iteratorShape struct {
 	it graph.Iterator
 }
@@ -277,6 +293,46 @@ func followMorphism(p *Path) morphism {
 	}
 }
 
+// containedInMorphism filters current nodes to the ones that are produced by a given path,
+// checking them against the path without iterating it.
+func containedInMorphism(p *Path) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return containedInMorphism(p), ctx },
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			return shape.ContainedIn{From: in, Other: p.Shape()}, ctx
+		},
+	}
+}
+
+// savePathMorphism applies a given list of morphisms and binds all nodes traversed by them to a tag.
+func savePathMorphism(stack []morphism, tag string) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) {
+			rev := make([]morphism, 0, len(stack))
+			for i := len(stack) - 1; i >= 0; i-- {
+				var m morphism
+				m, ctx = stack[i].Reversal(ctx)
+				rev = append(rev, m)
+			}
+			return savePathMorphism(rev, tag), ctx
+		},
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			s := in
+			var steps []string
+			for _, m := range stack {
+				s, ctx = m.Apply(s, ctx)
+				if m.IsTag {
+					continue
+				}
+				step := fmt.Sprintf("%s\x00%d", tag, len(steps))
+				s = shape.Save{From: s, Tags: []string{step}}
+				steps = append(steps, step)
+			}
+			return shape.SavePath{From: s, Tag: tag, Steps: steps}, ctx
+		},
+	}
+}
+
 type iteratorBuilder func(qs graph.QuadStore) graph.Iterator
 
 func (s iteratorBuilder) BuildIterator(qs graph.QuadStore) graph.Iterator {
@@ -338,7 +394,7 @@ func saveReverseMorphism(via interface{}, tag string) morphism {
 	return morphism{
 		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return saveReverseMorphism(via, tag), ctx },
 		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
-			return shape.SaveVia(in, buildVia(via), tag, true, false), ctx
+			return shape.SaveViaLabels(in, buildVia(via), ctx.labelSet, tag, true, false), ctx
 		},
 		tags: []string{tag},
 	}
@@ -348,7 +404,7 @@ func saveOptionalMorphism(via interface{}, tag string) morphism {
 	return morphism{
 		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return saveOptionalMorphism(via, tag), ctx },
 		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
-			return shape.SaveVia(in, buildVia(via), tag, false, true), ctx
+			return shape.SaveViaLabels(in, buildVia(via), ctx.labelSet, tag, false, true), ctx
 		},
 		tags: []string{tag},
 	}
@@ -358,7 +414,7 @@ func saveOptionalReverseMorphism(via interface{}, tag string) morphism {
 	return morphism{
 		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return saveOptionalReverseMorphism(via, tag), ctx },
 		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
-			return shape.SaveVia(in, buildVia(via), tag, true, true), ctx
+			return shape.SaveViaLabels(in, buildVia(via), ctx.labelSet, tag, true, true), ctx
 		},
 		tags: []string{tag},
 	}
@@ -421,6 +477,16 @@ func limitMorphism(v int64) morphism {
 	}
 }
 
+// indexHintMorphism asks the backend to use an index on a given direction.
+func indexHintMorphism(dir quad.Direction) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return indexHintMorphism(dir), ctx },
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			return shape.IndexHint{From: in, Dir: dir}, ctx
+		},
+	}
+}
+
 // countMorphism will return count of values.
 func countMorphism() morphism {
 	return morphism{
@@ -430,3 +496,13 @@ func countMorphism() morphism {
 		},
 	}
 }
+
+// reduceMorphism will fold values of a tag into a single value.
+func reduceMorphism(tag string, init quad.Value, fn func(acc, v quad.Value) quad.Value) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return reduceMorphism(tag, init, fn), ctx },
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			return shape.Reduce{Values: in, Tag: tag, Init: init, Fn: fn}, ctx
+		},
+	}
+}
@@ -241,6 +241,51 @@ func (p *Path) OutWithTags(tags []string, via ...interface{}) *Path {
 	return np
 }
 
+// OutAny updates this Path to represent the nodes that are adjacent to the
+// current nodes, via any of the given outbound predicates.
+//
+// For example:
+//  // Return the list of nodes that "B" follows or likes.
+//  //
+//  // Will return []string{"F", "G"} if there are the appropriate edges from "B".
+//  p.OutAny(quad.IRI("follows"), quad.IRI("likes"))
+//
+// If no predicates are given, any predicate is followed, as in Out.
+func (p *Path) OutAny(preds ...quad.IRI) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, outMorphism(nil, irisToVia(preds)...))
+	return np
+}
+
+// InAny updates this Path to represent the nodes that are adjacent to the
+// current nodes, via any of the given inbound predicates.
+//
+// For example:
+//  // Return the list of nodes that follow or like "B".
+//  //
+//  // Will return []string{"A", "C", "D"} if there are the appropriate edges to "B".
+//  p.InAny(quad.IRI("follows"), quad.IRI("likes"))
+//
+// If no predicates are given, any predicate is followed, as in In.
+func (p *Path) InAny(preds ...quad.IRI) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, inMorphism(nil, irisToVia(preds)...))
+	return np
+}
+
+// irisToVia converts a list of predicates to arguments for Out and In.
+// An empty list matches any predicate.
+func irisToVia(arr []quad.IRI) []interface{} {
+	if len(arr) == 0 {
+		return nil
+	}
+	vals := make([]quad.Value, 0, len(arr))
+	for _, v := range arr {
+		vals = append(vals, v)
+	}
+	return []interface{}{vals}
+}
+
 // Both updates this path following both inbound and outbound predicates.
 //
 // For example:
@@ -308,6 +353,22 @@ func (p *Path) SavePredicates(rev bool, tag string) *Path {
 	return np
 }
 
+// SaveQuads tags quads traversed by the previous Out, In or Both step
+// without changing path location. The path fails with shape.ErrNotTraversal
+// if the previous step is not a traversal.
+//
+// Tagged values are quads and can be resolved with QuadStore.Quad to get
+// the full quad, including its label.
+//
+// For example:
+//  // Returns "bob" and tags the quad <alice> <follows> <bob> <label> as "link".
+//  StartPath(qs, "alice").Out("follows").SaveQuads("link")
+func (p *Path) SaveQuads(tag string) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, saveQuadsMorphism(tag))
+	return np
+}
+
 // And updates the current Path to represent the nodes that match both the
 // current Path so far, and the given Path.
 func (p *Path) And(path *Path) *Path {
@@ -316,6 +377,15 @@ func (p *Path) And(path *Path) *Path {
 	return np
 }
 
+// ContainedIn updates the current Path to represent the nodes that are also produced by the given Path.
+// It is the same as And, but the given Path is only checked with Contains and is never iterated,
+// thus it is more efficient when the given Path is expensive to iterate, but cheap to check.
+func (p *Path) ContainedIn(path *Path) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, containedInMorphism(path))
+	return np
+}
+
 // Or updates the current Path to represent the nodes that match either the
 // current Path so far, or the given Path.
 func (p *Path) Or(path *Path) *Path {
@@ -452,6 +522,15 @@ func (p *Path) HasFilter(via interface{}, rev bool, filt ...shape.ValueFilter) *
 	return np
 }
 
+// SearchText limits the paths to be ones where the current nodes have a string value linked via a given
+// predicate that contains a given substring. Matching is case-insensitive and only string values are considered.
+//
+// It's a simple backend-agnostic alternative to a full-text search and requires a scan of all values of the predicate.
+func (p *Path) SearchText(pred quad.IRI, substr string) *Path {
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(substr))
+	return p.HasFilter(pred, false, shape.Regexp{Re: re, Refs: false})
+}
+
 // LabelContext restricts the following operations (such as In, Out) to only
 // traverse edges that match the given set of labels.
 func (p *Path) LabelContext(via ...interface{}) *Path {
@@ -513,6 +592,19 @@ func (p *Path) BuildIteratorOn(qs graph.QuadStore) graph.Iterator {
 	return shape.BuildIterator(qs, p.Shape())
 }
 
+// EstimateSize builds and optimizes an iterator for this path on the given QuadStore,
+// and returns an estimated number of results and whether the estimate is exact.
+// It does not iterate over the results.
+func (p *Path) EstimateSize(qs graph.QuadStore) (int64, bool) {
+	it := p.BuildIteratorOn(qs)
+	if nit, ok := it.Optimize(); ok {
+		it = nit
+	}
+	defer it.Close()
+	st := it.Stats()
+	return st.Size, st.ExactSize
+}
+
 // Morphism returns the morphism of this path.  The returned value is a
 // function that, when given a QuadStore and an existing Iterator, will
 // return a new Iterator that yields the subset of values from the existing
@@ -535,12 +627,44 @@ func (p *Path) Limit(v int64) *Path {
 	return p
 }
 
+// IndexHint asks the backend to use an index on a given quad direction when resolving the previous traversal.
+// It is an escape hatch for planner mistakes and will be ignored by backends that don't support index hints.
+func (p *Path) IndexHint(dir quad.Direction) *Path {
+	p.stack = append(p.stack, indexHintMorphism(dir))
+	return p
+}
+
+// SavePath binds a sequence of nodes traversed by the path so far to a given tag, for each result.
+// The value of the tag is iterator.PathNodes, starting with the first node of the path and ending with
+// the result itself. Nodes are recorded after each step of the path, but steps that leave the node
+// unchanged (filters, for example) are recorded once.
+//
+// For example:
+//  // Will bind []quad.Value{"A", "B", "C"} to "path" tag,
+//  // if "A" follows "B" and "B" follows "C".
+//  StartPath(qs, "A").Out("follows").Out("follows").SavePath("path")
+func (p *Path) SavePath(tag string) *Path {
+	np := p.clone()
+	stack := np.stack
+	np.stack = []morphism{savePathMorphism(stack[:len(stack):len(stack)], tag)}
+	return np
+}
+
 // Count will count a number of results as it's own result set.
 func (p *Path) Count() *Path {
 	p.stack = append(p.stack, countMorphism())
 	return p
 }
 
+// Reduce folds values of a given tag into a single value, as it's own result set.
+// Values are folded as they are produced, in iteration order. If tag is empty, current results are used.
+//
+// Init is returned if there are no results. If the accumulated value is nil, the path has no results.
+func (p *Path) Reduce(tag string, init quad.Value, fn func(acc, v quad.Value) quad.Value) *Path {
+	p.stack = append(p.stack, reduceMorphism(tag, init, fn))
+	return p
+}
+
 // Iterate is an shortcut for graph.Iterate.
 func (p *Path) Iterate(ctx context.Context) *graph.IterateChain {
 	return shape.Iterate(ctx, p.qs, p.Shape())
@@ -15,12 +15,301 @@
 package path_test
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 
-	_ "github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/graphtest"
+	"github.com/caivega/cayley/graph/iterator"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/graph/path"
 	"github.com/caivega/cayley/graph/path/pathtest"
+	"github.com/caivega/cayley/graph/shape"
+	"github.com/caivega/cayley/quad"
 )
 
 func TestMorphisms(t *testing.T) {
 	pathtest.RunTestMorphisms(t, nil)
 }
+
+func TestSaveQuads(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("alice", "follows", "bob", "g1"),
+		quad.MakeIRI("alice", "follows", "charlie", "g2"),
+		quad.MakeIRI("bob", "follows", "charlie", "g3"),
+	)
+	p := path.StartPath(qs, quad.IRI("alice")).Out(quad.IRI("follows")).SaveQuads("link")
+
+	got := make(map[string]string)
+	err := p.Iterate(context.TODO()).TagEach(func(tags map[string]graph.Value) {
+		q := qs.Quad(tags["link"])
+		got[q.Object.String()] = q.Label.String()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{
+		"<bob>":     "<g1>",
+		"<charlie>": "<g2>",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected quad labels: %v, expected: %v", got, expect)
+	}
+}
+
+func TestSaveQuadsNotTraversal(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("alice", "follows", "bob", ""),
+	)
+	for _, p := range []*path.Path{
+		path.StartPath(qs, quad.IRI("alice")).SaveQuads("link"),
+		path.StartPath(qs, quad.IRI("alice")).Out(quad.IRI("follows")).Is(quad.IRI("bob")).SaveQuads("link"),
+	} {
+		err := p.Iterate(context.TODO()).EachValue(nil, func(quad.Value) {})
+		if err != shape.ErrNotTraversal {
+			t.Errorf("expected an error for a path without traversal, got: %v", err)
+		}
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	var quads []quad.Quad
+	for _, s := range []string{"alice", "bob", "charlie", "dani", "emily"} {
+		quads = append(quads, quad.MakeIRI(s, "status", "cool", ""))
+	}
+	quads = append(quads, quad.MakeIRI("alice", "follows", "bob", ""))
+	qs := memstore.New(quads...)
+
+	all, _ := path.StartPath(qs).EstimateSize(qs)
+	has, _ := path.StartPath(qs).Has(quad.IRI("follows"), quad.IRI("bob")).EstimateSize(qs)
+	if has >= all {
+		t.Errorf("expected narrow path to be estimated smaller than all nodes: %d vs %d", has, all)
+	}
+}
+
+// makeHasManyStore creates a store with n nodes linked to distinct values, and returns
+// a list of m values to look up, including values that are not in the store.
+func makeHasManyStore(n, m int) (graph.QuadStore, []quad.Value) {
+	quads := make([]quad.Quad, 0, n)
+	for i := 0; i < n; i++ {
+		quads = append(quads, quad.MakeIRI(fmt.Sprintf("n%d", i), "code", fmt.Sprintf("c%d", i), ""))
+		quads = append(quads, quad.MakeIRI(fmt.Sprintf("n%d", i), "other", fmt.Sprintf("c%d", i+1), ""))
+	}
+	vals := make([]quad.Value, 0, m)
+	for i := 0; i < m; i++ {
+		vals = append(vals, quad.IRI(fmt.Sprintf("c%d", 2*i)))
+	}
+	return memstore.New(quads...), vals
+}
+
+func TestHasManyValues(t *testing.T) {
+	const n, m = 500, 400
+	qs, vals := makeHasManyStore(n, m)
+
+	var exp []string
+	for i := 0; i < n; i += 2 {
+		exp = append(exp, fmt.Sprintf("n%d", i))
+	}
+	sort.Strings(exp)
+
+	for _, opt := range []bool{false, true} {
+		pb := path.StartPath(qs).Has(quad.IRI("code"), vals...).Iterate(context.TODO())
+		if !opt {
+			pb = pb.UnOptimized()
+		}
+		got, err := pb.Paths(false).AllValues(qs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, v := range got {
+			names = append(names, string(v.(quad.IRI)))
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, exp) {
+			t.Errorf("unexpected results (optimized: %v): %d vs %d nodes", opt, len(names), len(exp))
+		}
+	}
+}
+
+func BenchmarkHasManyValues(b *testing.B) {
+	qs, vals := makeHasManyStore(20000, 10000)
+	p := path.StartPath(qs).Has(quad.IRI("code"), vals...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n, err := p.Iterate(context.TODO()).Count()
+		if err != nil {
+			b.Fatal(err)
+		} else if n != 10000 {
+			b.Fatalf("unexpected count: %d", n)
+		}
+	}
+}
+
+func TestReduce(t *testing.T) {
+	qs := memstore.New(
+		quad.Make(quad.IRI("a"), quad.IRI("score"), quad.Int(3), nil),
+		quad.Make(quad.IRI("b"), quad.IRI("score"), quad.Int(5), nil),
+		quad.Make(quad.IRI("c"), quad.IRI("score"), quad.Int(7), nil),
+		quad.Make(quad.IRI("a"), quad.IRI("name"), quad.String("x"), nil),
+		quad.Make(quad.IRI("b"), quad.IRI("name"), quad.String("y"), nil),
+	)
+	sum := func(acc, v quad.Value) quad.Value {
+		return acc.(quad.Int) + v.(quad.Int)
+	}
+	concat := func(acc, v quad.Value) quad.Value {
+		return acc.(quad.String) + v.(quad.String)
+	}
+	reduce := func(p *path.Path) quad.Value {
+		vals, err := p.Iterate(context.TODO()).AllValues(qs)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(vals) != 1 {
+			t.Fatalf("expected a single value, got: %v", vals)
+		}
+		return vals[0]
+	}
+
+	got := reduce(path.StartPath(qs).Out(quad.IRI("score")).Tag("score").Reduce("score", quad.Int(0), sum))
+	if got != quad.Int(15) {
+		t.Errorf("unexpected sum: %v", got)
+	}
+	got = reduce(path.StartPath(qs).Out(quad.IRI("name")).Reduce("", quad.String(""), concat))
+	if s := string(got.(quad.String)); s != "xy" && s != "yx" {
+		t.Errorf("unexpected concatenation: %q", s)
+	}
+	got = reduce(path.StartPath(qs).Out(quad.IRI("missing")).Reduce("", quad.String("none"), concat))
+	if got != quad.String("none") {
+		t.Errorf("expected init value for empty results, got: %v", got)
+	}
+}
+
+func TestSavePath(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("a", "follows", "b", ""),
+		quad.MakeIRI("a", "follows", "d", ""),
+		quad.MakeIRI("b", "follows", "c", ""),
+		quad.MakeIRI("d", "follows", "c", ""),
+		quad.MakeIRI("b", "status", "cool", ""),
+	)
+	paths := func(p *path.Path) []string {
+		var out []string
+		err := p.Iterate(context.TODO()).Paths(true).TagEach(func(m map[string]graph.Value) {
+			nodes, ok := m["path"].(iterator.PathNodes)
+			if !ok {
+				t.Fatalf("unexpected path value: %#v", m["path"])
+			}
+			if len(m) != 1 {
+				t.Errorf("unexpected tags: %v", m)
+			}
+			out = append(out, fmt.Sprint([]quad.Value(nodes)))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(out)
+		return out
+	}
+	follows := quad.IRI("follows")
+
+	got := paths(path.StartPath(qs, quad.IRI("a")).Out(follows).Has(quad.IRI("status")).Out(follows).SavePath("path"))
+	exp := []string{"[<a> <b> <c>]"}
+	if fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("unexpected paths: %v, expected: %v", got, exp)
+	}
+
+	got = paths(path.StartPath(qs, quad.IRI("a")).Out(follows).Out(follows).SavePath("path"))
+	exp = []string{"[<a> <b> <c>]", "[<a> <d> <c>]"}
+	if fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("unexpected paths: %v, expected: %v", got, exp)
+	}
+}
+
+func TestContainedIn(t *testing.T) {
+	rec := graphtest.NewRecorder(memstore.New(
+		quad.MakeIRI("a", "follows", "b", ""),
+		quad.MakeIRI("c", "follows", "b", ""),
+		quad.MakeIRI("d", "follows", "e", ""),
+		quad.MakeIRI("b", "status", "cool", ""),
+		quad.MakeIRI("e", "status", "cool", ""),
+		quad.MakeIRI("x", "status", "cool", ""),
+	))
+	followed := path.StartPath(rec).Out(quad.IRI("follows"))
+	p := path.StartPath(rec).Has(quad.IRI("status")).ContainedIn(followed)
+	vals, err := p.Iterate(context.TODO()).Paths(false).AllValues(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(vals))
+	for _, v := range vals {
+		got = append(got, quad.StringOf(v))
+	}
+	sort.Strings(got)
+	if exp := []string{"<b>", "<e>"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected results: %v, expected: %v", got, exp)
+	}
+	// each node with a status is checked against the followed nodes: b, e and x
+	checked := 0
+	for _, op := range rec.Ops() {
+		if op.Method == "QuadIterator" && op.Args[0] == quad.Object {
+			checked++
+		}
+	}
+	if checked != 3 {
+		t.Errorf("expected the other path to be checked for each node, got: %v", rec.Ops())
+	}
+
+	// all paths through the other path are returned
+	followed = path.StartPath(rec).Tag("who").Out(quad.IRI("follows"))
+	p = path.StartPath(rec).Has(quad.IRI("status")).Tag("id").ContainedIn(followed)
+	got = got[:0]
+	err = p.Iterate(context.TODO()).Paths(true).TagEach(func(m map[string]graph.Value) {
+		got = append(got, quad.StringOf(rec.NameOf(m["id"]))+" "+quad.StringOf(rec.NameOf(m["who"])))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if exp := []string{"<b> <a>", "<b> <c>", "<e> <d>"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected paths: %v, expected: %v", got, exp)
+	}
+}
+
+func TestSearchText(t *testing.T) {
+	qs := memstore.New(
+		quad.Make(quad.IRI("a"), quad.IRI("title"), quad.String("Hello World"), nil),
+		quad.Make(quad.IRI("b"), quad.IRI("title"), quad.TypedString{Value: "the world is round", Type: "xsd:string"}, nil),
+		quad.Make(quad.IRI("c"), quad.IRI("title"), quad.String("Goodbye"), nil),
+		quad.Make(quad.IRI("d"), quad.IRI("title"), quad.IRI("world"), nil),
+		quad.Make(quad.IRI("e"), quad.IRI("body"), quad.String("world"), nil),
+	)
+	search := func(substr string) []string {
+		vals, err := path.StartPath(qs).SearchText(quad.IRI("title"), substr).Iterate(context.TODO()).AllValues(qs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, v := range vals {
+			out = append(out, string(v.(quad.IRI)))
+		}
+		sort.Strings(out)
+		return out
+	}
+	for _, c := range []struct {
+		substr string
+		expect []string
+	}{
+		{substr: "world", expect: []string{"a", "b"}},
+		{substr: "WORLD", expect: []string{"a", "b"}},
+		{substr: "bye", expect: []string{"c"}},
+		{substr: "o.d", expect: nil},
+		{substr: "missing", expect: nil},
+	} {
+		if got := search(c.substr); !reflect.DeepEqual(got, c.expect) {
+			t.Errorf("unexpected results for %q: %v, expected: %v", c.substr, got, c.expect)
+		}
+	}
+}
@@ -224,6 +224,16 @@ func testSet(qs graph.QuadStore) []test {
 			path:    StartPath(qs, vAlice, vBob, vCharlie).Out(vFollows).Unique(),
 			expect:  []quad.Value{vBob, vDani, vFred},
 		},
+		{
+			message: "use out any with unique",
+			path:    StartPath(qs, vBob, vDani).OutAny(vFollows, vStatus).Unique(),
+			expect:  []quad.Value{vFred, vCool, vBob, vGreg},
+		},
+		{
+			message: "use in any with unique",
+			path:    StartPath(qs, vBob, vFred).InAny(vFollows, vStatus).Unique(),
+			expect:  []quad.Value{vAlice, vCharlie, vDani, vBob, vEmily},
+		},
 		{
 			message: "simple save",
 			path:    StartPath(qs).Save(vStatus, "somecool"),
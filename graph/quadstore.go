@@ -34,17 +34,287 @@ type BatchQuadStore interface {
 	ValuesOf(ctx context.Context, vals []Value) ([]quad.Value, error)
 }
 
+// ValuesOf returns quad values for a given list of graph values.
+// Result has the same length as the input, and contains nil for unknown values.
+//
+// It will use BatchValuer or BatchQuadStore if the quad store implements one of them,
+// or will call NameOf for each value otherwise.
 func ValuesOf(ctx context.Context, qs QuadStore, vals []Value) ([]quad.Value, error) {
-	if bq, ok := qs.(BatchQuadStore); ok {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if bv, ok := Unwrap(qs).(BatchValuer); ok {
+		return bv.NamesOf(ctx, vals)
+	} else if bq, ok := qs.(BatchQuadStore); ok {
 		return bq.ValuesOf(ctx, vals)
 	}
 	out := make([]quad.Value, len(vals))
 	for i, v := range vals {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		out[i] = qs.NameOf(v)
 	}
 	return out, nil
 }
 
+// BatchDeleter is an optional interface for quad stores that can efficiently delete
+// a large number of quads at once.
+type BatchDeleter interface {
+	// DeleteByPredicate removes all quads with a given predicate and returns the number of removed quads.
+	DeleteByPredicate(ctx context.Context, pred quad.Value) (int64, error)
+}
+
+// DeleteByPredicate removes all quads with a given predicate using a quad writer. It returns the number of removed quads.
+//
+// If the quad store implements BatchDeleter, quads are removed by the store directly, bypassing the writer.
+// Wrappers such as CachingQuadStore implement BatchDeleter themselves to stay consistent with the store.
+// Otherwise, quads are found using the predicate index and are removed in batches, the same way as DeleteLabel does.
+func DeleteByPredicate(ctx context.Context, qs QuadStore, w QuadWriter, pred quad.Value) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if pred == nil {
+		return 0, fmt.Errorf("predicate should not be nil")
+	}
+	bqs := qs
+	if h, ok := bqs.(*Handle); ok {
+		bqs = h.QuadStore
+	}
+	if bd, ok := bqs.(BatchDeleter); ok {
+		n, err := bd.DeleteByPredicate(ctx, pred)
+		if err != ErrOperationNotSupported {
+			return n, err
+		}
+	}
+	return deleteByDirection(ctx, qs, w, quad.Predicate, pred)
+}
+
+// DeleteLabel removes all quads with a given label using a quad writer. It returns the number of removed quads.
+//
+// Quads are found using the label index of the quad store and are removed in batches, each applied as a single
+// transaction, thus not all quads are loaded into memory at once. The delete is not atomic as a whole,
+// but can be safely re-run in case of a failure or cancellation.
+func DeleteLabel(ctx context.Context, qs QuadStore, w QuadWriter, label quad.Value) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if label == nil {
+		return 0, fmt.Errorf("label should not be nil")
+	}
+	return deleteByDirection(ctx, qs, w, quad.Label, label)
+}
+
+// deleteByDirection removes all quads with a given value in a given direction in batches using a quad writer.
+func deleteByDirection(ctx context.Context, qs QuadStore, w QuadWriter, d quad.Direction, val quad.Value) (int64, error) {
+	var n int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		v := qs.ValueOf(val)
+		if v == nil {
+			return n, nil
+		}
+		// read a single batch only, since not all backends allow to modify data while iterating;
+		// removed quads will not be returned by the next iterator
+		tx := NewTransactionN(quad.DefaultBatch)
+		err := Iterate(ctx, qs.QuadIterator(d, v)).On(qs).Limit(quad.DefaultBatch).Each(func(v Value) {
+			tx.RemoveQuad(qs.Quad(v))
+		})
+		if err != nil {
+			return n, err
+		} else if len(tx.Deltas) == 0 {
+			return n, nil
+		}
+		if err = w.ApplyTransaction(tx); err != nil {
+			return n, err
+		}
+		n += int64(len(tx.Deltas))
+	}
+}
+
+// RenamePredicate replaces the predicate of all quads that use old predicate with a new one.
+// Other directions and labels of quads are preserved. It returns the number of changed quads.
+//
+// Quads are processed in batches, each applied as a single transaction using a quad writer, thus not all quads
+// are loaded into memory at once. The rename is not atomic as a whole, but can be safely re-run
+// in case of a failure.
+func RenamePredicate(ctx context.Context, qs QuadStore, w QuadWriter, old, new quad.Value) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if old == nil || new == nil {
+		return 0, fmt.Errorf("predicate should not be nil")
+	} else if old.String() == new.String() {
+		return 0, nil
+	}
+	var n int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		pv := qs.ValueOf(old)
+		if pv == nil {
+			return n, nil
+		}
+		// read a single batch only, since not all backends allow to modify data while iterating;
+		// renamed quads will not be returned by the next iterator
+		tx := NewTransactionWithOptions(IgnoreOpts{IgnoreDup: true, IgnoreMissing: true})
+		err := Iterate(ctx, qs.QuadIterator(quad.Predicate, pv)).On(qs).Limit(quad.DefaultBatch).Each(func(v Value) {
+			q := qs.Quad(v)
+			tx.RemoveQuad(q)
+			q.Predicate = new
+			tx.AddQuad(q)
+		})
+		if err != nil {
+			return n, err
+		} else if len(tx.Deltas) == 0 {
+			return n, nil
+		}
+		if err = w.ApplyTransaction(tx); err != nil {
+			return n, err
+		}
+		n += int64(len(tx.Deltas) / 2)
+	}
+}
+
+// HealthChecker is an optional interface for quad stores that can check if the backend is reachable and responsive.
+type HealthChecker interface {
+	// Ping checks that the backend is available. It should return an error if the context is done before
+	// the backend responds.
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that the quad store backend is reachable and responsive.
+//
+// Quad stores that do not implement HealthChecker are assumed to be always available.
+func Ping(ctx context.Context, qs QuadStore) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if hc, ok := Unwrap(qs).(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
+// BatchValuer is an optional interface for quad stores that can efficiently resolve multiple values at once.
+type BatchValuer interface {
+	// RefsOf is the same as ValueOf, but resolves multiple values at once.
+	// Result has the same length as the input, and contains nil for values that are not in the store.
+	RefsOf(ctx context.Context, vals []quad.Value) ([]Value, error)
+	// NamesOf is the same as NameOf, but resolves multiple values at once.
+	// Result has the same length as the input, and contains nil for unknown values.
+	NamesOf(ctx context.Context, vals []Value) ([]quad.Value, error)
+}
+
+// LookupValues returns graph values for a given list of quad values.
+// Result has the same length as the input, and contains nil for values that are not in the store.
+//
+// It will use BatchValuer if the quad store implements it, or will call ValueOf for each value otherwise.
+func LookupValues(ctx context.Context, qs QuadStore, vals []quad.Value) ([]Value, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if bv, ok := Unwrap(qs).(BatchValuer); ok {
+		return bv.RefsOf(ctx, vals)
+	}
+	out := make([]Value, len(vals))
+	for i, v := range vals {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		out[i] = qs.ValueOf(v)
+	}
+	return out, nil
+}
+
+// Stats is an optional interface for quad stores that can efficiently compute statistics about stored quads.
+type Stats interface {
+	// PredicateStats returns the number of quads for each predicate in the quad store.
+	PredicateStats() (map[quad.Value]int64, error)
+}
+
+// PredicateStats returns the number of quads for each predicate in the quad store.
+//
+// It returns ErrOperationNotSupported if the quad store does not implement Stats.
+func PredicateStats(qs QuadStore) (map[quad.Value]int64, error) {
+	if st, ok := Unwrap(qs).(Stats); ok {
+		return st.PredicateStats()
+	}
+	return nil, ErrOperationNotSupported
+}
+
+// LabelStats is an optional interface for quad stores that can efficiently count quads in each label.
+type LabelStats interface {
+	// CountByLabel returns the number of quads for each label in the quad store.
+	// Quads without a label are counted under the nil key.
+	CountByLabel(ctx context.Context) (map[quad.Value]int64, error)
+}
+
+// CountByLabel returns the number of quads for each label in the quad store.
+// Quads without a label are counted under the nil key.
+//
+// It returns ErrOperationNotSupported if the quad store does not implement LabelStats.
+func CountByLabel(ctx context.Context, qs QuadStore) (map[quad.Value]int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if st, ok := Unwrap(qs).(LabelStats); ok {
+		return st.CountByLabel(ctx)
+	}
+	return nil, ErrOperationNotSupported
+}
+
+// Indexer is an optional interface for quad stores that can create secondary indexes to speed up
+// lookups of quads with specific predicates.
+type Indexer interface {
+	// EnsureIndexes creates indexes for quads with given predicates, if they do not exist yet.
+	EnsureIndexes(ctx context.Context, preds []quad.Value) error
+}
+
+// EnsureIndexes creates indexes for quads with given predicates.
+//
+// It does nothing if the quad store does not implement Indexer.
+func EnsureIndexes(ctx context.Context, qs QuadStore, preds []quad.Value) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ix, ok := Unwrap(qs).(Indexer); ok {
+		return ix.EnsureIndexes(ctx, preds)
+	}
+	return nil
+}
+
+// OptStatsOptimize is the Options key that controls the reordering of iterators based on their size estimates.
+// Setting it to false keeps the written order of intersections, while still applying structural optimizations.
+// It is useful for backends with unreliable size estimates.
+const OptStatsOptimize = "stats_optimize"
+
+// StatsOptimizer is an optional interface for quad stores that can disable the reordering of iterators
+// based on size estimates. See OptStatsOptimize.
+type StatsOptimizer interface {
+	// StatsOptimize reports if iterators can be reordered according to their size estimates.
+	StatsOptimize() bool
+}
+
+// CanStatsOptimize checks if iterators on a given quad store can be reordered according to their size estimates.
+//
+// It returns true if the quad store does not implement StatsOptimizer.
+func CanStatsOptimize(qs QuadStore) bool {
+	if qs == nil {
+		return true
+	}
+	if so, ok := Unwrap(qs).(StatsOptimizer); ok {
+		return so.StatsOptimize()
+	}
+	return true
+}
+
 type QuadStore interface {
 	// The only way in is through building a transaction, which
 	// is done by a replication strategy.
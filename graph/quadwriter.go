@@ -53,13 +53,19 @@ type Delta struct {
 	Action Procedure
 }
 
-// Unwrap returns an original QuadStore value if it was wrapped by Handle.
+// Unwrap returns an original QuadStore value if it was wrapped by Handle or CachingQuadStore.
 // This prevents shadowing of optional interface implementations.
 func Unwrap(qs QuadStore) QuadStore {
-	if h, ok := qs.(*Handle); ok {
-		return h.QuadStore
+	for {
+		switch w := qs.(type) {
+		case *Handle:
+			qs = w.QuadStore
+		case *CachingQuadStore:
+			qs = w.QuadStore
+		default:
+			return qs
+		}
 	}
-	return qs
 }
 
 type Handle struct {
@@ -82,6 +88,7 @@ var (
 	ErrQuadNotExist  = errors.New("quad does not exist")
 	ErrInvalidAction = errors.New("invalid action")
 	ErrNodeNotExists = errors.New("node does not exist")
+	ErrWriterClosed  = errors.New("writer is closed")
 )
 
 // DeltaError records an error and the delta that caused it.
@@ -197,13 +204,24 @@ type BatchWriter interface {
 // NewWriter creates a quad writer for a given QuadStore.
 //
 // Caller must call Flush or Close to flush an internal buffer.
+// Close is idempotent and returns the first error encountered by the writer.
+// Writes after Close fail with ErrWriterClosed. The underlying QuadWriter is not closed.
 func NewWriter(qs QuadWriter) BatchWriter {
 	return &batchWriter{qs: qs}
 }
 
 type batchWriter struct {
-	qs  QuadWriter
-	buf []quad.Quad
+	qs     QuadWriter
+	buf    []quad.Quad
+	err    error // first error encountered
+	closed bool
+}
+
+func (w *batchWriter) setErr(err error) error {
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return err
 }
 
 func (w *batchWriter) flushBuffer(force bool) error {
@@ -216,6 +234,9 @@ func (w *batchWriter) flushBuffer(force bool) error {
 }
 
 func (w *batchWriter) WriteQuad(q quad.Quad) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
 	if err := w.flushBuffer(false); err != nil {
 		return err
 	}
@@ -223,16 +244,27 @@ func (w *batchWriter) WriteQuad(q quad.Quad) error {
 	return nil
 }
 func (w *batchWriter) WriteQuads(quads []quad.Quad) (int, error) {
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
 	if err := w.qs.AddQuadSet(quads); err != nil {
-		return 0, err
+		return 0, w.setErr(err)
 	}
 	return len(quads), nil
 }
 func (w *batchWriter) Flush() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
 	return w.flushBuffer(true)
 }
 func (w *batchWriter) Close() error {
-	return w.Flush()
+	if w.closed {
+		return nil
+	}
+	w.Flush()
+	w.closed = true
+	return w.err
 }
 
 // NewTxWriter creates a writer that applies a given procedures for all quads in stream.
@@ -262,31 +294,57 @@ func (w *txWriter) WriteQuad(q quad.Quad) error {
 }
 
 // NewRemover creates a quad writer for a given QuadStore which removes quads instead of adding them.
+//
+// Close semantics are the same as for NewWriter.
 func NewRemover(qs QuadWriter) BatchWriter {
 	return &removeWriter{qs: qs}
 }
 
 type removeWriter struct {
-	qs QuadWriter
+	qs     QuadWriter
+	err    error // first error encountered
+	closed bool
+}
+
+func (w *removeWriter) setErr(err error) error {
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return err
 }
 
 func (w *removeWriter) WriteQuad(q quad.Quad) error {
-	return w.qs.RemoveQuad(q)
+	if w.closed {
+		return ErrWriterClosed
+	}
+	return w.setErr(w.qs.RemoveQuad(q))
 }
 func (w *removeWriter) WriteQuads(quads []quad.Quad) (int, error) {
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
 	tx := NewTransaction()
 	for _, q := range quads {
 		tx.RemoveQuad(q)
 	}
 	if err := w.qs.ApplyTransaction(tx); err != nil {
-		return 0, err
+		return 0, w.setErr(err)
 	}
 	return len(quads), nil
 }
 func (w *removeWriter) Flush() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
 	return nil // TODO: batch deletes automatically
 }
-func (w *removeWriter) Close() error { return nil }
+func (w *removeWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.err
+}
 
 // NewResultReader creates a quad reader for a given QuadStore.
 func NewQuadStoreReader(qs QuadStore) quad.ReadSkipCloser {
@@ -3,6 +3,8 @@ package graph
 import (
 	"errors"
 	"testing"
+
+	"github.com/caivega/cayley/quad"
 )
 
 func TestIsQuadExist(t *testing.T) {
@@ -61,3 +63,90 @@ func TestIsInvalidAction(t *testing.T) {
 		}
 	}
 }
+
+// recordingWriter is a QuadWriter that records all quads and can fail on a given call.
+type recordingWriter struct {
+	QuadWriter
+	quads   []quad.Quad
+	removed []quad.Quad
+	err     error
+}
+
+func (w *recordingWriter) AddQuadSet(quads []quad.Quad) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.quads = append(w.quads, quads...)
+	return nil
+}
+
+func (w *recordingWriter) RemoveQuad(q quad.Quad) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.removed = append(w.removed, q)
+	return nil
+}
+
+func TestWriterClose(t *testing.T) {
+	q1 := quad.MakeIRI("a", "follows", "b", "")
+	q2 := quad.MakeIRI("b", "follows", "c", "")
+
+	qw := &recordingWriter{}
+	w := NewWriter(qw)
+	if err := w.WriteQuad(q1); err != nil {
+		t.Fatal(err)
+	} else if err = w.WriteQuad(q2); err != nil {
+		t.Fatal(err)
+	}
+	if len(qw.quads) != 0 {
+		t.Fatalf("expected quads to be buffered, got: %v", qw.quads)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	} else if len(qw.quads) != 2 {
+		t.Fatalf("expected quads to be flushed on close, got: %v", qw.quads)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("expected second close to succeed, got: %v", err)
+	}
+	if err := w.WriteQuad(q1); err != ErrWriterClosed {
+		t.Errorf("unexpected error on write after close: %v", err)
+	}
+	if _, err := w.WriteQuads([]quad.Quad{q1}); err != ErrWriterClosed {
+		t.Errorf("unexpected error on write after close: %v", err)
+	}
+
+	// the first error is returned from close
+	errWrite := errors.New("write failed")
+	qw = &recordingWriter{err: errWrite}
+	w = NewWriter(qw)
+	if _, err := w.WriteQuads([]quad.Quad{q1}); err != errWrite {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	qw.err = nil
+	if err := w.Close(); err != errWrite {
+		t.Errorf("expected the first error on close, got: %v", err)
+	} else if err = w.Close(); err != nil {
+		t.Errorf("expected second close to succeed, got: %v", err)
+	}
+}
+
+func TestRemoverClose(t *testing.T) {
+	q := quad.MakeIRI("a", "follows", "b", "")
+	qw := &recordingWriter{}
+	w := NewRemover(qw)
+	if err := w.WriteQuad(q); err != nil {
+		t.Fatal(err)
+	} else if len(qw.removed) != 1 {
+		t.Fatalf("unexpected removed quads: %v", qw.removed)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	} else if err = w.Close(); err != nil {
+		t.Errorf("expected second close to succeed, got: %v", err)
+	}
+	if err := w.WriteQuad(q); err != ErrWriterClosed {
+		t.Errorf("unexpected error on write after close: %v", err)
+	}
+}
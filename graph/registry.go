@@ -30,11 +30,13 @@ var storeRegistry = make(map[string]QuadStoreRegistration)
 type NewStoreFunc func(string, Options) (QuadStore, error)
 type InitStoreFunc func(string, Options) error
 type UpgradeStoreFunc func(string, Options) error
+type RestoreStoreFunc func([]byte) (QuadStore, error)
 
 type QuadStoreRegistration struct {
 	NewFunc      NewStoreFunc
 	UpgradeFunc  UpgradeStoreFunc
 	InitFunc     InitStoreFunc
+	RestoreFunc  RestoreStoreFunc // optional; restores the store from a snapshot
 	IsPersistent bool
 }
 
@@ -81,6 +83,19 @@ func UpgradeQuadStore(name string, dbpath string, opts Options) error {
 	return r.UpgradeFunc(dbpath, opts)
 }
 
+// NewQuadStoreFromSnapshot creates a new quad store from a snapshot made by that quad store.
+//
+// It returns ErrOperationNotSupported if the backend cannot be restored from a snapshot.
+func NewQuadStoreFromSnapshot(name string, data []byte) (QuadStore, error) {
+	r, registered := storeRegistry[name]
+	if !registered {
+		return nil, ErrQuadStoreNotRegistred
+	} else if r.RestoreFunc == nil {
+		return nil, ErrOperationNotSupported
+	}
+	return r.RestoreFunc(data)
+}
+
 func IsRegistered(name string) bool {
 	_, ok := storeRegistry[name]
 	return ok
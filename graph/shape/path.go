@@ -2,6 +2,7 @@ package shape
 
 import (
 	"context"
+	"errors"
 
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/graph/iterator"
@@ -11,7 +12,9 @@ import (
 func IntersectShapes(s1, s2 Shape) Shape {
 	switch s1 := s1.(type) {
 	case AllNodes:
-		return s2
+		if _, ok := s2.(Optional); !ok {
+			return s2
+		}
 	case Intersect:
 		if s2, ok := s2.(Intersect); ok {
 			return append(s1, s2...)
@@ -103,6 +106,32 @@ func SavePredicates(from Shape, in bool, tag string) Shape {
 	return IntersectShapes(from, save)
 }
 
+// ErrNotTraversal is returned by SaveQuads for shapes that are not a result of a quad traversal.
+var ErrNotTraversal = errors.New("quads can only be saved right after Out, In or Both traversal")
+
+// SaveQuads tags quads that were traversed to get to nodes of a given shape.
+// Tagged results are quad values that can be resolved with QuadStore.Quad.
+//
+// Shape must be a result of Out or In traversal (or a Union of them), ErrNotTraversal is returned otherwise.
+func SaveQuads(from Shape, tag string) (Shape, error) {
+	switch s := from.(type) {
+	case NodesFrom:
+		s.Quads = Save{From: s.Quads, Tags: []string{tag}}
+		return s, nil
+	case Union:
+		out := make(Union, 0, len(s))
+		for _, sub := range s {
+			ss, err := SaveQuads(sub, tag)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, ss)
+		}
+		return out, nil
+	}
+	return nil, ErrNotTraversal
+}
+
 func Labels(from Shape) Shape {
 	return Unique{NodesFrom{
 		Quads: Union{
@@ -55,7 +55,10 @@ func Optimize(s Shape, qs graph.QuadStore) (Shape, bool) {
 	if s == nil {
 		return nil, false
 	}
-	qs = graph.Unwrap(qs)
+	// keep wrappers such as CachingQuadStore for lookups, but not a Handle
+	if h, ok := qs.(*graph.Handle); ok {
+		qs = h.QuadStore
+	}
 	var opt bool
 	if qs != nil {
 		// resolve all lookups earlier
@@ -72,7 +75,7 @@ func Optimize(s Shape, qs graph.QuadStore) (Shape, bool) {
 	}
 	opt = opt || opt1
 	// apply quadstore-specific optimizations
-	if so, ok := qs.(Optimizer); ok && s != nil {
+	if so, ok := graph.Unwrap(qs).(Optimizer); ok && s != nil {
 		var opt2 bool
 		s, opt2 = s.Optimize(so)
 		opt = opt || opt2
@@ -199,7 +202,6 @@ func IsNull(s Shape) bool {
 
 // BuildIterator optimizes the shape and builds a corresponding iterator tree.
 func BuildIterator(qs graph.QuadStore, s Shape) graph.Iterator {
-	qs = graph.Unwrap(qs)
 	if s != nil {
 		if clog.V(2) {
 			clog.Infof("shape: %#v", s)
@@ -212,7 +214,8 @@ func BuildIterator(qs graph.QuadStore, s Shape) graph.Iterator {
 	if IsNull(s) {
 		return iterator.NewNull()
 	}
-	return s.BuildIterator(qs)
+	// store-specific shapes require the original quad store
+	return s.BuildIterator(graph.Unwrap(qs))
 }
 
 // Null represent an empty set. Mostly used as a safe alias for nil shape.
@@ -426,6 +429,106 @@ func (s Count) Optimize(r Optimizer) (Shape, bool) {
 	return s, opt
 }
 
+// Reduce folds values of a source (or values of a given tag) with a function and returns a single
+// accumulated value. Init is returned if source is empty. If the accumulated value is nil, no results are returned.
+type Reduce struct {
+	Values Shape
+	Tag    string
+	Init   quad.Value
+	Fn     func(acc, v quad.Value) quad.Value
+}
+
+func (s Reduce) BuildIterator(qs graph.QuadStore) graph.Iterator {
+	var it graph.Iterator
+	if IsNull(s.Values) {
+		it = iterator.NewNull()
+	} else {
+		it = s.Values.BuildIterator(qs)
+	}
+	return iterator.NewReduce(it, qs, s.Tag, s.Init, s.Fn)
+}
+func (s Reduce) init() Shape {
+	if s.Init == nil {
+		return nil
+	}
+	return Fixed{graph.PreFetched(s.Init)}
+}
+func (s Reduce) Optimize(r Optimizer) (Shape, bool) {
+	if IsNull(s.Values) {
+		return s.init(), true
+	}
+	var opt bool
+	s.Values, opt = s.Values.Optimize(r)
+	if IsNull(s.Values) {
+		return s.init(), true
+	}
+	if r != nil {
+		ns, nopt := r.OptimizeShape(s)
+		return ns, opt || nopt
+	}
+	return s, opt
+}
+
+// ContainedIn filters a source to values that are also produced by another shape.
+// The other shape is only used for membership checks and is never iterated.
+type ContainedIn struct {
+	From  Shape
+	Other Shape
+}
+
+func (s ContainedIn) BuildIterator(qs graph.QuadStore) graph.Iterator {
+	if IsNull(s.From) || IsNull(s.Other) {
+		return iterator.NewNull()
+	}
+	return iterator.NewContainedIn(s.From.BuildIterator(qs), s.Other.BuildIterator(qs))
+}
+func (s ContainedIn) Optimize(r Optimizer) (Shape, bool) {
+	if IsNull(s.From) || IsNull(s.Other) {
+		return nil, true
+	}
+	var opt1, opt2 bool
+	s.From, opt1 = s.From.Optimize(r)
+	s.Other, opt2 = s.Other.Optimize(r)
+	if IsNull(s.From) || IsNull(s.Other) {
+		return nil, true
+	}
+	if r != nil {
+		ns, nopt := r.OptimizeShape(s)
+		return ns, opt1 || opt2 || nopt
+	}
+	return s, opt1 || opt2
+}
+
+// SavePath binds values of step tags of a source as a single iterator.PathNodes value to a given tag.
+// Step tags are not included into tagged results.
+type SavePath struct {
+	From  Shape
+	Tag   string
+	Steps []string
+}
+
+func (s SavePath) BuildIterator(qs graph.QuadStore) graph.Iterator {
+	if IsNull(s.From) {
+		return iterator.NewNull()
+	}
+	return iterator.NewSavePath(s.From.BuildIterator(qs), qs, s.Tag, s.Steps)
+}
+func (s SavePath) Optimize(r Optimizer) (Shape, bool) {
+	if IsNull(s.From) {
+		return nil, true
+	}
+	var opt bool
+	s.From, opt = s.From.Optimize(r)
+	if IsNull(s.From) {
+		return nil, true
+	}
+	if r != nil {
+		ns, nopt := r.OptimizeShape(s)
+		return ns, opt || nopt
+	}
+	return s, opt
+}
+
 // QuadFilter is a constraint used to filter quads that have a certain set of values on a given direction.
 // Analog of LinksTo iterator.
 type QuadFilter struct {
@@ -888,6 +991,16 @@ func clearFixedTags(arr []Shape) ([]Shape, map[string]graph.Value) {
 // Intersect computes an intersection of nodes between multiple queries. Similar to And iterator.
 type Intersect []Shape
 
+// onlyOptional checks if all shapes in the list are Optional.
+func onlyOptional(arr []Shape) bool {
+	for _, s := range arr {
+		if _, ok := s.(Optional); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func (s Intersect) BuildIterator(qs graph.QuadStore) graph.Iterator {
 	if len(s) == 0 {
 		return iterator.NewNull()
@@ -940,6 +1053,7 @@ func (s Intersect) Optimize(r Optimizer) (sout Shape, opt bool) {
 	}
 	var (
 		onlyAll = true   // contains only AllNodes shapes
+		hasAll  = false  // contains at least one AllNodes shape
 		fixed   []Fixed  // we will collect all Fixed, and will place it as a first iterator
 		tags    []string // if we find a Save inside, we will push it outside of Intersect
 		quads   Quads    // also, collect all quad filters into a single set
@@ -960,6 +1074,7 @@ func (s Intersect) Optimize(r Optimizer) (sout Shape, opt bool) {
 		switch c := c.(type) {
 		case AllNodes: // remove AllNodes - it's useless
 			remove(&i, true)
+			hasAll = true
 			// prevent resetting of onlyAll
 			continue
 		case Optional:
@@ -994,6 +1109,10 @@ func (s Intersect) Optimize(r Optimizer) (sout Shape, opt bool) {
 	if onlyAll {
 		return AllNodes{}, true
 	}
+	if hasAll && quads == nil && len(fixed) == 0 && onlyOptional(s) {
+		// optional shapes cannot be iterated, thus keep AllNodes as a base
+		s = append(Intersect{AllNodes{}}, s...)
+	}
 	if len(tags) != 0 {
 		// don't forget to move Save outside of Intersect at the end
 		defer func() {
@@ -1213,6 +1332,35 @@ func (s Page) ApplyPage(p Page) *Page {
 	return &s
 }
 
+// IndexHint asks the backend to prefer an index on a given quad direction when resolving the From shape.
+// It is an escape hatch for query planner mistakes, and is ignored by backends that don't support hints.
+type IndexHint struct {
+	From Shape
+	Dir  quad.Direction
+}
+
+func (s IndexHint) BuildIterator(qs graph.QuadStore) graph.Iterator {
+	if IsNull(s.From) {
+		return iterator.NewNull()
+	}
+	return s.From.BuildIterator(qs)
+}
+func (s IndexHint) Optimize(r Optimizer) (Shape, bool) {
+	if IsNull(s.From) {
+		return nil, true
+	}
+	var opt bool
+	s.From, opt = s.From.Optimize(r)
+	if IsNull(s.From) {
+		return nil, true
+	}
+	if r != nil {
+		ns, nopt := r.OptimizeShape(s)
+		return ns, opt || nopt
+	}
+	return s, opt
+}
+
 // Unique makes query results unique.
 type Unique struct {
 	From Shape
@@ -169,6 +169,26 @@ var optimizeCases = []struct {
 			quad.IRI("alice"): intVal(1),
 		},
 	},
+	{
+		name: "intersect optional, keep all nodes",
+		from: Intersect{
+			Save{
+				Tags: []string{"id"},
+				From: AllNodes{},
+			},
+			Optional{Fixed{intVal(1)}},
+			Optional{Fixed{intVal(2)}},
+		},
+		opt: true,
+		expect: Save{
+			Tags: []string{"id"},
+			From: Intersect{
+				AllNodes{},
+				Optional{Fixed{intVal(1)}},
+				Optional{Fixed{intVal(2)}},
+			},
+		},
+	},
 	{
 		name: "push Save out of intersect",
 		from: Intersect{
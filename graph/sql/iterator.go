@@ -45,7 +45,9 @@ func (qs *QuadStore) Query(ctx context.Context, s Shape) (*sql.Rows, error) {
 	}
 	b := NewBuilder(qs.flavor.QueryDialect)
 	qu := s.SQL(b)
+	start := graph.StartSlow(qs.log, qs.slow)
 	rows, err := qs.db.QueryContext(ctx, qu, vals...)
+	graph.LogSlow(qs.log, qs.slow, "sql: query", start, graph.LogField{Key: "query", Value: qu})
 	if err != nil {
 		return nil, fmt.Errorf("sql query failed: %v\nquery: %v", err, qu)
 	}
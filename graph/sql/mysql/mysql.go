@@ -21,6 +21,7 @@ var QueryDialect = csql.QueryDialect{
 		return "`" + name + "`"
 	},
 	Placeholder: func(n int) string { return "?" },
+	IndexHint:   csql.UseIndexHint,
 }
 
 func init() {
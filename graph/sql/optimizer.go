@@ -94,6 +94,8 @@ func (opt *Optimizer) OptimizeShape(s shape.Shape) (shape.Shape, bool) {
 		return opt.optimizeQuadsAction(s)
 	case shape.Save:
 		return opt.optimizeSave(s)
+	case shape.IndexHint:
+		return opt.optimizeIndexHint(s)
 	case shape.Page:
 		return opt.optimizePage(s)
 	default:
@@ -487,6 +489,27 @@ func (opt *Optimizer) optimizePage(s shape.Page) (shape.Shape, bool) {
 	return sel, true
 }
 
+func (opt *Optimizer) optimizeIndexHint(s shape.IndexHint) (shape.Shape, bool) {
+	sel, ok := s.From.(Select)
+	if !ok {
+		return s, false
+	}
+	index := indexForDir(s.Dir)
+	if index == "" || len(sel.From) == 0 {
+		// no index for this direction - drop the hint
+		return sel, true
+	}
+	// first table is always the one used by the last traversal
+	t, ok := sel.From[0].(Table)
+	if !ok || t.Name != "quads" {
+		return sel, true
+	}
+	sel.From = append([]Source{}, sel.From...)
+	t.Index = index
+	sel.From[0] = t
+	return sel, true
+}
+
 func (opt *Optimizer) optimizeIntersect(s shape.Intersect) (shape.Shape, bool) {
 	var (
 		sels  []Select
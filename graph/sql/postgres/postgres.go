@@ -19,6 +19,7 @@ const Type = "postgres"
 var QueryDialect = csql.QueryDialect{
 	RegexpOp:   "~",
 	FieldQuote: pq.QuoteIdentifier,
+	IndexHint:  csql.CommentIndexHint,
 	Placeholder: func(n int) string {
 		return fmt.Sprintf("$%d", n)
 	},
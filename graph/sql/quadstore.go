@@ -1,6 +1,8 @@
 package sql
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
@@ -120,13 +122,21 @@ type QuadStore struct {
 
 	mu   sync.RWMutex
 	size int64
+
+	log  graph.Logger
+	slow time.Duration
 }
 
 func connect(addr string, flavor string, opts graph.Options) (*sql.DB, error) {
+	l, err := opts.Logger()
+	if err != nil {
+		return nil, err
+	}
 	// TODO(barakmich): Parse options for more friendly addr
 	conn, err := sql.Open(flavor, addr)
 	if err != nil {
 		clog.Errorf("Couldn't open database at %s: %#v", addr, err)
+		l.Error("sql: connection failed", graph.LogField{Key: "driver", Value: flavor}, graph.LogField{Key: "error", Value: err})
 		return nil, err
 	}
 	// "Open may just validate its arguments without creating a connection to the database."
@@ -134,11 +144,29 @@ func connect(addr string, flavor string, opts graph.Options) (*sql.DB, error) {
 	// Source: http://golang.org/pkg/database/sql/#Open
 	if err := conn.Ping(); err != nil {
 		clog.Errorf("Couldn't open database at %s: %#v", addr, err)
+		l.Error("sql: connection failed", graph.LogField{Key: "driver", Value: flavor}, graph.LogField{Key: "error", Value: err})
 		return nil, err
 	}
+	l.Info("sql: connected", graph.LogField{Key: "driver", Value: flavor})
 	return conn, nil
 }
 
+// txRetry runs statements in a transaction using the retry strategy of the backend, reporting retries to the logger.
+func (qs *QuadStore) txRetry(tx *sql.Tx, op string, stmts func() error) error {
+	retry := qs.flavor.TxRetry
+	if retry == nil {
+		return stmts()
+	}
+	attempt := 0
+	return retry(tx, func() error {
+		if attempt > 0 {
+			qs.log.Warn("sql: retrying transaction", graph.LogField{Key: "op", Value: op}, graph.LogField{Key: "attempt", Value: attempt})
+		}
+		attempt++
+		return stmts()
+	})
+}
+
 var nodesColumns = []string{
 	"hash",
 	"value",
@@ -275,6 +303,12 @@ func New(typ string, addr string, options graph.Options) (graph.QuadStore, error
 	if qs.useEstimates, err = options.BoolKey("use_estimates", false); err != nil {
 		return nil, err
 	}
+	if qs.log, err = options.Logger(); err != nil {
+		return nil, err
+	}
+	if qs.slow, err = options.DurationKey(graph.OptSlowQuery, graph.DefaultSlowQuery); err != nil {
+		return nil, err
+	}
 	return qs, nil
 }
 
@@ -346,18 +380,12 @@ func (qs *QuadStore) ApplyDeltas(in []graph.Delta, opts graph.IgnoreOpts) error
 		return err
 	}
 
-	retry := qs.flavor.TxRetry
-	if retry == nil {
-		retry = func(tx *sql.Tx, stmts func() error) error {
-			return stmts()
-		}
-	}
 	p := make([]string, 4)
 	for i := range p {
 		p[i] = qs.flavor.Placeholder(i + 1)
 	}
 
-	err = retry(tx, func() error {
+	err = qs.txRetry(tx, "apply deltas", func() error {
 		err = qs.flavor.RunTx(tx, deltas.IncNode, deltas.QuadAdd, opts)
 		if err != nil {
 			return err
@@ -449,6 +477,86 @@ func (qs *QuadStore) ApplyDeltas(in []graph.Delta, opts graph.IgnoreOpts) error
 	return tx.Commit()
 }
 
+var _ graph.BatchDeleter = (*QuadStore)(nil)
+
+// DeleteByPredicate removes all quads with a given predicate using a single DELETE statement.
+func (qs *QuadStore) DeleteByPredicate(ctx context.Context, pred quad.Value) (int64, error) {
+	ph := HashOf(pred)
+	tx, err := qs.db.BeginTx(ctx, nil)
+	if err != nil {
+		clog.Errorf("couldn't begin write transaction: %v", err)
+		return 0, err
+	}
+	var n int64
+	err = qs.txRetry(tx, "delete by predicate", func() error {
+		n = 0
+		// collect node references first to fix counters after the delete
+		refs := make(map[NodeHash]int)
+		rows, err := tx.QueryContext(ctx, `SELECT subject_hash, object_hash, label_hash FROM quads WHERE predicate_hash = `+qs.flavor.Placeholder(1)+`;`, ph.SQLValue())
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var s, o, l NodeHash
+			if err := rows.Scan(&s, &o, &l); err != nil {
+				rows.Close()
+				return err
+			}
+			for _, h := range []NodeHash{s, o, l} {
+				if h.Valid() {
+					refs[h]++
+				}
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM quads WHERE predicate_hash = `+qs.flavor.Placeholder(1)+`;`, ph.SQLValue())
+		if err != nil {
+			clog.Errorf("couldn't exec DELETE statement: %v", err)
+			return err
+		}
+		n, err = res.RowsAffected()
+		if err != nil {
+			clog.Errorf("couldn't get DELETE RowsAffected: %v", err)
+			return err
+		} else if n == 0 {
+			return nil
+		}
+		refs[ph] += int(n)
+		updateNode, err := tx.PrepareContext(ctx, `UPDATE nodes SET refs = refs - `+qs.flavor.Placeholder(1)+` WHERE hash = `+qs.flavor.Placeholder(2)+`;`)
+		if err != nil {
+			return err
+		}
+		defer updateNode.Close()
+		for h, cnt := range refs {
+			if _, err := updateNode.ExecContext(ctx, cnt, h.SQLValue()); err != nil {
+				clog.Errorf("couldn't exec UPDATE statement: %v", err)
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM nodes WHERE refs <= 0;`)
+		if err != nil {
+			clog.Errorf("couldn't exec DELETE nodes statement: %v", err)
+		}
+		return err
+	})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	qs.mu.Lock()
+	qs.size = -1
+	qs.mu.Unlock()
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
 func (qs *QuadStore) Quad(val graph.Value) quad.Quad {
 	h := val.(QuadHashes)
 	return quad.Quad{
@@ -547,82 +655,19 @@ func (qs *QuadStore) NameOf(v graph.Value) quad.Value {
 	if val, ok := qs.ids.Get(hash.String()); ok {
 		return val.(quad.Value)
 	}
-	query := `SELECT
-		value,
-		value_string,
-		datatype,
-		language,
-		iri,
-		bnode,
-		value_int,
-		value_bool,
-		value_float,
-		value_time
-	FROM nodes WHERE hash = ` + qs.flavor.Placeholder(1) + ` LIMIT 1;`
+	query := `SELECT ` + nodeValueColumns + ` FROM nodes WHERE hash = ` + qs.flavor.Placeholder(1) + ` LIMIT 1;`
 	c := qs.db.QueryRow(query, hash.SQLValue())
-	var (
-		data   []byte
-		str    sql.NullString
-		typ    sql.NullString
-		lang   sql.NullString
-		iri    sql.NullBool
-		bnode  sql.NullBool
-		vint   sql.NullInt64
-		vbool  sql.NullBool
-		vfloat sql.NullFloat64
-		vtime  NullTime
-	)
-	if err := c.Scan(
-		&data,
-		&str,
-		&typ,
-		&lang,
-		&iri,
-		&bnode,
-		&vint,
-		&vbool,
-		&vfloat,
-		&vtime,
-	); err != nil {
+	var nv nodeValue
+	if err := c.Scan(nv.scanArgs()...); err != nil {
 		if err != sql.ErrNoRows {
 			clog.Errorf("Couldn't execute value lookup: %v", err)
 		}
 		return nil
 	}
-	var val quad.Value
-	if str.Valid {
-		if iri.Bool {
-			val = quad.IRI(str.String)
-		} else if bnode.Bool {
-			val = quad.BNode(str.String)
-		} else if lang.Valid {
-			val = quad.LangString{
-				Value: quad.String(unescapeNullByte(str.String)),
-				Lang:  lang.String,
-			}
-		} else if typ.Valid {
-			val = quad.TypedString{
-				Value: quad.String(unescapeNullByte(str.String)),
-				Type:  quad.IRI(typ.String),
-			}
-		} else {
-			val = quad.String(unescapeNullByte(str.String))
-		}
-	} else if vint.Valid {
-		val = quad.Int(vint.Int64)
-	} else if vbool.Valid {
-		val = quad.Bool(vbool.Bool)
-	} else if vfloat.Valid {
-		val = quad.Float(vfloat.Float64)
-	} else if vtime.Valid {
-		val = quad.Time(vtime.Time)
-	} else {
-		qv, err := pquads.UnmarshalValue(data)
-		if err != nil {
-			clog.Errorf("Couldn't unmarshal value: %v", err)
-			return nil
-		}
-		val = qv
+	val, err := nv.Value()
+	if err != nil {
+		clog.Errorf("Couldn't unmarshal value: %v", err)
+		return nil
 	}
 	if val != nil {
 		qs.ids.Put(hash.String(), val)
@@ -630,6 +675,167 @@ func (qs *QuadStore) NameOf(v graph.Value) quad.Value {
 	return val
 }
 
+// nodeValueColumns is a list of columns of nodes table that are scanned into nodeValue.
+const nodeValueColumns = `value, value_string, datatype, language, iri, bnode, value_int, value_bool, value_float, value_time`
+
+// nodeValue stores columns of a node value loaded from nodes table.
+type nodeValue struct {
+	data   []byte
+	str    sql.NullString
+	typ    sql.NullString
+	lang   sql.NullString
+	iri    sql.NullBool
+	bnode  sql.NullBool
+	vint   sql.NullInt64
+	vbool  sql.NullBool
+	vfloat sql.NullFloat64
+	vtime  NullTime
+}
+
+func (nv *nodeValue) scanArgs() []interface{} {
+	return []interface{}{
+		&nv.data,
+		&nv.str,
+		&nv.typ,
+		&nv.lang,
+		&nv.iri,
+		&nv.bnode,
+		&nv.vint,
+		&nv.vbool,
+		&nv.vfloat,
+		&nv.vtime,
+	}
+}
+
+// Value converts loaded columns to a quad value.
+func (nv *nodeValue) Value() (quad.Value, error) {
+	if nv.str.Valid {
+		if nv.iri.Bool {
+			return quad.IRI(nv.str.String), nil
+		} else if nv.bnode.Bool {
+			return quad.BNode(nv.str.String), nil
+		} else if nv.lang.Valid {
+			return quad.LangString{
+				Value: quad.String(unescapeNullByte(nv.str.String)),
+				Lang:  nv.lang.String,
+			}, nil
+		} else if nv.typ.Valid {
+			return quad.TypedString{
+				Value: quad.String(unescapeNullByte(nv.str.String)),
+				Type:  quad.IRI(nv.typ.String),
+			}, nil
+		}
+		return quad.String(unescapeNullByte(nv.str.String)), nil
+	} else if nv.vint.Valid {
+		return quad.Int(nv.vint.Int64), nil
+	} else if nv.vbool.Valid {
+		return quad.Bool(nv.vbool.Bool), nil
+	} else if nv.vfloat.Valid {
+		return quad.Float(nv.vfloat.Float64), nil
+	} else if nv.vtime.Valid {
+		return quad.Time(nv.vtime.Time), nil
+	}
+	return pquads.UnmarshalValue(nv.data)
+}
+
+var _ graph.BatchValuer = (*QuadStore)(nil)
+
+// RefsOf implements graph.BatchValuer. Values are resolved without querying the database.
+func (qs *QuadStore) RefsOf(ctx context.Context, vals []quad.Value) ([]graph.Value, error) {
+	out := make([]graph.Value, len(vals))
+	for i, v := range vals {
+		out[i] = qs.ValueOf(v)
+	}
+	return out, nil
+}
+
+// namesOfBatch is the maximal number of values resolved by a single query in NamesOf.
+const namesOfBatch = 500
+
+// NamesOf implements graph.BatchValuer. Values that are not cached are loaded with a single query per batch.
+func (qs *QuadStore) NamesOf(ctx context.Context, vals []graph.Value) ([]quad.Value, error) {
+	out := make([]quad.Value, len(vals))
+	// indexes of values that should be loaded, by hash
+	load := make(map[NodeHash][]int)
+	for i, v := range vals {
+		var hash NodeHash
+		switch h := v.(type) {
+		case nil:
+			continue
+		case graph.PreFetchedValue:
+			out[i] = h.NameOf()
+			continue
+		case NodeHash:
+			hash = h
+		case graph.ValueHash:
+			hash = NodeHash{h}
+		default:
+			return nil, fmt.Errorf("unexpected token: %T", v)
+		}
+		if !hash.Valid() {
+			continue
+		} else if val, ok := qs.ids.Get(hash.String()); ok {
+			out[i] = val.(quad.Value)
+			continue
+		}
+		load[hash] = append(load[hash], i)
+	}
+	hashes := make([]NodeHash, 0, len(load))
+	for h := range load {
+		hashes = append(hashes, h)
+	}
+	for len(hashes) != 0 {
+		batch := hashes
+		if len(batch) > namesOfBatch {
+			batch = batch[:namesOfBatch]
+		}
+		hashes = hashes[len(batch):]
+
+		args := make([]interface{}, 0, len(batch))
+		var buf bytes.Buffer
+		buf.WriteString(`SELECT hash, ` + nodeValueColumns + ` FROM nodes WHERE hash IN (`)
+		for i, h := range batch {
+			if i != 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(qs.flavor.Placeholder(i + 1))
+			args = append(args, h.SQLValue())
+		}
+		buf.WriteString(`);`)
+		rows, err := qs.db.QueryContext(ctx, buf.String(), args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var (
+				hash NodeHash
+				nv   nodeValue
+			)
+			if err = rows.Scan(append([]interface{}{&hash}, nv.scanArgs()...)...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			val, err := nv.Value()
+			if err != nil {
+				rows.Close()
+				return nil, err
+			} else if val == nil {
+				continue
+			}
+			qs.ids.Put(hash.String(), val)
+			for _, i := range load[hash] {
+				out[i] = val
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
 func (qs *QuadStore) Size() int64 {
 	qs.mu.RLock()
 	sz := qs.size
@@ -658,6 +864,11 @@ func (qs *QuadStore) Close() error {
 	return qs.db.Close()
 }
 
+// Ping checks that the database connection is alive.
+func (qs *QuadStore) Ping(ctx context.Context) error {
+	return qs.db.PingContext(ctx)
+}
+
 func (qs *QuadStore) QuadDirection(in graph.Value, d quad.Direction) graph.Value {
 	return NodeHash{in.(QuadHashes).Get(d)}
 }
@@ -38,6 +38,32 @@ type QueryDialect struct {
 	RegexpOp    CmpOp
 	FieldQuote  func(string) string
 	Placeholder func(int) string
+	IndexHint   IndexHintType
+}
+
+// IndexHintType is a syntax used by the database to force a specific index.
+type IndexHintType int
+
+const (
+	// NoIndexHint indicates that database does not support index hints. All hints will be ignored.
+	NoIndexHint = IndexHintType(iota)
+	// UseIndexHint adds a USE INDEX clause after the table name (MySQL).
+	UseIndexHint
+	// CommentIndexHint adds a /*+ IndexScan(table index) */ comment after SELECT (pg_hint_plan).
+	CommentIndexHint
+)
+
+// indexForDir returns the name of an index for a given quad direction.
+func indexForDir(d quad.Direction) string {
+	switch d {
+	case quad.Subject:
+		return "spo_index"
+	case quad.Predicate:
+		return "pos_index"
+	case quad.Object:
+		return "osp_index"
+	}
+	return ""
 }
 
 func NewBuilder(d QueryDialect) *Builder {
@@ -45,8 +71,9 @@ func NewBuilder(d QueryDialect) *Builder {
 }
 
 type Builder struct {
-	d  QueryDialect
-	pi int
+	d     QueryDialect
+	pi    int
+	depth int // nesting level of subqueries
 }
 
 func needQuotes(s string) bool {
@@ -174,6 +201,7 @@ type Source interface {
 type Table struct {
 	Name  string
 	Alias string
+	Index string // index hint
 }
 
 func (Table) isSource() {}
@@ -185,7 +213,9 @@ type Subquery struct {
 
 func (Subquery) isSource() {}
 func (s Subquery) SQL(b *Builder) string {
+	b.depth++
 	q := "(" + s.Query.SQL(b) + ")"
+	b.depth--
 	if s.Alias != "" {
 		q += " AS " + b.EscapeField(s.Alias)
 	}
@@ -196,10 +226,14 @@ func (s Subquery) Args() []Value {
 }
 
 func (f Table) SQL(b *Builder) string {
-	if f.Alias == "" {
-		return f.Name
+	name := f.Name
+	if f.Alias != "" {
+		name += " AS " + b.EscapeField(f.Alias)
 	}
-	return f.Name + " AS " + b.EscapeField(f.Alias)
+	if f.Index != "" && b.d.IndexHint == UseIndexHint {
+		name += " USE INDEX (" + f.Index + ")"
+	}
+	return name
 }
 
 func (f Table) Args() []Value {
@@ -332,7 +366,14 @@ func (s Select) SQL(b *Builder) string {
 	for _, f := range s.Fields {
 		fields = append(fields, f.SQL(b))
 	}
-	parts = append(parts, "SELECT "+strings.Join(fields, ", "))
+	sel := "SELECT "
+	if b.depth == 0 && b.d.IndexHint == CommentIndexHint {
+		// hints are only recognized in the first comment of the query
+		if hints := s.indexHints(); len(hints) != 0 {
+			sel += "/*+ " + strings.Join(hints, " ") + " */ "
+		}
+	}
+	parts = append(parts, sel+strings.Join(fields, ", "))
 
 	var tables []string
 	for _, t := range s.From {
@@ -359,6 +400,23 @@ func (s Select) SQL(b *Builder) string {
 	}
 	return strings.Join(parts, sep)
 }
+
+// indexHints collects index hints for all tables in the query, including subqueries.
+func (s Select) indexHints() []string {
+	var hints []string
+	for _, src := range s.From {
+		switch src := src.(type) {
+		case Table:
+			if src.Index != "" {
+				hints = append(hints, "IndexScan("+src.NameSQL()+" "+src.Index+")")
+			}
+		case Subquery:
+			hints = append(hints, src.Query.indexHints()...)
+		}
+	}
+	return hints
+}
+
 func (s Select) Args() []Value {
 	var args []Value
 	// first add args for FROM subqueries
@@ -6,6 +6,7 @@ import (
 
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/graph/iterator"
+	"github.com/caivega/cayley/graph/path"
 	"github.com/caivega/cayley/graph/shape"
 	"github.com/caivega/cayley/quad"
 	"github.com/stretchr/testify/require"
@@ -377,3 +378,41 @@ func TestSQLShapes(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLIndexHint(t *testing.T) {
+	p := path.StartMorphism().Out(quad.IRI("p")).IndexHint(quad.Object)
+	s, ok := p.Shape().Optimize(NewOptimizer())
+	require.True(t, ok, "%#v", s)
+	sq, ok := s.(Shape)
+	require.True(t, ok, "%#v", s)
+
+	var cases = []struct {
+		name string
+		hint IndexHintType
+		qu   string
+	}{
+		{
+			name: "no hints",
+			hint: NoIndexHint,
+			qu:   `SELECT t_1.object_hash AS __node FROM quads AS t_1, (SELECT hash AS __node FROM nodes WHERE hash = ?) AS t_2 WHERE t_1.predicate_hash = t_2.__node`,
+		},
+		{
+			name: "mysql",
+			hint: UseIndexHint,
+			qu:   `SELECT t_1.object_hash AS __node FROM quads AS t_1 USE INDEX (osp_index), (SELECT hash AS __node FROM nodes WHERE hash = ?) AS t_2 WHERE t_1.predicate_hash = t_2.__node`,
+		},
+		{
+			name: "postgres",
+			hint: CommentIndexHint,
+			qu:   `SELECT /*+ IndexScan(t_1 osp_index) */ t_1.object_hash AS __node FROM quads AS t_1, (SELECT hash AS __node FROM nodes WHERE hash = ?) AS t_2 WHERE t_1.predicate_hash = t_2.__node`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dialect := DefaultDialect
+			dialect.IndexHint = c.hint
+			b := NewBuilder(dialect)
+			require.Equal(t, c.qu, sq.SQL(b))
+		})
+	}
+}
@@ -22,6 +22,8 @@ type Transaction struct {
 	Deltas []Delta
 	// deltas stores the deltas in a map to avoid duplications
 	deltas map[Delta]struct{}
+	// opts overrides the conflict policy of the writer
+	opts *IgnoreOpts
 }
 
 // NewTransaction initialize a new transaction.
@@ -34,6 +36,23 @@ func NewTransactionN(n int) *Transaction {
 	return &Transaction{Deltas: make([]Delta, 0, n), deltas: make(map[Delta]struct{}, n)}
 }
 
+// NewTransactionWithOptions initialize a new transaction with a given conflict policy.
+// The options override the ones set for the writer that applies the transaction.
+func NewTransactionWithOptions(opts IgnoreOpts) *Transaction {
+	t := NewTransaction()
+	t.opts = &opts
+	return t
+}
+
+// Options returns conflict options set for the transaction.
+// It returns false if options were not set, and the writer defaults should be used.
+func (t *Transaction) Options() (IgnoreOpts, bool) {
+	if t.opts == nil {
+		return IgnoreOpts{}, false
+	}
+	return *t.opts, true
+}
+
 // AddQuad adds a new quad to the transaction if it is not already present in it.
 // If there is a 'remove' delta for that quad, it will remove that delta from
 // the transaction instead of actually adding the quad.
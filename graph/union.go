@@ -0,0 +1,428 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/caivega/cayley/quad"
+)
+
+// ErrReadOnly is returned when a write is attempted on a read-only quad store.
+var ErrReadOnly = errors.New("quadstore: read-only")
+
+// NewUnionStore creates a read-only view that merges the contents of multiple quad stores.
+//
+// Values returned by the union store are resolved by content, thus the same node or quad
+// stored in more than one backend will be returned only once. Stores are consulted in the
+// order they were passed, and writes will always fail with ErrReadOnly.
+//
+// Closing the union store closes all underlying stores.
+func NewUnionStore(stores ...QuadStore) QuadStore {
+	return &unionStore{stores: stores}
+}
+
+var _ QuadStore = (*unionStore)(nil)
+
+type unionStore struct {
+	stores []QuadStore
+}
+
+// unionNode is a node value of the union store.
+type unionNode struct {
+	h ValueHash
+	v quad.Value
+}
+
+func (n unionNode) Key() interface{}   { return n.h }
+func (n unionNode) IsNode() bool       { return true }
+func (n unionNode) NameOf() quad.Value { return n.v }
+
+func newUnionNode(v quad.Value) Value {
+	if v == nil {
+		return nil
+	}
+	return unionNode{h: HashOf(v), v: v}
+}
+
+// unionQuad is a quad value of the union store.
+type unionQuad struct {
+	h QuadHash
+	q quad.Quad
+}
+
+func (q unionQuad) Key() interface{} { return q.h }
+func (q unionQuad) IsNode() bool     { return false }
+
+func newUnionQuad(q quad.Quad) Value {
+	var h QuadHash
+	for _, d := range quad.Directions {
+		h.Set(d, HashOf(q.Get(d)))
+	}
+	return unionQuad{h: h, q: q}
+}
+
+// hasValue checks if a node exists in a given store.
+func hasValue(ctx context.Context, qs QuadStore, v Value) bool {
+	n, ok := v.(unionNode)
+	return ok && hasNode(ctx, qs, n.v)
+}
+
+// hasNode checks if a value is used by at least one quad in a given store.
+//
+// Some stores return a non-nil ValueOf for values they don't have, thus direction indexes are checked instead.
+func hasNode(ctx context.Context, qs QuadStore, v quad.Value) bool {
+	sv := qs.ValueOf(v)
+	if sv == nil {
+		return false
+	}
+	for _, d := range quad.Directions {
+		it := qs.QuadIterator(d, sv)
+		ok := it.Next(ctx)
+		it.Close()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasQuad checks if a quad exists in a given store.
+//
+// It iterates the smallest direction index of the quad and checks the other directions with Contains,
+// thus only quads that share a value with the quad in each direction are considered.
+func hasQuad(ctx context.Context, qs QuadStore, v Value) bool {
+	q, ok := v.(unionQuad)
+	if !ok {
+		return false
+	}
+	var its []Iterator
+	defer func() {
+		for _, it := range its {
+			it.Close()
+		}
+	}()
+	best, bestSize := -1, int64(0)
+	for _, d := range quad.Directions {
+		qv := q.q.Get(d)
+		if qv == nil {
+			// there is no index for a missing label; the whole quad is compared below
+			continue
+		}
+		dv := qs.ValueOf(qv)
+		if dv == nil {
+			return false
+		}
+		it := qs.QuadIterator(d, dv)
+		if sz, _ := it.Size(); best < 0 || sz < bestSize {
+			best, bestSize = len(its), sz
+		}
+		its = append(its, it)
+	}
+	if best < 0 {
+		return false
+	}
+	for its[best].Next(ctx) {
+		qv := its[best].Result()
+		found := true
+		for i, it := range its {
+			if i != best && !it.Contains(ctx, qv) {
+				found = false
+				break
+			}
+		}
+		if found && newUnionQuad(qs.Quad(qv)).Key() == q.h {
+			return true
+		}
+	}
+	return false
+}
+
+func (qs *unionStore) ApplyDeltas(in []Delta, opts IgnoreOpts) error {
+	return ErrReadOnly
+}
+
+func (qs *unionStore) Quad(v Value) quad.Quad {
+	q, ok := v.(unionQuad)
+	if !ok {
+		return quad.Quad{}
+	}
+	return q.q
+}
+
+func (qs *unionStore) ValueOf(v quad.Value) Value {
+	if v == nil {
+		return nil
+	}
+	for _, s := range qs.stores {
+		if hasNode(context.TODO(), s, v) {
+			return newUnionNode(v)
+		}
+	}
+	return nil
+}
+
+func (qs *unionStore) NameOf(v Value) quad.Value {
+	if n, ok := v.(unionNode); ok {
+		return n.v
+	}
+	return nil
+}
+
+// Size returns the sum of sizes of all underlying stores.
+// Quads present in more than one store are counted multiple times.
+func (qs *unionStore) Size() int64 {
+	var n int64
+	for _, s := range qs.stores {
+		n += s.Size()
+	}
+	return n
+}
+
+func (qs *unionStore) OptimizeIterator(it Iterator) (Iterator, bool) {
+	return it, false
+}
+
+func (qs *unionStore) Close() error {
+	var err error
+	for _, s := range qs.stores {
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (qs *unionStore) QuadDirection(v Value, d quad.Direction) Value {
+	q, ok := v.(unionQuad)
+	if !ok {
+		return nil
+	}
+	return newUnionNode(q.q.Get(d))
+}
+
+func (qs *unionStore) NodesAllIterator() Iterator {
+	return qs.newIterator("nodes",
+		func(s QuadStore) Iterator {
+			return s.NodesAllIterator()
+		},
+		func(s QuadStore, v Value) Value {
+			return newUnionNode(s.NameOf(v))
+		},
+		hasValue,
+		func(v Value) bool {
+			_, ok := v.(unionNode)
+			return ok
+		},
+	)
+}
+
+func (qs *unionStore) QuadsAllIterator() Iterator {
+	return qs.newIterator("quads",
+		func(s QuadStore) Iterator {
+			return s.QuadsAllIterator()
+		},
+		func(s QuadStore, v Value) Value {
+			return newUnionQuad(s.Quad(v))
+		},
+		hasQuad,
+		func(v Value) bool {
+			_, ok := v.(unionQuad)
+			return ok
+		},
+	)
+}
+
+func (qs *unionStore) QuadIterator(d quad.Direction, v Value) Iterator {
+	n, ok := v.(unionNode)
+	if !ok {
+		return qs.newIterator("quads", nil, nil, hasQuad, nil)
+	}
+	return qs.newIterator(fmt.Sprintf("quads(%v=%v)", d, n.v),
+		func(s QuadStore) Iterator {
+			sv := s.ValueOf(n.v)
+			if sv == nil {
+				return nil
+			}
+			return s.QuadIterator(d, sv)
+		},
+		func(s QuadStore, v Value) Value {
+			return newUnionQuad(s.Quad(v))
+		},
+		hasQuad,
+		func(v Value) bool {
+			q, ok := v.(unionQuad)
+			return ok && q.h.Get(d) == n.h
+		},
+	)
+}
+
+func (qs *unionStore) newIterator(
+	name string,
+	sub func(s QuadStore) Iterator,
+	conv func(s QuadStore, v Value) Value,
+	exists func(ctx context.Context, s QuadStore, v Value) bool,
+	match func(v Value) bool,
+) *unionIterator {
+	return &unionIterator{
+		uid: NextUID(), qs: qs, name: name,
+		sub: sub, conv: conv, exists: exists, match: match,
+	}
+}
+
+var _ Iterator = (*unionIterator)(nil)
+
+// unionIterator iterates over the results of the same iterator in each of the union stores,
+// skipping results that were already returned from one of the previous stores.
+type unionIterator struct {
+	uid    uint64
+	tags   Tagger
+	qs     *unionStore
+	name   string
+	sub    func(s QuadStore) Iterator
+	conv   func(s QuadStore, v Value) Value
+	exists func(ctx context.Context, s QuadStore, v Value) bool
+	match  func(v Value) bool
+
+	i      int
+	cur    Iterator
+	result Value
+	err    error
+}
+
+func (it *unionIterator) UID() uint64 { return it.uid }
+
+func (it *unionIterator) String() string { return "Union(" + it.name + ")" }
+
+func (it *unionIterator) Tagger() *Tagger { return &it.tags }
+
+func (it *unionIterator) TagResults(dst map[string]Value) {
+	it.tags.TagResult(dst, it.Result())
+}
+
+func (it *unionIterator) Result() Value { return it.result }
+
+func (it *unionIterator) Next(ctx context.Context) bool {
+	if it.sub == nil {
+		return false
+	}
+	for it.i < len(it.qs.stores) {
+		s := it.qs.stores[it.i]
+		if it.cur == nil {
+			if it.cur = it.sub(s); it.cur == nil {
+				it.i++
+				continue
+			}
+		}
+		for it.cur.Next(ctx) {
+			v := it.conv(s, it.cur.Result())
+			if v == nil || it.seen(ctx, v) {
+				continue
+			}
+			it.result = v
+			return true
+		}
+		if err := it.cur.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		it.closeCurrent()
+		it.i++
+	}
+	it.result = nil
+	return false
+}
+
+// seen checks if a value exists in one of the stores that were already iterated.
+func (it *unionIterator) seen(ctx context.Context, v Value) bool {
+	for _, s := range it.qs.stores[:it.i] {
+		if it.exists(ctx, s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *unionIterator) NextPath(ctx context.Context) bool { return false }
+
+func (it *unionIterator) Contains(ctx context.Context, v Value) bool {
+	if it.match == nil || !it.match(v) {
+		return false
+	}
+	for _, s := range it.qs.stores {
+		if it.exists(ctx, s, v) {
+			it.result = v
+			return true
+		}
+	}
+	return false
+}
+
+func (it *unionIterator) Err() error { return it.err }
+
+func (it *unionIterator) closeCurrent() {
+	if it.cur != nil {
+		if err := it.cur.Close(); err != nil && it.err == nil {
+			it.err = err
+		}
+		it.cur = nil
+	}
+}
+
+func (it *unionIterator) Reset() {
+	it.closeCurrent()
+	it.i = 0
+	it.result = nil
+	it.err = nil
+}
+
+func (it *unionIterator) Clone() Iterator {
+	out := it.qs.newIterator(it.name, it.sub, it.conv, it.exists, it.match)
+	out.tags.CopyFrom(it)
+	return out
+}
+
+func (it *unionIterator) Stats() IteratorStats {
+	sz, exact := it.Size()
+	n := int64(len(it.qs.stores))
+	return IteratorStats{
+		NextCost:     n,
+		ContainsCost: n * 2,
+		Size:         sz,
+		ExactSize:    exact,
+	}
+}
+
+// Size returns the sum of sizes of iterators for each store.
+// The size is exact only if the union consists of a single store.
+func (it *unionIterator) Size() (int64, bool) {
+	if it.sub == nil {
+		return 0, true
+	}
+	var (
+		n     int64
+		exact = len(it.qs.stores) <= 1
+	)
+	for _, s := range it.qs.stores {
+		sit := it.sub(s)
+		if sit == nil {
+			continue
+		}
+		sz, ex := sit.Size()
+		sit.Close()
+		n += sz
+		exact = exact && ex
+	}
+	return n, exact
+}
+
+func (it *unionIterator) Type() Type { return "union" }
+
+func (it *unionIterator) Optimize() (Iterator, bool) { return it, false }
+
+func (it *unionIterator) SubIterators() []Iterator { return nil }
+
+func (it *unionIterator) Close() error {
+	it.closeCurrent()
+	return it.err
+}
@@ -0,0 +1,128 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/graph/path"
+	"github.com/caivega/cayley/quad"
+)
+
+func unionQuads(t *testing.T, qs graph.QuadStore, it graph.Iterator) []string {
+	var got []string
+	err := graph.Iterate(context.TODO(), it).Each(func(v graph.Value) {
+		got = append(got, qs.Quad(v).String())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestUnionStore(t *testing.T) {
+	var (
+		q1 = quad.MakeIRI("a", "follows", "b", "")
+		q2 = quad.MakeIRI("b", "follows", "c", "")
+		q3 = quad.MakeIRI("c", "follows", "d", "")
+	)
+	qs := graph.NewUnionStore(
+		memstore.New(q1, q2),
+		memstore.New(q2, q3),
+	)
+	defer qs.Close()
+
+	exp := []string{q1.String(), q2.String(), q3.String()}
+	sort.Strings(exp)
+	if got := unionQuads(t, qs, qs.QuadsAllIterator()); fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("unexpected quads: %v, expected: %v", got, exp)
+	}
+
+	c := qs.ValueOf(quad.IRI("c"))
+	if c == nil {
+		t.Fatal("expected to find a value from the second store")
+	}
+	if got := qs.NameOf(c); got != quad.IRI("c") {
+		t.Errorf("unexpected name: %v", got)
+	}
+	exp = []string{q2.String(), q3.String()}
+	sort.Strings(exp)
+	var got []string
+	for _, d := range []quad.Direction{quad.Subject, quad.Object} {
+		got = append(got, unionQuads(t, qs, qs.QuadIterator(d, c))...)
+	}
+	sort.Strings(got)
+	if fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("unexpected quads: %v, expected: %v", got, exp)
+	}
+
+	vals, err := path.StartPath(qs, quad.IRI("a")).Out(quad.IRI("follows")).Out(quad.IRI("follows")).Out(quad.IRI("follows")).
+		Iterate(context.TODO()).AllValues(qs)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(vals) != 1 || vals[0] != quad.IRI("d") {
+		t.Errorf("unexpected path results: %v", vals)
+	}
+
+	// quads that differ only by a label are different quads
+	q4 := quad.MakeIRI("a", "follows", "b", "g")
+	qs2 := graph.NewUnionStore(
+		memstore.New(q1, q2),
+		memstore.New(q4, q2, q3),
+	)
+	defer qs2.Close()
+	exp = []string{q1.String(), q2.String(), q3.String(), q4.String()}
+	sort.Strings(exp)
+	if got := unionQuads(t, qs2, qs2.QuadsAllIterator()); fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("unexpected quads: %v, expected: %v", got, exp)
+	}
+	it := qs2.QuadsAllIterator()
+	if it.Type() == graph.Or {
+		t.Errorf("union iterator should not pretend to be an Or iterator")
+	}
+	for it.Next(context.TODO()) {
+		if v := it.Result(); !qs2.QuadsAllIterator().Contains(context.TODO(), v) {
+			t.Errorf("expected union to contain %v", qs2.Quad(v))
+		}
+	}
+	it.Close()
+
+	err = qs.ApplyDeltas([]graph.Delta{{Quad: q1, Action: graph.Delete}}, graph.IgnoreOpts{})
+	if err != graph.ErrReadOnly {
+		t.Errorf("expected read-only error, got: %v", err)
+	}
+}
+
+func TestUnionStoreAnyValue(t *testing.T) {
+	var (
+		q1 = quad.MakeIRI("a", "follows", "b", "")
+		q2 = quad.MakeIRI("c", "follows", "d", "")
+	)
+	qs := graph.NewUnionStore(
+		anyValueStore{memstore.New(q1)},
+		anyValueStore{memstore.New(q2)},
+	)
+	defer qs.Close()
+
+	if v := qs.ValueOf(quad.IRI("e")); v != nil {
+		t.Errorf("unexpected value: %v", v)
+	}
+	if v := qs.ValueOf(quad.IRI("c")); v == nil {
+		t.Error("expected to find a value from the second store")
+	}
+	var got []string
+	err := graph.Iterate(context.TODO(), qs.NodesAllIterator()).EachValue(qs, func(v quad.Value) {
+		got = append(got, v.String())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if exp := []string{"<a>", "<b>", "<c>", "<d>", "<follows>"}; fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("unexpected nodes: %v, expected: %v", got, exp)
+	}
+}
@@ -22,15 +22,18 @@ import (
 // TODO(kortschak) Reimplement without container/list.
 
 // cache implements an LRU cache.
+//
+// Keys must be comparable.
 type Cache struct {
 	mu       sync.Mutex
-	cache    map[string]*list.Element
+	cache    map[interface{}]*list.Element
 	priority *list.List
 	maxSize  int
+	onEvict  func(key, value interface{})
 }
 
 type kv struct {
-	key   string
+	key   interface{}
 	value interface{}
 }
 
@@ -38,11 +41,19 @@ func New(size int) *Cache {
 	return &Cache{
 		maxSize:  size,
 		priority: list.New(),
-		cache:    make(map[string]*list.Element),
+		cache:    make(map[interface{}]*list.Element),
 	}
 }
 
-func (lru *Cache) Put(key string, value interface{}) {
+// NewWithEvict is the same as New, but calls a given function for each entry evicted from the cache.
+// The function is called with the cache locked, thus it must not call any methods of the cache.
+func NewWithEvict(size int, onEvict func(key, value interface{})) *Cache {
+	c := New(size)
+	c.onEvict = onEvict
+	return c
+}
+
+func (lru *Cache) Put(key interface{}, value interface{}) {
 	if _, ok := lru.Get(key); ok {
 		return
 	}
@@ -50,14 +61,17 @@ func (lru *Cache) Put(key string, value interface{}) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 	if len(lru.cache) == lru.maxSize {
-		last := lru.priority.Remove(lru.priority.Back())
-		delete(lru.cache, last.(kv).key)
+		last := lru.priority.Remove(lru.priority.Back()).(kv)
+		delete(lru.cache, last.key)
+		if lru.onEvict != nil {
+			lru.onEvict(last.key, last.value)
+		}
 	}
 	lru.priority.PushFront(kv{key: key, value: value})
 	lru.cache[key] = lru.priority.Front()
 }
 
-func (lru *Cache) Del(key string) {
+func (lru *Cache) Del(key interface{}) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 	e := lru.cache[key]
@@ -68,7 +82,15 @@ func (lru *Cache) Del(key string) {
 	lru.priority.Remove(e)
 }
 
-func (lru *Cache) Get(key string) (interface{}, bool) {
+// Purge removes all entries from the cache. The evict function is not called.
+func (lru *Cache) Purge() {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	lru.cache = make(map[interface{}]*list.Element)
+	lru.priority.Init()
+}
+
+func (lru *Cache) Get(key interface{}) (interface{}, bool) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 	if element, ok := lru.cache[key]; ok {
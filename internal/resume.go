@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/caivega/cayley/clog"
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/internal/decompressor"
+	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/quad/nquads"
+)
+
+const (
+	checkpointFormat  = "cayley-import-checkpoint"
+	checkpointVersion = 1
+)
+
+// Checkpoint records the progress of a resumable import.
+//
+// It is saved as a JSON document after each batch of quads is committed to the store.
+type Checkpoint struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// Source is the path of the file being imported.
+	Source string `json:"source"`
+	// Offset is the byte offset in the (decompressed) source right after the last committed quad.
+	Offset int64 `json:"offset"`
+	// Batches and Quads are the number of batches and quads committed to the store.
+	Batches int64 `json:"batches"`
+	Quads   int64 `json:"quads"`
+	// Done is set when the whole source was imported.
+	Done bool `json:"done"`
+}
+
+// ReadCheckpoint reads a checkpoint from a given file.
+// It returns nil without an error if the file does not exist.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var c Checkpoint
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cannot decode checkpoint %q: %v", path, err)
+	}
+	if c.Format != checkpointFormat {
+		return nil, fmt.Errorf("%q is not an import checkpoint", path)
+	} else if c.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version: %d", c.Version)
+	}
+	return &c, nil
+}
+
+// WriteCheckpoint atomically replaces the checkpoint file with a given checkpoint.
+func WriteCheckpoint(path string, c *Checkpoint) error {
+	c.Format, c.Version = checkpointFormat, checkpointVersion
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// LoadResumable loads an N-Quads file from the given path and writes it to qw in batches.
+//
+// After each batch is committed, the progress is saved to the checkpoint file. If the checkpoint
+// file already exists, loading resumes right after the last checkpointed batch.
+//
+// The checkpoint is not written in the same transaction as the batch, thus a crash between the two
+// makes the last committed batch to be written again on resume. To make the replay safe, batches are
+// written with duplicate quads ignored, regardless of the writer settings.
+func LoadResumable(qw graph.QuadWriter, batch int, path, checkpoint string) error {
+	if batch <= 0 {
+		batch = quad.DefaultBatch
+	}
+	cp, err := ReadCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	} else if cp == nil {
+		cp = &Checkpoint{Source: path}
+	} else if cp.Source != path {
+		return fmt.Errorf("checkpoint %q was created for a different source: %q", checkpoint, cp.Source)
+	} else if cp.Done {
+		clog.Infof("%q is already loaded", path)
+		return nil
+	} else {
+		clog.Infof("resuming load of %q after %d quads", path, cp.Quads)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompressor.New(f)
+	if err == io.EOF {
+		cp.Done = true
+		return WriteCheckpoint(checkpoint, cp)
+	} else if err != nil {
+		return err
+	}
+	base := cp.Offset
+	if base > 0 {
+		if _, raw := r.(*bufio.Reader); raw {
+			// uncompressed file - seek directly to the offset
+			if _, err = f.Seek(base, io.SeekStart); err != nil {
+				return err
+			}
+			r = f
+		} else if _, err = io.CopyN(ioutil.Discard, r, base); err != nil {
+			return fmt.Errorf("cannot skip to offset %d: %v", base, err)
+		}
+	}
+	qr := nquads.NewReader(r, false)
+
+	buf := make([]quad.Quad, batch)
+	for {
+		n := 0
+		for ; n < len(buf); n++ {
+			buf[n], err = qr.ReadQuad()
+			if err != nil {
+				break
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		eof := err == io.EOF
+		if n != 0 {
+			tx := graph.NewTransactionWithOptions(graph.IgnoreOpts{IgnoreDup: true})
+			for _, q := range buf[:n] {
+				tx.AddQuad(q)
+			}
+			if err = qw.ApplyTransaction(tx); err != nil {
+				return fmt.Errorf("db: failed to load data: %v", err)
+			}
+			cp.Offset = base + qr.Offset()
+			cp.Batches++
+			cp.Quads += int64(n)
+			if clog.V(2) {
+				clog.Infof("Wrote %d quads.", cp.Quads)
+			}
+		}
+		cp.Done = eof
+		if err = WriteCheckpoint(checkpoint, cp); err != nil {
+			return err
+		}
+		if eof {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/quad/nquads"
+	"github.com/caivega/cayley/writer"
+)
+
+var errCrash = errors.New("crash")
+
+// crashingWriter fails after writing a given number of batches.
+// If commit is set, the failing batch is still written to the store.
+type crashingWriter struct {
+	graph.QuadWriter
+	batches int
+	commit  bool
+}
+
+func (w *crashingWriter) ApplyTransaction(tx *graph.Transaction) error {
+	if w.batches <= 0 {
+		if w.commit {
+			if err := w.QuadWriter.ApplyTransaction(tx); err != nil {
+				return err
+			}
+		}
+		return errCrash
+	}
+	w.batches--
+	return w.QuadWriter.ApplyTransaction(tx)
+}
+
+func testQuads(n int) []quad.Quad {
+	quads := make([]quad.Quad, 0, n)
+	for i := 0; i < n; i++ {
+		quads = append(quads, quad.MakeIRI(fmt.Sprintf("n%d", i), "follows", fmt.Sprintf("n%d", i+1), ""))
+	}
+	return quads
+}
+
+func TestLoadResumable(t *testing.T) {
+	for _, gz := range []bool{false, true} {
+		t.Run(fmt.Sprintf("gzip=%v", gz), func(t *testing.T) {
+			testLoadResumable(t, gz)
+		})
+	}
+}
+
+func testLoadResumable(t *testing.T, gz bool) {
+	dir, err := ioutil.TempDir("", "cayley-resume-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	const (
+		total = 25
+		batch = 10
+	)
+	quads := testQuads(total)
+
+	buf := bytes.NewBuffer(nil)
+	var (
+		out io.Writer = buf
+		zw  *gzip.Writer
+	)
+	if gz {
+		zw = gzip.NewWriter(buf)
+		out = zw
+	}
+	_, err = quad.Copy(nquads.NewWriter(out), quad.NewReader(quads))
+	require.NoError(t, err)
+	if zw != nil {
+		require.NoError(t, zw.Close())
+	}
+	src := filepath.Join(dir, "data.nq")
+	err = ioutil.WriteFile(src, buf.Bytes(), 0644)
+	require.NoError(t, err)
+	cpath := filepath.Join(dir, "data.nq.checkpoint")
+
+	qs := memstore.New()
+	qw, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+	count := func() int64 {
+		all, err := quad.ReadAll(graph.NewQuadStoreReader(qs))
+		require.NoError(t, err)
+		return int64(len(all))
+	}
+
+	// crash after two batches
+	err = LoadResumable(&crashingWriter{QuadWriter: qw, batches: 2}, batch, src, cpath)
+	require.Error(t, err)
+	require.Equal(t, int64(2*batch), count())
+
+	cp, err := ReadCheckpoint(cpath)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), cp.Batches)
+	require.Equal(t, int64(2*batch), cp.Quads)
+	require.False(t, cp.Done)
+
+	// crash after the third batch is committed, but before the checkpoint is written
+	err = LoadResumable(&crashingWriter{QuadWriter: qw, commit: true}, batch, src, cpath)
+	require.Error(t, err)
+	require.Equal(t, int64(total), count())
+
+	cp, err = ReadCheckpoint(cpath)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), cp.Batches)
+	require.False(t, cp.Done)
+
+	// the writer returns an error on duplicate quads, but replaying the last batch must succeed
+	err = LoadResumable(qw, batch, src, cpath)
+	require.NoError(t, err)
+	require.Equal(t, int64(total), count())
+
+	got, err := quad.ReadAll(graph.NewQuadStoreReader(qs))
+	require.NoError(t, err)
+	require.ElementsMatch(t, quads, got)
+
+	cp, err = ReadCheckpoint(cpath)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), cp.Batches)
+	require.Equal(t, int64(total), cp.Quads)
+	require.True(t, cp.Done)
+
+	// completed import is not repeated
+	err = LoadResumable(qw, batch, src, cpath)
+	require.NoError(t, err)
+	require.Equal(t, int64(total), count())
+
+	err = LoadResumable(qw, batch, filepath.Join(dir, "other.nq"), cpath)
+	require.Error(t, err)
+}
@@ -0,0 +1,127 @@
+// Package migrate implements ordered, idempotent data migrations for quad stores.
+//
+// Each completed step is recorded in the store itself as a marker quad
+// (<cayley:migrations> <cayley:migration> "step name"), thus running the same list
+// of steps again applies only the steps that were not completed yet.
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/quad"
+)
+
+const (
+	// MarkerNode is the subject of marker quads that record completed migration steps.
+	MarkerNode = quad.IRI("cayley:migrations")
+	// MarkerPred is the predicate of marker quads. The object is the name of a completed step.
+	MarkerPred = quad.IRI("cayley:migration")
+)
+
+// Step is a single named migration step.
+type Step interface {
+	// Name returns a unique name of the step. It is stored in the marker quad and must not change between runs.
+	Name() string
+	// Apply runs the migration step, writing all the changes with w.
+	Apply(ctx context.Context, qs graph.QuadStore, w graph.QuadWriter) error
+}
+
+// StepFunc is a function that implements a migration step.
+type StepFunc func(ctx context.Context, qs graph.QuadStore, w graph.QuadWriter) error
+
+// NewStep creates a migration step with a given name from a function.
+func NewStep(name string, fnc StepFunc) Step {
+	return funcStep{name: name, fnc: fnc}
+}
+
+type funcStep struct {
+	name string
+	fnc  StepFunc
+}
+
+func (s funcStep) Name() string { return s.name }
+func (s funcStep) Apply(ctx context.Context, qs graph.QuadStore, w graph.QuadWriter) error {
+	return s.fnc(ctx, qs, w)
+}
+
+// ErrStepFailed is returned when one of the migration steps fails.
+// All steps before it were applied and recorded.
+type ErrStepFailed struct {
+	Step string
+	Err  error
+}
+
+func (e *ErrStepFailed) Error() string {
+	return fmt.Sprintf("migration %q failed: %v", e.Step, e.Err)
+}
+
+// Marker returns a quad that records that a step with a given name was completed.
+func Marker(name string) quad.Quad {
+	return quad.Quad{Subject: MarkerNode, Predicate: MarkerPred, Object: quad.String(name)}
+}
+
+// Applied returns a set of names of migration steps that were already completed in the quad store.
+func Applied(ctx context.Context, qs graph.QuadStore) (map[string]struct{}, error) {
+	out := make(map[string]struct{})
+	sv := qs.ValueOf(MarkerNode)
+	if sv == nil {
+		return out, nil
+	}
+	err := graph.Iterate(ctx, qs.QuadIterator(quad.Subject, sv)).On(qs).Each(func(v graph.Value) {
+		q := qs.Quad(v)
+		if q.Predicate != MarkerPred || q.Label != nil {
+			return
+		}
+		if s, ok := q.Object.(quad.String); ok {
+			out[string(s)] = struct{}{}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Run applies all migration steps that were not completed yet, in order. A marker quad is written after
+// each successful step. Run stops on the first failed step and returns ErrStepFailed.
+//
+// Steps must have unique names. Run returns names of the steps that were applied.
+func Run(ctx context.Context, qs graph.QuadStore, w graph.QuadWriter, steps []Step) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	names := make(map[string]struct{}, len(steps))
+	for _, s := range steps {
+		name := s.Name()
+		if name == "" {
+			return nil, fmt.Errorf("migrate: step name should not be empty")
+		} else if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("migrate: duplicate step name: %q", name)
+		}
+		names[name] = struct{}{}
+	}
+	done, err := Applied(ctx, qs)
+	if err != nil {
+		return nil, err
+	}
+	var applied []string
+	for _, s := range steps {
+		name := s.Name()
+		if _, ok := done[name]; ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return applied, err
+		}
+		if err := s.Apply(ctx, qs, w); err != nil {
+			return applied, &ErrStepFailed{Step: name, Err: err}
+		}
+		if err := w.AddQuad(Marker(name)); err != nil {
+			return applied, &ErrStepFailed{Step: name, Err: err}
+		}
+		applied = append(applied, name)
+	}
+	return applied, nil
+}
@@ -0,0 +1,64 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/migrate"
+	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/writer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.TODO()
+	qs := memstore.New(quad.MakeIRI("a", "knows", "b", ""))
+	w, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+
+	calls := make(map[string]int)
+	steps := []migrate.Step{
+		migrate.NewStep("rename-knows", func(ctx context.Context, qs graph.QuadStore, w graph.QuadWriter) error {
+			calls["rename-knows"]++
+			tx := graph.NewTransaction()
+			tx.RemoveQuad(quad.MakeIRI("a", "knows", "b", ""))
+			tx.AddQuad(quad.MakeIRI("a", "follows", "b", ""))
+			return w.ApplyTransaction(tx)
+		}),
+		migrate.NewStep("backfill-name", func(ctx context.Context, qs graph.QuadStore, w graph.QuadWriter) error {
+			calls["backfill-name"]++
+			return w.AddQuad(quad.Make(quad.IRI("a"), quad.IRI("name"), "A", nil))
+		}),
+	}
+
+	applied, err := migrate.Run(ctx, qs, w, steps)
+	require.NoError(t, err)
+	require.Equal(t, []string{"rename-knows", "backfill-name"}, applied)
+
+	applied, err = migrate.Run(ctx, qs, w, steps)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+	require.Equal(t, map[string]int{"rename-knows": 1, "backfill-name": 1}, calls)
+
+	done, err := migrate.Applied(ctx, qs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]struct{}{"rename-knows": {}, "backfill-name": {}}, done)
+
+	// a new step is applied on top of the completed ones
+	errFail := errors.New("fail")
+	steps = append(steps, migrate.NewStep("broken", func(ctx context.Context, qs graph.QuadStore, w graph.QuadWriter) error {
+		return errFail
+	}))
+	applied, err = migrate.Run(ctx, qs, w, steps)
+	require.Empty(t, applied)
+	e, ok := err.(*migrate.ErrStepFailed)
+	require.True(t, ok, "unexpected error: %v", err)
+	require.Equal(t, "broken", e.Step)
+	require.Equal(t, errFail, e.Err)
+
+	_, err = migrate.Run(ctx, qs, w, []migrate.Step{steps[0], steps[0]})
+	require.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package quad
+
+import (
+	"github.com/caivega/cayley/internal/lru"
+)
+
+// DefaultDedupCacheSize is the number of recently seen quads remembered by NewDedupReader by default.
+const DefaultDedupCacheSize = 10000
+
+// NewDedupReader wraps a quad reader and drops quads that were already read recently.
+//
+// Reader remembers hashes of the last cacheSize distinct quads (LRU), thus it only catches duplicates
+// that are close enough to each other in the input. Duplicates outside of this window are passed through,
+// and should still be handled by the quad store. If cacheSize <= 0, DefaultDedupCacheSize is used.
+func NewDedupReader(r ReadCloser, cacheSize int) ReadCloser {
+	if cacheSize <= 0 {
+		cacheSize = DefaultDedupCacheSize
+	}
+	return &dedupReader{r: r, seen: lru.New(cacheSize)}
+}
+
+type dedupReader struct {
+	r    ReadCloser
+	seen *lru.Cache
+}
+
+func (r *dedupReader) ReadQuad() (Quad, error) {
+	for {
+		q, err := r.r.ReadQuad()
+		if err != nil {
+			return q, err
+		}
+		h := q.Hash()
+		if _, ok := r.seen.Get(h); ok {
+			continue
+		}
+		r.seen.Put(h, struct{}{})
+		return q, nil
+	}
+}
+
+func (r *dedupReader) Close() error {
+	return r.r.Close()
+}
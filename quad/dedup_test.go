@@ -0,0 +1,31 @@
+package quad
+
+import (
+	"reflect"
+	"testing"
+)
+
+type nopCloser struct {
+	Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestDedupReader(t *testing.T) {
+	a := MakeIRI("a", "p", "b", "")
+	b := MakeIRI("b", "p", "c", "")
+	c := MakeIRI("c", "p", "d", "")
+
+	in := []Quad{a, a, b, a, c, b, a}
+	r := NewDedupReader(nopCloser{NewReader(in)}, 2)
+	defer r.Close()
+	got, err := ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// adjacent duplicates are collapsed, while b and a are evicted from the window before they are seen again
+	exp := []Quad{a, b, c, b, a}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", got, exp)
+	}
+}
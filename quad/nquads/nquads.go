@@ -31,7 +31,9 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"unicode/utf8"
 
+	"github.com/caivega/cayley/clog"
 	"github.com/caivega/cayley/quad"
 )
 
@@ -59,6 +61,9 @@ func init() {
 			if v == nil {
 				return nil, nil
 			}
+			if ts, ok := quad.AsTypedString(v); ok {
+				v = ts
+			}
 			return []byte(v.String()), nil
 		},
 		UnmarshalValue: func(b []byte) (quad.Value, error) {
@@ -77,24 +82,92 @@ func init() {
 			return q.Object, nil
 		},
 	})
+	// N-Triples files are read by nquads format by default; this one is strict and must be requested by name
+	quad.RegisterFormat(quad.Format{
+		Name: "ntriples",
+		Reader: func(r io.Reader) quad.ReadCloser {
+			return NewTriplesReader(r, DecodeRaw)
+		},
+		Writer: func(w io.Writer) quad.WriteCloser { return NewTriplesWriter(w) },
+	})
 }
 
 // Reader implements N-Quad document parsing according to the RDF
 // 1.1 N-Quads specification.
 type Reader struct {
 	r    *bufio.Reader
+	cnt  *countingReader
 	line []byte
 	raw  bool
+	n    int  // number of lines read
+	tri  bool // allow triples only
+
+	// SkipErrors allows to skip lines that cannot be parsed instead of returning an error.
+	// Parse errors are logged as warnings in this case.
+	SkipErrors bool
+
+	// ExpandIRIs expands IRIs abbreviated with prefixes registered in voc package.
+	// It should be set to read the output of a writer with WriterOptions.ShortIRIs enabled.
+	ExpandIRIs bool
 }
 
 // NewReader returns an N-Quad decoder that takes its input from the
 // provided io.Reader.
 func NewReader(r io.Reader, raw bool) *Reader {
-	return &Reader{r: bufio.NewReader(r), raw: raw}
+	cnt := &countingReader{r: r}
+	return &Reader{r: bufio.NewReader(cnt), cnt: cnt, raw: raw}
+}
+
+// NewTriplesReader returns an N-Triples decoder that takes its input from the provided io.Reader.
+//
+// It is the same as N-Quads decoder, but returns a parse error for statements that have a label.
+// Thus, all quads returned by the reader have a nil label.
+func NewTriplesReader(r io.Reader, raw bool) *Reader {
+	dec := NewReader(r, raw)
+	dec.tri = true
+	return dec
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// Offset returns the number of bytes of the underlying reader consumed by the quads read so far.
+//
+// Reading can be resumed from this offset by creating a new Reader at the same position in the source.
+func (dec *Reader) Offset() int64 {
+	return dec.cnt.n - int64(dec.r.Buffered())
 }
 
 // ReadQuad returns the next valid N-Quad as a quad.Quad, or an error.
+//
+// Parse errors are returned as quad.ParseError with a line number set.
 func (dec *Reader) ReadQuad() (quad.Quad, error) {
+	for {
+		q, err := dec.readQuad()
+		if _, ok := err.(quad.ParseError); ok && dec.SkipErrors {
+			clog.Warningf("skipping quad: %v", err)
+			continue
+		} else if err != nil {
+			return quad.Quad{}, err
+		}
+		if q.IsValid() {
+			if dec.ExpandIRIs {
+				q = expandIRIs(q)
+			}
+			return q, nil
+		}
+	}
+}
+
+func (dec *Reader) readQuad() (quad.Quad, error) {
 	dec.line = dec.line[:0]
 	var line []byte
 	for {
@@ -108,6 +181,7 @@ func (dec *Reader) ReadQuad() (quad.Quad, error) {
 				break
 			}
 		}
+		dec.n++
 		if line = bytes.TrimSpace(dec.line); len(line) != 0 && line[0] != '#' {
 			break
 		}
@@ -123,15 +197,35 @@ func (dec *Reader) ReadQuad() (quad.Quad, error) {
 		q, err = Parse(string(line))
 	}
 	if err != nil {
-		return quad.Quad{}, fmt.Errorf("failed to parse %q: %v", dec.line, err)
+		perr := quad.ParseError{Line: dec.n, Err: err}
+		if e, ok := err.(quad.ParseError); ok {
+			perr.Err = e.Err
+			// the parser reports columns in runes; account for leading spaces that were trimmed
+			perr.Col = e.Col + utf8.RuneCount(dec.line[:bytes.Index(dec.line, line)])
+		}
+		return quad.Quad{}, perr
 	}
-	if !q.IsValid() {
-		return dec.ReadQuad()
+	if dec.tri && q.Label != nil {
+		return quad.Quad{}, quad.ParseError{Line: dec.n, Err: fmt.Errorf("expected 3 terms in N-Triples statement, got 4")}
 	}
 	return q, nil
 }
 func (dec *Reader) Close() error { return nil }
 
+// expandIRIs converts all abbreviated IRIs in the quad to full IRIs.
+func expandIRIs(q quad.Quad) quad.Quad {
+	for _, d := range quad.Directions {
+		switch v := q.Get(d).(type) {
+		case quad.IRI:
+			q.Set(d, v.Full())
+		case quad.TypedString:
+			v.Type = v.Type.Full()
+			q.Set(d, v)
+		}
+	}
+	return q
+}
+
 func unEscape(r []rune, spec int, isQuoted, isEscaped bool) quad.Value {
 	raw := r
 	var sp []rune
@@ -289,24 +383,70 @@ func unEscapeRaw(r []rune, isEscaped bool) quad.Value {
 // provided io.Writer.
 func NewWriter(w io.Writer) *Writer { return &Writer{w: w} }
 
+// NewTriplesWriter returns an N-Triples encoder that writes its output to the
+// provided io.Writer. Labels of all quads are omitted.
+func NewTriplesWriter(w io.Writer) *Writer { return &Writer{w: w, tri: true} }
+
+// WriterOptions configures N-Quads encoder.
+type WriterOptions struct {
+	// ShortIRIs enables abbreviation of IRIs using prefixes registered in voc package.
+	//
+	// The output is not strictly valid N-Quads in this case and should be read with
+	// Reader.ExpandIRIs set to restore full IRIs.
+	ShortIRIs bool
+
+	// FloatPrecision controls the lexical form of quad.Float values. Zero keeps the default form,
+	// a negative value selects the shortest form that parses back to exactly the same value, and a positive
+	// value sets a fixed number of significant digits, which gives a stable output regardless of rounding
+	// errors in calculations. See quad.FormatFloat.
+	FloatPrecision int
+}
+
+// NewWriterWithOptions is the same as NewWriter, but allows to set additional options.
+func NewWriterWithOptions(w io.Writer, opts *WriterOptions) *Writer {
+	enc := &Writer{w: w}
+	if opts != nil {
+		enc.opts = *opts
+	}
+	return enc
+}
+
 // Writer implements N-Quad document generator according to the RDF
 // 1.1 N-Quads specification.
 type Writer struct {
-	w   io.Writer
-	err error
+	w    io.Writer
+	opts WriterOptions
+	tri  bool // omit labels
+	err  error
 }
 
 func (enc *Writer) writeValue(v quad.Value) {
 	if enc.err != nil {
 		return
 	}
+	if f, ok := v.(quad.Float); ok && enc.opts.FloatPrecision != 0 {
+		ts := f.TypedString()
+		ts.Value = quad.String(quad.FormatFloat(float64(f), enc.opts.FloatPrecision))
+		v = ts
+	} else if ts, ok := quad.AsTypedString(v); ok {
+		v = ts
+	}
+	if enc.opts.ShortIRIs {
+		switch vt := v.(type) {
+		case quad.IRI:
+			v = vt.Short()
+		case quad.TypedString:
+			vt.Type = vt.Type.Short()
+			v = vt
+		}
+	}
 	_, enc.err = enc.w.Write([]byte(v.String() + " "))
 }
 func (enc *Writer) WriteQuad(q quad.Quad) error {
 	enc.writeValue(q.Subject)
 	enc.writeValue(q.Predicate)
 	enc.writeValue(q.Object)
-	if q.Label != nil {
+	if q.Label != nil && !enc.tri {
 		enc.writeValue(q.Label)
 	}
 	if enc.err != nil {
@@ -276,9 +276,9 @@ func ParseRaw(statement string) (quad.Quad, error) {
 
 		if p < len(data) {
 			if r := data[p]; r < unicode.MaxASCII {
-				return q, fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)
+				return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)}
 			} else {
-				return q, fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)
+				return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)}
 			}
 		}
 		return q, quad.ErrIncomplete
@@ -3767,9 +3767,9 @@ func ParseRaw(statement string) (quad.Quad, error) {
 
 				if p < len(data) {
 					if r := data[p]; r < unicode.MaxASCII {
-						return q, fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)
+						return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)}
 					} else {
-						return q, fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)
+						return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)}
 					}
 				}
 				return q, quad.ErrIncomplete
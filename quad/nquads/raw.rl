@@ -88,9 +88,9 @@ import (
     action Error {
         if p < len(data) {
             if r := data[p]; r < unicode.MaxASCII {
-                return q, fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)
+                return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)}
             } else {
-                return q, fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)
+                return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)}
             }
         }
         return q, quad.ErrIncomplete
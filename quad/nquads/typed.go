@@ -591,9 +591,9 @@ func Parse(statement string) (quad.Quad, error) {
 
 		if p < len(data) {
 			if r := data[p]; r < unicode.MaxASCII {
-				return q, fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)
+				return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)}
 			} else {
-				return q, fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)
+				return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)}
 			}
 		}
 		return q, quad.ErrIncomplete
@@ -7110,9 +7110,9 @@ func Parse(statement string) (quad.Quad, error) {
 
 				if p < len(data) {
 					if r := data[p]; r < unicode.MaxASCII {
-						return q, fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)
+						return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q at %d", quad.ErrInvalid, data[p], p)}
 					} else {
-						return q, fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)
+						return q, quad.ParseError{Col: p + 1, Err: fmt.Errorf("%v: unexpected rune %q (\\u%04x) at %d", quad.ErrInvalid, data[p], data[p], p)}
 					}
 				}
 				return q, quad.ErrIncomplete
@@ -16,6 +16,7 @@ package nquads
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -27,6 +28,8 @@ import (
 	"time"
 
 	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/voc"
+	"github.com/caivega/cayley/voc/rdf"
 	"github.com/stretchr/testify/require"
 )
 
@@ -868,3 +871,216 @@ func TestValueEncoding(t *testing.T) {
 		require.Equal(t, v, v2)
 	}
 }
+
+func TestWriterFloatPrecision(t *testing.T) {
+	a, b := quad.Float(0.1), quad.Float(0.2)
+	q := quad.Quad{Subject: quad.IRI("s"), Predicate: quad.IRI("p"), Object: a + b}
+	for _, c := range []struct {
+		prec int
+		exp  string
+	}{
+		{0, `"3.0000000000000004E-01"`},
+		{-1, `"0.30000000000000004"`},
+		{3, `"0.3"`},
+	} {
+		buf := bytes.NewBuffer(nil)
+		w := NewWriterWithOptions(buf, &WriterOptions{FloatPrecision: c.prec})
+		require.NoError(t, w.WriteQuad(q))
+		exp := `<s> <p> ` + c.exp + `^^<` + string(a.TypedString().Type) + `> .` + "\n"
+		require.Equal(t, exp, buf.String())
+	}
+}
+
+type testCurrency struct {
+	Amount int64
+	Code   string
+}
+
+func (c testCurrency) String() string      { return fmt.Sprintf("%d %s", c.Amount, c.Code) }
+func (c testCurrency) Native() interface{} { return c }
+
+const testCurrencyType = quad.IRI("http://example.com/currency")
+
+func TestCustomTypedString(t *testing.T) {
+	quad.RegisterTypedString(testCurrencyType, func(s string) (quad.Value, error) {
+		var c testCurrency
+		if _, err := fmt.Sscanf(s, "%d %s", &c.Amount, &c.Code); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}, func(v quad.Value) (string, bool) {
+		c, ok := v.(testCurrency)
+		if !ok {
+			return "", false
+		}
+		return c.String(), true
+	})
+	defer quad.RegisterTypedString(testCurrencyType, nil, nil)
+
+	quads := []quad.Quad{
+		quad.MakeIRI("a", "price", "", ""),
+		quad.MakeIRI("b", "price", "", ""),
+	}
+	quads[0].Object = testCurrency{Amount: 10, Code: "USD"}
+	quads[1].Object = quad.TypedString{Value: "5", Type: "http://example.com/unknown"}
+
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	for _, q := range quads {
+		require.NoError(t, w.WriteQuad(q))
+	}
+	require.NoError(t, w.Close())
+	require.Equal(t, `<a> <price> "10 USD"^^<http://example.com/currency> .
+<b> <price> "5"^^<http://example.com/unknown> .
+`, buf.String())
+
+	r := NewReader(buf, false)
+	got, err := quad.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, quads, got)
+}
+
+func TestReaderOffset(t *testing.T) {
+	const data = `<a> <b> <c> .
+# comment
+
+<d> <e> <f> .
+<g> <h> <i> .
+`
+	r := NewReader(strings.NewReader(data), false)
+	require.Equal(t, int64(0), r.Offset())
+	_, err := r.ReadQuad()
+	require.NoError(t, err)
+	_, err = r.ReadQuad()
+	require.NoError(t, err)
+	off := r.Offset()
+	require.Equal(t, int64(strings.Index(data, "<g>")), off)
+
+	r = NewReader(strings.NewReader(data[off:]), false)
+	q, err := r.ReadQuad()
+	require.NoError(t, err)
+	require.Equal(t, quad.MakeIRI("g", "h", "i", ""), q)
+	_, err = r.ReadQuad()
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, int64(len(data))-off, r.Offset())
+}
+
+func TestReaderParseError(t *testing.T) {
+	const data = `<a> <b> <c> .
+# comment
+  <d> <e> # <f> .
+<g> <h> <i> .
+`
+	r := NewReader(strings.NewReader(data), false)
+	q, err := r.ReadQuad()
+	require.NoError(t, err)
+	require.Equal(t, quad.MakeIRI("a", "b", "c", ""), q)
+	_, err = r.ReadQuad()
+	perr, ok := err.(quad.ParseError)
+	require.True(t, ok, "unexpected error: %v", err)
+	require.Equal(t, 3, perr.Line)
+	require.Equal(t, 11, perr.Col)
+
+	r = NewReader(strings.NewReader(data), false)
+	r.SkipErrors = true
+	got, err := quad.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, []quad.Quad{
+		quad.MakeIRI("a", "b", "c", ""),
+		quad.MakeIRI("g", "h", "i", ""),
+	}, got)
+}
+
+func TestParseErrorColMultibyte(t *testing.T) {
+	// columns are counted in runes, not bytes
+	const line = `<a> <b> "日本語"@ .`
+	for _, parse := range []func(string) (quad.Quad, error){Parse, ParseRaw} {
+		_, err := parse(line)
+		perr, ok := err.(quad.ParseError)
+		require.True(t, ok, "unexpected error: %v", err)
+		require.Equal(t, 15, perr.Col)
+	}
+
+	r := NewReader(strings.NewReader("\u00a0"+line+"\n"), false)
+	_, err := r.ReadQuad()
+	perr, ok := err.(quad.ParseError)
+	require.True(t, ok, "unexpected error: %v", err)
+	require.Equal(t, 1, perr.Line)
+	require.Equal(t, 16, perr.Col)
+}
+
+func TestShortIRIs(t *testing.T) {
+	voc.RegisterPrefix("nqtest:", "http://example.com/nqtest/")
+	quads := []quad.Quad{
+		{
+			Subject:   quad.IRI("http://example.com/nqtest/a"),
+			Predicate: quad.IRI(rdf.Type).Full(),
+			Object:    quad.IRI("http://example.com/nqtest/Thing"),
+			Label:     quad.IRI("http://example.com/nqtest/graph"),
+		},
+		{
+			Subject:   quad.IRI("http://example.com/nqtest/a"),
+			Predicate: quad.IRI("http://other.org/price"),
+			Object:    quad.TypedString{Value: "10", Type: "http://example.com/nqtest/usd"},
+		},
+		{
+			Subject:   quad.BNode("b"),
+			Predicate: quad.IRI("http://example.com/nqtest/count"),
+			Object:    quad.Int(3),
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := NewWriterWithOptions(buf, &WriterOptions{ShortIRIs: true})
+	for _, q := range quads {
+		require.NoError(t, w.WriteQuad(q))
+	}
+	require.NoError(t, w.Close())
+	require.Equal(t, `<nqtest:a> <rdf:type> <nqtest:Thing> <nqtest:graph> .
+<nqtest:a> <http://other.org/price> "10"^^<nqtest:usd> .
+_:b <nqtest:count> "3"^^<schema:Integer> .
+`, buf.String())
+
+	r := NewReader(buf, false)
+	r.ExpandIRIs = true
+	got, err := quad.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, quads, got)
+}
+
+func TestTriples(t *testing.T) {
+	quads := []quad.Quad{
+		quad.MakeIRI("a", "b", "c", "g"),
+		quad.Make(quad.BNode("x"), quad.IRI("name"), quad.String("X"), nil),
+	}
+	buf := bytes.NewBuffer(nil)
+	w := NewTriplesWriter(buf)
+	for _, q := range quads {
+		require.NoError(t, w.WriteQuad(q))
+	}
+	require.NoError(t, w.Close())
+	require.Equal(t, `<a> <b> <c> .
+_:x <name> "X" .
+`, buf.String())
+
+	got, err := quad.ReadAll(NewTriplesReader(buf, false))
+	require.NoError(t, err)
+	require.Equal(t, []quad.Quad{
+		quad.MakeIRI("a", "b", "c", ""),
+		quad.Make(quad.BNode("x"), quad.IRI("name"), quad.String("X"), nil),
+	}, got)
+
+	const data = `<a> <b> <c> .
+<d> <e> <f> <g> .
+`
+	r := NewTriplesReader(strings.NewReader(data), false)
+	_, err = r.ReadQuad()
+	require.NoError(t, err)
+	_, err = r.ReadQuad()
+	perr, ok := err.(quad.ParseError)
+	require.True(t, ok, "unexpected error: %v", err)
+	require.Equal(t, 2, perr.Line)
+
+	_, err = quad.ReadAll(NewTriplesReader(strings.NewReader("<a> <b> .\n"), false))
+	require.Error(t, err)
+}
@@ -40,6 +40,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
+	"time"
 )
 
 var (
@@ -47,6 +49,22 @@ var (
 	ErrIncomplete = errors.New("incomplete N-Quad")
 )
 
+// ParseError is returned by quad readers when a quad cannot be parsed.
+type ParseError struct {
+	Line int // line number, starting from 1; zero if unknown
+	Col  int // column number (in runes), starting from 1; zero if unknown
+	Err  error
+}
+
+func (e ParseError) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	} else if e.Col == 0 {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d, col %d: %v", e.Line, e.Col, e.Err)
+}
+
 // Make creates a quad with provided values.
 func Make(subject, predicate, object, label interface{}) (q Quad) {
 	var ok bool
@@ -263,6 +281,57 @@ func (q Quad) IsValid() bool {
 	return IsValidValue(q.Subject) && IsValidValue(q.Predicate) && IsValidValue(q.Object)
 }
 
+// Equal checks if two quads have equal values in all directions.
+func (q Quad) Equal(o Quad) bool {
+	for _, d := range Directions {
+		if !valuesEqual(q.Get(d), o.Get(d)) {
+			return false
+		}
+	}
+	return true
+}
+
+func valuesEqual(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	} else if eq, ok := a.(Equaler); ok {
+		return eq.Equal(b)
+	} else if eq, ok := b.(Equaler); ok {
+		return eq.Equal(a)
+	}
+	return a.String() == b.String()
+}
+
+// Hash calculates a hash of the quad. Quads that are Equal have the same hash.
+//
+// The hash is computed from canonical (N-Quads) representation of values, thus it does not
+// depend on how values were constructed.
+func (q Quad) Hash() [HashSize]byte {
+	h := hashPool.Get().(hash.Hash)
+	h.Reset()
+	defer hashPool.Put(h)
+	var buf [HashSize]byte
+	for _, d := range Directions {
+		hashQuadValue(q.Get(d), buf[:])
+		h.Write(buf[:])
+	}
+	h.Sum(buf[:0])
+	return buf
+}
+
+// hashQuadValue is the same as HashTo, but normalizes values that implement Equaler,
+// thus values that are Equal always have the same hash.
+func hashQuadValue(v Value, p []byte) {
+	if t, ok := v.(Time); ok {
+		// Time compares instants, thus the zone is normalized; sub-second precision is preserved
+		v = TypedString{
+			Value: String(time.Time(t).UTC().Format(time.RFC3339Nano)),
+			Type:  defaultTimeType,
+		}
+	}
+	HashTo(v, p)
+}
+
 // Prints a quad in N-Quad format.
 func (q Quad) NQuad() string {
 	if q.Label == nil || q.Label.String() == "" {
@@ -0,0 +1,61 @@
+package quad
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuadHash(t *testing.T) {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	q1 := Quad{
+		Subject:   IRI("alice"),
+		Predicate: IRI("born"),
+		Object:    Time(ts),
+		Label:     BNode("g"),
+	}
+	// same values, constructed differently
+	q2 := Quad{
+		Subject:   Raw("<alice>"),
+		Predicate: StringToValue("<born>"),
+		Object:    Time(ts.In(time.FixedZone("EST", -5*3600))),
+		Label:     Raw("_:g"),
+	}
+	if !q1.Equal(q2) {
+		t.Fatalf("expected quads to be equal: %v vs %v", q1, q2)
+	} else if q1.Hash() != q2.Hash() {
+		t.Fatalf("expected equal hashes for %v and %v", q1, q2)
+	}
+
+	// times are compared as instants, including sub-second precision
+	ns := time.Date(2018, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	for _, c := range []struct {
+		t1, t2 time.Time
+		equal  bool
+	}{
+		{t1: ns, t2: ns.In(time.FixedZone("EST", -5*3600)), equal: true},
+		{t1: ns, t2: ns.In(time.Local), equal: true},
+		{t1: ns, t2: ns.Add(time.Nanosecond)},
+	} {
+		q3, q4 := q1, q1
+		q3.Object, q4.Object = Time(c.t1), Time(c.t2)
+		if eq := q3.Equal(q4); eq != c.equal {
+			t.Errorf("unexpected result of Equal(%v, %v): %v", c.t1, c.t2, eq)
+		} else if eq != (q3.Hash() == q4.Hash()) {
+			t.Errorf("hashes do not match Equal for %v and %v", c.t1, c.t2)
+		}
+	}
+
+	// values moved between directions should not collide
+	for _, q3 := range []Quad{
+		{Subject: IRI("born"), Predicate: IRI("alice"), Object: q1.Object, Label: q1.Label},
+		{Subject: q1.Subject, Predicate: q1.Predicate, Object: q1.Object},
+		{Subject: q1.Subject, Predicate: q1.Predicate, Object: q1.Label, Label: q1.Object},
+		{Subject: q1.Subject, Predicate: q1.Predicate, Object: String("alice"), Label: q1.Label},
+	} {
+		if q1.Equal(q3) {
+			t.Errorf("expected quads to be different: %v vs %v", q1, q3)
+		} else if q1.Hash() == q3.Hash() {
+			t.Errorf("unexpected hash collision for %v and %v", q1, q3)
+		}
+	}
+}
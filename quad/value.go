@@ -4,12 +4,14 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"hash"
+	"math"
 	"math/rand"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/caivega/cayley/voc"
 	"github.com/caivega/cayley/voc/schema"
@@ -247,6 +249,38 @@ func (s IRI) FullWith(n *voc.Namespaces) IRI {
 	return IRI(n.FullIRI(string(s)))
 }
 
+// Valid checks if IRI is well-formed according to a simplified RFC 3987 grammar.
+//
+// It requires a scheme (or a namespace prefix) and rejects whitespace, control
+// characters and other characters that are not allowed in IRIs.
+func (s IRI) Valid() bool {
+	if s == "" {
+		return false
+	}
+	i := strings.IndexByte(string(s), ':')
+	if i <= 0 {
+		return false // no scheme
+	}
+	for j, r := range string(s[:i]) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case j != 0 && (r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	for _, r := range string(s[i+1:]) {
+		if r <= 0x20 || r == 0x7f {
+			return false
+		}
+		switch r {
+		case '<', '>', '"', '{', '}', '|', '\\', '^', '`', utf8.RuneError:
+			return false
+		}
+	}
+	return true
+}
+
 // BNode is an RDF Blank Node (ex: _:name).
 type BNode string
 
@@ -310,6 +344,51 @@ func RegisterStringConversion(dataType IRI, fnc StringConversion) {
 	}
 }
 
+// TypedStringEncoder is a function to convert custom Value types to a lexical form of a specific
+// IRI type. It returns false if the value is not of the expected type.
+type TypedStringEncoder func(Value) (string, bool)
+
+type typedStringEncoder struct {
+	dataType IRI
+	encode   TypedStringEncoder
+}
+
+var knownEncoders []typedStringEncoder
+
+// RegisterTypedString registers a custom datatype. Parse function will be used to convert
+// TypedString values with provided type to a native equivalent while reading, and native function
+// will be used to convert those native values back to a TypedString with this type while writing.
+//
+// TypedString values with unregistered types are left unchanged.
+//
+// If both functions are nil, the datatype will be unregistered.
+func RegisterTypedString(dataType IRI, parse StringConversion, native TypedStringEncoder) {
+	RegisterStringConversion(dataType, parse)
+	for i, e := range knownEncoders {
+		if e.dataType == dataType {
+			knownEncoders = append(knownEncoders[:i], knownEncoders[i+1:]...)
+			break
+		}
+	}
+	if native != nil {
+		knownEncoders = append(knownEncoders, typedStringEncoder{dataType: dataType, encode: native})
+	}
+}
+
+// AsTypedString converts a value to TypedString using functions registered with RegisterTypedString.
+// It returns false if no registered function recognized the value.
+func AsTypedString(v Value) (TypedString, bool) {
+	if v == nil {
+		return TypedString{}, false
+	}
+	for _, e := range knownEncoders {
+		if s, ok := e.encode(v); ok {
+			return TypedString{Value: String(s), Type: e.dataType}, true
+		}
+	}
+	return TypedString{}, false
+}
+
 func stringToInt(s string) (Value, error) {
 	v, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
@@ -374,6 +453,28 @@ func (s Float) TypedString() TypedString {
 	}
 }
 
+// FormatFloat returns a lexical form of a float, as defined by XML Schema, with a given number of
+// significant digits. Negative precision selects the shortest form that parses back to exactly the same value.
+//
+// It is intended for output formats that need a canonical or a more readable form of floats. Float values
+// are always stored and hashed in the default form returned by TypedString, regardless of this function.
+func FormatFloat(v float64, prec int) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "INF"
+	case math.IsInf(v, -1):
+		return "-INF"
+	}
+	if prec < 0 {
+		prec = -1
+	} else if prec == 0 {
+		prec = 1
+	}
+	return strconv.FormatFloat(v, 'g', prec, 64)
+}
+
 // Bool is a native wrapper for bool type.
 //
 // It uses NQuad notation similar to TypedString.
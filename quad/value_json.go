@@ -0,0 +1,132 @@
+package quad
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MarshalJSONValue encodes a value to JSON using a tagged representation:
+//
+//	IRI          -> {"@id": "http://example.org/iri"}
+//	BNode        -> {"@id": "_:id"}
+//	String       -> {"@value": "text"}
+//	TypedString  -> {"@value": "text", "@type": "http://example.org/type"}
+//	LangString   -> {"@value": "text", "@lang": "en"}
+//
+// Int, Float, Bool and Time are encoded as typed strings, thus all of them are decoded back to the same type
+// by UnmarshalJSONValue. Time values are encoded with a precision of one second. Nil value is encoded as null.
+func MarshalJSONValue(v Value) ([]byte, error) {
+	var out map[string]string
+	switch v := v.(type) {
+	case nil:
+		return []byte("null"), nil
+	case IRI:
+		if v == "" {
+			return nil, errors.New("cannot encode an empty IRI")
+		}
+		out = map[string]string{"@id": string(v)}
+	case BNode:
+		out = map[string]string{"@id": v.String()}
+	case String:
+		out = map[string]string{"@value": string(v)}
+	case LangString:
+		out = map[string]string{"@value": string(v.Value), "@lang": v.Lang}
+	case TypedString:
+		out = map[string]string{"@value": string(v.Value), "@type": string(v.Type)}
+	case TypedStringer:
+		ts := v.TypedString()
+		out = map[string]string{"@value": string(ts.Value), "@type": string(ts.Type)}
+	default:
+		return nil, fmt.Errorf("unsupported value type: %T", v)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSONValue decodes a value encoded by MarshalJSONValue.
+//
+// Typed strings of known types are converted to native values (see RegisterStringConversion).
+// For convenience, it also accepts "@language" instead of "@lang", and plain JSON strings, numbers
+// and booleans, which are decoded as String, Int or Float, and Bool respectively.
+func UnmarshalJSONValue(data []byte) (Value, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty json value")
+	} else if data[0] != '{' {
+		return unmarshalJSONNative(data)
+	}
+	var jv struct {
+		ID    *string         `json:"@id"`
+		Value json.RawMessage `json:"@value"`
+		Type  IRI             `json:"@type"`
+		Lang  string          `json:"@lang"`
+		Lang2 string          `json:"@language"`
+	}
+	if err := json.Unmarshal(data, &jv); err != nil {
+		return nil, err
+	}
+	if jv.Lang == "" {
+		jv.Lang = jv.Lang2
+	}
+	if jv.ID != nil {
+		if jv.Value != nil || jv.Type != "" || jv.Lang != "" {
+			return nil, errors.New("@id cannot be used with @value, @type or @lang")
+		}
+		id := *jv.ID
+		if id == "" {
+			return nil, errors.New("empty @id")
+		} else if strings.HasPrefix(id, "_:") {
+			return BNode(id[2:]), nil
+		}
+		return IRI(id), nil
+	}
+	if jv.Value == nil {
+		return nil, errors.New("expected @id or @value")
+	} else if jv.Type != "" && jv.Lang != "" {
+		return nil, errors.New("@type cannot be used with @lang")
+	}
+	var s string
+	if err := json.Unmarshal(jv.Value, &s); err != nil {
+		if jv.Type != "" || jv.Lang != "" {
+			return nil, errors.New("typed @value must be a string")
+		}
+		return unmarshalJSONNative(jv.Value)
+	}
+	switch {
+	case jv.Type != "":
+		return TypedString{Value: String(s), Type: jv.Type}.ParseValue()
+	case jv.Lang != "":
+		return LangString{Value: String(s), Lang: jv.Lang}, nil
+	}
+	return String(s), nil
+}
+
+// unmarshalJSONNative decodes a plain JSON string, number or boolean as a value.
+func unmarshalJSONNative(data []byte) (Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return String(v), nil
+	case bool:
+		return Bool(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return Int(i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return Float(f), nil
+	}
+	return nil, fmt.Errorf("unsupported json value: %s", data)
+}
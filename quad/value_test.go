@@ -2,6 +2,7 @@ package quad
 
 import (
 	"encoding/hex"
+	"math"
 	"testing"
 )
 
@@ -25,3 +26,155 @@ func TestHashOf(t *testing.T) {
 		}
 	}
 }
+
+func TestIRIValid(t *testing.T) {
+	for _, s := range []IRI{
+		"http://example.org/name",
+		"https://example.org/a/b?c=d#e",
+		"urn:isbn:0451450523",
+		"mailto:bob@example.com",
+		"ex:name",
+		"http://例子.测试/路径",
+	} {
+		if !s.Valid() {
+			t.Errorf("expected %q to be valid", s)
+		}
+	}
+	for _, s := range []IRI{
+		"",
+		"name",
+		"/relative/path",
+		":no-scheme",
+		"1http://example.org/",
+		"http://example.org/with space",
+		"http://example.org/<bracket>",
+		"http://example.org/\"quote\"",
+		"http://example.org/new\nline",
+		"http://example.org/\xff",
+	} {
+		if s.Valid() {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestFloatRoundTrip(t *testing.T) {
+	a, b := Float(0.1), Float(0.2)
+	for _, c := range []struct {
+		val   Float
+		str   string
+		short string
+	}{
+		{0.1, "1E-01", "0.1"},
+		{0.3, "3E-01", "0.3"},
+		{a + b, "3.0000000000000004E-01", "0.30000000000000004"},
+		{-2.5, "-2.5E+00", "-2.5"},
+		{1e20, "1E+20", "1e+20"},
+		{1.5e-10, "1.5E-10", "1.5e-10"},
+		{math.MaxFloat64, "1.7976931348623157E+308", "1.7976931348623157e+308"},
+		{math.SmallestNonzeroFloat64, "5E-324", "5e-324"},
+		{Float(math.Inf(1)), "+Inf", "INF"},
+		{Float(math.Inf(-1)), "-Inf", "-INF"},
+		{Float(math.NaN()), "NaN", "NaN"},
+	} {
+		// the stored form must not change, since it is used to compute hashes
+		ts := c.val.TypedString()
+		if string(ts.Value) != c.str {
+			t.Errorf("unexpected format for %v: %q vs %q", float64(c.val), ts.Value, c.str)
+			continue
+		}
+		short := FormatFloat(float64(c.val), -1)
+		if short != c.short {
+			t.Errorf("unexpected short format for %v: %q vs %q", float64(c.val), short, c.short)
+		}
+		for _, str := range []string{string(ts.Value), short} {
+			v, err := TypedString{Value: String(str), Type: ts.Type}.ParseValue()
+			if err != nil {
+				t.Errorf("cannot parse %q: %v", str, err)
+				continue
+			}
+			f, ok := v.(Float)
+			if !ok {
+				t.Errorf("unexpected value for %q: %T", str, v)
+			} else if math.IsNaN(float64(c.val)) {
+				if !math.IsNaN(float64(f)) {
+					t.Errorf("expected NaN, got %v", f)
+				}
+			} else if f != c.val {
+				t.Errorf("value changed after round-trip: %v vs %v", float64(f), float64(c.val))
+			}
+		}
+	}
+}
+
+func TestFloatPrecision(t *testing.T) {
+	a, b := Float(0.1), Float(0.2)
+	for _, c := range []struct {
+		val Float
+		str string
+	}{
+		{a + b, "0.3"},
+		{3.14159, "3.14"},
+		{1234567, "1.23e+06"},
+	} {
+		if s := FormatFloat(float64(c.val), 3); s != c.str {
+			t.Errorf("unexpected format for %v: %q vs %q", float64(c.val), s, c.str)
+		}
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	for _, v := range []Value{
+		IRI("http://example.org/iri"),
+		BNode("b1"),
+		String("text"),
+		String(""),
+		TypedString{Value: "text", Type: "http://example.org/type"},
+		LangString{Value: "text", Lang: "en"},
+		Int(-42),
+		Float(1),
+		Float(3.14),
+		Bool(true),
+		Bool(false),
+		nil,
+	} {
+		data, err := MarshalJSONValue(v)
+		if err != nil {
+			t.Errorf("cannot encode %#v: %v", v, err)
+			continue
+		}
+		got, err := UnmarshalJSONValue(data)
+		if err != nil {
+			t.Errorf("cannot decode %#v from %s: %v", v, data, err)
+		} else if got != v {
+			t.Errorf("value changed after a round-trip: %#v -> %s -> %#v", v, data, got)
+		}
+	}
+
+	for _, c := range []struct {
+		data string
+		exp  Value
+	}{
+		{`"text"`, String("text")},
+		{`12`, Int(12)},
+		{`1.5`, Float(1.5)},
+		{`true`, Bool(true)},
+		{`{"@value": "text", "@language": "en"}`, LangString{Value: "text", Lang: "en"}},
+	} {
+		got, err := UnmarshalJSONValue([]byte(c.data))
+		if err != nil {
+			t.Errorf("cannot decode %s: %v", c.data, err)
+		} else if got != c.exp {
+			t.Errorf("unexpected value for %s: %#v", c.data, got)
+		}
+	}
+
+	for _, data := range []string{
+		``, `[]`, `{}`, `{"@id": ""}`, `{"@id": "a", "@value": "b"}`,
+		`{"@value": 1, "@type": "http://example.org/type"}`,
+	} {
+		if _, err := UnmarshalJSONValue([]byte(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
@@ -112,8 +112,8 @@ func (g *graphObject) Emit(call goja.FunctionCall) goja.Value {
 	value := call.Argument(0)
 	if !goja.IsNull(value) && !goja.IsUndefined(value) {
 		val := exportArgs([]goja.Value{value})[0]
-		if val != nil {
-			g.s.send(nil, &Result{Val: val})
+		if val != nil && !g.s.send(nil, &Result{Val: val}) && g.s.abort != nil {
+			return throwErr(g.s.vm, g.s.abort)
 		}
 	}
 	return goja.Null()
@@ -14,13 +14,24 @@
 
 package gizmo
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/caivega/cayley/quad"
+)
 
 var (
 	errNoVia       = fmt.Errorf("expected predicate list")
 	errRegexpOnIRI = fmt.Errorf("regexps are not allowed on IRIs")
 )
 
+var (
+	// ErrQueryTimeout is returned when query execution exceeds the timeout set in Limits.
+	ErrQueryTimeout = fmt.Errorf("query timeout")
+	// ErrResultLimit is returned when query tries to emit more results than allowed by Limits.
+	ErrResultLimit = fmt.Errorf("result limit exceeded")
+)
+
 type errArgCount2 struct {
 	Expected int
 	Got      int
@@ -45,3 +56,19 @@ type errNotQuadValue struct {
 func (e errNotQuadValue) Error() string {
 	return fmt.Sprintf("not a quad.Value: %T", e.Val)
 }
+
+type errNotInt struct {
+	Val interface{}
+}
+
+func (e errNotInt) Error() string {
+	return fmt.Sprintf("expected an integer, got: %v", e.Val)
+}
+
+type errUnknownType struct {
+	Type quad.IRI
+}
+
+func (e errUnknownType) Error() string {
+	return fmt.Sprintf("type is not registered: %v", e.Type)
+}
@@ -15,9 +15,14 @@
 package gizmo
 
 import (
+	"math"
+	"reflect"
+
 	"github.com/dop251/goja"
 
+	"github.com/caivega/cayley/graph/iterator"
 	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/schema"
 )
 
 const TopResultTag = "id"
@@ -167,6 +172,68 @@ func (p *pathObject) Count() (int64, error) {
 	return p.s.countResults(it)
 }
 
+// LoadTo loads each result node into an object of a type registered in the schema package and adds it to the output set.
+// Signature: (type) or (type, depth)
+//
+// Arguments:
+//
+// * `type`: An IRI of the type, as passed to schema.RegisterType.
+// * `depth` (Optional): A maximal depth of nested objects to load. Negative value means unlimited depth, and zero means top level only.
+//
+// Example:
+// 	// javascript
+//	// Load all people that bob follows, without loading their followers
+//	g.V("<bob>").Out("<follows>").LoadTo("<ex:Person>", 0)
+func (p *pathObject) LoadTo(call goja.FunctionCall) goja.Value {
+	args := exportArgs(call.Arguments)
+	if n := len(args); n != 1 && n != 2 {
+		return throwErr(p.s.vm, errArgCount{Got: n})
+	}
+	tv, err := toQuadValue(args[0])
+	if err != nil {
+		return throwErr(p.s.vm, err)
+	}
+	var iri quad.IRI
+	switch tv := tv.(type) {
+	case quad.IRI:
+		iri = tv
+	case quad.String:
+		iri = quad.IRI(tv)
+	default:
+		return throwErr(p.s.vm, errUnknownType{Type: quad.IRI(tv.String())})
+	}
+	rt, ok := schema.TypeForIRI(iri)
+	if !ok {
+		return throwErr(p.s.vm, errUnknownType{Type: iri})
+	}
+	depth := -1
+	if len(args) > 1 {
+		if f, ok := args[1].(float64); ok && f != math.Trunc(f) {
+			return throwErr(p.s.vm, errNotInt{Val: args[1]})
+		} else if depth, ok = toInt(args[1]); !ok {
+			return throwErr(p.s.vm, errNotInt{Val: args[1]})
+		}
+	}
+	it := p.buildIteratorTree()
+	if p.s.shape != nil {
+		iterator.OutputQueryShapeForIterator(it, p.s.qs, p.s.shape)
+		return goja.Null()
+	}
+	list := reflect.New(reflect.SliceOf(rt)).Elem()
+	if err = p.s.sch.LoadIteratorToDepth(p.s.context(), p.s.qs, list, depth, it); err != nil {
+		return throwErr(p.s.vm, err)
+	}
+	for i := 0; i < list.Len(); i++ {
+		if !p.s.send(nil, &Result{Val: list.Index(i).Addr().Interface()}) {
+			if p.s.abort != nil {
+				return throwErr(p.s.vm, p.s.abort)
+			}
+			break
+		}
+	}
+	return goja.Null()
+}
+
 func quadValueToString(v quad.Value) string {
 	if s, ok := v.(quad.String); ok {
 		return string(s)
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/dop251/goja"
 
@@ -73,6 +74,11 @@ type Session struct {
 	limit int
 	count int
 
+	// per-execution limits
+	maxResults int
+	total      int
+	abort      error
+
 	// used only to collate web results
 	dataOutput []interface{}
 	err        error
@@ -168,12 +174,19 @@ func (s *Session) runIteratorWithCallback(it graph.Iterator, callback goja.Value
 }
 
 func (s *Session) send(ctx context.Context, r *Result) bool {
+	if s.abort != nil {
+		return false
+	}
 	if s.limit >= 0 && s.count >= s.limit {
 		return false
 	}
 	if s.out == nil {
 		return false
 	}
+	if !r.Meta && s.maxResults > 0 && s.total >= s.maxResults {
+		s.abort = ErrResultLimit
+		return false
+	}
 	if ctx == nil {
 		ctx = s.ctx
 	}
@@ -183,6 +196,9 @@ func (s *Session) send(ctx context.Context, r *Result) bool {
 		return false
 	}
 	s.count++
+	if !r.Meta {
+		s.total++
+	}
 	return s.limit < 0 || s.count < s.limit
 }
 
@@ -202,7 +218,7 @@ func (s *Session) runIterator(it graph.Iterator) error {
 		}
 	})
 	if stop {
-		err = nil
+		err = s.abort
 	}
 	return err
 }
@@ -249,10 +265,39 @@ func (s *Session) run(qu string) (v goja.Value, err error) {
 	return v, err
 }
 func (s *Session) Execute(ctx context.Context, qu string, out chan query.Result, limit int) {
+	s.execute(ctx, qu, out, limit, Limits{})
+}
+
+// Limits restricts resources that a single query execution may use.
+type Limits struct {
+	// Timeout is the maximal duration of query execution. Zero means no timeout.
+	Timeout time.Duration
+	// MaxResults is the maximal number of results a query may emit. Zero means no limit.
+	MaxResults int
+}
+
+// ExecuteWithLimits is the same as Execute, but aborts the query if it exceeds the limits.
+//
+// Results emitted before the limit was reached are still sent to the channel, followed by
+// an error result with either ErrQueryTimeout or ErrResultLimit.
+func (s *Session) ExecuteWithLimits(ctx context.Context, qu string, out chan query.Result, lim Limits) {
+	s.execute(ctx, qu, out, -1, lim)
+}
+
+func (s *Session) execute(ctx context.Context, qu string, out chan query.Result, limit int, lim Limits) {
 	defer close(out)
+	pctx := ctx
+	if lim.Timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, lim.Timeout)
+		defer cancel()
+	}
 	s.out = out
 	s.limit = limit
 	s.count = 0
+	s.maxResults = lim.MaxResults
+	s.total = 0
+	s.abort = nil
 	s.ctx = ctx
 	done := make(chan struct{})
 	defer close(done)
@@ -264,9 +309,14 @@ func (s *Session) Execute(ctx context.Context, qu string, out chan query.Result,
 		}
 	}()
 	v, err := s.run(qu)
+	if s.abort != nil {
+		err = s.abort
+	} else if ctx.Err() == context.DeadlineExceeded && pctx.Err() == nil {
+		err = ErrQueryTimeout
+	}
 	if err != nil {
 		select {
-		case <-ctx.Done():
+		case <-pctx.Done():
 		case out <- query.ErrorResult(err):
 		}
 		return
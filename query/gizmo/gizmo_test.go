@@ -20,6 +20,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -28,6 +29,7 @@ import (
 	_ "github.com/caivega/cayley/graph/memstore"
 	"github.com/caivega/cayley/quad"
 	"github.com/caivega/cayley/query"
+	"github.com/caivega/cayley/schema"
 	_ "github.com/caivega/cayley/writer"
 
 	// register global namespace for tests
@@ -358,6 +360,34 @@ var testQueries = []struct {
 		`,
 		expect: []string{"<dani>"},
 	},
+	{
+		message: "use Skip and Limit after traversal",
+		query: `
+				g.V("<alice>", "<charlie>", "<dani>").Out("<follows>").Skip(1).Limit(2).All()
+		`,
+		expect: []string{"<bob>", "<dani>"},
+	},
+	{
+		message: "page through traversal: first page",
+		query: `
+				g.V("<alice>", "<charlie>", "<dani>").Out("<follows>").Skip(0).Limit(2).All()
+		`,
+		expect: []string{"<bob>", "<bob>"},
+	},
+	{
+		message: "page through traversal: second page",
+		query: `
+				g.V("<alice>", "<charlie>", "<dani>").Out("<follows>").Skip(2).Limit(2).All()
+		`,
+		expect: []string{"<dani>", "<bob>"},
+	},
+	{
+		message: "use Skip past the end of traversal",
+		query: `
+				g.V("<alice>", "<charlie>", "<dani>").Out("<follows>").Skip(4).Limit(2).All()
+		`,
+		expect: []string{"<greg>"},
+	},
 
 	{
 		message: "show Count",
@@ -716,6 +746,108 @@ g.Emit({id: x.id})
 	require.NoError(t, err)
 }
 
+func runWithLimits(t *testing.T, qu string, lim Limits) ([]query.Result, error) {
+	ses := makeTestSession(testutil.LoadGraph(t, "../../data/testdata.nq"))
+	c := make(chan query.Result, 5)
+	go ses.ExecuteWithLimits(context.TODO(), qu, c, lim)
+	var (
+		results []query.Result
+		err     error
+	)
+	for res := range c {
+		if e := res.Err(); e != nil {
+			err = e
+			continue
+		}
+		results = append(results, res)
+	}
+	return results, err
+}
+
+func TestResultLimit(t *testing.T) {
+	res, err := runWithLimits(t, `g.V().All()`, Limits{MaxResults: 3})
+	require.Equal(t, ErrResultLimit, err)
+	require.Len(t, res, 3)
+
+	res, err = runWithLimits(t, `g.V().Has("<status>", "cool_person").All()`, Limits{MaxResults: 3})
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+
+	res, err = runWithLimits(t, `for (i = 0; i < 5; i++) { g.Emit(i) }`, Limits{MaxResults: 2})
+	require.Equal(t, ErrResultLimit, err)
+	require.Len(t, res, 2)
+}
+
+func TestQueryTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := runWithLimits(t, `while (true) {}`, Limits{Timeout: 50 * time.Millisecond})
+	require.Equal(t, ErrQueryTimeout, err)
+	require.True(t, time.Since(start) < 5*time.Second)
+}
+
+func init() {
+	schema.RegisterType(quad.IRI("http://example.org/Person"), gizmoPerson{})
+}
+
+type gizmoPerson struct {
+	ID     quad.IRI     `json:"@id"`
+	Name   string       `json:"name"`
+	Friend *gizmoPerson `quad:"friend,optional"`
+}
+
+type quadSlice []quad.Quad
+
+func (s *quadSlice) WriteQuad(q quad.Quad) error {
+	*s = append(*s, q)
+	return nil
+}
+
+func TestLoadTo(t *testing.T) {
+	bob := &gizmoPerson{ID: "bob", Name: "Bob"}
+	alice := &gizmoPerson{ID: "alice", Name: "Alice", Friend: bob}
+	var data quadSlice
+	_, err := schema.WriteAsQuads(&data, alice)
+	require.NoError(t, err)
+	// a node that is not a person
+	data = append(data, quad.MakeIRI("fred", "name", "Fred", ""))
+
+	run := func(qu string) []interface{} {
+		ses := makeTestSession(data)
+		c := make(chan query.Result, 5)
+		go ses.Execute(context.TODO(), qu, c, -1)
+		var out []interface{}
+		for res := range c {
+			require.NoError(t, res.Err())
+			out = append(out, res.Result())
+		}
+		return out
+	}
+
+	got := run(`g.V("<alice>").LoadTo("<http://example.org/Person>")`)
+	require.Equal(t, []interface{}{alice}, got)
+
+	got = run(`g.V("<alice>").LoadTo("<http://example.org/Person>", 0)`)
+	// nested objects are not loaded, only their ids
+	require.Equal(t, []interface{}{&gizmoPerson{ID: "alice", Name: "Alice", Friend: &gizmoPerson{ID: "bob"}}}, got)
+
+	got = run(`g.V("<alice>", "<bob>", "<fred>").LoadTo("<http://example.org/Person>")`)
+	require.Len(t, got, 2)
+
+	ses := makeTestSession(data)
+	c := make(chan query.Result, 1)
+	go ses.Execute(context.TODO(), `g.V().LoadTo("<http://example.org/Unknown>")`, c, -1)
+	res := <-c
+	require.Equal(t, errUnknownType{Type: "http://example.org/Unknown"}, res.Err())
+
+	for _, depth := range []string{`"1"`, `1.5`, `null`} {
+		ses = makeTestSession(data)
+		c = make(chan query.Result, 1)
+		go ses.Execute(context.TODO(), `g.V("<alice>").LoadTo("<http://example.org/Person>", `+depth+`)`, c, -1)
+		res = <-c
+		require.IsType(t, errNotInt{}, res.Err(), "depth: %s", depth)
+	}
+}
+
 const issue718Limit = 5
 
 func issue718Graph() []quad.Quad {
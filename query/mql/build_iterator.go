@@ -26,9 +26,7 @@ import (
 )
 
 func (q *Query) buildFixed(s string) graph.Iterator {
-	f := iterator.NewFixed()
-	f.Add(q.ses.qs.ValueOf(quad.StringToValue(s)))
-	return f
+	return iterator.NewFixedValues(q.ses.qs, quad.StringToValue(s))
 }
 
 func (q *Query) buildResultIterator(path Path) graph.Iterator {
@@ -137,8 +135,7 @@ func (q *Query) buildIteratorTreeMapInternal(query map[string]interface{}, path
 				return nil, err
 			}
 			subAnd := iterator.NewAnd(q.ses.qs)
-			predFixed := iterator.NewFixed()
-			predFixed.Add(q.ses.qs.ValueOf(quad.StringToValue(pred)))
+			predFixed := iterator.NewFixedValues(q.ses.qs, quad.StringToValue(pred))
 			subAnd.AddSubIterator(iterator.NewLinksTo(q.ses.qs, predFixed, quad.Predicate))
 			if reverse {
 				lto := iterator.NewLinksTo(q.ses.qs, builtIt, quad.Subject)
@@ -0,0 +1,260 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/iterator"
+	"github.com/caivega/cayley/graph/shape"
+	"github.com/caivega/cayley/internal/lru"
+	"github.com/caivega/cayley/quad"
+)
+
+// ValueCache is a bounded cache of quad values resolved to quad store values. It is used to seed
+// iterators on repeated loads, saving lookups of the same predicates and constant IRIs.
+// It is safe for concurrent use.
+//
+// The cache must only be used with a single quad store. Resolved values of deleted nodes may become
+// invalid, thus all deletions must be reported with Invalidate, or the cache must be Reset after writes.
+type ValueCache struct {
+	mu  sync.RWMutex
+	lru *lru.Cache
+	max int
+}
+
+// NewValueCache creates a cache that keeps at most size values. If size <= 0, values are not cached.
+func NewValueCache(size int) *ValueCache {
+	c := &ValueCache{max: size}
+	if size > 0 {
+		c.lru = lru.New(size)
+	}
+	return c
+}
+
+func (c *ValueCache) cache() *lru.Cache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lru
+}
+
+// ValueOf resolves a value using the cache. Values that are missing in the quad store are not cached.
+func (c *ValueCache) ValueOf(qs graph.QuadStore, v quad.Value) graph.Value {
+	if v == nil {
+		return nil
+	}
+	cache := c.cache()
+	if cache == nil {
+		return qs.ValueOf(v)
+	}
+	key := v.String()
+	if gv, ok := cache.Get(key); ok {
+		return gv.(graph.Value)
+	}
+	gv := qs.ValueOf(v)
+	if gv != nil {
+		cache.Put(key, gv)
+	}
+	return gv
+}
+
+// Invalidate removes all values of deleted quads from the cache.
+func (c *ValueCache) Invalidate(deltas []graph.Delta) {
+	cache := c.cache()
+	if cache == nil {
+		return
+	}
+	for _, d := range deltas {
+		if d.Action != graph.Delete {
+			continue
+		}
+		for _, dir := range quad.Directions {
+			if v := d.Quad.Get(dir); v != nil {
+				cache.Del(v.String())
+			}
+		}
+	}
+}
+
+// Reset removes all values from the cache.
+func (c *ValueCache) Reset() {
+	if c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.lru = lru.New(c.max)
+	c.mu.Unlock()
+}
+
+// valueOf resolves a value, using a ValueCache if it is set.
+func (c *Config) valueOf(qs graph.QuadStore, v quad.Value) graph.Value {
+	if c.ValueCache != nil {
+		return c.ValueCache.ValueOf(qs, v)
+	}
+	return qs.ValueOf(v)
+}
+
+// fixedValues returns an iterator for a given list of values, using a ValueCache if it is set.
+// Values that are missing in the quad store are skipped.
+func (c *Config) fixedValues(qs graph.QuadStore, vals ...quad.Value) graph.Iterator {
+	if c.ValueCache == nil {
+		return iterator.NewFixedValues(qs, vals...)
+	}
+	it := iterator.NewFixed()
+	for _, v := range vals {
+		if gv := c.ValueCache.ValueOf(qs, v); gv != nil {
+			it.Add(gv)
+		}
+	}
+	return it
+}
+
+// cachedLookups resolves all lookups in the shape using a ValueCache.
+type cachedLookups struct {
+	qs    graph.QuadStore
+	cache *ValueCache
+}
+
+func (r cachedLookups) OptimizeShape(s shape.Shape) (shape.Shape, bool) {
+	l, ok := s.(shape.Lookup)
+	if !ok {
+		return s, false
+	}
+	vals := make(shape.Fixed, 0, len(l))
+	for _, v := range l {
+		if gv := r.cache.ValueOf(r.qs, v); gv != nil {
+			vals = append(vals, gv)
+		}
+	}
+	if len(vals) == 0 {
+		return nil, true
+	}
+	return vals, true
+}
+
+// Cache is a bounded read-through cache of objects loaded by id. Unlike ValueCache, it keeps whole
+// loaded objects, thus repeated loads of the same id do not access the quad store at all.
+// It is safe for concurrent use.
+//
+// Objects are copied to the destination as-is, thus slices, maps and pointers of cached objects
+// are shared between all callers and must not be modified. Objects must be evicted with Invalidate
+// after their quads are changed.
+type Cache struct {
+	c  *Config
+	qs graph.QuadStore
+
+	mu  sync.Mutex
+	lru *lru.Cache
+	max int
+	// loading tracks ids that are being loaded, so objects invalidated during the load are not cached
+	loading map[string]*cacheLoad
+}
+
+// cacheLoad tracks loads of the same id that are in progress.
+type cacheLoad struct {
+	n   int    // number of loads in progress
+	gen uint64 // incremented on each invalidation of the id
+}
+
+// NewCache creates an object cache that keeps at most size ids, and loads objects from a given quad store.
+// If size <= 0, objects are not cached and are always loaded from the quad store.
+func NewCache(c *Config, qs graph.QuadStore, size int) *Cache {
+	if c == nil {
+		c = global
+	}
+	oc := &Cache{c: c, qs: qs, max: size}
+	if size > 0 {
+		oc.lru = lru.New(size)
+		oc.loading = make(map[string]*cacheLoad)
+	}
+	return oc
+}
+
+// cacheKey returns a key for an object id.
+func cacheKey(id quad.Value) string {
+	return quad.StringOf(id)
+}
+
+// Get loads an object with a given id to dst, which must be a pointer to struct.
+// The object is served from the cache if it was loaded before, otherwise it is loaded with LoadTo.
+func (c *Cache) Get(ctx context.Context, dst interface{}, id quad.Value) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to struct, got: %T", dst)
+	}
+	if c.max <= 0 {
+		return c.c.LoadTo(ctx, c.qs, dst, id)
+	}
+	rt := rv.Elem().Type()
+	key := cacheKey(id)
+
+	c.mu.Lock()
+	if v, ok := c.lru.Get(key); ok {
+		if obj, ok := v.(map[reflect.Type]reflect.Value)[rt]; ok {
+			c.mu.Unlock()
+			rv.Elem().Set(obj)
+			return nil
+		}
+	}
+	l := c.loading[key]
+	if l == nil {
+		l = &cacheLoad{}
+		c.loading[key] = l
+	}
+	l.n++
+	gen := l.gen
+	c.mu.Unlock()
+
+	err := c.c.LoadTo(ctx, c.qs, dst, id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l.n--; l.n == 0 {
+		delete(c.loading, key)
+	}
+	if err != nil {
+		return err
+	} else if l.gen != gen {
+		// invalidated during the load, thus the object may be stale
+		return nil
+	}
+	obj := reflect.New(rt).Elem()
+	obj.Set(rv.Elem())
+	if v, ok := c.lru.Get(key); ok {
+		v.(map[reflect.Type]reflect.Value)[rt] = obj
+	} else {
+		c.lru.Put(key, map[reflect.Type]reflect.Value{rt: obj})
+	}
+	return nil
+}
+
+// Invalidate removes objects with given ids from the cache.
+func (c *Cache) Invalidate(ids ...quad.Value) {
+	if c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		key := cacheKey(id)
+		c.lru.Del(key)
+		if l := c.loading[key]; l != nil {
+			l.gen++
+		}
+	}
+}
+
+// Reset removes all objects from the cache.
+func (c *Cache) Reset() {
+	if c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.lru = lru.New(c.max)
+	for _, l := range c.loading {
+		l.gen++
+	}
+	c.mu.Unlock()
+}
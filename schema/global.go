@@ -42,7 +42,7 @@ func WriteAsQuads(w quad.Writer, o interface{}) (quad.Value, error) {
 //
 // Deprecated: see Config.WriteNamespaces
 func WriteNamespaces(w quad.Writer, n *voc.Namespaces) error {
-	return global.WriteNamespaces(w, n)
+	return global.WriteNamespaces(context.Background(), w, n)
 }
 
 // LoadNamespaces will load namespaces stored in graph to a specified list.
@@ -8,18 +8,70 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/graph/iterator"
 	"github.com/caivega/cayley/graph/path"
+	"github.com/caivega/cayley/graph/shape"
 	"github.com/caivega/cayley/quad"
 	"github.com/caivega/cayley/voc"
 	"github.com/caivega/cayley/voc/rdf"
 )
 
+// ErrInvalidIRI is returned when writing a malformed IRI with Config.ValidateIRIs enabled.
+type ErrInvalidIRI struct {
+	IRI quad.IRI
+	Dir quad.Direction
+}
+
+func (e ErrInvalidIRI) Error() string {
+	return fmt.Sprintf("invalid IRI in %v: %q", e.Dir, string(e.IRI))
+}
+
+// ErrInvalidID is returned when writing an object with a string @id that cannot be converted to an IRI.
+// See Config.StringIDsAsIRI.
+type ErrInvalidID struct {
+	ID string
+}
+
+func (e ErrInvalidID) Error() string {
+	return fmt.Sprintf("string id cannot be used as an IRI: %q", e.ID)
+}
+
+// ErrIRINotNormalized is returned when writing an IRI that cannot be converted according to Config.IRIs mode,
+// with Config.StrictIRIs enabled.
+type ErrIRINotNormalized struct {
+	IRI  quad.IRI
+	Dir  quad.Direction
+	Mode IRIMode
+}
+
+func (e ErrIRINotNormalized) Error() string {
+	op := "shortened"
+	if e.Mode == IRIFull {
+		op = "expanded"
+	}
+	return fmt.Sprintf("IRI in %v cannot be %s: %q", e.Dir, op, string(e.IRI))
+}
+
+// DerivedField is a predicate that is not mapped to a struct field, but is computed from an object on write.
+// It can be used to store denormalized values, such as a normalized name, alongside the mapped ones.
+type DerivedField struct {
+	// Pred is a predicate of the derived value.
+	Pred quad.IRI
+	// Value computes the value for a given object. It is called for every object written, thus it should
+	// check the type of the object. If it returns false, nothing is written.
+	Value func(o interface{}) (quad.Value, bool)
+}
+
 type ErrReqFieldNotSet struct {
 	Field string
 }
@@ -28,6 +80,79 @@ func (e ErrReqFieldNotSet) Error() string {
 	return fmt.Sprintf("required field is not set: %s", e.Field)
 }
 
+// ErrFieldConversion is returned when a loaded value cannot be converted to the type of a field.
+type ErrFieldConversion struct {
+	ID    quad.Value   // id of the object being loaded, if known
+	Field string       // name of the field
+	Value quad.Value   // value that was loaded from the quad store
+	Type  reflect.Type // type of the field
+	Err   error        // conversion error
+}
+
+func (e ErrFieldConversion) Error() string {
+	msg := fmt.Sprintf("field %s: cannot load %v into %v: %v", e.Field, e.Value, e.Type, e.Err)
+	if e.ID != nil {
+		msg = fmt.Sprintf("object %v: %s", e.ID, msg)
+	}
+	return msg
+}
+
+// ErrMultipleValues is returned when a scalar field has more than one value and
+// Config.OnMultipleValues is set to MultipleValuesError.
+type ErrMultipleValues struct {
+	ID     quad.Value   // id of the object being loaded, if known
+	Field  string       // name of the field
+	Values []quad.Value // distinct values of the field
+}
+
+func (e ErrMultipleValues) Error() string {
+	msg := fmt.Sprintf("field %s: expected a single value, got %d: %v", e.Field, len(e.Values), e.Values)
+	if e.ID != nil {
+		msg = fmt.Sprintf("object %v: %s", e.ID, msg)
+	}
+	return msg
+}
+
+// isTypedLoadErr checks if a load error already includes an object id.
+func isTypedLoadErr(err error) bool {
+	switch err.(type) {
+	case ErrFieldConversion, ErrMultipleValues:
+		return true
+	}
+	return false
+}
+
+// ErrDepthTruncated is returned by loads with a depth limit if Config.StrictDepth is set and some of
+// the nested objects were not loaded completely because of the limit. Values loaded up to the limit
+// are still set in the destination.
+type ErrDepthTruncated struct {
+	Fields []string // paths of truncated fields, like "Parent.Child"
+}
+
+func (e ErrDepthTruncated) Error() string {
+	return fmt.Sprintf("depth limit reached, nested objects were not loaded: %s", strings.Join(e.Fields, ", "))
+}
+
+// ErrMaxDepthExceeded is returned when writing an object with nested objects deeper
+// than allowed by Config.MaxWriteDepth.
+type ErrMaxDepthExceeded struct {
+	Depth int
+}
+
+func (e ErrMaxDepthExceeded) Error() string {
+	return fmt.Sprintf("max depth of nested objects exceeded: %d", e.Depth)
+}
+
+// ErrDuplicate is returned by EnsureUnique if another object with the same field values already exists.
+type ErrDuplicate struct {
+	ID     quad.Value // id of an existing object
+	Fields []string
+}
+
+func (e ErrDuplicate) Error() string {
+	return fmt.Sprintf("object with the same %s already exists: %v", strings.Join(e.Fields, ", "), e.ID)
+}
+
 // IRIMode controls how IRIs are processed.
 type IRIMode int
 
@@ -40,6 +165,21 @@ const (
 	IRIFull
 )
 
+// MultiValuePolicy controls how multiple values of a scalar (non-slice) field are handled on load.
+//
+// Values are sorted in canonical order (by quad.StringOf) before one of them is picked, thus the result
+// of MultipleValuesLastWins and MultipleValuesFirstWins does not depend on the iteration order of the quad store.
+type MultiValuePolicy int
+
+const (
+	// MultipleValuesLastWins keeps the last value in canonical order.
+	MultipleValuesLastWins = MultiValuePolicy(iota)
+	// MultipleValuesFirstWins keeps the first value in canonical order.
+	MultipleValuesFirstWins
+	// MultipleValuesError fails the load with ErrMultipleValues.
+	MultipleValuesError
+)
+
 // NewConfig creates a new schema config.
 func NewConfig() *Config {
 	return &Config{
@@ -52,12 +192,98 @@ type Config struct {
 	// IRIs set a conversion mode for all IRIs.
 	IRIs IRIMode
 
+	// Namespaces is a set of namespaces used to shorten and expand IRIs according to the IRIs mode.
+	// If not set, the global namespace registry from voc package is used.
+	Namespaces *voc.Namespaces
+
 	// GenerateID is called when any object without an ID field is being saved.
 	GenerateID func(_ interface{}) quad.Value
 
+	// ChildID is called when a nested object without an ID field is being saved. It receives an ID
+	// of the parent object and the name of the parent's field that holds the object. If it returns nil,
+	// or if it is not set, GenerateID is used instead.
+	//
+	// All objects in a slice or map field share the same field name, thus the child itself
+	// should be used to distinguish them.
+	ChildID func(parentID quad.Value, field string, child interface{}) quad.Value
+
+	// Derived is a list of predicates computed from objects on write. They are written after all fields
+	// of each object, including nested ones, and are not loaded back.
+	Derived []DerivedField
+
 	// Label will be added to all quads written. Does not affect queries.
 	Label quad.Value
 
+	// RestrictLabels limits all loads to quads with one of the given labels.
+	// If Label is not set, the first label from this list is used for writes.
+	// Empty list means that all labels are considered.
+	RestrictLabels []quad.Value
+
+	// StrictIRIs requires all IRIs written to match a registered namespace, so they can be converted
+	// according to IRIs mode. Write will fail if an IRI cannot be shortened (or expanded).
+	// It has no effect in IRINative mode.
+	StrictIRIs bool
+
+	// ValidateIRIs enables validation of all IRIs written as a part of quads.
+	// Write will fail if any IRI is malformed (see quad.IRI.Valid).
+	ValidateIRIs bool
+
+	// MaxWriteDepth limits the depth of nested objects that can be written, with
+	// top-level object having a depth of 1. Zero means no limit.
+	// Write will fail with ErrMaxDepthExceeded if the limit is reached.
+	MaxWriteDepth int
+
+	// ResolveStringID is called to convert a string @id field to a quad value when writing objects.
+	// If not set, strings are converted to IRIs according to IRIs mode.
+	ResolveStringID func(id string) quad.Value
+
+	// StringIDsAsIRI controls how string @id fields are written, unless ResolveStringID is set.
+	// If not set or true, strings are converted to IRIs according to IRIs mode, and strings that
+	// cannot form an IRI (for example, containing spaces) are rejected with ErrInvalidID.
+	// If false, strings are written as string literals. Such subjects are not valid RDF, but
+	// some applications expect opaque IDs to be stored as-is.
+	StringIDsAsIRI *bool
+
+	// Optimize controls an optimization step performed before queries.
+	// If not set, the value of the global Optimize flag is used.
+	Optimize *bool
+
+	// TagKey is the name of the struct tag with field rules. DefaultTagKey is used if it's not set.
+	TagKey string
+
+	// NoJSONTags disables the fallback to "json" struct tags for fields without a TagKey tag.
+	NoJSONTags bool
+
+	// ValueCache is an optional cache of resolved values used to seed iterators on loads.
+	// See ValueCache for details on how to keep it consistent with writes.
+	ValueCache *ValueCache
+
+	// CoerceStrings enables parsing of string values when loading them into bool or numeric fields.
+	// Load will fail if the string cannot be parsed as a value of the field type.
+	CoerceStrings bool
+
+	// BaseIRI is used to resolve relative IRI values when loading them into fields,
+	// according to RFC 3986 reference resolution. Object IDs are not affected.
+	BaseIRI quad.IRI
+
+	// WriteRelativeIRIs enables writing IRI values of fields that are under the BaseIRI as relative IRIs.
+	// It has no effect if BaseIRI is not set.
+	WriteRelativeIRIs bool
+
+	// WriteAtomic makes WriteAsQuads collect all quads of an object and its nested objects before
+	// writing them, so nothing is written if the conversion fails. Collected quads are applied in a single
+	// transaction if the writer implements ApplyTransaction, or in a single WriteQuads call if it implements
+	// quad.BatchWriter. Other writers receive quads one by one, thus the write is not atomic for them.
+	WriteAtomic bool
+
+	// StrictDepth makes loads with a depth limit (see LoadToDepth) fail with ErrDepthTruncated
+	// if nested objects were not loaded completely because of the limit.
+	StrictDepth bool
+
+	// OnMultipleValues sets a policy for scalar fields that have more than one value in the quad store.
+	// Values are considered in canonical order. Default is MultipleValuesLastWins.
+	OnMultipleValues MultiValuePolicy
+
 	pathForTypeMu   sync.RWMutex
 	pathForType     map[reflect.Type]*path.Path
 	pathForTypeRoot map[reflect.Type]*path.Path
@@ -66,6 +292,51 @@ type Config struct {
 	rulesForType   map[reflect.Type]fieldRules
 }
 
+// writeOpts are options of a single write call that apply to the object and all nested objects.
+// The zero value uses settings from the config.
+type writeOpts struct {
+	genID func(interface{}) quad.Value // generates IDs for objects without one; GenerateID is used if nil
+	bnode BNodeGenerator               // generates auxiliary blank nodes; random ones are used if nil
+	label quad.Value                   // label for all written quads; Label is used if nil
+}
+
+// writeLabel returns a label for quads written by this config with given options.
+func (c *Config) writeLabel(opts writeOpts) quad.Value {
+	if opts.label != nil {
+		return opts.label
+	} else if c.Label != nil {
+		return c.Label
+	} else if len(c.RestrictLabels) != 0 {
+		return c.RestrictLabels[0]
+	}
+	return nil
+}
+
+// hasLabel checks if a label is allowed by RestrictLabels.
+func (c *Config) hasLabel(label quad.Value) bool {
+	if len(c.RestrictLabels) == 0 {
+		return true
+	}
+	for _, l := range c.RestrictLabels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// quadInLabels checks if the quad has one of the labels from RestrictLabels.
+func (c *Config) quadInLabels(qs graph.QuadStore, q graph.Value) bool {
+	if len(c.RestrictLabels) == 0 {
+		return true
+	}
+	var label quad.Value
+	if lv := qs.QuadDirection(q, quad.Label); lv != nil {
+		label = qs.NameOf(lv)
+	}
+	return c.hasLabel(label)
+}
+
 func (c *Config) genID(o interface{}) quad.Value {
 	gen := c.GenerateID
 	if gen == nil {
@@ -79,6 +350,15 @@ func (c *Config) genID(o interface{}) quad.Value {
 	return gen(o)
 }
 
+// newBNode returns a new blank node for an auxiliary node, such as a list element.
+// Writes of a WriteSession provide their own generator.
+func (c *Config) newBNode(opts writeOpts) quad.Value {
+	if opts.bnode != nil {
+		return opts.bnode()
+	}
+	return quad.RandomBlankNode()
+}
+
 type rule interface {
 	isRule()
 }
@@ -92,29 +372,58 @@ type constraintRule struct {
 func (constraintRule) isRule() {}
 
 type saveRule struct {
-	Pred quad.IRI
-	Rev  bool
-	Opt  bool
+	Pred   quad.IRI
+	Rev    bool
+	Opt    bool
+	IDOnly bool // load only an id of the linked node
+	IFP    bool // inverse-functional property; nodes sharing its value are merged on load
+	Count  bool // load a number of linked nodes instead of the nodes; the field is never written
+	List   bool // slice is stored as an ordered RDF collection (rdf:first/rdf:rest)
 }
 
 func (saveRule) isRule() {}
 
-type idRule struct{}
+// idRule is used for "@id" fields. If there are multiple id fields, the one with the lowest Priority
+// that is set is used as an id.
+type idRule struct {
+	Priority int
+}
 
 func (idRule) isRule() {}
 
+// mapRule is used for map fields that store values of all predicates that are not mapped to other fields.
+type mapRule struct{}
+
+func (mapRule) isRule() {}
+
 const iriType = quad.IRI(rdf.Type)
 
 func (c *Config) iri(v quad.IRI) quad.IRI {
 	switch c.IRIs {
 	case IRIShort:
-		v = v.Short()
+		v = c.shortIRI(v)
 	case IRIFull:
-		v = v.Full()
+		v = c.fullIRI(v)
 	}
 	return v
 }
 
+// shortIRI compacts the IRI using Namespaces, or the global registry if it's not set.
+func (c *Config) shortIRI(v quad.IRI) quad.IRI {
+	if c.Namespaces == nil {
+		return v.Short()
+	}
+	return quad.IRI(c.Namespaces.ShortIRI(string(v)))
+}
+
+// fullIRI expands the IRI using Namespaces, or the global registry if it's not set.
+func (c *Config) fullIRI(v quad.IRI) quad.IRI {
+	if c.Namespaces == nil {
+		return v.Full()
+	}
+	return quad.IRI(c.Namespaces.FullIRI(string(v)))
+}
+
 func (c *Config) toIRI(s string) quad.IRI {
 	var v quad.IRI
 	if s == "@type" {
@@ -125,10 +434,100 @@ func (c *Config) toIRI(s string) quad.IRI {
 	return c.iri(v)
 }
 
+// resolveIRI resolves a relative IRI against the BaseIRI.
+// IRI is returned unchanged if it's already absolute, or if BaseIRI is not set.
+func (c *Config) resolveIRI(v quad.IRI) quad.IRI {
+	if c.BaseIRI == "" {
+		return v
+	}
+	ref, err := url.Parse(string(v))
+	if err != nil || ref.IsAbs() {
+		return v
+	}
+	base, err := url.Parse(string(c.BaseIRI))
+	if err != nil {
+		return v
+	}
+	return quad.IRI(base.ResolveReference(ref).String())
+}
+
+// relativeIRI converts an absolute IRI under the BaseIRI to a relative one, if WriteRelativeIRIs is enabled.
+// IRI is returned unchanged if the relative form does not resolve back to the same IRI.
+func (c *Config) relativeIRI(v quad.IRI) quad.IRI {
+	if c.BaseIRI == "" || !c.WriteRelativeIRIs {
+		return v
+	}
+	rel := strings.TrimPrefix(string(v), string(c.BaseIRI))
+	if rel == "" || rel == string(v) {
+		return v
+	}
+	if c.resolveIRI(quad.IRI(rel)) != v {
+		return v
+	}
+	return quad.IRI(rel)
+}
+
+// relativeValue applies relativeIRI to IRI values. Other values are returned unchanged.
+func (c *Config) relativeValue(v quad.Value) quad.Value {
+	if iri, ok := v.(quad.IRI); ok {
+		return c.relativeIRI(iri)
+	}
+	return v
+}
+
+func (c *Config) stringID(s string) (quad.Value, error) {
+	if c.ResolveStringID != nil {
+		return c.ResolveStringID(s), nil
+	} else if c.StringIDsAsIRI != nil && !*c.StringIDsAsIRI {
+		return quad.String(s), nil
+	}
+	if !validSubjectIRI(s) {
+		return nil, ErrInvalidID{ID: s}
+	}
+	return c.toIRI(s), nil
+}
+
+// validSubjectIRI checks if a string can be used as an IRI of a subject. Unlike quad.IRI.Valid,
+// it allows IRIs without a scheme, and only rejects characters that cannot appear in an IRI.
+func validSubjectIRI(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r <= 0x20 || r == 0x7f {
+			return false
+		}
+		switch r {
+		case '<', '>', '"', '{', '}', '|', '\\', '^', '`', utf8.RuneError:
+			return false
+		}
+	}
+	return true
+}
+
 var reflEmptyStruct = reflect.TypeOf(struct{}{})
 
 func (c Config) fieldRule(fld reflect.StructField) (rule, error) {
-	tag := fld.Tag.Get("quad")
+	r, err := c.parseFieldRule(fld)
+	if err != nil || r == nil || fld.PkgPath == "" {
+		return r, err
+	}
+	// reflect cannot read or set unexported fields, thus only constraints are allowed on them
+	if _, ok := r.(constraintRule); ok {
+		return r, nil
+	}
+	return nil, fmt.Errorf("unexported field %s cannot be used with tag `%s`", fld.Name, fld.Tag)
+}
+
+// DefaultTagKey is the default name of the struct tag with field rules.
+const DefaultTagKey = "quad"
+
+func (c *Config) parseFieldRule(fld reflect.StructField) (rule, error) {
+	key := c.TagKey
+	if key == "" {
+		key = DefaultTagKey
+	}
+	tag := fld.Tag.Get(key)
 	sub := strings.Split(tag, ",")
 	tag, sub = sub[0], sub[1:]
 	const (
@@ -139,7 +538,7 @@ func (c Config) fieldRule(fld reflect.StructField) (rule, error) {
 	)
 	tag = strings.Trim(tag, trim)
 	jsn := false
-	if tag == "" {
+	if tag == "" && !c.NoJSONTags {
 		tag = strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
 		jsn = true
 	}
@@ -148,10 +547,32 @@ func (c Config) fieldRule(fld reflect.StructField) (rule, error) {
 	}
 	rule := strings.Trim(tag, trim)
 	if rule == this {
-		return idRule{}, nil
+		var r idRule
+		for _, s := range sub {
+			if !strings.HasPrefix(s, "priority=") {
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimPrefix(s, "priority="))
+			if err != nil {
+				return nil, fmt.Errorf("wrong id priority: '%s'", s)
+			}
+			r.Priority = n
+		}
+		return r, nil
+	} else if rule == any && fld.Type.Kind() == reflect.Map {
+		switch fld.Type.Key() {
+		case reflect.TypeOf(quad.IRI("")), reflect.TypeOf(""):
+		default:
+			return nil, fmt.Errorf("unsupported key type for predicates map: %v", fld.Type.Key())
+		}
+		return mapRule{}, nil
 	}
 	opt := false
 	req := false
+	idOnly := false
+	ifp := false
+	count := false
+	list := false
 	for _, s := range sub {
 		if s == "opt" || s == "optional" {
 			opt = true
@@ -159,6 +580,18 @@ func (c Config) fieldRule(fld reflect.StructField) (rule, error) {
 		if s == "req" || s == "required" {
 			req = true
 		}
+		if s == "idonly" {
+			idOnly = true
+		}
+		if s == "ifp" {
+			ifp = true
+		}
+		if s == "count" {
+			count = true
+		}
+		if s == "list" {
+			list = true
+		}
 	}
 	if req {
 		opt = false
@@ -194,8 +627,30 @@ func (c Config) fieldRule(fld reflect.StructField) (rule, error) {
 		return nil, fmt.Errorf("wrong quad format: '%s': no predicate", rule)
 	}
 	p := c.toIRI(ps)
+	if count {
+		switch fld.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return nil, fmt.Errorf("count field %s should be an integer, got %v", fld.Name, fld.Type)
+		}
+		if vs != "" && vs != any {
+			return nil, fmt.Errorf("wrong quad tag format: '%s': count of a constraint", rule)
+		}
+		return saveRule{Pred: p, Rev: rev, Opt: true, Count: true}, nil
+	}
+	if list {
+		if fld.Type.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("list field %s should be a slice, got %v", fld.Name, fld.Type)
+		} else if rev || count {
+			return nil, fmt.Errorf("wrong quad tag format: '%s': list should be a forward link", rule)
+		} else if vs != "" && vs != any {
+			return nil, fmt.Errorf("wrong quad tag format: '%s': list of a constraint", rule)
+		}
+		return saveRule{Pred: p, Opt: opt, IDOnly: idOnly, List: true}, nil
+	}
 	if vs == "" || vs == any && fld.Type != reflEmptyStruct {
-		return saveRule{Pred: p, Rev: rev, Opt: opt}, nil
+		return saveRule{Pred: p, Rev: rev, Opt: opt, IDOnly: idOnly, IFP: ifp}, nil
 	} else {
 		return constraintRule{Pred: p, Val: c.toIRI(vs), Rev: rev}, nil
 	}
@@ -216,38 +671,71 @@ func checkFieldType(ftp reflect.Type) error {
 }
 
 // Optimize flags controls an optimization step performed before queries.
+//
+// See Config.Optimize for a per-config setting.
 var Optimize = true
 
-func iteratorFromPath(qs graph.QuadStore, root graph.Iterator, p *path.Path) (graph.Iterator, error) {
-	it := p.BuildIteratorOn(qs)
+// optimize reports if queries should be optimized.
+func (c *Config) optimize() bool {
+	if c.Optimize != nil {
+		return *c.Optimize
+	}
+	return Optimize
+}
+
+func (c *Config) iteratorFromPath(qs graph.QuadStore, root graph.Iterator, p *path.Path) (graph.Iterator, error) {
+	var it graph.Iterator
+	if c.ValueCache != nil {
+		s, _ := p.Shape().Optimize(cachedLookups{qs: qs, cache: c.ValueCache})
+		if s == nil {
+			s = shape.Null{}
+		}
+		it = shape.BuildIterator(qs, s)
+	} else {
+		it = p.BuildIteratorOn(qs)
+	}
 	if root != nil {
 		it = iterator.NewAnd(qs, root, it)
 	}
-	if Optimize {
+	if c.optimize() {
 		it, _ = it.Optimize()
 		it, _ = qs.OptimizeIterator(it)
 	}
 	return it, nil
 }
 
-func (c *Config) iteratorForType(qs graph.QuadStore, root graph.Iterator, rt reflect.Type, rootOnly bool) (graph.Iterator, error) {
-	p, err := c.makePathForType(rt, "", rootOnly)
+func (c *Config) iteratorForType(qs graph.QuadStore, root graph.Iterator, rt reflect.Type, rootOnly, allOpt bool) (graph.Iterator, error) {
+	p, err := c.makePathForType(rt, "", rootOnly, allOpt)
 	if err != nil {
 		return nil, err
 	}
-	return iteratorFromPath(qs, root, p)
+	return c.iteratorFromPath(qs, root, p)
 }
 
 var (
-	typesMu   sync.RWMutex
-	typeToIRI = make(map[reflect.Type]quad.IRI)
-	iriToType = make(map[quad.IRI]reflect.Type)
+	typesMu     sync.RWMutex
+	typeToIRI   = make(map[reflect.Type]quad.IRI)
+	iriToType   = make(map[quad.IRI]reflect.Type)
+	noWriteType = make(map[reflect.Type]struct{})
 )
 
+// Options controls how a registered type is written and queried.
+type Options struct {
+	// WriteType indicates that a type triple should be written for each object of this type.
+	// If set to false, the type triple is not stored, but is still required on queries.
+	// This is useful when type triples are inferred from other data.
+	WriteType bool
+}
+
 // RegisterType associates an IRI with a given Go type.
 //
 // All queries and writes will require or add a type triple.
 func RegisterType(iri quad.IRI, obj interface{}) {
+	RegisterTypeOpts(iri, obj, Options{WriteType: true})
+}
+
+// RegisterTypeOpts is the same as RegisterType, but allows to set additional options for the type.
+func RegisterTypeOpts(iri quad.IRI, obj interface{}, opts Options) {
 	var rt reflect.Type
 	if obj != nil {
 		if t, ok := obj.(reflect.Type); ok {
@@ -266,6 +754,7 @@ func RegisterType(iri quad.IRI, obj interface{}) {
 		tp := iriToType[full]
 		delete(typeToIRI, tp)
 		delete(iriToType, full)
+		delete(noWriteType, tp)
 		return
 	}
 	if _, exists := typeToIRI[rt]; exists {
@@ -276,16 +765,93 @@ func RegisterType(iri quad.IRI, obj interface{}) {
 	}
 	typeToIRI[rt] = iri
 	iriToType[full] = rt
+	if !opts.WriteType {
+		noWriteType[rt] = struct{}{}
+	}
+}
+
+var (
+	implsMu      sync.RWMutex
+	ifaceToImpls = make(map[reflect.Type][]reflect.Type)
+)
+
+// RegisterImpl associates a list of Go types with an interface type.
+//
+// When a field or a top-level object of this interface type is loaded, the concrete type is selected
+// from the list based on the node's type IRI. All types must be registered with RegisterType first.
+// Without this association, all registered types that implement the interface are considered.
+func RegisterImpl(iface reflect.Type, impls ...interface{}) {
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Errorf("expected interface type, got %v", iface))
+	}
+	arr := make([]reflect.Type, 0, len(impls))
+	typesMu.RLock()
+	for _, obj := range impls {
+		rt, ok := obj.(reflect.Type)
+		if !ok {
+			rt = reflect.TypeOf(obj)
+		}
+		if rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if _, ok := typeToIRI[rt]; !ok {
+			typesMu.RUnlock()
+			panic(fmt.Errorf("type %v is not registered", rt))
+		} else if !rt.Implements(iface) && !reflect.PtrTo(rt).Implements(iface) {
+			typesMu.RUnlock()
+			panic(fmt.Errorf("type %v does not implement %v", rt, iface))
+		}
+		arr = append(arr, rt)
+	}
+	typesMu.RUnlock()
+	implsMu.Lock()
+	defer implsMu.Unlock()
+	if len(arr) == 0 {
+		delete(ifaceToImpls, iface)
+		return
+	}
+	ifaceToImpls[iface] = arr
+}
+
+// implsFor returns all registered types that can be loaded to a given interface type.
+func implsFor(iface reflect.Type) []reflect.Type {
+	implsMu.RLock()
+	impls, ok := ifaceToImpls[iface]
+	implsMu.RUnlock()
+	if ok {
+		return impls
+	}
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	for rt := range typeToIRI {
+		if rt.Implements(iface) || reflect.PtrTo(rt).Implements(iface) {
+			impls = append(impls, rt)
+		}
+	}
+	sort.Slice(impls, func(i, j int) bool {
+		return typeToIRI[impls[i]] < typeToIRI[impls[j]]
+	})
+	return impls
+}
+
+// TypeForIRI returns a Go type registered for a given IRI with RegisterType.
+func TypeForIRI(iri quad.IRI) (reflect.Type, bool) {
+	typesMu.RLock()
+	rt, ok := iriToType[iri.Full()]
+	typesMu.RUnlock()
+	return rt, ok
 }
 
-func (c *Config) makePathForType(rt reflect.Type, tagPref string, rootOnly bool) (*path.Path, error) {
+// makePathForType builds a path that matches objects of a given type and tags their fields.
+// If allOpt is set, required fields are treated as optional.
+func (c *Config) makePathForType(rt reflect.Type, tagPref string, rootOnly, allOpt bool) (*path.Path, error) {
 	for rt.Kind() == reflect.Ptr {
 		rt = rt.Elem()
 	}
 	if rt.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("expected struct, got %v", rt)
 	}
-	if tagPref != "" {
+	if tagPref != "" && !allOpt {
 		c.pathForTypeMu.RLock()
 		m := c.pathForType
 		if rootOnly {
@@ -299,6 +865,9 @@ func (c *Config) makePathForType(rt reflect.Type, tagPref string, rootOnly bool)
 	}
 
 	p := path.StartMorphism()
+	if len(c.RestrictLabels) != 0 {
+		p = p.LabelContext(c.RestrictLabels)
+	}
 	typesMu.RLock()
 	iri := typeToIRI[rt]
 	typesMu.RUnlock()
@@ -308,7 +877,7 @@ func (c *Config) makePathForType(rt reflect.Type, tagPref string, rootOnly bool)
 	for i := 0; i < rt.NumField(); i++ {
 		f := rt.Field(i)
 		if f.Anonymous {
-			pa, err := c.makePathForType(f.Type, tagPref+f.Name+".", rootOnly)
+			pa, err := c.makePathForType(f.Type, tagPref+f.Name+".", rootOnly, allOpt)
 			if err != nil {
 				return nil, err
 			}
@@ -330,7 +899,7 @@ func (c *Config) makePathForType(rt reflect.Type, tagPref string, rootOnly bool)
 			return nil, err
 		}
 		switch rule := rule.(type) {
-		case idRule:
+		case idRule, mapRule:
 			p = p.Tag(tagPref + name)
 		case constraintRule:
 			var nodes []quad.Value
@@ -344,7 +913,12 @@ func (c *Config) makePathForType(rt reflect.Type, tagPref string, rootOnly bool)
 			}
 		case saveRule:
 			tag := tagPref + name
-			if rule.Opt {
+			if rule.Count {
+				// links are counted for the tagged node on load
+				p = p.Tag(tag)
+				continue
+			}
+			if rule.Opt || allOpt {
 				if !rootOnly {
 					if rule.Rev {
 						p = p.SaveOptionalReverse(rule.Pred, tag)
@@ -367,7 +941,7 @@ func (c *Config) makePathForType(rt reflect.Type, tagPref string, rootOnly bool)
 			}
 		}
 	}
-	if tagPref == "" {
+	if tagPref == "" || allOpt {
 		return p, nil
 	}
 
@@ -393,7 +967,78 @@ func (c *Config) makePathForType(rt reflect.Type, tagPref string, rootOnly bool)
 
 // PathForType builds a path (morphism) for a given Go type.
 func (c *Config) PathForType(rt reflect.Type) (*path.Path, error) {
-	return c.makePathForType(rt, "", false)
+	return c.makePathForType(rt, "", false, false)
+}
+
+// PredicatesForType returns a sorted list of predicates that are used in queries for a given Go type.
+// It includes the type predicate if the type is registered.
+func (c *Config) PredicatesForType(rt reflect.Type) ([]quad.IRI, error) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %v", rt)
+	}
+	rules, err := c.rulesFor(rt)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[quad.IRI]struct{})
+	typesMu.RLock()
+	_, registered := typeToIRI[rt]
+	typesMu.RUnlock()
+	if registered {
+		seen[c.iri(iriType)] = struct{}{}
+	}
+	for _, r := range rules {
+		switch r := r.(type) {
+		case saveRule:
+			seen[r.Pred] = struct{}{}
+		case constraintRule:
+			seen[r.Pred] = struct{}{}
+		}
+	}
+	out := make([]quad.IRI, 0, len(seen))
+	for p := range seen {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out, nil
+}
+
+// EnsureIndexes creates indexes in the quad store for all predicates used in queries for types of given
+// objects. If no objects are passed, predicates of all registered types are used.
+//
+// It does nothing if the quad store does not implement graph.Indexer.
+func (c *Config) EnsureIndexes(ctx context.Context, qs graph.QuadStore, types ...interface{}) error {
+	var rts []reflect.Type
+	if len(types) == 0 {
+		typesMu.RLock()
+		for rt := range typeToIRI {
+			rts = append(rts, rt)
+		}
+		typesMu.RUnlock()
+	} else {
+		for _, o := range types {
+			rts = append(rts, reflect.TypeOf(o))
+		}
+	}
+	seen := make(map[quad.IRI]struct{})
+	for _, rt := range rts {
+		preds, err := c.PredicatesForType(rt)
+		if err != nil {
+			return err
+		}
+		for _, p := range preds {
+			seen[p] = struct{}{}
+		}
+	}
+	preds := make([]quad.Value, 0, len(seen))
+	for p := range seen {
+		preds = append(preds, p)
+	}
+	sort.Sort(quad.ByValueString(preds))
+	return graph.EnsureIndexes(ctx, qs, preds)
 }
 
 func anonFieldType(fld reflect.StructField) (reflect.Type, bool) {
@@ -506,6 +1151,56 @@ func init() {
 	})
 }
 
+// setValue sets a loaded value to the destination, coercing strings if it's enabled in the config.
+func (c *Config) setValue(dst reflect.Value, src reflect.Value) error {
+	if c.CoerceStrings {
+		if s, ok := src.Interface().(quad.String); ok {
+			v, ok, err := coerceString(dst.Type(), string(s))
+			if err != nil {
+				return err
+			} else if ok {
+				src = v
+			}
+		}
+	}
+	return DefaultConverter.SetValue(dst, src)
+}
+
+// coerceString parses a string as a bool or numeric value of a given type.
+// Pointer and slice types are dereferenced; the value of the element type is returned.
+// It returns false if the type is not a bool or numeric.
+func coerceString(rt reflect.Type, s string) (reflect.Value, bool, error) {
+	for rt.Kind() == reflect.Ptr || rt.Kind() == reflect.Slice {
+		rt = rt.Elem()
+	}
+	v := reflect.New(rt).Elem()
+	var err error
+	switch rt.Kind() {
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, rt.Bits())
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, rt.Bits())
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, rt.Bits())
+		v.SetFloat(f)
+	default:
+		return reflect.Value{}, false, nil
+	}
+	if err != nil {
+		return reflect.Value{}, false, fmt.Errorf("cannot parse %q as %v", s, rt)
+	}
+	return v, true, nil
+}
+
 // IsNotFound check if error is related to a missing object (either because of wrong ID or because of type constrains).
 func IsNotFound(err error) bool {
 	return err == errNotFound || err == errRequiredFieldIsMissing
@@ -537,7 +1232,9 @@ func (c *Config) loadToValue(ctx context.Context, qs graph.QuadStore, dst reflec
 		}
 		fields = nfields
 	}
-	if depth != 0 { // do not check required fields if depth limit is reached
+	lopt, _ := ctx.Value(loadOptsCtxKey{}).(LoadOptions)
+	// do not check required fields if depth limit is reached
+	if depth != 0 && !lopt.IgnoreMissingRequired {
 		for name, field := range fields {
 			if r, ok := field.(saveRule); ok && !r.Opt {
 				if vals := m[name]; len(vals) == 0 {
@@ -559,6 +1256,18 @@ func (c *Config) loadToValue(ctx context.Context, qs graph.QuadStore, dst reflec
 		}
 		df := dst.Field(i)
 		if f.Anonymous {
+			if df.Kind() == reflect.Ptr {
+				// allocate embedded struct only if any of its fields are present
+				if !hasValuesWithPrefix(m, tagPref+name+".") {
+					continue
+				}
+				if df.IsNil() {
+					if !df.CanSet() {
+						return fmt.Errorf("cannot allocate embedded field %s of unexported type", f.Name)
+					}
+					df.Set(reflect.New(df.Type().Elem()))
+				}
+			}
 			if err := c.loadToValue(ctx, qs, df, depth, m, tagPref+name+"."); err != nil {
 				return fmt.Errorf("load anonymous field %s failed: %v", f.Name, err)
 			}
@@ -567,6 +1276,11 @@ func (c *Config) loadToValue(ctx context.Context, qs graph.QuadStore, dst reflec
 		rules := fields[tagPref+name]
 		if rules == nil {
 			continue
+		} else if _, ok := rules.(idRule); ok {
+			// only the id field with the highest priority is loaded
+			if ids := fields.idFields(); len(ids) > 1 && ids[0] != tagPref+name {
+				continue
+			}
 		}
 		arr, ok := m[tagPref+name]
 		if !ok || len(arr) == 0 {
@@ -578,32 +1292,595 @@ func (c *Config) loadToValue(ctx context.Context, qs graph.QuadStore, dst reflec
 			native = native || isNative(ft)
 			ft = ft.Elem()
 		}
-		recursive := !native && ft.Kind() == reflect.Struct
-		for _, fv := range arr {
-			var sv reflect.Value
-			if recursive {
-				sv = reflect.New(ft).Elem()
-				sit := iterator.NewFixed()
-				sit.Add(fv)
-				err := c.loadIteratorToDepth(ctx, qs, sv, depth-1, sit)
-				if err == errRequiredFieldIsMissing {
-					continue
-				} else if err != nil {
-					return err
-				}
-			} else {
-				fv := qs.NameOf(fv)
-				if fv == nil {
-					continue
-				}
-				sv = reflect.ValueOf(fv)
+		fctx := withFieldPath(ctx, tagPref+name)
+		if r, ok := rules.(saveRule); ok && r.Count {
+			if depth == 0 {
+				continue
+			}
+			n, err := c.countLinks(ctx, qs, arr[0], r.Pred, r.Rev)
+			if err != nil {
+				return fmt.Errorf("field %s: %v", f.Name, err)
 			}
-			if err := DefaultConverter.SetValue(df, sv); err != nil {
+			if err = DefaultConverter.SetValue(df, reflect.ValueOf(n)); err != nil {
 				return fmt.Errorf("field %s: %v", f.Name, err)
 			}
+			continue
 		}
-	}
-	return nil
+		if r, ok := rules.(saveRule); ok && r.List {
+			items, err := c.listItems(ctx, qs, arr[0])
+			if err != nil {
+				return fmt.Errorf("field %s: %v", f.Name, err)
+			} else if len(items) == 0 {
+				// an empty list is different from a missing one
+				df.Set(reflect.MakeSlice(df.Type(), 0, 0))
+				continue
+			}
+			arr = items
+		}
+		if _, ok := rules.(mapRule); ok {
+			if depth == 0 {
+				continue
+			}
+			if err := c.loadMapField(fctx, qs, df, arr[0], depth, fields); err != nil {
+				return fmt.Errorf("field %s: %v", f.Name, err)
+			}
+			continue
+		}
+		recursive := !native && ft.Kind() == reflect.Struct
+		exact := ft == quadValueType
+		idOnly := false
+		if r, ok := rules.(saveRule); ok {
+			idOnly = r.IDOnly
+		}
+		_, isID := rules.(idRule)
+		scalar := !isID && isScalar(f.Type)
+		if scalar && len(arr) > 1 {
+			if c.OnMultipleValues == MultipleValuesError {
+				if vals := distinctNames(qs, arr); len(vals) > 1 {
+					return ErrMultipleValues{Field: f.Name, Values: vals}
+				}
+			} else {
+				arr = sortedByName(qs, arr)
+			}
+		}
+		for _, fv := range arr {
+			sv, err := c.loadFieldValue(fctx, qs, ft, recursive, idOnly, fv, depth)
+			if isTypedLoadErr(err) {
+				// keep the error of a nested object as-is, it already has an id
+				return err
+			} else if err != nil {
+				return fmt.Errorf("field %s: %v", f.Name, err)
+			} else if !sv.IsValid() {
+				continue
+			}
+			if exact {
+				setQuadValue(df, sv)
+			} else {
+				if iri, ok := sv.Interface().(quad.IRI); ok && !isID {
+					sv = reflect.ValueOf(c.resolveIRI(iri))
+				}
+				if err := c.setValue(df, sv); err != nil {
+					cerr := ErrFieldConversion{Field: f.Name, Type: df.Type(), Err: err}
+					cerr.Value, _ = sv.Interface().(quad.Value)
+					return cerr
+				}
+			}
+			if scalar && c.OnMultipleValues == MultipleValuesFirstWins {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+var quadValueType = reflect.TypeOf((*quad.Value)(nil)).Elem()
+
+// setQuadValue sets a quad value to a field of quad.Value type, a pointer to it or a slice of it.
+func setQuadValue(dst reflect.Value, v reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Slice:
+		dst.Set(reflect.Append(dst, v.Convert(quadValueType)))
+	case reflect.Ptr:
+		p := reflect.New(dst.Type().Elem())
+		setQuadValue(p.Elem(), v)
+		dst.Set(p)
+	default:
+		dst.Set(v.Convert(quadValueType))
+	}
+}
+
+// isScalar checks if a field of a given type can hold only a single value.
+func isScalar(rt reflect.Type) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt.Kind() != reflect.Slice || isNative(rt)
+}
+
+// sortedByName returns a copy of a list of quad store values, sorted in canonical order of their names.
+func sortedByName(qs graph.QuadStore, arr []graph.Value) []graph.Value {
+	type named struct {
+		v    graph.Value
+		name string
+	}
+	vals := make([]named, 0, len(arr))
+	for _, v := range arr {
+		vals = append(vals, named{v: v, name: quad.StringOf(qs.NameOf(v))})
+	}
+	sort.SliceStable(vals, func(i, j int) bool { return vals[i].name < vals[j].name })
+	out := make([]graph.Value, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, v.v)
+	}
+	return out
+}
+
+// distinctNames returns distinct values for a list of quad store values.
+func distinctNames(qs graph.QuadStore, arr []graph.Value) []quad.Value {
+	var (
+		out  []quad.Value
+		seen = make(map[string]struct{}, len(arr))
+	)
+	for _, v := range arr {
+		nv := qs.NameOf(v)
+		if isNilValue(nv) {
+			continue
+		}
+		key := quad.StringOf(nv)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, nv)
+	}
+	return out
+}
+
+// mergeTags adds tagged values of a path to the values of an object, skipping duplicates.
+func mergeTags(mo map[string][]graph.Value, mp map[string]graph.Value) {
+	// TODO(dennwc): replace with more efficient
+	for k, v := range mp {
+		if sl, ok := mo[k]; !ok {
+			mo[k] = []graph.Value{v}
+		} else if len(sl) == 1 {
+			if !keysEqual(sl[0], v) {
+				mo[k] = append(sl, v)
+			}
+		} else {
+			found := false
+			for _, sv := range sl {
+				if keysEqual(sv, v) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				mo[k] = append(sl, v)
+			}
+		}
+	}
+}
+
+// collectPaths adds tagged values of all alternative paths of the current result to the values of an object.
+func collectPaths(ctx context.Context, it graph.Iterator, mo map[string][]graph.Value) error {
+	for it.NextPath(ctx) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		mp := make(map[string]graph.Value)
+		it.TagResults(mp)
+		if len(mp) == 0 {
+			continue
+		}
+		mergeTags(mo, mp)
+	}
+	return nil
+}
+
+// truncCtxKey is a context key for a truncatedFields collector. It is set only if Config.StrictDepth is enabled.
+type truncCtxKey struct{}
+
+// fieldPathCtxKey is a context key for a path of the field that is being loaded.
+type fieldPathCtxKey struct{}
+
+// truncatedFields collects paths of fields with nested objects that were truncated by the depth limit.
+type truncatedFields map[string]struct{}
+
+func (t truncatedFields) err() error {
+	if len(t) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(t))
+	for f := range t {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return ErrDepthTruncated{Fields: fields}
+}
+
+// withFieldPath adds a field name to the path of the field being loaded.
+// The path is tracked only if truncated fields are collected.
+func withFieldPath(ctx context.Context, name string) context.Context {
+	if _, ok := ctx.Value(truncCtxKey{}).(truncatedFields); !ok {
+		return ctx
+	}
+	if pref, _ := ctx.Value(fieldPathCtxKey{}).(string); pref != "" {
+		name = pref + "." + name
+	}
+	return context.WithValue(ctx, fieldPathCtxKey{}, name)
+}
+
+// checkTruncated records the current field as truncated if a nested object of a given type
+// was loaded at the last level of depth and has fields other than the id.
+func (c *Config) checkTruncated(ctx context.Context, rt reflect.Type, depth int) {
+	if depth != 1 {
+		return
+	}
+	tr, ok := ctx.Value(truncCtxKey{}).(truncatedFields)
+	if !ok {
+		return
+	}
+	rules, err := c.rulesFor(rt)
+	if err != nil {
+		return
+	}
+	for _, r := range rules {
+		switch r.(type) {
+		case saveRule, mapRule:
+			name, _ := ctx.Value(fieldPathCtxKey{}).(string)
+			tr[name] = struct{}{}
+			return
+		}
+	}
+}
+
+// loadFieldValue loads a single value of a field with a given (dereferenced) type.
+// It returns an invalid reflect.Value if the value should be skipped.
+func (c *Config) loadFieldValue(ctx context.Context, qs graph.QuadStore, ft reflect.Type, recursive, idOnly bool, fv graph.Value, depth int) (reflect.Value, error) {
+	var sv reflect.Value
+	if ft.Kind() == reflect.Interface && ft != quadValueType && !idOnly {
+		rt, err := c.typeForNode(ctx, qs, fv, ft)
+		if err != nil {
+			return reflect.Value{}, err
+		} else if rt != nil {
+			sv = reflect.New(rt)
+			sit := iterator.NewFixed()
+			sit.Add(fv)
+			err := c.loadIteratorToDepth(ctx, qs, sv.Elem(), depth-1, sit)
+			if err == errRequiredFieldIsMissing {
+				return reflect.Value{}, nil
+			} else if err != nil {
+				return reflect.Value{}, err
+			}
+			c.checkTruncated(ctx, rt, depth)
+			if !rt.Implements(ft) {
+				return sv, nil
+			}
+			return sv.Elem(), nil
+		}
+		// not an object of a registered type - load as a value
+	}
+	if recursive && idOnly {
+		id := qs.NameOf(fv)
+		if isNilValue(id) {
+			return reflect.Value{}, nil
+		}
+		sv = reflect.New(ft).Elem()
+		if err := c.setID(sv, id); err != nil {
+			return reflect.Value{}, err
+		}
+	} else if recursive {
+		sv = reflect.New(ft).Elem()
+		sit := iterator.NewFixed()
+		sit.Add(fv)
+		err := c.loadIteratorToDepth(ctx, qs, sv, depth-1, sit)
+		if err == errRequiredFieldIsMissing {
+			return reflect.Value{}, nil
+		} else if err != nil {
+			return reflect.Value{}, err
+		}
+		c.checkTruncated(ctx, ft, depth)
+	} else {
+		fv := qs.NameOf(fv)
+		if isNilValue(fv) {
+			return reflect.Value{}, nil
+		}
+		sv = reflect.ValueOf(fv)
+	}
+	return sv, nil
+}
+
+// prefetchNames resolves all values of an object at once, if quad store supports batch lookups.
+// Backends cache resolved values, thus following NameOf calls will not hit the database.
+func prefetchNames(ctx context.Context, qs graph.QuadStore, m map[string][]graph.Value) error {
+	if _, ok := graph.Unwrap(qs).(graph.BatchValuer); !ok {
+		return nil
+	}
+	var vals []graph.Value
+	for _, sl := range m {
+		vals = append(vals, sl...)
+	}
+	if len(vals) < 2 {
+		return nil
+	}
+	_, err := graph.ValuesOf(ctx, qs, vals)
+	return err
+}
+
+// typeForNode finds a registered Go type of a node that can be assigned to a given interface type.
+// Either the type itself or a pointer to it must implement the interface. It returns nil if there is no such type.
+// If the interface was registered with RegisterImpl, only associated types are considered.
+func (c *Config) typeForNode(ctx context.Context, qs graph.QuadStore, node graph.Value, iface reflect.Type) (reflect.Type, error) {
+	typ := c.valueOf(qs, c.iri(iriType))
+	if typ == nil {
+		return nil, nil
+	}
+	impls := implsFor(iface)
+	it := iterator.NewAnd(qs,
+		qs.QuadIterator(quad.Subject, node),
+		qs.QuadIterator(quad.Predicate, typ),
+	)
+	defer it.Close()
+	for it.Next(ctx) {
+		q := it.Result()
+		if !c.quadInLabels(qs, q) {
+			continue
+		}
+		iri, ok := qs.NameOf(qs.QuadDirection(q, quad.Object)).(quad.IRI)
+		if !ok {
+			continue
+		}
+		rt, ok := TypeForIRI(iri)
+		if !ok {
+			continue
+		}
+		for _, impl := range impls {
+			if impl == rt {
+				return rt, nil
+			}
+		}
+	}
+	return nil, it.Err()
+}
+
+// countLinks returns the number of quads with a given predicate that have a node as a subject,
+// or as an object if rev is set.
+func (c *Config) countLinks(ctx context.Context, qs graph.QuadStore, node graph.Value, pred quad.IRI, rev bool) (int64, error) {
+	pv := c.valueOf(qs, pred)
+	if pv == nil {
+		return 0, nil
+	}
+	dir := quad.Subject
+	if rev {
+		dir = quad.Object
+	}
+	it := iterator.NewAnd(qs,
+		qs.QuadIterator(dir, node),
+		qs.QuadIterator(quad.Predicate, pv),
+	)
+	defer it.Close()
+	var n int64
+	for it.Next(ctx) {
+		if c.quadInLabels(qs, it.Result()) {
+			n++
+		}
+	}
+	return n, it.Err()
+}
+
+// sameIRI checks if a value is a given IRI, regardless of the IRI form.
+func (c *Config) sameIRI(v quad.Value, iri quad.IRI) bool {
+	vi, ok := v.(quad.IRI)
+	return ok && (vi == iri || c.fullIRI(vi) == c.fullIRI(iri))
+}
+
+// listItems returns items of an RDF collection that starts at a given node, in order.
+func (c *Config) listItems(ctx context.Context, qs graph.QuadStore, node graph.Value) ([]graph.Value, error) {
+	var (
+		out  []graph.Value
+		seen = make(map[interface{}]struct{})
+	)
+	for !c.sameIRI(qs.NameOf(node), rdf.Nil) {
+		if _, ok := seen[node.Key()]; ok {
+			return nil, fmt.Errorf("list node %v is visited twice", qs.NameOf(node))
+		}
+		seen[node.Key()] = struct{}{}
+		var first, rest graph.Value
+		err := graph.Iterate(ctx, qs.QuadIterator(quad.Subject, node)).Each(func(q graph.Value) {
+			if !c.quadInLabels(qs, q) {
+				return
+			}
+			switch p := qs.NameOf(qs.QuadDirection(q, quad.Predicate)); {
+			case first == nil && c.sameIRI(p, rdf.First):
+				first = qs.QuadDirection(q, quad.Object)
+			case rest == nil && c.sameIRI(p, rdf.Rest):
+				rest = qs.QuadDirection(q, quad.Object)
+			}
+		})
+		if err != nil {
+			return nil, err
+		} else if first == nil || rest == nil {
+			return nil, fmt.Errorf("list node %v has no rdf:first or rdf:rest", qs.NameOf(node))
+		}
+		out = append(out, first)
+		node = rest
+	}
+	return out, nil
+}
+
+// loadMapField loads values of all predicates of a node that are not mapped to other fields to a map field.
+// Map keys are predicates, and values are loaded the same way as for regular fields.
+func (c *Config) loadMapField(ctx context.Context, qs graph.QuadStore, df reflect.Value, node graph.Value, depth int, fields fieldRules) error {
+	known := map[quad.Value]struct{}{
+		c.iri(iriType): {},
+	}
+	for _, r := range fields {
+		switch r := r.(type) {
+		case saveRule:
+			if !r.Rev {
+				known[r.Pred] = struct{}{}
+			}
+		case constraintRule:
+			if !r.Rev {
+				known[r.Pred] = struct{}{}
+			}
+		}
+	}
+	mt := df.Type()
+	vt := mt.Elem()
+	ft := vt
+	native := isNative(ft)
+	for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+		native = native || isNative(ft)
+		ft = ft.Elem()
+	}
+	recursive := !native && ft.Kind() == reflect.Struct
+
+	it := qs.QuadIterator(quad.Subject, node)
+	defer it.Close()
+	for it.Next(ctx) {
+		q := it.Result()
+		pred := qs.NameOf(qs.QuadDirection(q, quad.Predicate))
+		if _, ok := known[pred]; ok || isNilValue(pred) {
+			continue
+		}
+		if !c.quadInLabels(qs, q) {
+			continue
+		}
+		sv, err := c.loadFieldValue(ctx, qs, ft, recursive, false, qs.QuadDirection(q, quad.Object), depth)
+		if err != nil {
+			return err
+		} else if !sv.IsValid() {
+			continue
+		}
+		key := reflect.New(mt.Key()).Elem()
+		if err = DefaultConverter.SetValue(key, reflect.ValueOf(pred)); err != nil {
+			return fmt.Errorf("map key: %v", err)
+		}
+		if df.IsNil() {
+			df.Set(reflect.MakeMap(mt))
+		}
+		val := reflect.New(vt).Elem()
+		if cur := df.MapIndex(key); cur.IsValid() {
+			val.Set(cur)
+		}
+		if err = c.setValue(val, sv); err != nil {
+			return err
+		}
+		df.SetMapIndex(key, val)
+	}
+	return it.Err()
+}
+
+// ifpKey is a value of an inverse-functional property field.
+type ifpKey struct {
+	field string
+	value interface{}
+}
+
+// ifpFields returns names of all inverse-functional property fields, in a stable order.
+func (f fieldRules) ifpFields() []string {
+	var out []string
+	for name, r := range f {
+		if r, ok := r.(saveRule); ok && r.IFP {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// mergeValues merges fields of src struct into dst struct. Slices are unioned,
+// while other fields are only set if they are empty in dst.
+func mergeValues(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		df, sf := dst.Field(i), src.Field(i)
+		if !df.CanSet() {
+			continue
+		}
+		switch {
+		case dst.Type().Field(i).Anonymous && df.Kind() == reflect.Struct:
+			mergeValues(df, sf)
+		case dst.Type().Field(i).Anonymous && df.Kind() == reflect.Ptr && !df.IsNil() && !sf.IsNil():
+			mergeValues(df.Elem(), sf.Elem())
+		case df.Kind() == reflect.Slice:
+			for j := 0; j < sf.Len(); j++ {
+				v, found := sf.Index(j), false
+				for k := 0; k < df.Len(); k++ {
+					if reflect.DeepEqual(df.Index(k).Interface(), v.Interface()) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					df.Set(reflect.Append(df, v))
+				}
+			}
+		case df.Kind() == reflect.Map:
+			if df.IsNil() {
+				df.Set(sf)
+			}
+		case df.Type().Comparable() && isZero(df):
+			df.Set(sf)
+		}
+	}
+}
+
+func hasValuesWithPrefix(m map[string][]graph.Value, pref string) bool {
+	for name, vals := range m {
+		if len(vals) != 0 && strings.HasPrefix(name, pref) {
+			return true
+		}
+	}
+	return false
+}
+
+// idField returns the @id field of a given struct value.
+// Embedded struct pointers on the way to the field are allocated.
+func (c *Config) idField(dst reflect.Value) (reflect.Value, error) {
+	rules, err := c.rulesFor(dst.Type())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if names := rules.idFields(); len(names) != 0 {
+		name := names[0]
+		// id may be defined in an anonymous field
+		fv := dst
+		for _, fname := range strings.Split(name, ".") {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.FieldByName(fname)
+		}
+		return fv, nil
+	}
+	return reflect.Value{}, fmt.Errorf("%v has no @id field", dst.Type())
+}
+
+// setID sets the @id field of a given struct value.
+func (c *Config) setID(dst reflect.Value, id quad.Value) error {
+	fv, err := c.idField(dst)
+	if err != nil {
+		return err
+	}
+	return DefaultConverter.SetValue(fv, reflect.ValueOf(id))
+}
+
+// isNilValue checks if the value is nil, or if it's a typed nil returned by some quad stores.
+func isNilValue(v quad.Value) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
 }
 
 func isNative(rt reflect.Type) bool { // TODO(dennwc): replace
@@ -627,7 +1904,13 @@ func keysEqual(v1, v2 graph.Value) bool {
 }
 
 // LoadTo will load a sub-graph of objects starting from ids (or from any nodes, if empty)
-// to a destination Go object. Destination can be a struct, slice or channel.
+// to a destination Go object. Destination can be a struct, slice, map or channel.
+//
+// Map destinations are populated with objects keyed by their "@id" field,
+// thus the element type of the map must have one:
+//
+//	var people map[quad.IRI]*Person
+//	err := LoadTo(ctx, qs, &people)
 //
 // Mapping to quads is done via Go struct tag "quad" or "json" as a fallback.
 //
@@ -640,6 +1923,14 @@ func keysEqual(v1, v2 graph.Value) bool {
 // Field with an "@id" tag is omitted, but in case of Go->quads mapping new ID will be generated
 // using GenerateID callback, which can be changed to provide a custom mappings.
 //
+// Multiple "@id" fields can be used as candidates with a given priority (lower value wins).
+// The first non-zero candidate is used as an ID on write, and only the first candidate is set on load:
+//
+//	type Item struct{
+//		IRI quad.IRI `quad:"@id,priority=1"`
+//		Key string   `quad:"@id,priority=2"`
+// 	}
+//
 // All other tags are interpreted as a predicate name for a specific field:
 //
 //	type Person struct{
@@ -682,12 +1973,48 @@ func keysEqual(v1, v2 graph.Value) bool {
 // All fields in structs are interpreted as required (except slices), thus struct will not be
 // loaded if one of fields is missing. An "optional" tag can be specified to relax this requirement.
 // Also, "required" can be specified for slices to alter default value.
+// An "idonly" tag on a struct field will only load the @id of the linked object instead of loading it recursively.
+// Embedded struct pointers are only allocated if any of the embedded fields are present, thus
+// they can be used for groups of optional fields.
+//
+// Zero values of fields are not written, except for booleans: bool fields are written as quad.Bool,
+// including false values. A nil *bool is not written, while a pointer to false is.
+//
+// Slices of structs or pointers to structs are linked to one node per element; nil elements are not written.
+// When loading, a new value is allocated for each linked node. Empty slices are loaded as nil.
+//
+// An "ifp" tag marks a field as inverse-functional property: when loading to a slice, map or channel,
+// nodes sharing a value of the same field are merged into a single object. Merging is transitive: nodes
+// are merged if they are linked through other nodes with shared values. The first loaded node wins
+// for conflicting non-slice fields (including @id), while slices are unioned. Channels receive
+// objects only after all nodes are loaded in this case.
+//
+// An integer field with a "count" option is set to the number of linked nodes instead of loading them,
+// for example `quad:"follows < *,count"` counts incoming links. Count fields are never written.
+//
+// A slice field with a "list" option is stored as an ordered RDF collection (rdf:first/rdf:rest linked list),
+// thus the order of elements is preserved, unlike for repeated predicates. An empty slice is stored as rdf:nil
+// and is loaded as an empty non-nil slice, while a nil slice is not written.
+//
+// A map field with `quad:"*"` tag and IRI or string keys collects all predicates of a node that are not
+// mapped to other fields. Map values can be of any type supported for regular fields, including structs
+// and slices of structs, which are loaded recursively and respect the depth limit.
+//
+// Interface fields can hold objects of types registered with RegisterType. When loading, the type of the
+// object is determined by its rdf:type, and the first registered type that implements the interface is used.
+// Nodes without a matching type are loaded as quad values.
+//
+// Fields of quad.Value type (or slices of it) receive values exactly as they are stored, be it an IRI,
+// a BNode or a literal. Neither the value converter nor BaseIRI resolution is applied to them.
+//
+// AfterLoad method is called on each loaded object that implements AfterLoader.
 //
 //	type Person struct{
 //		ID quad.IRI `json:"@id"`
 //		Name string `json:"name"` // required field
 //		ThirdName string `quad:"thirdName,optional"` // can be empty
 //		FollowedBy []quad.IRI `quad:"follows"`
+//		Manager *Person `quad:"manager,optional,idonly"` // only Manager.ID is loaded
 // 	}
 func (c *Config) LoadTo(ctx context.Context, qs graph.QuadStore, dst interface{}, ids ...quad.Value) error {
 	return c.LoadToDepth(ctx, qs, dst, -1, ids...)
@@ -701,11 +2028,7 @@ func (c *Config) LoadToDepth(ctx context.Context, qs graph.QuadStore, dst interf
 	}
 	var it graph.Iterator
 	if len(ids) != 0 {
-		fixed := iterator.NewFixed()
-		for _, id := range ids {
-			fixed.Add(qs.ValueOf(id))
-		}
-		it = fixed
+		it = c.fixedValues(qs, ids...)
 	}
 	var rv reflect.Value
 	if v, ok := dst.(reflect.Value); ok {
@@ -716,6 +2039,235 @@ func (c *Config) LoadToDepth(ctx context.Context, qs graph.QuadStore, dst interf
 	return c.LoadIteratorToDepth(ctx, qs, rv, depth, it)
 }
 
+type asOfCtxKey struct{}
+
+// LoadToAsOf is the same as LoadTo, but loads only values that were valid at a given time.
+//
+// Quad labels are interpreted as the time since which the quad is valid. A label can be a quad.Time
+// or a string in RFC 3339 format. For each field of an object, only quads with the latest label
+// time that is not after asOf are considered; they supersede all quads with earlier times for the same
+// subject and predicate. Quads without a time label are ignored for such fields.
+//
+// Only value fields are filtered; @id, @type constraints and predicate maps are loaded as in LoadTo.
+func (c *Config) LoadToAsOf(ctx context.Context, qs graph.QuadStore, dst interface{}, asOf time.Time, ids ...quad.Value) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = context.WithValue(ctx, asOfCtxKey{}, asOf)
+	return c.LoadTo(ctx, qs, dst, ids...)
+}
+
+type loadOptsCtxKey struct{}
+
+// LoadOptions controls how objects are loaded by LoadToOpts.
+type LoadOptions struct {
+	// IgnoreMissingRequired allows to load objects with missing required fields.
+	// Such fields are left with zero values. Applies to nested objects as well.
+	IgnoreMissingRequired bool
+	// PartialResults allows to load objects that failed to load, for example, because of a field
+	// of a wrong type. Fields that were loaded before the error are kept, and the error is ignored.
+	PartialResults bool
+}
+
+// LoadToOpts is the same as LoadTo, but allows to relax constraints on loaded objects.
+// It is useful to inspect objects that were written only partially.
+func (c *Config) LoadToOpts(ctx context.Context, qs graph.QuadStore, dst interface{}, opts LoadOptions, ids ...quad.Value) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = context.WithValue(ctx, loadOptsCtxKey{}, opts)
+	return c.LoadTo(ctx, qs, dst, ids...)
+}
+
+// labelTime returns a time encoded in the label.
+func labelTime(v quad.Value) (time.Time, bool) {
+	var s string
+	switch v := v.(type) {
+	case quad.Time:
+		return time.Time(v), true
+	case quad.String:
+		s = string(v)
+	case quad.IRI:
+		s = string(v)
+	case quad.TypedString:
+		s = string(v.Value)
+	default:
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
+
+// filterAsOf removes field values of the node that are superseded or not yet valid at a given time.
+// See LoadToAsOf for details.
+func (c *Config) filterAsOf(ctx context.Context, qs graph.QuadStore, node graph.Value, fields fieldRules, m map[string][]graph.Value, asOf time.Time) error {
+	for name, r := range fields {
+		sr, ok := r.(saveRule)
+		if !ok || sr.Count || len(m[name]) == 0 {
+			continue
+		}
+		dir, other := quad.Subject, quad.Object
+		if sr.Rev {
+			dir, other = other, dir
+		}
+		var (
+			last  time.Time
+			valid []graph.Value
+		)
+		if pred := qs.ValueOf(sr.Pred); pred != nil {
+			it := iterator.NewAnd(qs, qs.QuadIterator(dir, node), qs.QuadIterator(quad.Predicate, pred))
+			for it.Next(ctx) {
+				q := it.Result()
+				lv := qs.QuadDirection(q, quad.Label)
+				if lv == nil {
+					continue
+				}
+				t, ok := labelTime(qs.NameOf(lv))
+				if !ok || t.After(asOf) || t.Before(last) {
+					continue
+				} else if t.After(last) {
+					last, valid = t, valid[:0]
+				}
+				valid = append(valid, qs.QuadDirection(q, other))
+			}
+			err := it.Err()
+			it.Close()
+			if err != nil {
+				return err
+			}
+		}
+		var out []graph.Value
+		for _, v := range m[name] {
+			for _, v2 := range valid {
+				if keysEqual(v, v2) {
+					out = append(out, v)
+					break
+				}
+			}
+		}
+		if len(out) == 0 {
+			delete(m, name)
+		} else {
+			m[name] = out
+		}
+	}
+	return nil
+}
+
+// LoadStats describes the result of a load.
+type LoadStats struct {
+	// Loaded is the number of objects loaded to the destination.
+	Loaded int
+	// Skipped is the number of nodes that were not loaded because of missing required fields or constraints.
+	// Nodes that do not exist in the quad store are not counted.
+	Skipped int
+}
+
+// statsCtxKey is a context key for a loadStats collector.
+type statsCtxKey struct{}
+
+// loadStats collects LoadStats of root objects.
+type loadStats struct {
+	LoadStats
+	seen map[interface{}]struct{}
+}
+
+func (s *loadStats) loaded() {
+	if s != nil {
+		s.Loaded++
+	}
+}
+
+// visit marks a node as processed.
+func (s *loadStats) visit(v graph.Value) {
+	if s != nil {
+		s.seen[graph.ToKey(v)] = struct{}{}
+	}
+}
+
+// skip records a node that was skipped.
+func (s *loadStats) skip(v graph.Value) {
+	if s == nil {
+		return
+	}
+	if _, ok := s.seen[graph.ToKey(v)]; !ok {
+		s.Skipped++
+		s.visit(v)
+	}
+}
+
+// LoadToStats is the same as LoadTo, but also reports how many objects were loaded and how many were skipped.
+//
+// When loading to a slice, map or channel, objects with missing required fields are skipped. If ids are passed,
+// each existing node that was not loaded is reported as skipped, including nodes that do not match
+// the constraints of the type. Without ids, only nodes that matched the type constraints are considered.
+func (c *Config) LoadToStats(ctx context.Context, qs graph.QuadStore, dst interface{}, ids ...quad.Value) (LoadStats, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	st := &loadStats{seen: make(map[interface{}]struct{})}
+	ctx = context.WithValue(ctx, statsCtxKey{}, st)
+	if err := c.LoadTo(ctx, qs, dst, ids...); err != nil {
+		return st.LoadStats, err
+	}
+	for _, id := range ids {
+		if v := c.valueOf(qs, id); v != nil {
+			st.skip(v)
+		}
+	}
+	return st.LoadStats, nil
+}
+
+// ExistsMany checks which of the given ids refer to objects that satisfy constraints of the type of dst,
+// which must be a struct or a pointer to struct. Only the type, constraints and required fields are checked,
+// objects are not loaded. The result contains an entry for each id.
+//
+// All ids are checked in a single pass of one iterator, which is cheaper than checking them one by one.
+func (c *Config) ExistsMany(ctx context.Context, qs graph.QuadStore, dst interface{}, ids []quad.Value) (map[quad.Value]bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rt := reflect.TypeOf(dst)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct or a pointer to struct, got: %T", dst)
+	}
+	out := make(map[quad.Value]bool, len(ids))
+	byKey := make(map[interface{}][]quad.Value, len(ids))
+	root := iterator.NewFixed()
+	for _, id := range ids {
+		out[id] = false
+		v := c.valueOf(qs, id)
+		if v == nil {
+			continue
+		}
+		k := graph.ToKey(v)
+		if _, ok := byKey[k]; !ok {
+			root.Add(v)
+		}
+		byKey[k] = append(byKey[k], id)
+	}
+	if len(byKey) == 0 {
+		return out, nil
+	}
+	it, err := c.iteratorForType(qs, root, rt, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	for it.Next(ctx) {
+		for _, id := range byKey[graph.ToKey(it.Result())] {
+			out[id] = true
+		}
+	}
+	if err = it.Err(); err != nil {
+		return nil, err
+	}
+	return out, ctx.Err()
+}
+
 // LoadPathTo is the same as LoadTo, but starts loading objects from a given path.
 func (c *Config) LoadPathTo(ctx context.Context, qs graph.QuadStore, dst interface{}, p *path.Path) error {
 	return c.LoadIteratorTo(ctx, qs, reflect.ValueOf(dst), p.BuildIterator())
@@ -738,10 +2290,180 @@ func (c *Config) LoadIteratorToDepth(ctx context.Context, qs graph.QuadStore, ds
 		// 0 depth means "current level only" for user, but it's easier to make depth=0 a stop condition
 		depth++
 	}
-	return c.loadIteratorToDepth(ctx, qs, dst, depth, list)
+	if !c.StrictDepth || depth < 0 {
+		return c.loadIteratorToDepth(ctx, qs, dst, depth, list)
+	}
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	tr := make(truncatedFields)
+	ctx = context.WithValue(ctx, truncCtxKey{}, tr)
+	if err := c.loadIteratorToDepth(ctx, qs, dst, depth, list); err != nil {
+		return err
+	}
+	return tr.err()
+}
+
+// LoadPage loads a window of objects to a destination slice, skipping the first skip objects and loading at most
+// limit objects. Zero or negative limit means no limit. It returns the total number of matching root objects.
+//
+// Root objects outside of the window are counted by the same iterator, but are not loaded. Thus, the total may
+// include objects that are dropped while loading because of missing required fields in nested objects.
+// Types with inverse-functional properties are fully loaded before the window is applied.
+//
+// See LoadTo for the description of ids and struct tags.
+func (c *Config) LoadPage(ctx context.Context, qs graph.QuadStore, dst interface{}, skip, limit int, ids ...quad.Value) (int64, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return 0, fmt.Errorf("expected a pointer to slice, got: %T", dst)
+	}
+	rv = rv.Elem()
+	if skip < 0 {
+		skip = 0
+	}
+	var it graph.Iterator
+	if len(ids) != 0 {
+		it = c.fixedValues(qs, ids...)
+	}
+	fields, err := c.rulesFor(rv.Type().Elem())
+	if err != nil {
+		return 0, err
+	}
+	if len(fields.ifpFields()) != 0 {
+		// objects might be merged, so we can't tell the window boundaries until all objects are loaded
+		all := reflect.New(rv.Type()).Elem()
+		if err = c.loadIteratorToDepth(ctx, qs, all, -1, it); err != nil {
+			return 0, err
+		}
+		n := all.Len()
+		start, end := skip, n
+		if start > n {
+			start = n
+		}
+		if limit > 0 && start+limit < end {
+			end = start + limit
+		}
+		rv.Set(reflect.AppendSlice(rv, all.Slice(start, end)))
+		return int64(n), nil
+	}
+	page := &loadPage{skip: int64(skip), limit: int64(limit)}
+	if err = c.loadIteratorToDepthOpt(ctx, qs, rv, -1, it, loadOptions{page: page}); err != nil {
+		return 0, err
+	}
+	return page.total, nil
+}
+
+// loadPage tracks a window of root objects being loaded.
+type loadPage struct {
+	skip, limit int64
+	total       int64 // number of root objects seen so far
+}
+
+// next counts a root object and reports if it should be loaded.
+func (p *loadPage) next() bool {
+	n := p.total
+	p.total++
+	return n >= p.skip && (p.limit <= 0 || n < p.skip+p.limit)
+}
+
+// AfterLoader is an optional interface for objects that need to be post-processed after being loaded,
+// for example to validate them or to compute derived fields.
+type AfterLoader interface {
+	AfterLoad(c *Config) error
+}
+
+// BeforeWriter is an optional interface for objects that need to be validated or modified before being written.
+// Returning an error prevents the object from being written.
+type BeforeWriter interface {
+	BeforeWrite(c *Config) error
+}
+
+// beforeWrite calls BeforeWrite method of an object, if it's defined, and returns the object that should be written.
+// If the method is declared on a pointer receiver and the object is not addressable, it is called on a copy.
+func (c *Config) beforeWrite(rv reflect.Value) (reflect.Value, error) {
+	if !rv.CanAddr() && reflect.PtrTo(rv.Type()).Implements(reflect.TypeOf((*BeforeWriter)(nil)).Elem()) {
+		nv := reflect.New(rv.Type()).Elem()
+		nv.Set(rv)
+		rv = nv
+	}
+	pv := rv
+	if rv.CanAddr() {
+		pv = rv.Addr()
+	}
+	if h, ok := pv.Interface().(BeforeWriter); ok {
+		if err := h.BeforeWrite(c); err != nil {
+			return rv, err
+		}
+	}
+	return rv, nil
+}
+
+// afterLoad calls AfterLoad method of a loaded object, if it's defined.
+// The method can be declared on either value or pointer receiver.
+func (c *Config) afterLoad(rv reflect.Value) error {
+	if rv.CanAddr() {
+		rv = rv.Addr()
+	}
+	if h, ok := rv.Interface().(AfterLoader); ok {
+		return h.AfterLoad(c)
+	}
+	return nil
+}
+
+// Result is an object loaded by LoadToChan, or an error that occurred while loading it.
+type Result struct {
+	// Value is a loaded object of the requested type. It is nil if Err is set.
+	Value interface{}
+	// Err is an error that occurred while loading a single object, or a final error of the load.
+	Err error
+}
+
+// LoadToChan is similar to LoadTo with a channel destination, but sends objects wrapped into Result.
+//
+// Errors that occur when loading a specific object are sent to the channel, and loading continues
+// with the next object. An error that stops the load (for example an iterator failure) is sent as the last result.
+// The channel is closed when all objects are loaded.
+//
+// The type of objects is defined by typ, which can either be a reflect.Type or a value of that type.
+func (c *Config) LoadToChan(ctx context.Context, qs graph.QuadStore, out chan<- Result, typ interface{}, ids ...quad.Value) {
+	defer close(out)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rt, ok := typ.(reflect.Type)
+	if !ok {
+		rt = reflect.TypeOf(typ)
+	}
+	var it graph.Iterator
+	if len(ids) != 0 {
+		it = c.fixedValues(qs, ids...)
+	}
+	var err error
+	if rt == nil {
+		err = fmt.Errorf("nil object type")
+	} else {
+		dst := reflect.New(reflect.SliceOf(rt)).Elem()
+		err = c.loadIteratorToDepthOpt(ctx, qs, dst, -1, it, loadOptions{results: out})
+	}
+	if err != nil {
+		select {
+		case out <- Result{Err: err}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// loadOptions controls how root objects are loaded.
+type loadOptions struct {
+	page    *loadPage     // load only a window of objects
+	results chan<- Result // send objects and per-object errors to the channel instead of the destination slice
 }
 
 func (c *Config) loadIteratorToDepth(ctx context.Context, qs graph.QuadStore, dst reflect.Value, depth int, list graph.Iterator) error {
+	return c.loadIteratorToDepthOpt(ctx, qs, dst, depth, list, loadOptions{})
+}
+
+func (c *Config) loadIteratorToDepthOpt(ctx context.Context, qs graph.QuadStore, dst reflect.Value, depth int, list graph.Iterator, opt loadOptions) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -749,7 +2471,7 @@ func (c *Config) loadIteratorToDepth(ctx context.Context, qs graph.QuadStore, ds
 		dst = dst.Elem()
 	}
 	et := dst.Type()
-	slice, chanl := false, false
+	slice, chanl, mapt := false, false, false
 	if dst.Kind() == reflect.Slice {
 		et = et.Elem()
 		slice = true
@@ -757,23 +2479,114 @@ func (c *Config) loadIteratorToDepth(ctx context.Context, qs graph.QuadStore, ds
 		et = et.Elem()
 		chanl = true
 		defer dst.Close()
+	} else if dst.Kind() == reflect.Map {
+		et = et.Elem()
+		mapt = true
+	}
+	// map values can be pointers to structs
+	ptrElem := mapt && et.Kind() == reflect.Ptr
+	if ptrElem {
+		et = et.Elem()
+	}
+	if et.Kind() == reflect.Interface {
+		if mapt {
+			return fmt.Errorf("cannot load interface values to a map")
+		}
+		return c.loadIteratorToIface(ctx, qs, dst, et, depth, list, opt)
 	}
 	fields, err := c.rulesFor(et)
 	if err != nil {
 		return err
 	}
+	if mapt {
+		if _, err = c.idField(reflect.New(et).Elem()); err != nil {
+			return err
+		}
+		if dst.IsNil() {
+			if !dst.CanSet() {
+				return fmt.Errorf("cannot load to a nil map, pass a pointer instead")
+			}
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+	}
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 	rootOnly := depth == 0
-	it, err := c.iteratorForType(qs, list, et, rootOnly)
+	lopt, _ := ctx.Value(loadOptsCtxKey{}).(LoadOptions)
+	it, err := c.iteratorForType(qs, list, et, rootOnly, lopt.IgnoreMissingRequired)
 	if err != nil {
 		return err
 	}
 	defer it.Close()
 
+	// only root objects are counted
+	st, _ := ctx.Value(statsCtxKey{}).(*loadStats)
+	if st != nil {
+		ctx = context.WithValue(ctx, statsCtxKey{}, (*loadStats)(nil))
+	}
+	emit := func(cur reflect.Value) error {
+		if err := c.afterLoad(cur.Elem()); err != nil && opt.results != nil {
+			select {
+			case opt.results <- Result{Err: err}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else if err != nil {
+			return err
+		}
+		st.loaded()
+		if opt.results != nil {
+			select {
+			case opt.results <- Result{Value: cur.Elem().Interface()}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else if slice {
+			dst.Set(reflect.Append(dst, cur.Elem()))
+		} else if chanl {
+			dst.Send(cur.Elem())
+		} else if mapt {
+			id, err := c.idField(cur.Elem())
+			if err != nil {
+				return err
+			}
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err = DefaultConverter.SetValue(key, id); err != nil {
+				return fmt.Errorf("map key: %v", err)
+			}
+			if ptrElem {
+				dst.SetMapIndex(key, cur)
+			} else {
+				dst.SetMapIndex(key, cur.Elem())
+			}
+		}
+		return nil
+	}
+	// objects sharing inverse-functional property values are merged before being emitted
+	var (
+		ifp    []string
+		merged []reflect.Value // all loaded objects, in load order
+		parent []int           // union-find forest of merged objects; the root is the first object of a group
+		byIFP  map[ifpKey]int  // first object with a given field value
+	)
+	if slice || chanl || mapt {
+		ifp = fields.ifpFields()
+		if len(ifp) != 0 {
+			byIFP = make(map[ifpKey]int)
+		}
+	}
+	group := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
 	ctx = context.WithValue(ctx, fieldsCtxKey{}, fields)
 	for it.Next(ctx) {
 		select {
@@ -785,69 +2598,125 @@ func (c *Config) loadIteratorToDepth(ctx context.Context, qs graph.QuadStore, ds
 		it.TagResults(mp)
 		if len(mp) == 0 {
 			continue
+		} else if opt.page != nil && !opt.page.next() {
+			continue
 		}
 		cur := dst
-		if slice || chanl {
+		if slice || chanl || mapt {
 			cur = reflect.New(et)
 		}
+		res := it.Result()
 		mo := make(map[string][]graph.Value, len(mp))
 		for k, v := range mp {
 			mo[k] = []graph.Value{v}
 		}
-		for it.NextPath(ctx) {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
-			mp = make(map[string]graph.Value)
-			it.TagResults(mp)
-			if len(mp) == 0 {
-				continue
-			}
-			// TODO(dennwc): replace with more efficient
-			for k, v := range mp {
-				if sl, ok := mo[k]; !ok {
-					mo[k] = []graph.Value{v}
-				} else if len(sl) == 1 {
-					if !keysEqual(sl[0], v) {
-						mo[k] = append(sl, v)
-					}
-				} else {
-					found := false
-					for _, sv := range sl {
-						if keysEqual(sv, v) {
-							found = true
-							break
-						}
-					}
-					if !found {
-						mo[k] = append(sl, v)
-					}
+		if err := collectPaths(ctx, it, mo); err != nil {
+			return err
+		}
+		if !slice && !chanl && !mapt {
+			// other values of required fields of the same object are returned as separate results
+			for it.Next(ctx) && keysEqual(it.Result(), res) {
+				mp = make(map[string]graph.Value)
+				it.TagResults(mp)
+				mergeTags(mo, mp)
+				if err := collectPaths(ctx, it, mo); err != nil {
+					return err
 				}
 			}
 		}
+		if asOf, ok := ctx.Value(asOfCtxKey{}).(time.Time); ok {
+			if err := c.filterAsOf(ctx, qs, res, fields, mo, asOf); err != nil {
+				return err
+			}
+		}
+		if err := prefetchNames(ctx, qs, mo); err != nil {
+			return err
+		}
 		err := c.loadToValue(ctx, qs, cur, depth, mo, "")
+		switch e := err.(type) {
+		case ErrFieldConversion:
+			if e.ID == nil {
+				e.ID = qs.NameOf(res)
+				err = e
+			}
+		case ErrMultipleValues:
+			if e.ID == nil {
+				e.ID = qs.NameOf(res)
+				err = e
+			}
+		}
+		if err != nil && lopt.PartialResults && ctx.Err() == nil {
+			// keep fields that were loaded before the error
+			err = nil
+		}
 		if err == errRequiredFieldIsMissing {
-			if !slice && !chanl {
+			if !slice && !chanl && !mapt {
 				return err
 			}
+			st.skip(res)
+			continue
+		} else if err != nil && opt.results != nil {
+			if !isTypedLoadErr(err) {
+				err = fmt.Errorf("object %v: %v", qs.NameOf(res), err)
+			}
+			select {
+			case opt.results <- Result{Err: err}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 			continue
 		} else if err != nil {
 			return err
 		}
-		if slice {
-			dst.Set(reflect.Append(dst, cur.Elem()))
-		} else if chanl {
-			dst.Send(cur.Elem())
-		} else {
-			return nil
+		st.visit(res)
+		if len(ifp) != 0 {
+			// join groups of all objects that share any of the values; objects are merged after the iteration,
+			// since a later object may link groups together
+			ind := len(merged)
+			merged = append(merged, cur)
+			parent = append(parent, ind)
+			for _, name := range ifp {
+				for _, v := range mo[name] {
+					k := ifpKey{field: name, value: graph.ToKey(v)}
+					i, ok := byIFP[k]
+					if !ok {
+						byIFP[k] = ind
+						continue
+					}
+					if g1, g2 := group(i), group(ind); g1 < g2 {
+						parent[g2] = g1
+					} else if g2 < g1 {
+						parent[g1] = g2
+					}
+				}
+			}
+			continue
+		}
+		if !slice && !chanl && !mapt {
+			st.loaded()
+			return c.afterLoad(cur)
+		} else if err = emit(cur); err != nil {
+			return err
 		}
 	}
 	if err := it.Err(); err != nil {
 		return err
 	}
-	if slice || chanl {
+	// merge in load order, thus the first object of a group wins
+	for i, cur := range merged {
+		if g := group(i); g != i {
+			mergeValues(merged[g].Elem(), cur.Elem())
+		}
+	}
+	for i, cur := range merged {
+		if group(i) != i {
+			continue
+		}
+		if err := emit(cur); err != nil {
+			return err
+		}
+	}
+	if slice || chanl || mapt {
 		return nil
 	}
 	if list != nil && list.Type() != graph.All {
@@ -862,22 +2731,150 @@ func (c *Config) loadIteratorToDepth(ctx context.Context, qs graph.QuadStore, ds
 	return errNotFound
 }
 
+// loadIteratorToIface loads objects of registered types that implement a given interface type.
+// Concrete type of each object is selected based on the node's type IRI.
+func (c *Config) loadIteratorToIface(ctx context.Context, qs graph.QuadStore, dst reflect.Value, iface reflect.Type, depth int, list graph.Iterator, opt loadOptions) error {
+	impls := implsFor(iface)
+	if len(impls) == 0 {
+		return fmt.Errorf("no registered types implement %v", iface)
+	}
+	iris := make([]quad.Value, 0, len(impls))
+	typesMu.RLock()
+	for _, rt := range impls {
+		iris = append(iris, typeToIRI[rt])
+	}
+	typesMu.RUnlock()
+	p := path.StartMorphism()
+	if len(c.RestrictLabels) != 0 {
+		p = p.LabelContext(c.RestrictLabels)
+	}
+	p = p.Has(c.iri(iriType), iris...)
+	it, err := c.iteratorFromPath(qs, list, p)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	single := dst.Kind() == reflect.Interface
+	for it.Next(ctx) {
+		node := it.Result()
+		rt, err := c.typeForNode(ctx, qs, node, iface)
+		if err != nil {
+			return err
+		} else if rt == nil {
+			continue
+		} else if opt.page != nil && !opt.page.next() {
+			continue
+		}
+		sv := reflect.New(rt)
+		sit := iterator.NewFixed()
+		sit.Add(node)
+		err = c.loadIteratorToDepth(ctx, qs, sv.Elem(), depth, sit)
+		if IsNotFound(err) {
+			continue
+		} else if err != nil && opt.results != nil {
+			err = fmt.Errorf("object %v: %v", qs.NameOf(node), err)
+			select {
+			case opt.results <- Result{Err: err}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		if rt.Implements(iface) {
+			sv = sv.Elem()
+		}
+		switch {
+		case opt.results != nil:
+			select {
+			case opt.results <- Result{Value: sv.Interface()}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case single:
+			dst.Set(sv)
+			return nil
+		case dst.Kind() == reflect.Slice:
+			dst.Set(reflect.Append(dst, sv))
+		case dst.Kind() == reflect.Chan:
+			dst.Send(sv)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	} else if single {
+		return errNotFound
+	}
+	return nil
+}
+
 func isZero(rv reflect.Value) bool {
 	return rv.Interface() == reflect.Zero(rv.Type()).Interface() // TODO(dennwc): rewrite
 }
 
-func (c *Config) writeOneValReflect(w quad.Writer, id quad.Value, pred quad.Value, rv reflect.Value, rev bool) error {
-	if isZero(rv) {
+// isUnset checks if a field value should be skipped when writing. Unlike other zero values,
+// false is a meaningful boolean value and is always written.
+func isUnset(rv reflect.Value) bool {
+	return rv.Kind() != reflect.Bool && isZero(rv)
+}
+
+func (c *Config) writeQuad(w quad.Writer, q quad.Quad) error {
+	if c.ValidateIRIs {
+		for _, d := range []quad.Direction{quad.Subject, quad.Predicate, quad.Object, quad.Label} {
+			if v, ok := q.Get(d).(quad.IRI); ok && !v.Valid() {
+				return ErrInvalidIRI{IRI: v, Dir: d}
+			}
+		}
+	}
+	if c.StrictIRIs && c.IRIs != IRINative {
+		for _, d := range []quad.Direction{quad.Subject, quad.Predicate, quad.Object, quad.Label} {
+			// IRI has no known namespace if both forms are the same
+			if v, ok := q.Get(d).(quad.IRI); ok && c.shortIRI(v) == c.fullIRI(v) {
+				return ErrIRINotNormalized{IRI: v, Dir: d, Mode: c.IRIs}
+			}
+		}
+	}
+	return w.WriteQuad(q)
+}
+
+// writeOneValReflect writes a single value of a field. Nested objects are written as well,
+// and field is the name of the parent field that is passed to ChildID.
+func (c *Config) writeOneValReflect(w quad.Writer, opts writeOpts, id quad.Value, pred quad.Value, field string, rv reflect.Value, rev bool, depth int) error {
+	if isUnset(rv) {
 		return nil
 	}
+	return c.writeValReflect(w, opts, id, pred, field, rv, rev, depth)
+}
+
+// writeValReflect is the same as writeOneValReflect, but writes zero values as well.
+func (c *Config) writeValReflect(w quad.Writer, opts writeOpts, id quad.Value, pred quad.Value, field string, rv reflect.Value, rev bool, depth int) error {
 	targ, ok := quad.AsValue(rv.Interface())
-	if !ok {
+	if ok {
+		targ = c.relativeValue(targ)
+	} else {
+		iface := rv.Kind() == reflect.Interface
+		if iface {
+			rv = rv.Elem()
+		}
 		if rv.Kind() == reflect.Ptr {
 			rv = rv.Elem()
 		}
 		targ, ok = quad.AsValue(rv.Interface())
-		if !ok && rv.Kind() == reflect.Struct {
-			sid, err := c.WriteAsQuads(w, rv.Interface())
+		if ok {
+			targ = c.relativeValue(targ)
+		} else if rv.Kind() == reflect.Struct {
+			if iface {
+				// type triple is required to load the value back to an interface field
+				typesMu.RLock()
+				_, registered := typeToIRI[rv.Type()]
+				typesMu.RUnlock()
+				if !registered {
+					return fmt.Errorf("type %v must be registered to be written to an interface field", rv.Type())
+				}
+			}
+			sid, err := c.writeChildAsQuads(w, opts, rv.Interface(), depth+1, id, field)
 			if err != nil {
 				return err
 			}
@@ -891,26 +2888,30 @@ func (c *Config) writeOneValReflect(w quad.Writer, id quad.Value, pred quad.Valu
 	if rev {
 		s, o = o, s
 	}
-	return w.WriteQuad(quad.Quad{Subject: s, Predicate: pred, Object: o, Label: c.Label})
+	return c.writeQuad(w, quad.Quad{Subject: s, Predicate: pred, Object: o, Label: c.writeLabel(opts)})
 }
 
-func (c *Config) writeValueAs(w quad.Writer, id quad.Value, rv reflect.Value, pref string, rules fieldRules) error {
+func (c *Config) writeValueAs(w quad.Writer, opts writeOpts, id quad.Value, rv reflect.Value, pref string, rules fieldRules, depth int) error {
 	if rv.Kind() == reflect.Ptr {
 		rv = rv.Elem()
 	}
 	rt := rv.Type()
 	typesMu.RLock()
 	iri := typeToIRI[rt]
+	_, noType := noWriteType[rt]
 	typesMu.RUnlock()
-	if iri != quad.IRI("") {
-		if err := w.WriteQuad(quad.Quad{Subject: id, Predicate: c.iri(iriType), Object: c.iri(iri), Label: c.Label}); err != nil {
+	if iri != quad.IRI("") && !noType {
+		if err := c.writeQuad(w, quad.Quad{Subject: id, Predicate: c.iri(iriType), Object: c.iri(iri), Label: c.writeLabel(opts)}); err != nil {
 			return err
 		}
 	}
 	for i := 0; i < rt.NumField(); i++ {
 		f := rt.Field(i)
 		if f.Anonymous {
-			if err := c.writeValueAs(w, id, rv.Field(i), pref+f.Name+".", rules); err != nil {
+			if fv := rv.Field(i); fv.Kind() == reflect.Ptr && fv.IsNil() {
+				continue
+			}
+			if err := c.writeValueAs(w, opts, id, rv.Field(i), pref+f.Name+".", rules, depth); err != nil {
 				return err
 			}
 			continue
@@ -921,49 +2922,195 @@ func (c *Config) writeValueAs(w quad.Writer, id quad.Value, rv reflect.Value, pr
 			if r.Rev {
 				s, o = o, s
 			}
-			if err := w.WriteQuad(quad.Quad{Subject: s, Predicate: r.Pred, Object: o, Label: c.Label}); err != nil {
+			if err := c.writeQuad(w, quad.Quad{Subject: s, Predicate: r.Pred, Object: o, Label: c.writeLabel(opts)}); err != nil {
+				return err
+			}
+		case mapRule:
+			if err := c.writeMapField(w, opts, id, f.Name, rv.Field(i), depth); err != nil {
 				return err
 			}
 		case saveRule:
+			if r.Count {
+				continue
+			} else if r.List {
+				if err := c.writeListField(w, opts, id, r.Pred, f.Name, rv.Field(i), depth); err != nil {
+					return err
+				}
+				continue
+			}
 			if f.Type.Kind() == reflect.Slice {
 				sl := rv.Field(i)
 				for j := 0; j < sl.Len(); j++ {
-					if err := c.writeOneValReflect(w, id, r.Pred, sl.Index(j), r.Rev); err != nil {
+					if err := c.writeOneValReflect(w, opts, id, r.Pred, f.Name, sl.Index(j), r.Rev, depth); err != nil {
 						return err
 					}
 				}
 			} else {
 				fv := rv.Field(i)
-				if !r.Opt && isZero(fv) {
+				if !r.Opt && isUnset(fv) {
 					return ErrReqFieldNotSet{Field: f.Name}
 				}
-				if err := c.writeOneValReflect(w, id, r.Pred, fv, r.Rev); err != nil {
+				if err := c.writeOneValReflect(w, opts, id, r.Pred, f.Name, fv, r.Rev, depth); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if pref == "" {
+		return c.writeDerived(w, opts, id, rv)
+	}
+	return nil
+}
+
+// writeDerived writes all derived predicates of an object. See Config.Derived.
+func (c *Config) writeDerived(w quad.Writer, opts writeOpts, id quad.Value, rv reflect.Value) error {
+	if len(c.Derived) == 0 || !rv.CanInterface() {
+		return nil
+	}
+	o := rv.Interface()
+	for _, d := range c.Derived {
+		if d.Value == nil {
+			continue
+		}
+		v, ok := d.Value(o)
+		if !ok || v == nil {
+			continue
+		}
+		if err := c.writeQuad(w, quad.Quad{Subject: id, Predicate: c.iri(d.Pred), Object: v, Label: c.writeLabel(opts)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeListField writes a slice as an ordered RDF collection linked to the object. A nil slice is not written,
+// while an empty one is written as rdf:nil.
+func (c *Config) writeListField(w quad.Writer, opts writeOpts, id quad.Value, pred quad.IRI, field string, sl reflect.Value, depth int) error {
+	if sl.IsNil() {
+		return nil
+	}
+	var (
+		first = c.iri(rdf.First)
+		rest  = c.iri(rdf.Rest)
+		head  = quad.Value(c.iri(rdf.Nil))
+	)
+	nodes := make([]quad.Value, sl.Len())
+	for i := range nodes {
+		nodes[i] = c.newBNode(opts)
+	}
+	if len(nodes) != 0 {
+		head = nodes[0]
+	}
+	if err := c.writeQuad(w, quad.Quad{Subject: id, Predicate: pred, Object: head, Label: c.writeLabel(opts)}); err != nil {
+		return err
+	}
+	for i, node := range nodes {
+		ev := sl.Index(i)
+		if (ev.Kind() == reflect.Ptr || ev.Kind() == reflect.Interface) && ev.IsNil() {
+			return fmt.Errorf("list field %s: element %d is nil", field, i)
+		}
+		if err := c.writeValReflect(w, opts, node, first, field, ev, false, depth); err != nil {
+			return err
+		}
+		next := quad.Value(c.iri(rdf.Nil))
+		if i+1 < len(nodes) {
+			next = nodes[i+1]
+		}
+		if err := c.writeQuad(w, quad.Quad{Subject: node, Predicate: rest, Object: next, Label: c.writeLabel(opts)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMapField writes all entries of a predicates map. Keys are written in sorted order.
+func (c *Config) writeMapField(w quad.Writer, opts writeOpts, id quad.Value, field string, mv reflect.Value, depth int) error {
+	keys := mv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, k := range keys {
+		var pred quad.Value
+		switch k := k.Interface().(type) {
+		case quad.IRI:
+			pred = c.iri(k)
+		case string:
+			pred = c.toIRI(k)
+		}
+		v := mv.MapIndex(k)
+		if v.Kind() == reflect.Slice {
+			for j := 0; j < v.Len(); j++ {
+				if err := c.writeOneValReflect(w, opts, id, pred, field, v.Index(j), false, depth); err != nil {
 					return err
 				}
 			}
+			continue
+		}
+		if err := c.writeOneValReflect(w, opts, id, pred, field, v, false, depth); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// idFields returns names of all id fields, ordered by priority.
+func (f fieldRules) idFields() []string {
+	var out []string
+	for name, r := range f {
+		if _, ok := r.(idRule); ok {
+			out = append(out, name)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		pi, pj := f[out[i]].(idRule).Priority, f[out[j]].(idRule).Priority
+		if pi != pj {
+			return pi < pj
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+func (c *Config) idValue(vid interface{}) (quad.Value, error) {
+	switch vid := vid.(type) {
+	case quad.IRI:
+		return c.iri(vid), nil
+	case quad.BNode:
+		return vid, nil
+	case string:
+		return c.stringID(vid)
+	}
+	return nil, fmt.Errorf("unsupported type for id field: %T", vid)
+}
+
+// idForCandidates returns an id from the first non-zero id field, in the order of priority.
+func (c *Config) idForCandidates(rv reflect.Value, names []string) (quad.Value, error) {
+	for _, name := range names {
+		fv, ok := fieldByRule(rv, name)
+		if !ok {
+			continue
+		}
+		vid := fv.Interface()
+		if vid == reflect.Zero(fv.Type()).Interface() {
+			continue
+		}
+		return c.idValue(vid)
+	}
+	return nil, nil
+}
+
 func (c *Config) idFor(rules fieldRules, rt reflect.Type, rv reflect.Value, pref string) (id quad.Value, err error) {
+	if pref == "" {
+		if names := rules.idFields(); len(names) > 1 {
+			return c.idForCandidates(rv, names)
+		}
+	}
 	hasAnon := false
 	for i := 0; i < rt.NumField(); i++ {
 		fld := rt.Field(i)
 		hasAnon = hasAnon || fld.Anonymous
 		if _, ok := rules[pref+fld.Name].(idRule); ok {
-			vid := rv.Field(i).Interface()
-			switch vid := vid.(type) {
-			case quad.IRI:
-				id = c.iri(vid)
-			case quad.BNode:
-				id = vid
-			case string:
-				id = c.toIRI(vid)
-			default:
-				err = fmt.Errorf("unsupported type for id field: %T", vid)
-			}
-			return
+			return c.idValue(rv.Field(i).Interface())
 		}
 	}
 	if !hasAnon {
@@ -975,7 +3122,14 @@ func (c *Config) idFor(rules fieldRules, rt reflect.Type, rv reflect.Value, pref
 		if !fld.Anonymous {
 			continue
 		}
-		id, err = c.idFor(rules, fld.Type, rv.Field(i), pref+fld.Name+".")
+		ft, fv := fld.Type, rv.Field(i)
+		if ft.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			ft, fv = ft.Elem(), fv.Elem()
+		}
+		id, err = c.idFor(rules, ft, fv, pref+fld.Name+".")
 		if err != nil || id != nil {
 			return
 		}
@@ -989,11 +3143,88 @@ func (c *Config) idFor(rules fieldRules, rt reflect.Type, rv reflect.Value, pref
 // an annotated ID field, it's value will be converted to quad.Value and returned.
 // Otherwise, a new BNode will be generated using GenerateID function.
 //
+// BeforeWrite method is called on each object that implements BeforeWriter before writing it.
+//
 // See LoadTo for a list of quads mapping rules.
 func (c *Config) WriteAsQuads(w quad.Writer, o interface{}) (quad.Value, error) {
+	return c.writeAtomic(w, func(w quad.Writer) (quad.Value, error) {
+		return c.writeAsQuads(w, writeOpts{}, o, 1)
+	})
+}
+
+// txApplier is implemented by writers that can apply a transaction atomically.
+type txApplier interface {
+	ApplyTransaction(tx *graph.Transaction) error
+}
+
+// writeAtomic calls a given write function with a buffer if WriteAtomic is set, and writes collected quads
+// to w only if the function succeeds. Otherwise, the function writes to w directly.
+func (c *Config) writeAtomic(w quad.Writer, fnc func(w quad.Writer) (quad.Value, error)) (quad.Value, error) {
+	if !c.WriteAtomic {
+		return fnc(w)
+	}
+	var buf quadBuffer
+	id, err := fnc(&buf)
+	if err != nil {
+		return nil, err
+	}
+	switch w := w.(type) {
+	case txApplier:
+		tx := graph.NewTransactionN(len(buf))
+		for _, q := range buf {
+			tx.AddQuad(q)
+		}
+		err = w.ApplyTransaction(tx)
+	case quad.BatchWriter:
+		var n int
+		n, err = w.WriteQuads(buf)
+		if err == nil && n != len(buf) {
+			err = fmt.Errorf("short write: %d of %d quads", n, len(buf))
+		}
+	default:
+		for _, q := range buf {
+			if err = w.WriteQuad(q); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// WriteAsQuadsWith is the same as WriteAsQuads, but uses a given function instead of GenerateID
+// to generate IDs for this object and all nested objects without an ID.
+// If genID is nil, the function is the same as WriteAsQuads.
+func (c *Config) WriteAsQuadsWith(w quad.Writer, o interface{}, genID func(interface{}) quad.Value) (quad.Value, error) {
+	return c.writeAtomic(w, func(w quad.Writer) (quad.Value, error) {
+		return c.writeAsQuads(w, writeOpts{genID: genID}, o, 1)
+	})
+}
+
+// WriteAsQuadsLabeled is the same as WriteAsQuads, but writes all quads of this object and all nested objects
+// with a given label, instead of the one set in the config.
+// If label is nil, the function is the same as WriteAsQuads.
+func (c *Config) WriteAsQuadsLabeled(w quad.Writer, o interface{}, label quad.Value) (quad.Value, error) {
+	return c.writeAtomic(w, func(w quad.Writer) (quad.Value, error) {
+		return c.writeAsQuads(w, writeOpts{label: label}, o, 1)
+	})
+}
+
+func (c *Config) writeAsQuads(w quad.Writer, opts writeOpts, o interface{}, depth int) (quad.Value, error) {
+	return c.writeChildAsQuads(w, opts, o, depth, nil, "")
+}
+
+// writeChildAsQuads is the same as writeAsQuads, but also accepts an ID of the parent object
+// and the name of its field that references the object. They are used to generate an ID with ChildID.
+func (c *Config) writeChildAsQuads(w quad.Writer, opts writeOpts, o interface{}, depth int, parent quad.Value, field string) (quad.Value, error) {
 	if v, ok := o.(quad.Value); ok {
 		return v, nil
 	}
+	if c.MaxWriteDepth > 0 && depth > c.MaxWriteDepth {
+		return nil, ErrMaxDepthExceeded{Depth: c.MaxWriteDepth}
+	}
 	rv := reflect.ValueOf(o)
 	if rv.Kind() == reflect.Ptr {
 		rv = rv.Elem()
@@ -1006,14 +3237,24 @@ func (c *Config) WriteAsQuads(w quad.Writer, o interface{}) (quad.Value, error)
 	if len(rules) == 0 {
 		return nil, fmt.Errorf("no rules for struct: %v", rt)
 	}
+	if rv, err = c.beforeWrite(rv); err != nil {
+		return nil, err
+	}
 	id, err := c.idFor(rules, rt, rv, "")
 	if err != nil {
 		return nil, err
 	}
+	if id == nil && parent != nil && c.ChildID != nil {
+		id = c.ChildID(parent, field, o)
+	}
 	if id == nil {
-		id = c.genID(o)
+		if opts.genID != nil {
+			id = opts.genID(o)
+		} else {
+			id = c.genID(o)
+		}
 	}
-	if err = c.writeValueAs(w, id, rv, "", rules); err != nil {
+	if err = c.writeValueAs(w, opts, id, rv, "", rules, depth); err != nil {
 		return nil, err
 	}
 	return id, nil
@@ -1025,19 +3266,122 @@ type namespace struct {
 	Prefix quad.IRI `quad:"cayley:prefix"`
 }
 
-// WriteNamespaces will writes namespaces list into graph.
-func (c *Config) WriteNamespaces(w quad.Writer, n *voc.Namespaces) error {
+// fieldByRule returns a struct field for a given rule name. Nil embedded pointers are not allocated.
+func fieldByRule(rv reflect.Value, name string) (reflect.Value, bool) {
+	for _, fname := range strings.Split(name, ".") {
+		if rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.FieldByName(fname)
+	}
+	return rv, rv.IsValid()
+}
+
+// EnsureUnique checks that there is no other object in the graph with the same values
+// of specified fields as the given object. It returns ErrDuplicate with an ID of existing object if one is found.
+//
+// Fields are referenced by Go field names, and multiple fields form a composite key.
+// If the object type was registered with RegisterType, only objects of the same type are considered.
+// The object itself (with the same ID) is not considered a duplicate.
+//
+// The check is not atomic with respect to subsequent writes.
+func (c *Config) EnsureUnique(ctx context.Context, qs graph.QuadStore, o interface{}, fields ...string) error {
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("no fields specified")
+	}
+	rv := reflect.ValueOf(o)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	rt := rv.Type()
+	rules, err := c.rulesFor(rt)
+	if err != nil {
+		return fmt.Errorf("can't load rules: %v", err)
+	}
+	p := path.StartPath(qs)
+	if len(c.RestrictLabels) != 0 {
+		p = p.LabelContext(c.RestrictLabels)
+	}
+	typesMu.RLock()
+	iri := typeToIRI[rt]
+	typesMu.RUnlock()
+	if iri != quad.IRI("") {
+		p = p.Has(c.iri(iriType), c.iri(iri))
+	}
+	for _, name := range fields {
+		r, ok := rules[name]
+		if !ok {
+			// field might be defined in an embedded struct
+			for rname, rule := range rules {
+				if strings.HasSuffix(rname, "."+name) {
+					name, r, ok = rname, rule, true
+					break
+				}
+			}
+		}
+		sr, ok := r.(saveRule)
+		if !ok {
+			return fmt.Errorf("%v has no field %s with a predicate", rt, name)
+		}
+		fv, ok := fieldByRule(rv, name)
+		if !ok || isZero(fv) {
+			return ErrReqFieldNotSet{Field: name}
+		}
+		v, ok := quad.AsValue(fv.Interface())
+		if !ok {
+			return fmt.Errorf("unsupported type for unique field %s: %v", name, fv.Type())
+		}
+		if sr.Rev {
+			p = p.HasReverse(sr.Pred, v)
+		} else {
+			p = p.Has(sr.Pred, v)
+		}
+	}
+	id, err := c.idFor(rules, rt, rv, "")
+	if err != nil {
+		return err
+	}
+	// at most one of the results might be the object itself
+	vals, err := p.Iterate(ctx).Limit(2).AllValues(qs)
+	if err != nil {
+		return err
+	}
+	for _, v := range vals {
+		if id == nil || v.String() != id.String() {
+			return ErrDuplicate{ID: v, Fields: fields}
+		}
+	}
+	return nil
+}
+
+// WriteNamespaces will writes namespaces list into graph. Namespaces are written in order of their full IRIs.
+// Context is checked before writing each namespace, and its error is returned if it's cancelled.
+func (c *Config) WriteNamespaces(ctx context.Context, w quad.Writer, n *voc.Namespaces) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	rules, err := c.rulesFor(reflect.TypeOf(namespace{}))
 	if err != nil {
 		return fmt.Errorf("can't load rules: %v", err)
 	}
-	for _, ns := range n.List() {
+	list := n.List()
+	sort.Sort(voc.ByFullName(list))
+	for _, ns := range list {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		obj := namespace{
 			Full:   quad.IRI(ns.Full),
 			Prefix: quad.IRI(ns.Prefix),
 		}
 		rv := reflect.ValueOf(obj)
-		if err = c.writeValueAs(w, obj.Full, rv, "", rules); err != nil {
+		if err = c.writeValueAs(w, writeOpts{}, obj.Full, rv, "", rules, 1); err != nil {
 			return err
 		}
 	}
@@ -1046,10 +3390,16 @@ func (c *Config) WriteNamespaces(w quad.Writer, n *voc.Namespaces) error {
 
 // LoadNamespaces will load namespaces stored in graph to a specified list.
 // If destination list is empty, global namespace registry will be used.
+// No namespaces are registered if the context is cancelled before all of them are loaded.
 func (c *Config) LoadNamespaces(ctx context.Context, qs graph.QuadStore, dest *voc.Namespaces) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	var list []namespace
 	if err := c.LoadTo(ctx, qs, &list); err != nil {
 		return err
+	} else if err = ctx.Err(); err != nil {
+		return err
 	}
 	register := dest.Register
 	if dest == nil {
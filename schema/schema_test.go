@@ -2,17 +2,24 @@ package schema_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/graphtest"
 	"github.com/caivega/cayley/graph/iterator"
 	"github.com/caivega/cayley/graph/memstore"
 	"github.com/caivega/cayley/quad"
 	"github.com/caivega/cayley/schema"
 	"github.com/caivega/cayley/voc"
 	"github.com/caivega/cayley/voc/rdf"
+	"github.com/caivega/cayley/writer"
 )
 
 type item struct {
@@ -94,6 +101,23 @@ type subObject struct {
 	Num int `quad:"num"`
 }
 
+type OptGroup struct {
+	Spec string `quad:"spec,optional"`
+	Num  int    `quad:"num,optional"`
+}
+
+type withOptGroup struct {
+	ID   quad.IRI `quad:"@id"`
+	Name string   `quad:"name"`
+	*OptGroup
+}
+
+var optGroupQuads = []quad.Quad{
+	{iri("1"), iri("name"), quad.String("first"), nil},
+	{iri("1"), iri("spec"), quad.String("special"), nil},
+	{iri("2"), iri("name"), quad.String("second"), nil},
+}
+
 type subSubObject struct {
 	subObject
 	Num2 int `quad:"num2"`
@@ -102,6 +126,13 @@ type subSubObject struct {
 func init() {
 	voc.RegisterPrefix("ex:", "http://example.org/")
 	schema.RegisterType(quad.IRI("ex:Coords"), Coords{})
+	schema.RegisterType(quad.IRI("ex:UniqPerson"), uniqPerson{})
+	schema.RegisterType(quad.IRI("ex:Article"), article{})
+	schema.RegisterType(quad.IRI("ex:Picture"), picture{})
+	schema.RegisterType(quad.IRI("ex:Circle"), circle{})
+	schema.RegisterType(quad.IRI("ex:Square"), square{})
+	schema.RegisterImpl(reflect.TypeOf((*figure)(nil)).Elem(), circle{}, square{})
+	schema.RegisterTypeOpts(quad.IRI("ex:Tag"), inferredTag{}, schema.Options{WriteType: false})
 }
 
 type Coords struct {
@@ -291,6 +322,41 @@ var testWriteValueCases = []struct {
 		},
 		nil,
 	},
+	{
+		"embedded pointer",
+		withOptGroup{ID: "1", Name: "first", OptGroup: &OptGroup{Spec: "special"}},
+		iri("1"),
+		[]quad.Quad{
+			{iri("1"), iri("name"), quad.String("first"), nil},
+			{iri("1"), iri("spec"), quad.String("special"), nil},
+		},
+		nil,
+	},
+	{
+		"nil embedded pointer",
+		withOptGroup{ID: "2", Name: "second"},
+		iri("2"),
+		[]quad.Quad{
+			{iri("2"), iri("name"), quad.String("second"), nil},
+		},
+		nil,
+	},
+	{
+		"embedded pointer with id",
+		struct {
+			*genObject
+			Num int `quad:"num"`
+		}{
+			genObject: &genObject{ID: "1", Name: "first"},
+			Num:       3,
+		},
+		iri("1"),
+		[]quad.Quad{
+			{iri("1"), iri("name"), quad.String("first"), nil},
+			{iri("1"), iri("num"), quad.Int(3), nil},
+		},
+		nil,
+	},
 }
 
 type quadSlice []quad.Quad
@@ -573,6 +639,72 @@ var testFillValueCases = []struct {
 			{iri("c1"), iri("ex:lng"), quad.Float(34.5), nil},
 		},
 	},
+	{
+		name: "id only field",
+		expect: struct {
+			ID    quad.IRI `quad:"@id"`
+			Name  string   `quad:"name"`
+			Sub   *item    `quad:"sub,optional,idonly"`
+			Items []item   `quad:"items,idonly"`
+		}{
+			ID:    "1234",
+			Name:  "some item",
+			Sub:   &item{ID: "sub1"},
+			Items: []item{{ID: "sub2"}},
+		},
+		quads: []quad.Quad{
+			{iri("1234"), iri("name"), quad.String("some item"), nil},
+			{iri("1234"), iri("sub"), iri("sub1"), nil},
+			{iri("1234"), iri("items"), iri("sub2"), nil},
+			{iri("sub1"), typeIRI, iri("some:item"), nil},
+			{iri("sub1"), iri("name"), quad.String("Sub 1"), nil},
+			// not a valid item, but it's not loaded
+			{iri("sub2"), iri("name"), quad.String("Sub 2"), nil},
+		},
+	},
+	{
+		name: "id only field (embedded id)",
+		expect: struct {
+			ID  quad.IRI   `quad:"@id"`
+			Sub *subObject `quad:"sub,idonly"`
+		}{
+			ID:  "1234",
+			Sub: &subObject{genObject: genObject{ID: "sub1"}},
+		},
+		quads: []quad.Quad{
+			{iri("1234"), iri("sub"), iri("sub1"), nil},
+		},
+	},
+	{
+		name: "not id only field",
+		expect: struct {
+			ID   quad.IRI `quad:"@id"`
+			Name string   `quad:"name"`
+			Sub  *item    `quad:"sub,optional"`
+		}{
+			ID:   "1234",
+			Name: "some item",
+			Sub:  &item{ID: "sub1", Name: "Sub 1"},
+		},
+		quads: []quad.Quad{
+			{iri("1234"), iri("name"), quad.String("some item"), nil},
+			{iri("1234"), iri("sub"), iri("sub1"), nil},
+			{iri("sub1"), typeIRI, iri("some:item"), nil},
+			{iri("sub1"), iri("name"), quad.String("Sub 1"), nil},
+		},
+	},
+	{
+		name:   "embedded pointer",
+		expect: withOptGroup{ID: "1", Name: "first", OptGroup: &OptGroup{Spec: "special"}},
+		quads:  optGroupQuads,
+		from:   []quad.Value{iri("1")},
+	},
+	{
+		name:   "nil embedded pointer",
+		expect: withOptGroup{ID: "2", Name: "second"},
+		quads:  optGroupQuads,
+		from:   []quad.Value{iri("2")},
+	},
 }
 
 func TestLoadIteratorTo(t *testing.T) {
@@ -617,48 +749,2494 @@ func TestLoadIteratorTo(t *testing.T) {
 	}
 }
 
-func TestSaveNamespaces(t *testing.T) {
+func TestLoadToMap(t *testing.T) {
 	sch := schema.NewConfig()
-	save := []voc.Namespace{
-		{Full: "http://example.org/", Prefix: "ex:"},
-		{Full: "http://cayley.io/", Prefix: "c:"},
+	qs := memstore.New(
+		quad.Quad{Subject: iri("alice"), Predicate: iri("name"), Object: quad.String("Alice")},
+		quad.Quad{Subject: iri("bob"), Predicate: iri("name"), Object: quad.String("Bob")},
+		quad.Quad{Subject: iri("charlie"), Predicate: iri("name"), Object: quad.String("Charlie")},
+	)
+	var out map[quad.IRI]*genObject
+	if err := sch.LoadTo(nil, qs, &out); err != nil {
+		t.Fatal(err)
 	}
-	var ns voc.Namespaces
-	for _, n := range save {
-		ns.Register(n)
+	if len(out) != 3 {
+		t.Fatalf("unexpected number of objects: %d", len(out))
 	}
-	qs := memstore.New()
-	err := sch.WriteNamespaces(qs, &ns)
-	if err != nil {
+	for id, name := range map[quad.IRI]string{"alice": "Alice", "bob": "Bob", "charlie": "Charlie"} {
+		o := out[id]
+		if o == nil {
+			t.Errorf("object %v not found", id)
+		} else if o.ID != id || o.Name != name {
+			t.Errorf("unexpected object for %v: %#v", id, o)
+		}
+	}
+
+	// non-pointer values and a subset of ids
+	out2 := make(map[quad.IRI]genObject)
+	if err := sch.LoadTo(nil, qs, out2, iri("bob")); err != nil {
 		t.Fatal(err)
 	}
-	var ns2 voc.Namespaces
-	err = sch.LoadNamespaces(context.TODO(), qs, &ns2)
-	if err != nil {
+	if exp := map[quad.IRI]genObject{"bob": {ID: "bob", Name: "Bob"}}; !reflect.DeepEqual(out2, exp) {
+		t.Errorf("unexpected result: %#v", out2)
+	}
+
+	// element type must have an @id field
+	var out3 map[quad.IRI]item2
+	if err := sch.LoadTo(nil, qs, &out3); err == nil {
+		t.Error("expected an error for a type without @id")
+	}
+}
+
+type ifpPerson struct {
+	ID     quad.IRI `quad:"@id"`
+	Email  string   `quad:"email,ifp"`
+	Name   string   `quad:"name,optional"`
+	Phones []string `quad:"phone"`
+}
+
+func TestLoadIFP(t *testing.T) {
+	sch := schema.NewConfig()
+	qs := memstore.New(
+		quad.Quad{Subject: iri("p1"), Predicate: iri("email"), Object: quad.String("alice@example.org")},
+		quad.Quad{Subject: iri("p1"), Predicate: iri("name"), Object: quad.String("Alice")},
+		quad.Quad{Subject: iri("p1"), Predicate: iri("phone"), Object: quad.String("111")},
+		quad.Quad{Subject: iri("p2"), Predicate: iri("email"), Object: quad.String("alice@example.org")},
+		quad.Quad{Subject: iri("p2"), Predicate: iri("name"), Object: quad.String("Alice B.")},
+		quad.Quad{Subject: iri("p2"), Predicate: iri("phone"), Object: quad.String("222")},
+		quad.Quad{Subject: iri("p3"), Predicate: iri("email"), Object: quad.String("bob@example.org")},
+	)
+	var out []ifpPerson
+	if err := sch.LoadTo(nil, qs, &out); err != nil {
 		t.Fatal(err)
 	}
-	got := ns2.List()
-	sort.Sort(voc.ByFullName(save))
-	sort.Sort(voc.ByFullName(got))
-	if !reflect.DeepEqual(save, got) {
-		t.Fatalf("wrong namespaces returned: got: %v, expect: %v", got, save)
+	if len(out) != 2 {
+		t.Fatalf("expected 2 objects, got: %#v", out)
 	}
-	qr := graph.NewQuadStoreReader(qs)
-	q, err := quad.ReadAll(qr)
-	qr.Close()
-	if err != nil {
+	sort.Slice(out, func(i, j int) bool { return out[i].Email < out[j].Email })
+	alice := out[0]
+	// first node wins for scalar fields
+	if exp := map[quad.IRI]string{"p1": "Alice", "p2": "Alice B."}[alice.ID]; exp == "" || alice.Name != exp {
+		t.Errorf("scalar fields should be taken from the same node: %#v", alice)
+	}
+	sort.Strings(alice.Phones)
+	if !reflect.DeepEqual(alice.Phones, []string{"111", "222"}) {
+		t.Errorf("slices should be merged: %#v", alice)
+	}
+	if exp := (ifpPerson{ID: "p3", Email: "bob@example.org"}); !reflect.DeepEqual(out[1], exp) {
+		t.Errorf("unexpected object: %#v", out[1])
+	}
+}
+
+type ifpAccount struct {
+	ID     quad.IRI `quad:"@id"`
+	Email  string   `quad:"email,ifp,optional"`
+	Handle string   `quad:"handle,ifp,optional"`
+	Names  []string `quad:"name,required"`
+}
+
+func TestLoadIFPTransitive(t *testing.T) {
+	sch := schema.NewConfig()
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a1"), Predicate: iri("email"), Object: quad.String("x")},
+		quad.Quad{Subject: iri("a1"), Predicate: iri("name"), Object: quad.String("A")},
+		quad.Quad{Subject: iri("a2"), Predicate: iri("handle"), Object: quad.String("h")},
+		quad.Quad{Subject: iri("a2"), Predicate: iri("name"), Object: quad.String("B")},
+		// links both accounts above
+		quad.Quad{Subject: iri("a3"), Predicate: iri("email"), Object: quad.String("x")},
+		quad.Quad{Subject: iri("a3"), Predicate: iri("handle"), Object: quad.String("h")},
+		quad.Quad{Subject: iri("a3"), Predicate: iri("name"), Object: quad.String("C")},
+		// the same value in a different field is not shared
+		quad.Quad{Subject: iri("a4"), Predicate: iri("handle"), Object: quad.String("x")},
+		quad.Quad{Subject: iri("a4"), Predicate: iri("name"), Object: quad.String("D")},
+	)
+	var out []ifpAccount
+	if err := sch.LoadTo(nil, qs, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 objects, got: %#v", out)
+	}
+	sort.Slice(out, func(i, j int) bool { return len(out[i].Names) > len(out[j].Names) })
+	sort.Strings(out[0].Names)
+	if got := out[0]; got.Email != "x" || got.Handle != "h" || !reflect.DeepEqual(got.Names, []string{"A", "B", "C"}) {
+		t.Errorf("unexpected merged object: %#v", got)
+	}
+	if exp := (ifpAccount{ID: "a4", Handle: "x", Names: []string{"D"}}); !reflect.DeepEqual(out[1], exp) {
+		t.Errorf("unexpected object: %#v", out[1])
+	}
+}
+
+type address struct {
+	ID     quad.IRI `quad:"@id"`
+	Street string   `quad:"street"`
+	City   string   `quad:"city"`
+}
+
+type withAddresses struct {
+	ID    quad.IRI              `quad:"@id"`
+	Name  string                `quad:"name"`
+	Addrs map[quad.IRI]*address `quad:"*"`
+}
+
+func TestPredicateMapOfStructs(t *testing.T) {
+	sch := schema.NewConfig()
+	obj := withAddresses{
+		ID:   "alice",
+		Name: "Alice",
+		Addrs: map[quad.IRI]*address{
+			"home": {ID: "addr1", Street: "Main St. 1", City: "Springfield"},
+			"work": {ID: "addr2", Street: "Market St. 10", City: "Shelbyville"},
+		},
+	}
+	var out quadSlice
+	if _, err := sch.WriteAsQuads(&out, obj); err != nil {
 		t.Fatal(err)
 	}
 	expect := []quad.Quad{
-		quad.MakeIRI("http://cayley.io/", "cayley:prefix", "c:", ""),
-		quad.MakeIRI("http://cayley.io/", "rdf:type", "cayley:namespace", ""),
+		{Subject: iri("alice"), Predicate: iri("name"), Object: quad.String("Alice")},
+		{Subject: iri("addr1"), Predicate: iri("street"), Object: quad.String("Main St. 1")},
+		{Subject: iri("addr1"), Predicate: iri("city"), Object: quad.String("Springfield")},
+		{Subject: iri("alice"), Predicate: iri("home"), Object: iri("addr1")},
+		{Subject: iri("addr2"), Predicate: iri("street"), Object: quad.String("Market St. 10")},
+		{Subject: iri("addr2"), Predicate: iri("city"), Object: quad.String("Shelbyville")},
+		{Subject: iri("alice"), Predicate: iri("work"), Object: iri("addr2")},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), expect) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, expect)
+	}
+	qs := memstore.New(out...)
 
-		quad.MakeIRI("http://example.org/", "cayley:prefix", "ex:", ""),
-		quad.MakeIRI("http://example.org/", "rdf:type", "cayley:namespace", ""),
+	var obj2 withAddresses
+	if err := sch.LoadTo(nil, qs, &obj2, iri("alice")); err != nil {
+		t.Fatal(err)
 	}
-	sort.Sort(quad.ByQuadString(expect))
-	sort.Sort(quad.ByQuadString(q))
-	if !reflect.DeepEqual(expect, q) {
-		t.Fatalf("wrong quads returned: got: %v, expect: %v", q, expect)
+	if !reflect.DeepEqual(obj2, obj) {
+		t.Errorf("unexpected object:\n%#v\nexpected:\n%#v", obj2, obj)
+	}
+
+	// nested objects are loaded without fields if depth limit is reached
+	obj2 = withAddresses{}
+	if err := sch.LoadToDepth(nil, qs, &obj2, 0, iri("alice")); err != nil {
+		t.Fatal(err)
+	}
+	expObj := withAddresses{
+		ID:   "alice",
+		Name: "Alice",
+		Addrs: map[quad.IRI]*address{
+			"home": {ID: "addr1"},
+			"work": {ID: "addr2"},
+		},
+	}
+	if !reflect.DeepEqual(obj2, expObj) {
+		t.Errorf("unexpected object:\n%#v\nexpected:\n%#v", obj2, expObj)
+	}
+}
+
+type labeledPerson struct {
+	ID      quad.IRI   `quad:"@id"`
+	Name    string     `quad:"name"`
+	Knows   []quad.IRI `quad:"knows"`
+	KnownBy []quad.IRI `quad:"knows < *"`
+}
+
+func TestRestrictLabels(t *testing.T) {
+	g1, g2 := iri("g1"), iri("g2")
+	qs := memstore.New([]quad.Quad{
+		{Subject: iri("alice"), Predicate: iri("name"), Object: quad.String("Alice"), Label: g1},
+		{Subject: iri("alice"), Predicate: iri("knows"), Object: iri("bob"), Label: g1},
+		{Subject: iri("alice"), Predicate: iri("knows"), Object: iri("carol"), Label: g2},
+		{Subject: iri("bob"), Predicate: iri("knows"), Object: iri("alice"), Label: g1},
+		{Subject: iri("carol"), Predicate: iri("knows"), Object: iri("alice"), Label: g2},
+		{Subject: iri("bob"), Predicate: iri("name"), Object: quad.String("Bob"), Label: g2},
+		{Subject: iri("carol"), Predicate: iri("name"), Object: quad.String("Carol")},
+	}...)
+
+	sch := schema.NewConfig()
+	sch.RestrictLabels = []quad.Value{g1}
+	var out []labeledPerson
+	if err := sch.LoadTo(nil, qs, &out); err != nil {
+		t.Fatal(err)
+	}
+	expect := []labeledPerson{
+		{ID: "alice", Name: "Alice", Knows: []quad.IRI{"bob"}, KnownBy: []quad.IRI{"bob"}},
+	}
+	if !reflect.DeepEqual(out, expect) {
+		t.Errorf("unexpected objects:\n%#v\nexpected:\n%#v", out, expect)
+	}
+
+	// objects from other labels are not found
+	var p labeledPerson
+	if err := sch.LoadTo(nil, qs, &p, iri("bob")); err == nil {
+		t.Errorf("expected an error, got: %#v", p)
+	}
+
+	// first label is used for writes
+	var quads quadSlice
+	if _, err := sch.WriteAsQuads(&quads, labeledPerson{ID: "dave", Name: "Dave"}); err != nil {
+		t.Fatal(err)
+	}
+	expQuads := []quad.Quad{
+		{Subject: iri("dave"), Predicate: iri("name"), Object: quad.String("Dave"), Label: g1},
+	}
+	if !reflect.DeepEqual([]quad.Quad(quads), expQuads) {
+		t.Errorf("unexpected quads:\n%v\nexpected:\n%v", quads, expQuads)
+	}
+}
+
+func TestLoadPage(t *testing.T) {
+	type node struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+	}
+	var quads []quad.Quad
+	for i := 0; i < 10; i++ {
+		quads = append(quads, quad.Quad{
+			Subject:   iri(fmt.Sprintf("n%d", i)),
+			Predicate: iri("name"),
+			Object:    quad.String(fmt.Sprintf("node %d", i)),
+		})
+	}
+	// nodes without a name are not counted
+	quads = append(quads, quad.Quad{Subject: iri("n10"), Predicate: iri("follows"), Object: iri("n1")})
+	qs := memstore.New(quads...)
+	sch := schema.NewConfig()
+
+	var all []node
+	if err := sch.LoadTo(nil, qs, &all); err != nil {
+		t.Fatal(err)
+	} else if len(all) != 10 {
+		t.Fatalf("unexpected number of objects: %d", len(all))
+	}
+
+	for _, c := range []struct {
+		skip, limit int
+		expect      []node
+	}{
+		{skip: 3, limit: 4, expect: all[3:7]},
+		{skip: 8, limit: 4, expect: all[8:]},
+		{skip: 0, limit: 0, expect: all},
+		{skip: 12, limit: 2, expect: nil},
+	} {
+		var out []node
+		total, err := sch.LoadPage(nil, qs, &out, c.skip, c.limit)
+		if err != nil {
+			t.Fatal(err)
+		} else if total != 10 {
+			t.Errorf("unexpected total for [%d:+%d]: %d", c.skip, c.limit, total)
+		}
+		if !reflect.DeepEqual(out, c.expect) {
+			t.Errorf("unexpected objects for [%d:+%d]:\n%v\nexpected:\n%v", c.skip, c.limit, out, c.expect)
+		}
+	}
+}
+
+type article struct {
+	ID    quad.IRI `quad:"@id"`
+	Title string   `quad:"title"`
+}
+
+type picture struct {
+	ID  quad.IRI `quad:"@id"`
+	URL string   `quad:"url"`
+}
+
+type post struct {
+	ID      quad.IRI    `quad:"@id"`
+	Content interface{} `quad:"content"`
+}
+
+func TestInterfaceField(t *testing.T) {
+	sch := schema.NewConfig()
+	posts := []post{
+		{ID: "post1", Content: article{ID: "a1", Title: "Hello"}},
+		{ID: "post2", Content: picture{ID: "p1", URL: "http://example.org/cat.png"}},
+	}
+	var out quadSlice
+	for _, p := range posts {
+		if _, err := sch.WriteAsQuads(&out, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qs := memstore.New(out...)
+
+	for _, p := range posts {
+		var got post
+		if err := sch.LoadTo(nil, qs, &got, p.ID); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("unexpected object:\n%#v\nexpected:\n%#v", got, p)
+		}
+	}
+
+	// unregistered types cannot be loaded back, thus they are not allowed
+	_, err := sch.WriteAsQuads(&out, post{ID: "post3", Content: item2{Name: "name", Spec: "spec"}})
+	if err == nil {
+		t.Error("expected an error for unregistered type")
+	}
+}
+
+type figure interface {
+	Area() float64
+}
+
+type circle struct {
+	ID     quad.IRI `quad:"@id"`
+	Radius float64  `quad:"radius"`
+}
+
+func (c circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type square struct {
+	ID   quad.IRI `quad:"@id"`
+	Side float64  `quad:"side"`
+}
+
+func (s *square) Area() float64 { return s.Side * s.Side }
+
+func TestLoadInterface(t *testing.T) {
+	sch := schema.NewConfig()
+	var out quadSlice
+	for _, o := range []interface{}{
+		circle{ID: "c1", Radius: 1},
+		square{ID: "s1", Side: 2},
+		circle{ID: "c2", Radius: 3},
+		article{ID: "a1", Title: "Hello"},
+	} {
+		if _, err := sch.WriteAsQuads(&out, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qs := memstore.New(out...)
+
+	var figs []figure
+	if err := sch.LoadTo(nil, qs, &figs); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(figs, func(i, j int) bool {
+		return figs[i].Area() < figs[j].Area()
+	})
+	exp := []figure{
+		circle{ID: "c1", Radius: 1},
+		&square{ID: "s1", Side: 2},
+		circle{ID: "c2", Radius: 3},
+	}
+	if !reflect.DeepEqual(figs, exp) {
+		t.Errorf("unexpected objects:\n%#v\nexpected:\n%#v", figs, exp)
+	}
+
+	var fig figure
+	if err := sch.LoadTo(nil, qs, &fig, iri("s1")); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(fig, exp[1]) {
+		t.Errorf("unexpected object: %#v, expected: %#v", fig, exp[1])
+	}
+	if err := sch.LoadTo(nil, qs, &fig, iri("a1")); !schema.IsNotFound(err) {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+}
+
+func TestLoadToChan(t *testing.T) {
+	type person struct {
+		ID  quad.IRI `quad:"@id"`
+		Age int      `quad:"age"`
+	}
+	qs := memstore.New([]quad.Quad{
+		{Subject: iri("alice"), Predicate: iri("age"), Object: quad.Int(30)},
+		{Subject: iri("bob"), Predicate: iri("age"), Object: iri("unknown")},
+		{Subject: iri("carol"), Predicate: iri("age"), Object: quad.Int(25)},
+	}...)
+	sch := schema.NewConfig()
+
+	out := make(chan schema.Result)
+	go sch.LoadToChan(nil, qs, out, person{})
+
+	var (
+		got  []person
+		errs []error
+	)
+	for r := range out {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			continue
+		}
+		got = append(got, r.Value.(person))
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got: %v", errs)
+	} else if !strings.Contains(errs[0].Error(), "bob") {
+		t.Errorf("expected an error for bob, got: %v", errs[0])
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].ID < got[j].ID })
+	expect := []person{{ID: "alice", Age: 30}, {ID: "carol", Age: 25}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected objects:\n%v\nexpected:\n%v", got, expect)
+	}
+}
+
+type hookPerson struct {
+	ID    quad.IRI `quad:"@id"`
+	First string   `quad:"first"`
+	Last  string   `quad:"last"`
+	Full  string   // computed on load
+}
+
+func (p hookPerson) BeforeWrite(c *schema.Config) error {
+	if p.First == "" {
+		return fmt.Errorf("first name is required")
+	}
+	return nil
+}
+
+func (p *hookPerson) AfterLoad(c *schema.Config) error {
+	p.Full = p.First + " " + p.Last
+	return nil
+}
+
+type hookTag struct {
+	ID   quad.IRI `quad:"@id"`
+	Name string   `quad:"name"`
+}
+
+func (t *hookTag) BeforeWrite(c *schema.Config) error {
+	t.Name = strings.ToLower(t.Name)
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	sch := schema.NewConfig()
+
+	var out quadSlice
+	if _, err := sch.WriteAsQuads(&out, hookPerson{ID: "bob", Last: "Smith"}); err == nil {
+		t.Fatal("expected an error from BeforeWrite")
+	} else if len(out) != 0 {
+		t.Fatalf("unexpected quads written: %v", out)
+	}
+	if _, err := sch.WriteAsQuads(&out, hookPerson{ID: "alice", First: "Alice", Last: "Smith"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// pointer receiver is called on a copy of the value
+	tag := hookTag{ID: "tag", Name: "GoLang"}
+	if _, err := sch.WriteAsQuads(&out, tag); err != nil {
+		t.Fatal(err)
+	} else if tag.Name != "GoLang" {
+		t.Fatalf("object was modified: %#v", tag)
+	}
+	expect := []quad.Quad{
+		{Subject: iri("alice"), Predicate: iri("first"), Object: quad.String("Alice")},
+		{Subject: iri("alice"), Predicate: iri("last"), Object: quad.String("Smith")},
+		{Subject: iri("tag"), Predicate: iri("name"), Object: quad.String("golang")},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), expect) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, expect)
+	}
+
+	qs := memstore.New(out...)
+	var p hookPerson
+	if err := sch.LoadTo(nil, qs, &p, iri("alice")); err != nil {
+		t.Fatal(err)
+	} else if p.Full != "Alice Smith" {
+		t.Errorf("AfterLoad was not called: %#v", p)
+	}
+	var arr []hookPerson
+	if err := sch.LoadTo(nil, qs, &arr); err != nil {
+		t.Fatal(err)
+	} else if len(arr) != 1 || arr[0].Full != "Alice Smith" {
+		t.Errorf("AfterLoad was not called: %#v", arr)
+	}
+}
+
+type looseFlags struct {
+	ID     quad.IRI `quad:"@id"`
+	Active bool     `quad:"active"`
+	Count  int      `quad:"count"`
+}
+
+func TestCoerceStrings(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("active"), Object: quad.String("true")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("count"), Object: quad.String("42")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("active"), Object: quad.String("yes")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("count"), Object: quad.String("1")},
+	)
+	sch := schema.NewConfig()
+	var f looseFlags
+	if err := sch.LoadTo(nil, qs, &f, iri("a")); err == nil {
+		t.Fatalf("expected an error without coercion, got: %#v", f)
+	}
+
+	sch.CoerceStrings = true
+	f = looseFlags{}
+	if err := sch.LoadTo(nil, qs, &f, iri("a")); err != nil {
+		t.Fatal(err)
+	}
+	expect := looseFlags{ID: iri("a"), Active: true, Count: 42}
+	if f != expect {
+		t.Errorf("unexpected object: %#v, expected: %#v", f, expect)
 	}
-}
\ No newline at end of file
+
+	err := sch.LoadTo(nil, qs, &f, iri("b"))
+	if err == nil {
+		t.Fatal("expected a parse error")
+	} else if !strings.Contains(err.Error(), "Active") {
+		t.Errorf("error should name the field: %v", err)
+	}
+}
+
+type withLink struct {
+	ID   quad.IRI `quad:"@id"`
+	Link quad.IRI `quad:"link"`
+}
+
+func TestBaseIRI(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("link"), Object: iri("foo")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("link"), Object: iri("http://other/bar")},
+	)
+	sch := schema.NewConfig()
+	sch.BaseIRI = "http://ex/"
+
+	for _, c := range []struct {
+		id   quad.IRI
+		link quad.IRI
+	}{
+		{id: "a", link: "http://ex/foo"},
+		{id: "b", link: "http://other/bar"},
+	} {
+		var out withLink
+		if err := sch.LoadTo(nil, qs, &out, c.id); err != nil {
+			t.Fatal(err)
+		}
+		if exp := (withLink{ID: c.id, Link: c.link}); out != exp {
+			t.Errorf("unexpected object: %#v, expected: %#v", out, exp)
+		}
+	}
+
+	obj := withLink{ID: "a", Link: "http://ex/foo"}
+	for _, c := range []struct {
+		rel  bool
+		link quad.IRI
+	}{
+		{rel: false, link: "http://ex/foo"},
+		{rel: true, link: "foo"},
+	} {
+		sch.WriteRelativeIRIs = c.rel
+		var out quadSlice
+		if _, err := sch.WriteAsQuads(&out, obj); err != nil {
+			t.Fatal(err)
+		}
+		exp := quadSlice{{Subject: iri("a"), Predicate: iri("link"), Object: c.link}}
+		if !reflect.DeepEqual(out, exp) {
+			t.Errorf("unexpected quads:\n%v\nvs\n%v", out, exp)
+		}
+	}
+}
+
+type indexRecorder struct {
+	graph.QuadStore
+	calls [][]quad.Value
+}
+
+func (qs *indexRecorder) EnsureIndexes(ctx context.Context, preds []quad.Value) error {
+	qs.calls = append(qs.calls, preds)
+	return nil
+}
+
+func TestEnsureIndexes(t *testing.T) {
+	sch := schema.NewConfig()
+	preds, err := sch.PredicatesForType(reflect.TypeOf(&Coords{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []quad.IRI{"ex:lat", "ex:lng", quad.IRI(rdf.Type)}; !reflect.DeepEqual(preds, exp) {
+		t.Errorf("unexpected predicates: %v, expected: %v", preds, exp)
+	}
+
+	qs := &indexRecorder{QuadStore: memstore.New()}
+	if err = sch.EnsureIndexes(nil, qs, Coords{}, article{}); err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]quad.Value{{iri("ex:lat"), iri("ex:lng"), iri(rdf.Type), iri("title")}}
+	if !reflect.DeepEqual(qs.calls, exp) {
+		t.Errorf("unexpected index calls: %v, expected: %v", qs.calls, exp)
+	}
+
+	// all registered types are used by default
+	qs.calls = nil
+	if err = sch.EnsureIndexes(nil, qs); err != nil {
+		t.Fatal(err)
+	} else if len(qs.calls) != 1 {
+		t.Fatalf("expected a single call, got: %v", qs.calls)
+	}
+	got := make(map[quad.Value]bool)
+	for _, p := range qs.calls[0] {
+		got[p] = true
+	}
+	for _, p := range exp[0] {
+		if !got[p] {
+			t.Errorf("expected index on %v", p)
+		}
+	}
+
+	// backends without indexes are ignored
+	if err = sch.EnsureIndexes(nil, memstore.New()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type withCount struct {
+	ID    quad.IRI `quad:"@id"`
+	Count int      `quad:"count"`
+}
+
+type withCountRef struct {
+	ID  quad.IRI  `quad:"@id"`
+	Ref withCount `quad:"ref"`
+}
+
+func TestFieldConversionError(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("count"), Object: quad.String("many")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("ref"), Object: iri("a")},
+	)
+	sch := schema.NewConfig()
+	exp := schema.ErrFieldConversion{
+		ID:    iri("a"),
+		Field: "Count",
+		Value: quad.String("many"),
+		Type:  reflect.TypeOf(int(0)),
+	}
+
+	var out withCount
+	err := sch.LoadTo(nil, qs, &out, iri("a"))
+	e, ok := err.(schema.ErrFieldConversion)
+	if !ok {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if e.Err == nil {
+		t.Error("expected the conversion error to be set")
+	}
+	e.Err = nil
+	if !reflect.DeepEqual(e, exp) {
+		t.Errorf("unexpected error: %#v, expected: %#v", e, exp)
+	}
+
+	// error of a nested object has the id of that object
+	var ref withCountRef
+	err = sch.LoadTo(nil, qs, &ref, iri("b"))
+	if e, ok = err.(schema.ErrFieldConversion); !ok {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if e.ID != iri("a") {
+		t.Errorf("unexpected object id: %v", e.ID)
+	}
+}
+
+type cachedItem struct {
+	rdfType struct{} `quad:"rdf:type > ex:CachedItem"`
+	ID      quad.IRI `quad:"@id"`
+	Name    string   `quad:"name"`
+}
+
+func TestValueCache(t *testing.T) {
+	quads := []quad.Quad{
+		{Subject: iri("a"), Predicate: iri(rdf.Type), Object: iri("ex:CachedItem")},
+		{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")},
+	}
+	qs := memstore.New(quads...)
+	sch := schema.NewConfig()
+	sch.ValueCache = schema.NewValueCache(100)
+
+	load := func() error {
+		var out cachedItem
+		err := sch.LoadTo(nil, qs, &out, iri("a"))
+		if err == nil && out.Name != "A" {
+			t.Errorf("unexpected object: %#v", out)
+		}
+		return err
+	}
+	if err := load(); err != nil {
+		t.Fatal(err)
+	}
+
+	var del, add []graph.Delta
+	for _, q := range quads {
+		del = append(del, graph.Delta{Quad: q, Action: graph.Delete})
+		add = append(add, graph.Delta{Quad: q, Action: graph.Add})
+	}
+	if err := qs.ApplyDeltas(del, graph.IgnoreOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	sch.ValueCache.Invalidate(del)
+	if err := load(); !schema.IsNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+
+	// values are assigned new ids when added back
+	if err := qs.ApplyDeltas(add, graph.IgnoreOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := load(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValueCacheDisabled(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri(rdf.Type), Object: iri("ex:CachedItem")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")},
+	)
+	sch := schema.NewConfig()
+	sch.ValueCache = schema.NewValueCache(0)
+	var out cachedItem
+	if err := sch.LoadTo(nil, qs, &out, iri("a")); err != nil {
+		t.Fatal(err)
+	} else if out.Name != "A" {
+		t.Errorf("unexpected object: %#v", out)
+	}
+	sch.ValueCache.Invalidate(nil)
+	sch.ValueCache.Reset()
+}
+
+// countLookups counts ValueOf calls, which are round-trips for remote backends.
+type countLookups struct {
+	graph.QuadStore
+	n int64
+}
+
+func (qs *countLookups) ValueOf(v quad.Value) graph.Value {
+	atomic.AddInt64(&qs.n, 1)
+	return qs.QuadStore.ValueOf(v)
+}
+
+func BenchmarkValueCache(b *testing.B) {
+	var quads []quad.Quad
+	for i := 0; i < 100; i++ {
+		id := iri(fmt.Sprintf("n%d", i))
+		quads = append(quads,
+			quad.Quad{Subject: id, Predicate: iri(rdf.Type), Object: iri("ex:CachedItem")},
+			quad.Quad{Subject: id, Predicate: iri("name"), Object: quad.String(fmt.Sprint(i))},
+		)
+	}
+	for _, c := range []struct {
+		name  string
+		cache *schema.ValueCache
+	}{
+		{name: "nocache"},
+		{name: "cache", cache: schema.NewValueCache(200)},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			qs := &countLookups{QuadStore: memstore.New(quads...)}
+			sch := schema.NewConfig()
+			sch.ValueCache = c.cache
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var out cachedItem
+				if err := sch.LoadTo(nil, qs, &out, iri(fmt.Sprintf("n%d", i%100))); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&qs.n))/float64(b.N), "lookups/op")
+		})
+	}
+}
+
+type customTags struct {
+	ID    quad.IRI `rdf:"@id"`
+	Name  string   `rdf:"name"`
+	Title string   `json:"title"`
+	Skip  string   `quad:"skip"`
+}
+
+func TestTagKey(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("title"), Object: quad.String("Mr")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("skip"), Object: quad.String("S")},
+	)
+	sch := schema.NewConfig()
+	sch.TagKey = "rdf"
+	var out customTags
+	if err := sch.LoadTo(nil, qs, &out, iri("a")); err != nil {
+		t.Fatal(err)
+	}
+	if exp := (customTags{ID: "a", Name: "A", Title: "Mr"}); out != exp {
+		t.Errorf("unexpected object: %#v, expected: %#v", out, exp)
+	}
+
+	var quads quadSlice
+	if _, err := sch.WriteAsQuads(&quads, customTags{ID: "b", Name: "B", Title: "Dr", Skip: "S"}); err != nil {
+		t.Fatal(err)
+	}
+	exp := quadSlice{
+		{Subject: iri("b"), Predicate: iri("name"), Object: quad.String("B")},
+		{Subject: iri("b"), Predicate: iri("title"), Object: quad.String("Dr")},
+	}
+	if !reflect.DeepEqual(quads, exp) {
+		t.Errorf("unexpected quads:\n%v\nvs\n%v", quads, exp)
+	}
+
+	// json fallback can be disabled
+	sch = schema.NewConfig()
+	sch.TagKey = "rdf"
+	sch.NoJSONTags = true
+	out = customTags{}
+	if err := sch.LoadTo(nil, qs, &out, iri("a")); err != nil {
+		t.Fatal(err)
+	}
+	if exp := (customTags{ID: "a", Name: "A"}); out != exp {
+		t.Errorf("unexpected object: %#v, expected: %#v", out, exp)
+	}
+
+	// default tags are not affected
+	sch = schema.NewConfig()
+	out = customTags{}
+	if err := sch.LoadTo(nil, qs, &out, iri("a")); err != nil {
+		t.Fatal(err)
+	}
+	if exp := (customTags{Title: "Mr", Skip: "S"}); out != exp {
+		t.Errorf("unexpected object: %#v, expected: %#v", out, exp)
+	}
+}
+
+func TestTx(t *testing.T) {
+	sch := schema.NewConfig()
+	type person struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+	}
+	old := person{ID: "bob", Name: "Bob"}
+	other := person{ID: "alice", Name: "Alice"}
+
+	var out quadSlice
+	for _, p := range []person{old, other} {
+		if _, err := sch.WriteAsQuads(&out, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qs := memstore.New(out...)
+	// lenient writer; transaction must still fail on conflicts
+	w, err := writer.NewSingle(qs, graph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadNames := func() []string {
+		var arr []person
+		if err := sch.LoadTo(nil, qs, &arr); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, p := range arr {
+			names = append(names, p.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	// conflicting write (the object already exists) must not apply the delete
+	tx := sch.NewTx()
+	if _, err := tx.DeleteAsQuads(old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.WriteAsQuads(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Apply(w); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := loadNames(); !reflect.DeepEqual(got, []string{"Alice", "Bob"}) {
+		t.Fatalf("transaction was partially applied: %v", got)
+	}
+
+	tx = sch.NewTx()
+	if _, err := tx.DeleteAsQuads(old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.WriteAsQuads(person{ID: "bob", Name: "Robert"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Apply(w); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadNames(); !reflect.DeepEqual(got, []string{"Alice", "Robert"}) {
+		t.Fatalf("unexpected objects after update: %v", got)
+	}
+
+	// objects without an id cannot be deleted
+	tx = sch.NewTx()
+	_, err = tx.DeleteAsQuads(person{Name: "Robert"})
+	if exp := (schema.ErrReqFieldNotSet{Field: "ID"}); err != exp {
+		t.Fatalf("unexpected error: %v, expected: %v", err, exp)
+	}
+}
+
+type multiID struct {
+	IRI  quad.IRI `quad:"@id,priority=1"`
+	Key  string   `quad:"@id,priority=2"`
+	Name string   `quad:"name"`
+}
+
+func TestIDPriority(t *testing.T) {
+	sch := schema.NewConfig()
+	sch.GenerateID = func(_ interface{}) quad.Value {
+		return quad.BNode("gen")
+	}
+	for _, c := range []struct {
+		obj    multiID
+		expect quad.Value
+	}{
+		{obj: multiID{IRI: "primary", Key: "fallback", Name: "a"}, expect: iri("primary")},
+		{obj: multiID{Key: "fallback", Name: "b"}, expect: iri("fallback")},
+		{obj: multiID{Name: "c"}, expect: quad.BNode("gen")},
+	} {
+		var out quadSlice
+		id, err := sch.WriteAsQuads(&out, c.obj)
+		if err != nil {
+			t.Fatal(err)
+		} else if id != c.expect {
+			t.Errorf("unexpected id for %#v: %v, expected: %v", c.obj, id, c.expect)
+		}
+		expect := []quad.Quad{
+			{Subject: c.expect, Predicate: iri("name"), Object: quad.String(c.obj.Name)},
+		}
+		if !reflect.DeepEqual([]quad.Quad(out), expect) {
+			t.Errorf("unexpected quads:\n%v\nexpected:\n%v", out, expect)
+		}
+	}
+
+	qs := memstore.New(quad.Quad{Subject: iri("primary"), Predicate: iri("name"), Object: quad.String("a")})
+	var got multiID
+	if err := sch.LoadTo(nil, qs, &got, iri("primary")); err != nil {
+		t.Fatal(err)
+	}
+	expect := multiID{IRI: "primary", Name: "a"}
+	if got != expect {
+		t.Errorf("unexpected object: %#v, expected: %#v", got, expect)
+	}
+}
+
+// nilValue is a quad value that is returned as a typed nil by nilNameStore.
+type nilValue struct{}
+
+func (*nilValue) String() string      { return "<nil>" }
+func (*nilValue) Native() interface{} { return nil }
+
+// nilNameStore returns a typed nil from NameOf for values listed in nils.
+type nilNameStore struct {
+	graph.QuadStore
+	nils map[string]struct{}
+}
+
+func (qs nilNameStore) NameOf(v graph.Value) quad.Value {
+	name := qs.QuadStore.NameOf(v)
+	if name == nil {
+		return nil
+	} else if _, ok := qs.nils[name.String()]; ok {
+		return (*nilValue)(nil)
+	}
+	return name
+}
+
+func TestLoadTypedNilName(t *testing.T) {
+	type person struct {
+		ID    quad.IRI   `quad:"@id"`
+		Names []string   `quad:"name,req"`
+		Knows []quad.IRI `quad:"knows,idonly"`
+	}
+	qs := nilNameStore{
+		QuadStore: memstore.New(
+			quad.Quad{Subject: iri("bob"), Predicate: iri("name"), Object: quad.String("Bob")},
+			quad.Quad{Subject: iri("bob"), Predicate: iri("name"), Object: quad.String("broken")},
+			quad.Quad{Subject: iri("bob"), Predicate: iri("knows"), Object: iri("alice")},
+			quad.Quad{Subject: iri("bob"), Predicate: iri("knows"), Object: iri("ghost")},
+		),
+		nils: map[string]struct{}{
+			`"broken"`: {},
+			"<ghost>":  {},
+		},
+	}
+	var p person
+	if err := schema.NewConfig().LoadTo(nil, qs, &p, iri("bob")); err != nil {
+		t.Fatal(err)
+	}
+	expect := person{ID: "bob", Names: []string{"Bob"}, Knows: []quad.IRI{"alice"}}
+	if !reflect.DeepEqual(p, expect) {
+		t.Errorf("unexpected object: %#v, expected: %#v", p, expect)
+	}
+}
+
+// optimizeSpyStore counts calls to OptimizeIterator.
+type optimizeSpyStore struct {
+	graph.QuadStore
+	calls int
+}
+
+func (qs *optimizeSpyStore) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool) {
+	qs.calls++
+	return qs.QuadStore.OptimizeIterator(it)
+}
+
+func TestConfigOptimize(t *testing.T) {
+	type person struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+	}
+	qs := &optimizeSpyStore{QuadStore: memstore.New(
+		quad.Quad{Subject: iri("bob"), Predicate: iri("name"), Object: quad.String("Bob")},
+	)}
+	for _, c := range []struct {
+		name     string
+		optimize *bool
+		expect   bool
+	}{
+		{name: "default", expect: schema.Optimize},
+		{name: "disabled", optimize: new(bool)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			qs.calls = 0
+			sch := schema.NewConfig()
+			sch.Optimize = c.optimize
+			var arr []person
+			if err := sch.LoadTo(nil, qs, &arr); err != nil {
+				t.Fatal(err)
+			} else if len(arr) != 1 {
+				t.Fatalf("unexpected objects: %v", arr)
+			}
+			if optimized := qs.calls != 0; optimized != c.expect {
+				t.Errorf("unexpected optimization: %v, expected: %v", optimized, c.expect)
+			}
+		})
+	}
+}
+
+func TestLoadToAsOf(t *testing.T) {
+	type person struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+		Tags []string `quad:"tag"`
+	}
+	day := func(y int) time.Time {
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	qs := memstore.New(
+		quad.Quad{Subject: iri("bob"), Predicate: iri("name"), Object: quad.String("Bob"), Label: quad.Time(day(2010))},
+		quad.Quad{Subject: iri("bob"), Predicate: iri("name"), Object: quad.String("Robert"), Label: quad.String(day(2015).Format(time.RFC3339))},
+		quad.Quad{Subject: iri("bob"), Predicate: iri("tag"), Object: quad.String("a"), Label: quad.Time(day(2010))},
+		quad.Quad{Subject: iri("bob"), Predicate: iri("tag"), Object: quad.String("b"), Label: quad.Time(day(2012))},
+		quad.Quad{Subject: iri("bob"), Predicate: iri("tag"), Object: quad.String("c"), Label: quad.Time(day(2012))},
+	)
+	sch := schema.NewConfig()
+	for _, c := range []struct {
+		asOf   time.Time
+		expect *person
+	}{
+		{asOf: day(2005)},
+		{asOf: day(2011), expect: &person{ID: "bob", Name: "Bob", Tags: []string{"a"}}},
+		{asOf: day(2012), expect: &person{ID: "bob", Name: "Bob", Tags: []string{"b", "c"}}},
+		{asOf: day(2020), expect: &person{ID: "bob", Name: "Robert", Tags: []string{"b", "c"}}},
+	} {
+		var p person
+		err := sch.LoadToAsOf(nil, qs, &p, c.asOf, iri("bob"))
+		if c.expect == nil {
+			if !schema.IsNotFound(err) {
+				t.Errorf("expected not found error as of %v, got: %v", c.asOf, err)
+			}
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(p.Tags)
+		if !reflect.DeepEqual(p, *c.expect) {
+			t.Errorf("unexpected object as of %v: %#v, expected: %#v", c.asOf, p, *c.expect)
+		}
+	}
+}
+
+func TestLoadToOpts(t *testing.T) {
+	type person struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+		Age  int      `quad:"age"`
+	}
+	qs := memstore.New(
+		quad.Make(iri("bob"), iri("name"), "Bob", nil),
+		quad.Make(iri("alice"), iri("name"), "Alice", nil),
+		quad.Make(iri("alice"), iri("age"), "twenty", nil),
+	)
+	sch := schema.NewConfig()
+
+	var p person
+	if err := sch.LoadTo(nil, qs, &p, iri("bob")); !schema.IsNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+	p = person{}
+	err := sch.LoadToOpts(nil, qs, &p, schema.LoadOptions{IgnoreMissingRequired: true}, iri("bob"))
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := (person{ID: "bob", Name: "Bob"}); p != exp {
+		t.Errorf("unexpected object: %#v, expected: %#v", p, exp)
+	}
+
+	p = person{}
+	err = sch.LoadToOpts(nil, qs, &p, schema.LoadOptions{IgnoreMissingRequired: true}, iri("alice"))
+	if err == nil {
+		t.Fatal("expected an error for a field of a wrong type")
+	}
+	p = person{}
+	err = sch.LoadToOpts(nil, qs, &p, schema.LoadOptions{PartialResults: true}, iri("alice"))
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := (person{ID: "alice", Name: "Alice"}); p != exp {
+		t.Errorf("unexpected object: %#v, expected: %#v", p, exp)
+	}
+}
+
+// cancelWriter cancels a context after a given number of quads is written.
+type cancelWriter struct {
+	quadSlice
+	n      int
+	cancel func()
+}
+
+func (w *cancelWriter) WriteQuad(q quad.Quad) error {
+	if err := w.quadSlice.WriteQuad(q); err != nil {
+		return err
+	}
+	if len(w.quadSlice) == w.n {
+		w.cancel()
+	}
+	return nil
+}
+
+func TestNamespacesCancel(t *testing.T) {
+	sch := schema.NewConfig()
+	var ns voc.Namespaces
+	ns.Register(voc.Namespace{Full: "http://example.org/", Prefix: "ex:"})
+	ns.Register(voc.Namespace{Full: "http://cayley.io/", Prefix: "c:"})
+	ns.Register(voc.Namespace{Full: "http://schema.org/", Prefix: "sc:"})
+
+	var full quadSlice
+	if err := sch.WriteNamespaces(context.TODO(), &full, &ns); err != nil {
+		t.Fatal(err)
+	}
+	perNS := len(full) / 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelWriter{n: perNS, cancel: cancel}
+	if err := sch.WriteNamespaces(ctx, w, &ns); err != context.Canceled {
+		t.Fatalf("expected cancellation error, got: %v", err)
+	}
+	if len(w.quadSlice) != perNS {
+		t.Fatalf("unexpected quads written after cancellation: %v", w.quadSlice)
+	}
+	for _, q := range w.quadSlice {
+		if q.Subject != iri("http://cayley.io/") {
+			t.Errorf("unexpected quad: %v", q)
+		}
+	}
+
+	var ns2 voc.Namespaces
+	err := sch.LoadNamespaces(ctx, memstore.New(full...), &ns2)
+	if err == nil {
+		t.Fatal("expected cancellation error")
+	} else if got := ns2.List(); len(got) != 0 {
+		t.Errorf("unexpected namespaces loaded: %v", got)
+	}
+}
+
+func TestSaveNamespaces(t *testing.T) {
+	sch := schema.NewConfig()
+	save := []voc.Namespace{
+		{Full: "http://example.org/", Prefix: "ex:"},
+		{Full: "http://cayley.io/", Prefix: "c:"},
+	}
+	var ns voc.Namespaces
+	for _, n := range save {
+		ns.Register(n)
+	}
+	qs := memstore.New()
+	err := sch.WriteNamespaces(context.TODO(), qs, &ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ns2 voc.Namespaces
+	err = sch.LoadNamespaces(context.TODO(), qs, &ns2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ns2.List()
+	sort.Sort(voc.ByFullName(save))
+	sort.Sort(voc.ByFullName(got))
+	if !reflect.DeepEqual(save, got) {
+		t.Fatalf("wrong namespaces returned: got: %v, expect: %v", got, save)
+	}
+	qr := graph.NewQuadStoreReader(qs)
+	q, err := quad.ReadAll(qr)
+	qr.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []quad.Quad{
+		quad.MakeIRI("http://cayley.io/", "cayley:prefix", "c:", ""),
+		quad.MakeIRI("http://cayley.io/", "rdf:type", "cayley:namespace", ""),
+
+		quad.MakeIRI("http://example.org/", "cayley:prefix", "ex:", ""),
+		quad.MakeIRI("http://example.org/", "rdf:type", "cayley:namespace", ""),
+	}
+	sort.Sort(quad.ByQuadString(expect))
+	sort.Sort(quad.ByQuadString(q))
+	if !reflect.DeepEqual(expect, q) {
+		t.Fatalf("wrong quads returned: got: %v, expect: %v", q, expect)
+	}
+}
+
+func TestWriteValidateIRIs(t *testing.T) {
+	type node struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"http://example.org/name"`
+		Link quad.IRI `quad:"http://example.org/link,optional"`
+	}
+	sch := schema.NewConfig()
+	obj := node{ID: "http://example.org/bad id", Name: "a"}
+
+	var out quadSlice
+	if _, err := sch.WriteAsQuads(&out, obj); err != nil {
+		t.Fatal("unexpected error without validation:", err)
+	}
+
+	sch.ValidateIRIs = true
+	out = nil
+	_, err := sch.WriteAsQuads(&out, obj)
+	if e, ok := err.(schema.ErrInvalidIRI); !ok {
+		t.Fatalf("expected invalid IRI error, got: %v", err)
+	} else if e.IRI != obj.ID || e.Dir != quad.Subject {
+		t.Fatalf("unexpected error: %v", e)
+	}
+	if len(out) != 0 {
+		t.Fatalf("unexpected quads written: %v", out)
+	}
+
+	out = nil
+	_, err = sch.WriteAsQuads(&out, node{ID: "http://example.org/a", Name: "a", Link: "no scheme"})
+	if e, ok := err.(schema.ErrInvalidIRI); !ok || e.Dir != quad.Object {
+		t.Fatalf("expected invalid IRI error, got: %v", err)
+	}
+
+	out = nil
+	_, err = sch.WriteAsQuads(&out, node{ID: "http://example.org/a", Name: "a", Link: "http://example.org/b"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(out) != 2 {
+		t.Fatalf("unexpected quads written: %v", out)
+	}
+}
+
+func TestWriteStrictIRIs(t *testing.T) {
+	type node struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"http://example.org/name"`
+		Link quad.IRI `quad:"http://example.org/link,optional"`
+	}
+	sch := schema.NewConfig()
+	sch.IRIs = schema.IRIShort
+	sch.StrictIRIs = true
+
+	var out quadSlice
+	_, err := sch.WriteAsQuads(&out, node{ID: "http://example.org/a", Name: "a", Link: "http://unknown.example/b"})
+	if e, ok := err.(schema.ErrIRINotNormalized); !ok {
+		t.Fatalf("expected IRI normalization error, got: %v", err)
+	} else if e.IRI != "http://unknown.example/b" || e.Dir != quad.Object {
+		t.Fatalf("unexpected error: %v", e)
+	}
+
+	out = nil
+	_, err = sch.WriteAsQuads(&out, node{ID: "http://example.org/a", Name: "a", Link: "http://example.org/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []quad.Quad{
+		{Subject: iri("ex:a"), Predicate: iri("ex:name"), Object: quad.String("a")},
+		{Subject: iri("ex:a"), Predicate: iri("ex:link"), Object: iri("http://example.org/b")},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), expect) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, expect)
+	}
+
+	// non-strict mode writes IRIs as-is
+	sch.StrictIRIs = false
+	out = nil
+	_, err = sch.WriteAsQuads(&out, node{ID: "http://example.org/a", Name: "a", Link: "http://unknown.example/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteChildID(t *testing.T) {
+	type address struct {
+		City string `quad:"city"`
+	}
+	type person struct {
+		ID   quad.IRI `quad:"@id"`
+		Home address  `quad:"home"`
+		Work *address `quad:"work,optional"`
+	}
+	sch := schema.NewConfig()
+	sch.ChildID = func(parent quad.Value, field string, child interface{}) quad.Value {
+		if _, ok := child.(address); !ok {
+			return nil
+		}
+		return iri(string(parent.(quad.IRI)) + "/" + field)
+	}
+	sch.GenerateID = func(_ interface{}) quad.Value {
+		return quad.BNode("gen")
+	}
+
+	var out quadSlice
+	id, err := sch.WriteAsQuads(&out, person{
+		ID:   "bob",
+		Home: address{City: "Paris"},
+		Work: &address{City: "Lyon"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != iri("bob") {
+		t.Fatalf("unexpected id: %v", id)
+	}
+	expect := []quad.Quad{
+		{Subject: iri("bob/Home"), Predicate: iri("city"), Object: quad.String("Paris")},
+		{Subject: iri("bob"), Predicate: iri("home"), Object: iri("bob/Home")},
+		{Subject: iri("bob/Work"), Predicate: iri("city"), Object: quad.String("Lyon")},
+		{Subject: iri("bob"), Predicate: iri("work"), Object: iri("bob/Work")},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), expect) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, expect)
+	}
+
+	// top-level objects without an ID are not affected
+	out = nil
+	id, err = sch.WriteAsQuads(&out, address{City: "Rome"})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != quad.BNode("gen") {
+		t.Fatalf("unexpected id: %v", id)
+	}
+}
+
+func TestResolveStringID(t *testing.T) {
+	type node struct {
+		ID   string `quad:"@id"`
+		Name string `quad:"name"`
+	}
+	sch := schema.NewConfig()
+
+	var out quadSlice
+	sch.ResolveStringID = func(id string) quad.Value {
+		return quad.IRI("base:" + id)
+	}
+	id, err := sch.WriteAsQuads(&out, node{ID: "alice", Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != quad.IRI("base:alice") {
+		t.Fatalf("unexpected id: %#v", id)
+	} else if len(out) != 1 || out[0].Subject != id {
+		t.Fatalf("unexpected quads written: %v", out)
+	}
+
+	sch.ResolveStringID = func(id string) quad.Value {
+		if strings.HasPrefix(id, "_:") {
+			return quad.BNode(id[2:])
+		}
+		return quad.IRI(id)
+	}
+	for _, c := range []struct {
+		id  string
+		exp quad.Value
+	}{
+		{id: "_:b1", exp: quad.BNode("b1")},
+		{id: "bob", exp: quad.IRI("bob")},
+	} {
+		out = nil
+		id, err = sch.WriteAsQuads(&out, node{ID: c.id, Name: "Name"})
+		if err != nil {
+			t.Fatal(err)
+		} else if id != c.exp {
+			t.Errorf("unexpected id for %q: %#v", c.id, id)
+		} else if len(out) != 1 || out[0].Subject != c.exp {
+			t.Errorf("unexpected quads written: %v", out)
+		}
+	}
+}
+
+func TestStringIDsAsIRI(t *testing.T) {
+	type node struct {
+		ID   string `quad:"@id"`
+		Name string `quad:"name"`
+	}
+	sch := schema.NewConfig()
+
+	var out quadSlice
+	id, err := sch.WriteAsQuads(&out, node{ID: "bob", Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != quad.IRI("bob") {
+		t.Fatalf("unexpected id: %#v", id)
+	}
+
+	_, err = sch.WriteAsQuads(&out, node{ID: "bob smith", Name: "Bob"})
+	if _, ok := err.(schema.ErrInvalidID); !ok {
+		t.Fatalf("expected invalid id error, got: %v", err)
+	}
+
+	no := false
+	sch.StringIDsAsIRI = &no
+	out = nil
+	id, err = sch.WriteAsQuads(&out, node{ID: "bob smith", Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != quad.String("bob smith") {
+		t.Fatalf("unexpected id: %#v", id)
+	} else if len(out) != 1 || out[0].Subject != id {
+		t.Fatalf("unexpected quads written: %v", out)
+	}
+}
+
+type chainNode struct {
+	ID   quad.IRI   `quad:"@id"`
+	Next *chainNode `quad:"next,optional"`
+}
+
+func TestWriteMaxDepth(t *testing.T) {
+	var root *chainNode
+	for i := 5; i > 0; i-- {
+		root = &chainNode{ID: quad.IRI(fmt.Sprintf("n%d", i)), Next: root}
+	}
+	sch := schema.NewConfig()
+
+	var out quadSlice
+	if _, err := sch.WriteAsQuads(&out, root); err != nil {
+		t.Fatal("unexpected error without limit:", err)
+	} else if len(out) != 4 {
+		t.Fatalf("unexpected quads written: %v", out)
+	}
+
+	sch.MaxWriteDepth = 5
+	out = nil
+	if _, err := sch.WriteAsQuads(&out, root); err != nil {
+		t.Fatal("unexpected error with a limit equal to depth:", err)
+	}
+
+	sch.MaxWriteDepth = 3
+	out = nil
+	_, err := sch.WriteAsQuads(&out, root)
+	if e, ok := err.(schema.ErrMaxDepthExceeded); !ok {
+		t.Fatalf("expected max depth error, got: %v", err)
+	} else if e.Depth != 3 {
+		t.Fatalf("unexpected error: %v", e)
+	}
+
+	// slice elements are on the same level
+	type list struct {
+		ID    quad.IRI    `quad:"@id"`
+		Items []chainNode `quad:"item"`
+	}
+	sch.MaxWriteDepth = 2
+	out = nil
+	_, err = sch.WriteAsQuads(&out, list{ID: "l", Items: []chainNode{{ID: "a"}, {ID: "b"}, {ID: "c"}}})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(out) != 3 {
+		t.Fatalf("unexpected quads written: %v", out)
+	}
+}
+
+type uniqPerson struct {
+	ID    quad.IRI `quad:"@id"`
+	Name  string   `quad:"name"`
+	Email string   `quad:"email"`
+}
+
+func TestEnsureUnique(t *testing.T) {
+	sch := schema.NewConfig()
+	qs := memstore.New()
+	for _, p := range []uniqPerson{
+		{ID: "alice", Name: "Alice", Email: "alice@example.org"},
+		{ID: "bob", Name: "Bob", Email: "bob@example.org"},
+	} {
+		if _, err := sch.WriteAsQuads(qs, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// same values, but not a person
+	qs.AddQuad(quad.Quad{Subject: iri("dan"), Predicate: iri("email"), Object: quad.String("dan@example.org")})
+
+	for _, c := range []struct {
+		name   string
+		obj    uniqPerson
+		fields []string
+		dup    quad.Value
+	}{
+		{
+			name:   "unique field",
+			obj:    uniqPerson{ID: "carol", Name: "Alice", Email: "carol@example.org"},
+			fields: []string{"Email"},
+		},
+		{
+			name:   "duplicate field",
+			obj:    uniqPerson{ID: "carol", Name: "Carol", Email: "alice@example.org"},
+			fields: []string{"Email"},
+			dup:    iri("alice"),
+		},
+		{
+			name:   "same object",
+			obj:    uniqPerson{ID: "alice", Name: "Alice", Email: "alice@example.org"},
+			fields: []string{"Email"},
+		},
+		{
+			name:   "other type",
+			obj:    uniqPerson{ID: "carol", Name: "Carol", Email: "dan@example.org"},
+			fields: []string{"Email"},
+		},
+		{
+			name:   "unique pair",
+			obj:    uniqPerson{ID: "carol", Name: "Alice", Email: "bob@example.org"},
+			fields: []string{"Name", "Email"},
+		},
+		{
+			name:   "duplicate pair",
+			obj:    uniqPerson{ID: "carol", Name: "Bob", Email: "bob@example.org"},
+			fields: []string{"Name", "Email"},
+			dup:    iri("bob"),
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := sch.EnsureUnique(nil, qs, c.obj, c.fields...)
+			if c.dup == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if e, ok := err.(schema.ErrDuplicate); !ok {
+				t.Fatalf("expected duplicate error, got: %v", err)
+			} else if e.ID != c.dup {
+				t.Fatalf("unexpected duplicate: %v", e.ID)
+			}
+		})
+	}
+	if err := sch.EnsureUnique(nil, qs, uniqPerson{ID: "carol"}, "Email"); err == nil {
+		t.Fatal("expected an error for an empty field")
+	}
+}
+
+type inferredTag struct {
+	ID   quad.IRI `quad:"@id"`
+	Name string   `quad:"name"`
+}
+
+func TestRegisterTypeNoWrite(t *testing.T) {
+	sch := schema.NewConfig()
+	var out quadSlice
+	id, err := sch.WriteAsQuads(&out, inferredTag{ID: "t1", Name: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []quad.Quad{
+		{id, iri("name"), quad.String("go"), nil},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), exp) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, exp)
+	}
+
+	// type triple is still required on load
+	qs := memstore.New(out...)
+	var tag inferredTag
+	if err := sch.LoadTo(nil, qs, &tag, id); !schema.IsNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+
+	// when it's inferred by other means, the object can be loaded
+	qs = memstore.New(append(out, quad.Quad{id, typeIRI, quad.IRI("ex:Tag"), nil})...)
+	if err := sch.LoadTo(nil, qs, &tag, id); err != nil {
+		t.Fatal(err)
+	} else if exp := (inferredTag{ID: "t1", Name: "go"}); tag != exp {
+		t.Errorf("unexpected object: %#v, expected: %#v", tag, exp)
+	}
+}
+
+func TestWriteAsQuadsWith(t *testing.T) {
+	type city struct {
+		Name string `quad:"name"`
+	}
+	type trip struct {
+		From city  `quad:"from"`
+		To   *city `quad:"to"`
+	}
+	sch := schema.NewConfig()
+	sch.GenerateID = func(_ interface{}) quad.Value {
+		return quad.BNode("default")
+	}
+	n := 0
+	gen := func(o interface{}) quad.Value {
+		n++
+		return quad.BNode(fmt.Sprintf("id%d", n))
+	}
+
+	var out quadSlice
+	id, err := sch.WriteAsQuadsWith(&out, trip{From: city{Name: "Paris"}, To: &city{Name: "Rome"}}, gen)
+	if err != nil {
+		t.Fatal(err)
+	} else if id != quad.BNode("id1") {
+		t.Fatalf("unexpected id: %v", id)
+	}
+	expect := []quad.Quad{
+		{Subject: quad.BNode("id2"), Predicate: iri("name"), Object: quad.String("Paris")},
+		{Subject: quad.BNode("id1"), Predicate: iri("from"), Object: quad.BNode("id2")},
+		{Subject: quad.BNode("id3"), Predicate: iri("name"), Object: quad.String("Rome")},
+		{Subject: quad.BNode("id1"), Predicate: iri("to"), Object: quad.BNode("id3")},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), expect) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, expect)
+	}
+
+	// config default is not affected
+	out = nil
+	id, err = sch.WriteAsQuads(&out, city{Name: "Rome"})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != quad.BNode("default") {
+		t.Fatalf("unexpected id: %v", id)
+	}
+}
+
+type withUnexported struct {
+	ID     quad.IRI `quad:"@id"`
+	Name   string   `quad:"name"`
+	secret string   `quad:"secret"`
+}
+
+type withUnexportedNoTag struct {
+	ID     quad.IRI `quad:"@id"`
+	Name   string   `quad:"name"`
+	secret string
+}
+
+func TestUnexportedFields(t *testing.T) {
+	sch := schema.NewConfig()
+	var out quadSlice
+	_, err := sch.WriteAsQuads(&out, withUnexported{ID: "a", Name: "A", secret: "s"})
+	if err == nil || !strings.Contains(err.Error(), "unexported field secret") {
+		t.Fatalf("expected an error about unexported field, got: %v", err)
+	}
+	qs := memstore.New(quad.Quad{iri("a"), iri("name"), quad.String("A"), nil})
+	var v withUnexported
+	err = sch.LoadTo(nil, qs, &v, iri("a"))
+	if err == nil || !strings.Contains(err.Error(), "unexported field secret") {
+		t.Fatalf("expected an error about unexported field, got: %v", err)
+	}
+
+	// fields without tags are ignored
+	out = nil
+	_, err = sch.WriteAsQuads(&out, withUnexportedNoTag{ID: "a", Name: "A", secret: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v2 withUnexportedNoTag
+	if err = sch.LoadTo(nil, memstore.New(out...), &v2, iri("a")); err != nil {
+		t.Fatal(err)
+	} else if exp := (withUnexportedNoTag{ID: "a", Name: "A"}); v2 != exp {
+		t.Errorf("unexpected object: %#v, expected: %#v", v2, exp)
+	}
+}
+
+type withAddressList struct {
+	ID        quad.IRI   `quad:"@id"`
+	Name      string     `quad:"name"`
+	Addresses []*address `quad:"address"`
+	Refs      []*address `quad:"ref,idonly"`
+}
+
+func TestSliceOfPointers(t *testing.T) {
+	sch := schema.NewConfig()
+	var out quadSlice
+	obj := withAddressList{ID: "bob", Name: "Bob", Addresses: []*address{
+		{ID: "a1", Street: "Main", City: "Springfield"},
+		nil,
+		{ID: "a2", Street: "Elm", City: "Shelbyville"},
+	}, Refs: []*address{nil, {ID: "a3", Street: "Oak", City: "Ogdenville"}}}
+	id, err := sch.WriteAsQuads(&out, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []quad.Quad{
+		{iri("bob"), iri("name"), quad.String("Bob"), nil},
+		{iri("a1"), iri("street"), quad.String("Main"), nil},
+		{iri("a1"), iri("city"), quad.String("Springfield"), nil},
+		{iri("bob"), iri("address"), iri("a1"), nil},
+		{iri("a2"), iri("street"), quad.String("Elm"), nil},
+		{iri("a2"), iri("city"), quad.String("Shelbyville"), nil},
+		{iri("bob"), iri("address"), iri("a2"), nil},
+		{iri("a3"), iri("street"), quad.String("Oak"), nil},
+		{iri("a3"), iri("city"), quad.String("Ogdenville"), nil},
+		{iri("bob"), iri("ref"), iri("a3"), nil},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), exp) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, exp)
+	}
+
+	var got withAddressList
+	if err = sch.LoadTo(nil, memstore.New(out...), &got, id); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(got.Addresses, func(i, j int) bool {
+		return got.Addresses[i].ID < got.Addresses[j].ID
+	})
+	expObj := withAddressList{ID: "bob", Name: "Bob", Addresses: []*address{
+		{ID: "a1", Street: "Main", City: "Springfield"},
+		{ID: "a2", Street: "Elm", City: "Shelbyville"},
+	}, Refs: []*address{{ID: "a3"}}}
+	if !reflect.DeepEqual(got, expObj) {
+		t.Errorf("unexpected object:\n%#v\nexpected:\n%#v", got, expObj)
+	}
+	if got.Addresses[0] == got.Addresses[1] {
+		t.Errorf("expected a new pointer for each element")
+	}
+
+	// empty slice
+	out = nil
+	id, err = sch.WriteAsQuads(&out, withAddressList{ID: "alice", Name: "Alice", Addresses: []*address{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = []quad.Quad{
+		{iri("alice"), iri("name"), quad.String("Alice"), nil},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), exp) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, exp)
+	}
+	got = withAddressList{}
+	if err = sch.LoadTo(nil, memstore.New(out...), &got, id); err != nil {
+		t.Fatal(err)
+	} else if expObj := (withAddressList{ID: "alice", Name: "Alice"}); !reflect.DeepEqual(got, expObj) {
+		t.Errorf("unexpected object:\n%#v\nexpected:\n%#v", got, expObj)
+	}
+}
+
+type withName struct {
+	ID   quad.IRI `quad:"@id"`
+	Name string   `quad:"name"`
+}
+
+func TestOnMultipleValues(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("first")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("second")},
+	)
+	// values are sorted, thus the result does not depend on the order of the store
+	rqs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("second")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("first")},
+	)
+	for _, c := range []struct {
+		policy schema.MultiValuePolicy
+		expect string
+	}{
+		{policy: schema.MultipleValuesLastWins, expect: "second"},
+		{policy: schema.MultipleValuesFirstWins, expect: "first"},
+	} {
+		sch := schema.NewConfig()
+		sch.OnMultipleValues = c.policy
+		for _, qs := range []graph.QuadStore{qs, rqs} {
+			var out withName
+			if err := sch.LoadTo(nil, qs, &out, iri("a")); err != nil {
+				t.Fatal(err)
+			} else if out.Name != c.expect {
+				t.Errorf("unexpected value for policy %v: %q, expected: %q", c.policy, out.Name, c.expect)
+			}
+		}
+	}
+
+	sch := schema.NewConfig()
+	sch.OnMultipleValues = schema.MultipleValuesError
+	var out withName
+	err := sch.LoadTo(nil, qs, &out, iri("a"))
+	exp := schema.ErrMultipleValues{
+		ID:     iri("a"),
+		Field:  "Name",
+		Values: []quad.Value{quad.String("first"), quad.String("second")},
+	}
+	if e, ok := err.(schema.ErrMultipleValues); !ok {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if !reflect.DeepEqual(e, exp) {
+		t.Errorf("unexpected error: %#v, expected: %#v", e, exp)
+	}
+
+	// slice fields are not affected
+	var list struct {
+		ID    quad.IRI `quad:"@id"`
+		Names []string `quad:"name"`
+	}
+	if err := sch.LoadTo(nil, qs, &list, iri("a")); err != nil {
+		t.Fatal(err)
+	} else if len(list.Names) != 2 {
+		t.Errorf("unexpected values: %v", list.Names)
+	}
+}
+
+func TestWriteAsQuadsLabeled(t *testing.T) {
+	type city struct {
+		rdfType struct{} `quad:"@type > ex:City"`
+		ID      quad.IRI `quad:"@id"`
+		Name    string   `quad:"name"`
+	}
+	type trip struct {
+		ID   quad.IRI `quad:"@id"`
+		From city     `quad:"from"`
+	}
+	sch := schema.NewConfig()
+	sch.Label = quad.IRI("default")
+
+	var out quadSlice
+	_, err := sch.WriteAsQuadsLabeled(&out, trip{ID: "t1", From: city{ID: "paris", Name: "Paris"}}, quad.IRI("source.nq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []quad.Quad{
+		{Subject: iri("paris"), Predicate: typeIRI, Object: iri("ex:City"), Label: quad.IRI("source.nq")},
+		{Subject: iri("paris"), Predicate: iri("name"), Object: quad.String("Paris"), Label: quad.IRI("source.nq")},
+		{Subject: iri("t1"), Predicate: iri("from"), Object: iri("paris"), Label: quad.IRI("source.nq")},
+	}
+	if !reflect.DeepEqual([]quad.Quad(out), expect) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, expect)
+	}
+
+	// config default is not affected
+	out = nil
+	if _, err = sch.WriteAsQuads(&out, city{ID: "rome", Name: "Rome"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range out {
+		if q.Label != quad.IRI("default") {
+			t.Errorf("unexpected label: %v", q)
+		}
+	}
+}
+
+type depthNode struct {
+	ID    quad.IRI   `quad:"@id"`
+	Name  string     `quad:"name"`
+	Next  *depthNode `quad:"next,opt"`
+	Owner *depthNode `quad:"owner,opt,idonly"`
+}
+
+func TestStrictDepth(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("name"), Object: quad.String("B")},
+		quad.Quad{Subject: iri("c"), Predicate: iri("name"), Object: quad.String("C")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("next"), Object: iri("b")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("next"), Object: iri("c")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("owner"), Object: iri("c")},
+	)
+	sch := schema.NewConfig()
+
+	// truncation is silent by default
+	var out depthNode
+	if err := sch.LoadToDepth(nil, qs, &out, 0, iri("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	sch.StrictDepth = true
+	for _, c := range []struct {
+		depth  int
+		expect []string
+	}{
+		{depth: 0, expect: []string{"Next"}},
+		{depth: 1, expect: []string{"Next.Next"}},
+		{depth: 2, expect: nil},
+		{depth: -1, expect: nil},
+	} {
+		var out depthNode
+		err := sch.LoadToDepth(nil, qs, &out, c.depth, iri("a"))
+		if c.expect == nil {
+			if err != nil {
+				t.Errorf("unexpected error for depth %d: %v", c.depth, err)
+			}
+			continue
+		}
+		if e, ok := err.(schema.ErrDepthTruncated); !ok {
+			t.Errorf("unexpected error for depth %d: %v", c.depth, err)
+		} else if !reflect.DeepEqual(e.Fields, c.expect) {
+			t.Errorf("unexpected truncated fields for depth %d: %v, expected: %v", c.depth, e.Fields, c.expect)
+		}
+		// values up to the limit are still loaded
+		if out.Name != "A" || out.Next == nil || out.Next.ID != iri("b") {
+			t.Errorf("unexpected object for depth %d: %#v", c.depth, out)
+		}
+	}
+}
+
+type failingChild struct {
+	ID   quad.IRI `quad:"@id"`
+	Name string   `quad:"name"`
+}
+
+func (failingChild) BeforeWrite(c *schema.Config) error {
+	return errors.New("child write failed")
+}
+
+type withFailingChild struct {
+	ID    quad.IRI     `quad:"@id"`
+	Name  string       `quad:"name"`
+	Child failingChild `quad:"child"`
+}
+
+// storeWriter writes quads to a quad store immediately. Batches are applied in a single transaction.
+type storeWriter struct {
+	qs      graph.QuadStore
+	batches int
+}
+
+func (w *storeWriter) WriteQuad(q quad.Quad) error {
+	return w.qs.ApplyDeltas([]graph.Delta{{Quad: q, Action: graph.Add}}, graph.IgnoreOpts{})
+}
+
+func (w *storeWriter) WriteQuads(quads []quad.Quad) (int, error) {
+	w.batches++
+	tx := graph.NewTransactionN(len(quads))
+	for _, q := range quads {
+		tx.AddQuad(q)
+	}
+	if err := w.qs.ApplyDeltas(tx.Deltas, graph.IgnoreOpts{}); err != nil {
+		return 0, err
+	}
+	return len(quads), nil
+}
+
+func TestWriteAtomic(t *testing.T) {
+	obj := withFailingChild{ID: "a", Name: "A", Child: failingChild{ID: "b", Name: "B"}}
+
+	// partial object is written by default
+	qs := memstore.New()
+	sch := schema.NewConfig()
+	if _, err := sch.WriteAsQuads(&storeWriter{qs: qs}, obj); err == nil {
+		t.Fatal("expected an error")
+	} else if qs.Size() == 0 {
+		t.Fatal("expected a partial write")
+	}
+
+	qs = memstore.New()
+	sch.WriteAtomic = true
+	if _, err := sch.WriteAsQuads(&storeWriter{qs: qs}, obj); err == nil {
+		t.Fatal("expected an error")
+	} else if n := qs.Size(); n != 0 {
+		t.Fatalf("expected no quads to be written, got: %d", n)
+	}
+
+	// successful writes are applied in a single batch
+	w := &storeWriter{qs: qs}
+	id, err := sch.WriteAsQuads(w, withName{ID: "c", Name: "C"})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != iri("c") {
+		t.Errorf("unexpected id: %v", id)
+	} else if w.batches != 1 {
+		t.Errorf("expected a single batch write, got: %d", w.batches)
+	}
+	var out withName
+	if err = sch.LoadTo(nil, qs, &out, iri("c")); err != nil {
+		t.Fatal(err)
+	} else if out.Name != "C" {
+		t.Errorf("unexpected object: %#v", out)
+	}
+}
+
+type withValues struct {
+	ID   quad.IRI     `quad:"@id"`
+	Vals []quad.Value `quad:"val"`
+	One  quad.Value   `quad:"one,opt"`
+}
+
+func TestLoadQuadValues(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("val"), Object: quad.BNode("b1")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("val"), Object: iri("x")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("val"), Object: quad.String("s")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("one"), Object: iri("y")},
+	)
+	sch := schema.NewConfig()
+	// values are not resolved against the base IRI
+	sch.BaseIRI = "http://example.org/"
+
+	var out withValues
+	if err := sch.LoadTo(nil, qs, &out, iri("a")); err != nil {
+		t.Fatal(err)
+	}
+	exp := withValues{
+		ID:   iri("a"),
+		Vals: []quad.Value{quad.BNode("b1"), iri("x"), quad.String("s")},
+		One:  iri("y"),
+	}
+	if !reflect.DeepEqual(out, exp) {
+		t.Errorf("unexpected object:\n%#v\nexpected:\n%#v", out, exp)
+	}
+}
+
+type withFollowers struct {
+	ID           quad.IRI `quad:"@id"`
+	Name         string   `quad:"name"`
+	NumFollowers int      `quad:"follows < *,count"`
+	NumFollows   int64    `quad:"follows,count"`
+}
+
+func TestLoadCount(t *testing.T) {
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("name"), Object: quad.String("B")},
+		quad.Quad{Subject: iri("c"), Predicate: iri("name"), Object: quad.String("C")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("follows"), Object: iri("a")},
+		quad.Quad{Subject: iri("c"), Predicate: iri("follows"), Object: iri("a")},
+		quad.Quad{Subject: iri("d"), Predicate: iri("follows"), Object: iri("a")},
+		quad.Quad{Subject: iri("a"), Predicate: iri("follows"), Object: iri("b")},
+	)
+	sch := schema.NewConfig()
+	var out []withFollowers
+	if err := sch.LoadTo(nil, qs, &out, iri("a"), iri("c")); err != nil {
+		t.Fatal(err)
+	}
+	exp := []withFollowers{
+		{ID: "a", Name: "A", NumFollowers: 3, NumFollows: 1},
+		{ID: "c", Name: "C", NumFollowers: 0, NumFollows: 1},
+	}
+	if !reflect.DeepEqual(out, exp) {
+		t.Errorf("unexpected objects:\n%#v\nexpected:\n%#v", out, exp)
+	}
+
+	// counts are not written
+	var quads quadSlice
+	if _, err := sch.WriteAsQuads(&quads, exp[0]); err != nil {
+		t.Fatal(err)
+	} else if len(quads) != 1 {
+		t.Errorf("unexpected quads: %v", quads)
+	}
+
+	var bad struct {
+		ID    quad.IRI `quad:"@id"`
+		Count string   `quad:"follows,count"`
+	}
+	if err := sch.LoadTo(nil, qs, &bad, iri("a")); err == nil {
+		t.Error("expected an error for a non-integer count field")
+	}
+}
+
+func TestConfigNamespaces(t *testing.T) {
+	type node struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"http://tenant.example/vocab/name"`
+	}
+	type shortNode struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"t1:name"`
+	}
+	var ns1, ns2 voc.Namespaces
+	ns1.Register(voc.Namespace{Prefix: "t1:", Full: "http://tenant.example/vocab/"})
+	ns2.Register(voc.Namespace{Prefix: "t2:", Full: "http://tenant.example/"})
+
+	write := func(ns *voc.Namespaces) quad.Value {
+		sch := schema.NewConfig()
+		sch.IRIs = schema.IRIShort
+		sch.Namespaces = ns
+		var out quadSlice
+		if _, err := sch.WriteAsQuads(&out, node{ID: "http://tenant.example/vocab/a", Name: "a"}); err != nil {
+			t.Fatal(err)
+		} else if len(out) != 1 {
+			t.Fatalf("unexpected quads: %v", out)
+		}
+		return out[0].Predicate
+	}
+	if p := write(&ns1); p != iri("t1:name") {
+		t.Errorf("unexpected predicate: %v", p)
+	}
+	if p := write(&ns2); p != iri("t2:vocab/name") {
+		t.Errorf("unexpected predicate: %v", p)
+	}
+	// global registry doesn't know this namespace
+	if p := write(nil); p != iri("http://tenant.example/vocab/name") {
+		t.Errorf("unexpected predicate: %v", p)
+	}
+
+	// predicates are expanded with local namespaces on load
+	qs := memstore.New(quad.Quad{Subject: iri("http://tenant.example/vocab/a"), Predicate: iri("http://tenant.example/vocab/name"), Object: quad.String("a")})
+	sch := schema.NewConfig()
+	sch.IRIs = schema.IRIFull
+	sch.Namespaces = &ns1
+	var out shortNode
+	if err := sch.LoadTo(nil, qs, &out, iri("http://tenant.example/vocab/a")); err != nil {
+		t.Fatal(err)
+	} else if out.Name != "a" {
+		t.Errorf("unexpected object: %#v", out)
+	}
+}
+
+type withBools struct {
+	ID    quad.IRI `quad:"@id"`
+	Flag  bool     `quad:"flag"`
+	Opt   bool     `quad:"opt,optional"`
+	PFlag *bool    `quad:"pflag,optional"`
+}
+
+func TestBoolFields(t *testing.T) {
+	tr, fl := true, false
+	cases := []struct {
+		obj   withBools
+		quads int
+	}{
+		{withBools{ID: "a", Flag: true, Opt: true, PFlag: &tr}, 3},
+		{withBools{ID: "b", PFlag: &fl}, 3},
+		{withBools{ID: "c"}, 2},
+	}
+	sch := schema.NewConfig()
+	for _, c := range cases {
+		var out quadSlice
+		if _, err := sch.WriteAsQuads(&out, c.obj); err != nil {
+			t.Fatal(err)
+		} else if len(out) != c.quads {
+			t.Errorf("unexpected quads for %v: %v", c.obj.ID, out)
+		}
+		for _, q := range out {
+			if _, ok := q.Object.(quad.Bool); !ok {
+				t.Errorf("expected a bool value, got: %#v", q.Object)
+			}
+		}
+		qs := memstore.New(out...)
+		var got withBools
+		if err := sch.LoadTo(nil, qs, &got, c.obj.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != c.obj.ID || got.Flag != c.obj.Flag || got.Opt != c.obj.Opt {
+			t.Errorf("unexpected object: %+v, expected: %+v", got, c.obj)
+		}
+		if (got.PFlag == nil) != (c.obj.PFlag == nil) {
+			t.Errorf("unexpected pointer field: %v, expected: %v", got.PFlag, c.obj.PFlag)
+		} else if got.PFlag != nil && *got.PFlag != *c.obj.PFlag {
+			t.Errorf("unexpected pointer field value: %v, expected: %v", *got.PFlag, *c.obj.PFlag)
+		}
+	}
+}
+
+type withList struct {
+	ID    quad.IRI   `quad:"@id"`
+	Items []string   `quad:"items,list"`
+	Links []quad.IRI `quad:"links,list"`
+}
+
+func TestListFields(t *testing.T) {
+	sch := schema.NewConfig()
+	objs := []withList{
+		{ID: "a", Items: []string{"c", "a", "b"}, Links: []quad.IRI{"z", "x", "y"}},
+		{ID: "b", Items: []string{}},
+		{ID: "c", Items: []string{"", "x", "x"}},
+	}
+	var out quadSlice
+	for _, o := range objs {
+		if _, err := sch.WriteAsQuads(&out, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qs := memstore.New(out...)
+	for _, exp := range objs {
+		var got withList
+		if err := sch.LoadTo(nil, qs, &got, exp.ID); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("unexpected object:\n%#v\nexpected:\n%#v", got, exp)
+		}
+	}
+
+	var bad struct {
+		ID    quad.IRI `quad:"@id"`
+		Items string   `quad:"items,list"`
+	}
+	if _, err := sch.WriteAsQuads(&quadSlice{}, bad); err == nil {
+		t.Error("expected an error for a non-slice list field")
+	}
+}
+
+func TestLoadToStats(t *testing.T) {
+	type node struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+		Age  int      `quad:"age,optional"`
+	}
+	qs := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("name"), Object: quad.String("B")},
+		quad.Quad{Subject: iri("c"), Predicate: iri("age"), Object: quad.Int(3)},
+	)
+	sch := schema.NewConfig()
+
+	var out []node
+	st, err := sch.LoadToStats(nil, qs, &out, iri("a"), iri("b"), iri("c"), iri("missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (schema.LoadStats{Loaded: 2, Skipped: 1}); st != exp {
+		t.Errorf("unexpected stats: %+v, expected: %+v", st, exp)
+	}
+	exp := []node{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	if !reflect.DeepEqual(out, exp) {
+		t.Errorf("unexpected objects: %v, expected: %v", out, exp)
+	}
+
+	out = nil
+	st, err = sch.LoadToStats(nil, qs, &out, iri("missing"))
+	if err != nil {
+		t.Fatal(err)
+	} else if st != (schema.LoadStats{}) || len(out) != 0 {
+		t.Errorf("unexpected result for missing nodes: %+v, %v", st, out)
+	}
+
+	var one node
+	st, err = sch.LoadToStats(nil, qs, &one, iri("a"))
+	if err != nil {
+		t.Fatal(err)
+	} else if st != (schema.LoadStats{Loaded: 1}) {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestObjectCache(t *testing.T) {
+	type node struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+	}
+	ms := memstore.New(quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")})
+	qs := graphtest.NewRecorder(ms)
+	cache := schema.NewCache(schema.NewConfig(), qs, 10)
+
+	var got node
+	if err := cache.Get(nil, &got, iri("a")); err != nil {
+		t.Fatal(err)
+	} else if got != (node{ID: "a", Name: "A"}) {
+		t.Fatalf("unexpected object: %+v", got)
+	} else if len(qs.Ops()) == 0 {
+		t.Fatal("expected the first load to access the store")
+	}
+
+	qs.Reset()
+	got = node{}
+	if err := cache.Get(nil, &got, iri("a")); err != nil {
+		t.Fatal(err)
+	} else if got != (node{ID: "a", Name: "A"}) {
+		t.Fatalf("unexpected cached object: %+v", got)
+	} else if ops := qs.Ops(); len(ops) != 0 {
+		t.Fatalf("expected no store access, got: %v", ops)
+	}
+
+	err := ms.ApplyDeltas([]graph.Delta{
+		{Action: graph.Delete, Quad: quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")}},
+		{Action: graph.Add, Quad: quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("B")}},
+	}, graph.IgnoreOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Invalidate(iri("a"))
+	if err := cache.Get(nil, &got, iri("a")); err != nil {
+		t.Fatal(err)
+	} else if got != (node{ID: "a", Name: "B"}) {
+		t.Fatalf("expected a reloaded object, got: %+v", got)
+	} else if len(qs.Ops()) == 0 {
+		t.Fatal("expected the store to be accessed after invalidation")
+	}
+
+	if err := cache.Get(nil, &got, iri("missing")); err == nil {
+		t.Fatal("expected an error for a missing object")
+	}
+
+	// objects are always loaded if caching is disabled
+	cache = schema.NewCache(schema.NewConfig(), qs, 0)
+	for i := 0; i < 2; i++ {
+		qs.Reset()
+		if err := cache.Get(nil, &got, iri("a")); err != nil {
+			t.Fatal(err)
+		} else if len(qs.Ops()) == 0 {
+			t.Fatal("expected the store to be accessed")
+		}
+	}
+	cache.Invalidate(iri("a"))
+	cache.Reset()
+}
+
+// hookStore calls a function on each ValueOf call.
+type hookStore struct {
+	graph.QuadStore
+	hook func()
+}
+
+func (qs hookStore) ValueOf(v quad.Value) graph.Value {
+	qs.hook()
+	return qs.QuadStore.ValueOf(v)
+}
+
+func TestObjectCacheInvalidateDuringLoad(t *testing.T) {
+	type node struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+	}
+	ms := memstore.New(quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")})
+	var (
+		cache       *schema.Cache
+		calls       int
+		invalidated bool
+	)
+	qs := hookStore{QuadStore: ms, hook: func() {
+		calls++
+		if !invalidated {
+			// concurrent write that happens while the object is loaded
+			invalidated = true
+			cache.Invalidate(iri("a"))
+		}
+	}}
+	cache = schema.NewCache(schema.NewConfig(), qs, 10)
+
+	var got node
+	if err := cache.Get(nil, &got, iri("a")); err != nil {
+		t.Fatal(err)
+	}
+	// the object could be stale, thus it must not be cached
+	calls = 0
+	if err := cache.Get(nil, &got, iri("a")); err != nil {
+		t.Fatal(err)
+	} else if calls == 0 {
+		t.Fatal("expected the object to be loaded again")
+	}
+	calls = 0
+	if err := cache.Get(nil, &got, iri("a")); err != nil {
+		t.Fatal(err)
+	} else if calls != 0 {
+		t.Fatal("expected the object to be cached")
+	}
+}
+
+func TestExistsMany(t *testing.T) {
+	type node struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+	}
+	ms := memstore.New(
+		quad.Quad{Subject: iri("a"), Predicate: iri("name"), Object: quad.String("A")},
+		quad.Quad{Subject: iri("b"), Predicate: iri("name"), Object: quad.String("B")},
+		quad.Quad{Subject: iri("c"), Predicate: iri("age"), Object: quad.Int(3)},
+	)
+	qs := graphtest.NewRecorder(ms)
+	sch := schema.NewConfig()
+
+	got, err := sch.ExistsMany(nil, qs, node{}, []quad.Value{iri("a"), iri("c"), iri("d"), iri("b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[quad.Value]bool{iri("a"): true, iri("b"): true, iri("c"): false, iri("d"): false}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected result: %v, expected: %v", got, exp)
+	}
+	// all ids are checked by a single scan of the predicate
+	if n := qs.Count("QuadIterator"); n != 1 {
+		t.Errorf("expected a single scan, got %d: %v", n, qs.Ops())
+	}
+	if n := qs.Count("NodesAllIterator"); n != 0 {
+		t.Errorf("unexpected scan of all nodes: %v", qs.Ops())
+	}
+
+	if _, err = sch.ExistsMany(nil, qs, "a", []quad.Value{iri("a")}); err == nil {
+		t.Error("expected an error for a non-struct type")
+	}
+}
+
+func TestWriteSession(t *testing.T) {
+	type child struct {
+		Name string `quad:"name"`
+	}
+	type parent struct {
+		Name     string   `quad:"name"`
+		Children []child  `quad:"child"`
+		Items    []string `quad:"items,list"`
+	}
+	objs := []parent{
+		{Name: "a", Children: []child{{Name: "b"}, {Name: "c"}}, Items: []string{"x", "y"}},
+		{Name: "d", Items: []string{"z"}},
+	}
+	sch := schema.NewConfig()
+	write := func() quadSlice {
+		var out quadSlice
+		s := sch.WriteSession(&out)
+		for _, o := range objs {
+			if _, err := s.WriteAsQuads(o); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return out
+	}
+	out1, out2 := write(), write()
+	if !reflect.DeepEqual(out1, out2) {
+		t.Fatalf("sessions produced different quads:\n%v\n%v", out1, out2)
+	}
+	seen := make(map[quad.Value]struct{})
+	for _, q := range out1 {
+		if b, ok := q.Subject.(quad.BNode); !ok || !strings.HasPrefix(string(b), "s") {
+			t.Errorf("unexpected subject: %v", q.Subject)
+		}
+		seen[q.Subject] = struct{}{}
+	}
+	// 2 parents, 2 children and 3 list nodes
+	if len(seen) != 7 {
+		t.Errorf("expected 7 distinct blank nodes, got %d", len(seen))
+	}
+}
+
+func TestDerivedFields(t *testing.T) {
+	type person struct {
+		ID   quad.IRI `quad:"@id"`
+		Name string   `quad:"name"`
+	}
+	sch := schema.NewConfig()
+	sch.Derived = []schema.DerivedField{{
+		Pred: "normName",
+		Value: func(o interface{}) (quad.Value, bool) {
+			p, ok := o.(person)
+			if !ok {
+				return nil, false
+			}
+			norm := strings.ToLower(p.Name)
+			return quad.String(norm), norm != p.Name
+		},
+	}}
+
+	var out quadSlice
+	if _, err := sch.WriteAsQuads(&out, person{ID: "bob", Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	exp := quadSlice{
+		{Subject: iri("bob"), Predicate: iri("name"), Object: quad.String("Bob")},
+		{Subject: iri("bob"), Predicate: iri("normName"), Object: quad.String("bob")},
+	}
+	if !reflect.DeepEqual(out, exp) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, exp)
+	}
+
+	out = nil
+	// already normalized names are not duplicated
+	if _, err := sch.WriteAsQuads(&out, person{ID: "alice", Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	exp = quadSlice{
+		{Subject: iri("alice"), Predicate: iri("name"), Object: quad.String("alice")},
+	}
+	if !reflect.DeepEqual(out, exp) {
+		t.Fatalf("unexpected quads:\n%v\nexpected:\n%v", out, exp)
+	}
+}
@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/quad"
+)
+
+// Tx accumulates quads of multiple objects written or deleted with a schema config,
+// so they can be applied to a quad store in a single atomic transaction.
+type Tx struct {
+	c  *Config
+	tx *graph.Transaction
+}
+
+// NewTx creates a new schema transaction that uses the global config.
+func NewTx() *Tx {
+	return global.NewTx()
+}
+
+// NewTx creates a new schema transaction that uses this config.
+func (c *Config) NewTx() *Tx {
+	// conflicts are never ignored, regardless of the writer settings; see Apply
+	return &Tx{c: c, tx: graph.NewTransactionWithOptions(graph.IgnoreOpts{})}
+}
+
+// collect converts an object to quads. Nothing is returned if the conversion fails.
+func (tx *Tx) collect(o interface{}) ([]quad.Quad, quad.Value, error) {
+	var buf quadBuffer
+	id, err := tx.c.WriteAsQuads(&buf, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf, id, nil
+}
+
+// WriteAsQuads adds quads of a given object to the transaction. See Config.WriteAsQuads.
+func (tx *Tx) WriteAsQuads(o interface{}) (quad.Value, error) {
+	quads, id, err := tx.collect(o)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range quads {
+		tx.tx.AddQuad(q)
+	}
+	return id, nil
+}
+
+// DeleteAsQuads adds removal of quads of a given object to the transaction.
+// The object must have an ID, and quads are generated the same way as in Config.WriteAsQuads.
+// It returns ErrReqFieldNotSet if the ID is not set.
+func (tx *Tx) DeleteAsQuads(o interface{}) (quad.Value, error) {
+	if err := tx.requireID(o); err != nil {
+		return nil, err
+	}
+	quads, id, err := tx.collect(o)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range quads {
+		tx.tx.RemoveQuad(q)
+	}
+	return id, nil
+}
+
+// requireID checks that an object has one of its ID fields set. Otherwise, a random ID would be generated
+// for it, and the delete would silently do nothing.
+func (tx *Tx) requireID(o interface{}) error {
+	if _, ok := o.(quad.Value); ok {
+		return nil
+	}
+	rv := reflect.ValueOf(o)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		// let the writer report an error
+		return nil
+	}
+	rules, err := tx.c.rulesFor(rv.Type())
+	if err != nil {
+		return fmt.Errorf("can't load rules: %v", err)
+	}
+	names := rules.idFields()
+	if len(names) == 0 {
+		return ErrReqFieldNotSet{Field: "@id"}
+	}
+	for _, name := range names {
+		if fv, ok := fieldByRule(rv, name); ok && !isZero(fv) {
+			return nil
+		}
+	}
+	return ErrReqFieldNotSet{Field: names[0]}
+}
+
+// Transaction returns the underlying graph transaction.
+func (tx *Tx) Transaction() *graph.Transaction {
+	return tx.tx
+}
+
+// Apply applies all accumulated changes using a quad writer in a single transaction.
+// Either all changes are applied, or none of them are, if the store supports atomic writes.
+//
+// The transaction fails if an added quad already exists or a removed quad is missing,
+// even if the writer is configured to ignore such conflicts.
+func (tx *Tx) Apply(w graph.QuadWriter) error {
+	return w.ApplyTransaction(tx.tx)
+}
+
+type quadBuffer []quad.Quad
+
+func (b *quadBuffer) WriteQuad(q quad.Quad) error {
+	*b = append(*b, q)
+	return nil
+}
@@ -23,6 +23,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -101,9 +103,13 @@ func (api *APIv2) RegisterQueryOn(r *httprouter.Router, wrappers ...HandlerWrapp
 	r.POST("/api/v2/query", wrap(api.ServeQuery, wrappers))
 	r.GET("/api/v2/query", wrap(api.ServeQuery, wrappers))
 }
+func (api *APIv2) RegisterHealthOn(r *httprouter.Router, wrappers ...HandlerWrapper) {
+	r.GET("/health", wrap(api.ServeHealth, wrappers))
+}
 func (api *APIv2) RegisterOn(r *httprouter.Router, wrappers ...HandlerWrapper) {
 	api.RegisterDataOn(r, wrappers...)
 	api.RegisterQueryOn(r, wrappers...)
+	api.RegisterHealthOn(r, wrappers...)
 }
 
 const (
@@ -112,6 +118,7 @@ const (
 	hdrContentEncoding = "Content-Encoding"
 	hdrAccept          = "Accept"
 	hdrAcceptEncoding  = "Accept-Encoding"
+	hdrQueryTimeout    = "X-Query-Timeout"
 	contentTypeJSON    = "application/json"
 )
 
@@ -169,6 +176,22 @@ func (api *APIv2) handleForRequest(r *http.Request) (*graph.Handle, error) {
 	return HandleForRequest(api.h, api.wtyp, api.wopt, r)
 }
 
+// ServeHealth checks that the quad store backend is reachable. It responds with 503 status if it's not.
+func (api *APIv2) ServeHealth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if api.timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, api.timeout)
+		defer cancel()
+	}
+	if err := graph.Ping(ctx, api.h.QuadStore); err != nil {
+		jsonResponse(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	w.Header().Set(hdrContentType, contentTypeJSON)
+	w.Write([]byte(`{"result": "ok"}` + "\n"))
+}
+
 func (api *APIv2) ServeWrite(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	if api.ro {
@@ -361,14 +384,44 @@ func (api *APIv2) ServeFormats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(out)
 }
 
-func (api *APIv2) queryContext(r *http.Request) (ctx context.Context, cancel func()) {
-	ctx = context.TODO() // TODO(dennwc): get from request
-	if api.timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, api.timeout)
+// queryTimeout returns a timeout for a query request. Client may set a timeout with X-Query-Timeout
+// header either as a duration string ("1.5s") or as a number of seconds, but it cannot exceed
+// the timeout set for the server.
+func (api *APIv2) queryTimeout(r *http.Request) (time.Duration, error) {
+	s := r.Header.Get(hdrQueryTimeout)
+	if s == "" {
+		return api.timeout, nil
+	}
+	dt, err := time.ParseDuration(s)
+	if err != nil {
+		sec, err2 := strconv.ParseFloat(s, 64)
+		if err2 != nil {
+			return 0, fmt.Errorf("invalid query timeout: %q", s)
+		}
+		dt = time.Duration(sec * float64(time.Second))
+	}
+	if dt <= 0 {
+		return 0, fmt.Errorf("invalid query timeout: %q", s)
+	}
+	if api.timeout > 0 && dt > api.timeout {
+		dt = api.timeout
+	}
+	return dt, nil
+}
+
+func (api *APIv2) queryContext(r *http.Request) (ctx context.Context, cancel func(), err error) {
+	dt, err := api.queryTimeout(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	// request context is cancelled if the client goes away
+	ctx = r.Context()
+	if dt > 0 {
+		ctx, cancel = context.WithTimeout(ctx, dt)
 	} else {
 		ctx, cancel = context.WithCancel(ctx)
 	}
-	return ctx, cancel
+	return ctx, cancel, nil
 }
 
 func defaultErrorFunc(w query.ResponseWriter, err error) {
@@ -379,12 +432,79 @@ func defaultErrorFunc(w query.ResponseWriter, err error) {
 	w.Write([]byte("}\n"))
 }
 
-func writeResults(w io.Writer, r interface{}) {
+func timeoutErrorFunc(ctx context.Context, errFunc func(query.ResponseWriter, error)) func(query.ResponseWriter, error) {
+	return func(w query.ResponseWriter, err error) {
+		if ctx.Err() == context.DeadlineExceeded {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "query timeout"}` + "\n"))
+			return
+		}
+		errFunc(w, err)
+	}
+}
+
+func writeResults(w io.Writer, r interface{}, pretty bool) {
+	r = resultValue(r)
+	if pretty {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]interface{}{"result": r})
+		return
+	}
 	w.Write([]byte(`{"result": `))
 	json.NewEncoder(w).Encode(r)
 	w.Write([]byte("}\n"))
 }
 
+// resultValue converts quad values in query results to their JSON form as produced by quad.MarshalJSONValue.
+// Values that cannot be encoded this way are converted to strings. Maps with string keys and slices are converted recursively.
+func resultValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case quad.Value:
+		data, err := quad.MarshalJSONValue(v)
+		if err != nil {
+			return v.String()
+		}
+		return json.RawMessage(data)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, sv := range v {
+			out[k] = resultValue(sv)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, sv := range v {
+			out[i] = resultValue(sv)
+		}
+		return out
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		out := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			out[k.String()] = resultValue(rv.MapIndex(k).Interface())
+		}
+		return out
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = resultValue(rv.Index(i).Interface())
+		}
+		return out
+	}
+	return v
+}
+
 const maxQuerySize = 1024 * 1024 // 1 MB
 func readLimit(r io.Reader) ([]byte, error) {
 	lr := io.LimitReader(r, maxQuerySize).(*io.LimitedReader)
@@ -396,7 +516,11 @@ func readLimit(r io.Reader) ([]byte, error) {
 }
 
 func (api *APIv2) ServeQuery(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := api.queryContext(r)
+	ctx, cancel, err := api.queryContext(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
 	defer cancel()
 	vals := r.URL.Query()
 	lang := vals.Get("lang")
@@ -419,6 +543,8 @@ func (api *APIv2) ServeQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	default:
 	}
+	// report query timeouts with a separate status code
+	errFunc = timeoutErrorFunc(ctx, errFunc)
 	h, err := api.handleForRequest(r)
 	if err != nil {
 		errFunc(w, err)
@@ -466,10 +592,15 @@ func (api *APIv2) ServeQuery(w http.ResponseWriter, r *http.Request) {
 		}
 		ses.Collate(res)
 	}
+	if err := ctx.Err(); err != nil {
+		// query was interrupted; results are incomplete
+		errFunc(w, err)
+		return
+	}
 	output, err := ses.Results()
 	if err != nil {
 		errFunc(w, err)
 		return
 	}
-	writeResults(w, output)
+	writeResults(w, output, vals.Get("pretty") != "" && vals.Get("pretty") != "0")
 }
@@ -1,15 +1,23 @@
 package cayleyhttp
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/caivega/cayley/client"
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/graph/graphtest"
 	"github.com/caivega/cayley/graph/memstore"
 	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/query"
 	"github.com/caivega/cayley/writer"
 	"github.com/stretchr/testify/require"
 )
@@ -64,3 +72,170 @@ func TestV2Read(t *testing.T) {
 	sort.Sort(quad.ByQuadString(expect))
 	require.Equal(t, expect, quads)
 }
+
+// slowSession iterates over all nodes, spending some time on each of them.
+type slowSession struct {
+	qs graph.QuadStore
+}
+
+func (s *slowSession) Execute(ctx context.Context, qu string, out chan query.Result, limit int) {
+	defer close(out)
+	it := s.qs.NodesAllIterator()
+	defer it.Close()
+	err := graph.Iterate(ctx, it).Each(func(graph.Value) {
+		time.Sleep(10 * time.Millisecond)
+	})
+	if err != nil {
+		out <- query.ErrorResult(err)
+	}
+}
+func (s *slowSession) ShapeOf(string) (interface{}, error) { return nil, nil }
+func (s *slowSession) Collate(query.Result)                {}
+func (s *slowSession) Results() (interface{}, error)       { return "done", nil }
+
+func init() {
+	query.RegisterLanguage(query.Language{
+		Name: "slowtest",
+		HTTP: func(qs graph.QuadStore) query.HTTP {
+			return &slowSession{qs: qs}
+		},
+	})
+}
+
+func TestV2QueryTimeout(t *testing.T) {
+	var quads []quad.Quad
+	for i := 0; i < 100; i++ {
+		quads = append(quads, quad.MakeIRI(fmt.Sprintf("n%d", i), "p", "o", ""))
+	}
+	h := makeHandle(t, quads...)
+	defer h.Close()
+	api := NewAPIv2(h)
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	doQuery := func(timeout string) (int, time.Duration) {
+		req, err := http.NewRequest("GET", srv.URL+"/api/v2/query?lang=slowtest&qu=x", nil)
+		require.NoError(t, err)
+		if timeout != "" {
+			req.Header.Set(hdrQueryTimeout, timeout)
+		}
+		start := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode, time.Since(start)
+	}
+
+	// timeout from the request header
+	code, dt := doQuery("50ms")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.True(t, dt < 500*time.Millisecond, "query was not cancelled: %v", dt)
+
+	code, _ = doQuery("nope")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// default timeout of the server
+	api.SetQueryTimeout(50 * time.Millisecond)
+	code, dt = doQuery("")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.True(t, dt < 500*time.Millisecond, "query was not cancelled: %v", dt)
+
+	// request cannot extend the server timeout
+	code, dt = doQuery("1m")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.True(t, dt < 500*time.Millisecond, "query was not cancelled: %v", dt)
+}
+
+// deadStore is a quad store with unreachable backend.
+type deadStore struct {
+	graph.QuadStore
+}
+
+func (deadStore) Ping(ctx context.Context) error { return errors.New("connection refused") }
+
+func TestV2Health(t *testing.T) {
+	h := makeHandle(t)
+	defer h.Close()
+	api := NewAPIv2(h)
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	h.QuadStore = deadStore{h.QuadStore}
+	resp, err = http.Get(srv.URL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+// valuesSession returns a fixed set of quad values as a result.
+type valuesSession struct{}
+
+func (valuesSession) Execute(ctx context.Context, qu string, out chan query.Result, limit int) {
+	close(out)
+}
+func (valuesSession) ShapeOf(string) (interface{}, error) { return nil, nil }
+func (valuesSession) Collate(query.Result)                {}
+func (valuesSession) Results() (interface{}, error) {
+	return []interface{}{
+		map[string]quad.Value{"id": quad.IRI("http://example.org/a")},
+	}, nil
+}
+
+func init() {
+	query.RegisterLanguage(query.Language{
+		Name: "valuestest",
+		HTTP: func(qs graph.QuadStore) query.HTTP {
+			return valuesSession{}
+		},
+	})
+}
+
+func TestV2QueryPretty(t *testing.T) {
+	addr, closer := makeServerV2(t)
+	defer closer()
+
+	doQuery := func(pretty string) string {
+		resp, err := http.Get(addr + "/api/v2/query?lang=valuestest&qu=x" + pretty)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(data)
+	}
+	require.Equal(t, `{"result": [{"id":"http://example.org/a"}]`+"\n}\n", doQuery(""))
+	require.Equal(t, `{
+  "result": [
+    {
+      "id": "http://example.org/a"
+    }
+  ]
+}
+`, doQuery("&pretty=1"))
+}
+
+func TestResultValue(t *testing.T) {
+	tm := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, c := range []struct {
+		val    quad.Value
+		expect string
+	}{
+		{quad.IRI("http://example.org/a"), `{"@id":"http://example.org/a"}`},
+		{quad.BNode("b1"), `{"@id":"_:b1"}`},
+		{quad.String("text"), `{"@value":"text"}`},
+		{quad.TypedString{Value: "1", Type: "http://example.org/num"}, `{"@value":"1","@type":"http://example.org/num"}`},
+		{quad.LangString{Value: "hello", Lang: "en"}, `{"@value":"hello","@lang":"en"}`},
+		{quad.Int(42), `{"@value":"42","@type":"http://schema.org/Integer"}`},
+		{quad.Float(1.5), `{"@value":"1.5","@type":"http://schema.org/Float"}`},
+		{quad.Bool(true), `{"@value":"true","@type":"http://schema.org/Boolean"}`},
+		{quad.Time(tm), `{"@value":"2018-01-02T03:04:05Z","@type":"http://schema.org/DateTime"}`},
+	} {
+		data, err := json.Marshal(resultValue(c.val))
+		require.NoError(t, err)
+		require.Equal(t, c.expect, string(data), "%T", c.val)
+	}
+}
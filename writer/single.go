@@ -15,6 +15,11 @@
 package writer
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/caivega/cayley/graph"
 	"github.com/caivega/cayley/quad"
 )
@@ -23,9 +28,39 @@ func init() {
 	graph.RegisterWriter("single", NewSingleReplication)
 }
 
+// DeltaFunc is a callback that observes deltas applied by the writer.
+type DeltaFunc func(ctx context.Context, deltas []graph.Delta) error
+
+// ErrObservers is returned when deltas were applied successfully, but some of the observers failed.
+type ErrObservers struct {
+	Errs []error
+}
+
+func (e ErrObservers) Error() string {
+	if len(e.Errs) == 1 {
+		return fmt.Sprintf("delta observer failed: %v", e.Errs[0])
+	}
+	arr := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		arr = append(arr, err.Error())
+	}
+	return fmt.Sprintf("%d delta observers failed: %s", len(e.Errs), strings.Join(arr, "; "))
+}
+
+// OptMaxTxSize is the Options key for the maximal number of deltas applied in a single transaction.
+// See Single.SetMaxTxSize for details.
+const OptMaxTxSize = "max_tx_size"
+
 type Single struct {
 	qs         graph.QuadStore
 	ignoreOpts graph.IgnoreOpts
+	maxTxSize  int
+
+	mu        sync.RWMutex
+	observers []DeltaFunc
+	closed    bool
+	// writes tracks writes in progress, so Close can wait for them
+	writes sync.WaitGroup
 }
 
 func NewSingle(qs graph.QuadStore, opts graph.IgnoreOpts) (graph.QuadWriter, error) {
@@ -46,10 +81,96 @@ func NewSingleReplication(qs graph.QuadStore, opts graph.Options) (graph.QuadWri
 		return nil, err
 	}
 
-	return NewSingle(qs, graph.IgnoreOpts{
+	maxTxSize, err := opts.IntKey(OptMaxTxSize, 0)
+	if err != nil {
+		return nil, err
+	} else if maxTxSize < 0 {
+		return nil, fmt.Errorf("writer: invalid %s: %d", OptMaxTxSize, maxTxSize)
+	}
+
+	w, err := NewSingle(qs, graph.IgnoreOpts{
 		IgnoreMissing: ignoreMissing,
 		IgnoreDup:     ignoreDuplicate,
 	})
+	if err != nil {
+		return nil, err
+	}
+	w.(*Single).SetMaxTxSize(maxTxSize)
+	return w, nil
+}
+
+// SetMaxTxSize sets the maximal number of deltas applied to the quad store in a single transaction.
+// Larger delta sets written with AddQuadSet are split into chunks of at most n deltas that are committed in sequence.
+// Zero or negative value disables splitting.
+//
+// This allows to stay within the limits of backends that restrict the size of a single write.
+// Note that atomicity is then guaranteed only per chunk: if one of the chunks fails, all previous
+// chunks remain applied and the rest of the deltas is not applied. Observers are notified
+// of each committed chunk separately.
+//
+// Explicit transactions passed to ApplyTransaction are never split.
+func (s *Single) SetMaxTxSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	s.mu.Lock()
+	s.maxTxSize = n
+	s.mu.Unlock()
+}
+
+// OnDelta registers a callback that will be called after deltas are successfully applied to the quad store.
+//
+// Callbacks are called in the order of registration. Errors returned by callbacks do not affect
+// the applied deltas; they are returned by the write method as ErrObservers.
+func (s *Single) OnDelta(fnc DeltaFunc) {
+	s.mu.Lock()
+	s.observers = append(s.observers, fnc)
+	s.mu.Unlock()
+}
+
+func (s *Single) applyDeltas(deltas []graph.Delta) error {
+	return s.applyDeltasOpts(deltas, s.ignoreOpts, true)
+}
+
+// applyDeltasOpts applies deltas to the quad store and notifies observers.
+// If split is set, deltas are committed in chunks of at most maxTxSize.
+func (s *Single) applyDeltasOpts(deltas []graph.Delta, opts graph.IgnoreOpts, split bool) error {
+	s.mu.RLock()
+	observers := s.observers
+	max := s.maxTxSize
+	closed := s.closed
+	if !closed {
+		// registered under the lock, thus Close will wait for this write
+		s.writes.Add(1)
+	}
+	s.mu.RUnlock()
+	if closed {
+		return graph.ErrWriterClosed
+	}
+	defer s.writes.Done()
+	if !split || max <= 0 || len(deltas) <= max {
+		max = len(deltas)
+	}
+	var errs []error
+	for first := true; first || len(deltas) != 0; first = false {
+		chunk := deltas
+		if len(chunk) > max {
+			chunk = chunk[:max]
+		}
+		deltas = deltas[len(chunk):]
+		if err := s.qs.ApplyDeltas(chunk, opts); err != nil {
+			return err
+		}
+		for _, fnc := range observers {
+			if err := fnc(context.Background(), chunk); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return ErrObservers{Errs: errs}
+	}
+	return nil
 }
 
 func (s *Single) AddQuad(q quad.Quad) error {
@@ -58,7 +179,7 @@ func (s *Single) AddQuad(q quad.Quad) error {
 		Quad:   q,
 		Action: graph.Add,
 	}
-	return s.qs.ApplyDeltas(deltas, s.ignoreOpts)
+	return s.applyDeltas(deltas)
 }
 
 func (s *Single) AddQuadSet(set []quad.Quad) error {
@@ -66,7 +187,7 @@ func (s *Single) AddQuadSet(set []quad.Quad) error {
 	for _, q := range set {
 		tx.AddQuad(q)
 	}
-	return s.qs.ApplyDeltas(tx.Deltas, s.ignoreOpts)
+	return s.applyDeltas(tx.Deltas)
 }
 
 func (s *Single) RemoveQuad(q quad.Quad) error {
@@ -75,13 +196,19 @@ func (s *Single) RemoveQuad(q quad.Quad) error {
 		Quad:   q,
 		Action: graph.Delete,
 	}
-	return s.qs.ApplyDeltas(deltas, s.ignoreOpts)
+	return s.applyDeltas(deltas)
 }
 
 // RemoveNode removes all quads with the given value.
 //
 // It returns ErrNodeNotExists if node is missing.
 func (s *Single) RemoveNode(v quad.Value) error {
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return graph.ErrWriterClosed
+	}
 	gv := s.qs.ValueOf(v)
 	if gv == nil {
 		return graph.ErrNodeNotExists
@@ -109,11 +236,22 @@ func (s *Single) RemoveNode(v quad.Value) error {
 	return nil
 }
 
+// Close marks the writer as closed and waits for writes that are already in progress.
+// The writer applies all deltas immediately, thus there is nothing to flush.
+// Close is idempotent, and all writes started after it fail with graph.ErrWriterClosed.
 func (s *Single) Close() error {
-	// Nothing to clean up locally.
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.writes.Wait()
 	return nil
 }
 
+// ApplyTransaction applies all deltas of the transaction atomically, regardless of the max transaction size.
 func (s *Single) ApplyTransaction(t *graph.Transaction) error {
-	return s.qs.ApplyDeltas(t.Deltas, s.ignoreOpts)
+	opts, ok := t.Options()
+	if !ok {
+		opts = s.ignoreOpts
+	}
+	return s.applyDeltasOpts(t.Deltas, opts, false)
 }
@@ -0,0 +1,217 @@
+package writer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/caivega/cayley/graph"
+	"github.com/caivega/cayley/graph/memstore"
+	"github.com/caivega/cayley/quad"
+	"github.com/caivega/cayley/writer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleOnDelta(t *testing.T) {
+	qs := memstore.New()
+	w, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+	s := w.(*writer.Single)
+
+	var got1, got2 [][]graph.Delta
+	s.OnDelta(func(_ context.Context, deltas []graph.Delta) error {
+		got1 = append(got1, deltas)
+		return nil
+	})
+	s.OnDelta(func(_ context.Context, deltas []graph.Delta) error {
+		got2 = append(got2, deltas)
+		return nil
+	})
+
+	q1 := quad.MakeIRI("a", "follows", "b", "")
+	q2 := quad.MakeIRI("b", "follows", "c", "")
+	require.NoError(t, w.AddQuadSet([]quad.Quad{q1, q2}))
+	require.NoError(t, w.RemoveQuad(q1))
+
+	// failed writes are not observed
+	require.Error(t, w.RemoveQuad(q1))
+
+	exp := [][]graph.Delta{
+		{{Quad: q1, Action: graph.Add}, {Quad: q2, Action: graph.Add}},
+		{{Quad: q1, Action: graph.Delete}},
+	}
+	require.Equal(t, exp, got1)
+	require.Equal(t, exp, got2)
+}
+
+func TestSingleOnDeltaError(t *testing.T) {
+	qs := memstore.New()
+	w, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+	s := w.(*writer.Single)
+
+	errObs := errors.New("observer failed")
+	called := 0
+	s.OnDelta(func(_ context.Context, deltas []graph.Delta) error {
+		return errObs
+	})
+	s.OnDelta(func(_ context.Context, deltas []graph.Delta) error {
+		called++
+		return nil
+	})
+
+	q := quad.MakeIRI("a", "follows", "b", "")
+	err = w.AddQuad(q)
+	require.Equal(t, writer.ErrObservers{Errs: []error{errObs}}, err)
+	require.Equal(t, 1, called)
+
+	// the write is not rolled back
+	it := qs.QuadsAllIterator()
+	defer it.Close()
+	require.True(t, it.Next(context.TODO()))
+	require.Equal(t, q, qs.Quad(it.Result()))
+}
+
+func TestSingleTxOptions(t *testing.T) {
+	q1 := quad.MakeIRI("a", "follows", "b", "")
+	q2 := quad.MakeIRI("b", "follows", "c", "")
+
+	for _, c := range []struct {
+		name   string
+		opts   graph.IgnoreOpts
+		delete bool
+		check  func(err error) bool
+	}{
+		{name: "strict delete", delete: true, check: graph.IsQuadNotExist},
+		{name: "lenient delete", delete: true, opts: graph.IgnoreOpts{IgnoreMissing: true}},
+		{name: "strict add", check: graph.IsQuadExist},
+		{name: "lenient add", opts: graph.IgnoreOpts{IgnoreDup: true}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			qs := memstore.New(q1)
+			// writer defaults are lenient, but transaction options override them
+			w, err := writer.NewSingle(qs, graph.IgnoreOpts{IgnoreMissing: true, IgnoreDup: true})
+			require.NoError(t, err)
+
+			tx := graph.NewTransactionWithOptions(c.opts)
+			if c.delete {
+				tx.RemoveQuad(q2)
+			} else {
+				tx.AddQuad(q1)
+			}
+			err = w.ApplyTransaction(tx)
+			if c.check != nil {
+				require.True(t, c.check(err), "unexpected error: %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+type countingStore struct {
+	graph.QuadStore
+	commits []int
+}
+
+func (qs *countingStore) ApplyDeltas(deltas []graph.Delta, opts graph.IgnoreOpts) error {
+	qs.commits = append(qs.commits, len(deltas))
+	return qs.QuadStore.ApplyDeltas(deltas, opts)
+}
+
+func TestSingleMaxTxSize(t *testing.T) {
+	qs := &countingStore{QuadStore: memstore.New()}
+	w, err := writer.NewSingleReplication(qs, graph.Options{writer.OptMaxTxSize: 3})
+	require.NoError(t, err)
+
+	var observed []int
+	w.(*writer.Single).OnDelta(func(_ context.Context, deltas []graph.Delta) error {
+		observed = append(observed, len(deltas))
+		return nil
+	})
+
+	var quads []quad.Quad
+	for i := 0; i < 8; i++ {
+		quads = append(quads, quad.Make(quad.IRI("a"), quad.IRI("value"), quad.Int(i), nil))
+	}
+	require.NoError(t, w.AddQuadSet(quads))
+	require.Equal(t, []int{3, 3, 2}, qs.commits)
+	require.Equal(t, qs.commits, observed)
+
+	var got []quad.Quad
+	it := qs.QuadsAllIterator()
+	defer it.Close()
+	for it.Next(context.TODO()) {
+		got = append(got, qs.Quad(it.Result()))
+	}
+	require.NoError(t, it.Err())
+	require.ElementsMatch(t, quads, got)
+
+	// small writes are applied in a single transaction
+	qs.commits = nil
+	require.NoError(t, w.RemoveQuad(quads[0]))
+	require.Equal(t, []int{1}, qs.commits)
+
+	// explicit transactions are never split
+	qs.commits = nil
+	tx := graph.NewTransaction()
+	for _, q := range quads[1:] {
+		tx.RemoveQuad(q)
+	}
+	require.NoError(t, w.ApplyTransaction(tx))
+	require.Equal(t, []int{7}, qs.commits)
+
+	_, err = writer.NewSingleReplication(qs, graph.Options{writer.OptMaxTxSize: -1})
+	require.Error(t, err)
+}
+
+func TestSingleClose(t *testing.T) {
+	qs := memstore.New()
+	w, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+
+	q := quad.MakeIRI("a", "follows", "b", "")
+	require.NoError(t, w.AddQuad(q))
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+
+	require.Equal(t, graph.ErrWriterClosed, w.AddQuad(quad.MakeIRI("b", "follows", "c", "")))
+	require.Equal(t, graph.ErrWriterClosed, w.RemoveQuad(q))
+	require.Equal(t, graph.ErrWriterClosed, w.RemoveNode(quad.IRI("a")))
+	all, err := graph.Iterate(context.TODO(), qs.QuadsAllIterator()).All()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+}
+
+func TestSingleCloseWaits(t *testing.T) {
+	qs := memstore.New()
+	w, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	w.(*writer.Single).OnDelta(func(_ context.Context, _ []graph.Delta) error {
+		close(started)
+		<-release
+		return nil
+	})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- w.AddQuad(quad.MakeIRI("a", "follows", "b", ""))
+	}()
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("close returned before the write finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-closed
+	require.NoError(t, <-errc)
+}